@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/osbuild/images/pkg/ostree"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] refs|show|log\n\n", os.Args[0])
+	fmt.Fprintln(flag.CommandLine.Output(), "  refs  list the refs of the repository")
+	fmt.Fprintln(flag.CommandLine.Output(), "  show  show the metadata of the commit of -ref")
+	fmt.Fprintln(flag.CommandLine.Output(), "  log   show the metadata of the commit of -ref and its parents")
+	fmt.Fprintln(flag.CommandLine.Output())
+	flag.PrintDefaults()
+}
+
+func printCommit(w io.Writer, info ostree.CommitInfo) {
+	fmt.Fprintf(w, "commit %s\n", info.Checksum)
+	if info.Parent != "" {
+		fmt.Fprintf(w, "Parent:  %s\n", info.Parent)
+	}
+	fmt.Fprintf(w, "Date:    %s\n", info.Timestamp.Format(time.RFC3339))
+	if info.Version != "" {
+		fmt.Fprintf(w, "Version: %s\n", info.Version)
+	}
+	if info.Bootable {
+		fmt.Fprintf(w, "Kernel:  %s\n", info.KernelVersion)
+	}
+	fmt.Fprintf(w, "\n    %s\n", info.Subject)
+	if info.Body != "" {
+		fmt.Fprintf(w, "\n    %s\n", info.Body)
+	}
+	fmt.Fprintln(w)
+}
+
+func run(w io.Writer, command string, source ostree.SourceSpec, depth int, asJSON bool) error {
+	var result interface{}
+	switch command {
+	case "refs":
+		refs, err := ostree.ListRefs(source)
+		if err != nil {
+			return err
+		}
+		if !asJSON {
+			for _, ref := range refs {
+				fmt.Fprintf(w, "%s %s\n", ref.Checksum, ref.Name)
+			}
+			return nil
+		}
+		result = refs
+	case "show":
+		info, err := ostree.InspectCommit(source)
+		if err != nil {
+			return err
+		}
+		if !asJSON {
+			printCommit(w, info)
+			return nil
+		}
+		result = info
+	case "log":
+		history, err := ostree.CommitHistory(source, depth)
+		if err != nil {
+			return err
+		}
+		if !asJSON {
+			for _, info := range history {
+				printCommit(w, info)
+			}
+			return nil
+		}
+		result = history
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal result: %w", err)
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	return nil
+}
+
+func main() {
+	var source ostree.SourceSpec
+	var ca, clientCert, clientKey string
+	var depth int
+	var asJSON bool
+
+	flag.StringVar(&source.URL, "url", "", "URL of the ostree repository (required)")
+	flag.StringVar(&source.Ref, "ref", "", "ref or commit checksum to show (required for show and log)")
+	flag.BoolVar(&source.RHSM, "rhsm", false, "use the RHSM consumer certificates of the system to access the repository")
+	flag.StringVar(&ca, "ca", "", "CA certificate of the repository for mtls")
+	flag.StringVar(&clientCert, "client-cert", "", "client certificate for mtls")
+	flag.StringVar(&clientKey, "client-key", "", "client key for mtls")
+	flag.StringVar(&source.Proxy, "proxy", "", "HTTP proxy to access the repository")
+	flag.IntVar(&depth, "depth", 0, "maximum number of commits to show for log, all if 0")
+	flag.BoolVar(&asJSON, "json", false, "print the result as JSON")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 || source.URL == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if ca != "" || clientCert != "" || clientKey != "" {
+		source.MTLS = &ostree.MTLS{
+			CA:         ca,
+			ClientCert: clientCert,
+			ClientKey:  clientKey,
+		}
+	}
+
+	if err := run(os.Stdout, flag.Arg(0), source, depth, asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
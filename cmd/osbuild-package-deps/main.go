@@ -0,0 +1,244 @@
+// Tool to depsolve the packages of a specific distro x arch x image type and
+// print the dependency graph of the transaction, either in full or only the
+// chains that explain why a single package is installed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/osbuild/images/internal/buildconfig"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distrofactory"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/reporegistry"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/sbom"
+)
+
+// requirementNames returns the requirements of pkg that are provided by dep.
+func requirementNames(g *rpmmd.DependencyGraph, pkg, dep string) string {
+	var names []string
+	for _, req := range g.Requires[pkg] {
+		if req.ProvidedBy == dep {
+			names = append(names, req.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// dependencies returns the sorted, unique packages that pkg requires.
+func dependencies(g *rpmmd.DependencyGraph, pkg string) []string {
+	var deps []string
+	for _, req := range g.Requires[pkg] {
+		if req.ProvidedBy != pkg {
+			deps = append(deps, req.ProvidedBy)
+		}
+	}
+	slices.Sort(deps)
+	return slices.Compact(deps)
+}
+
+func printTree(w io.Writer, g *rpmmd.DependencyGraph) {
+	requested := slices.Clone(g.Requested)
+	slices.Sort(requested)
+
+	printed := make(map[string]bool)
+	var walk func(pkg, parent string, depth int)
+	walk = func(pkg, parent string, depth int) {
+		line := strings.Repeat("  ", depth) + pkg
+		if parent != "" {
+			line += fmt.Sprintf(" [%s]", requirementNames(g, parent, pkg))
+		}
+		if printed[pkg] {
+			// already expanded elsewhere in the tree
+			fmt.Fprintf(w, "%s (*)\n", line)
+			return
+		}
+		fmt.Fprintln(w, line)
+		printed[pkg] = true
+		for _, dep := range dependencies(g, pkg) {
+			walk(dep, pkg, depth+1)
+		}
+	}
+	for _, pkg := range slices.Compact(requested) {
+		walk(pkg, "", 0)
+	}
+}
+
+func printChains(w io.Writer, g *rpmmd.DependencyGraph, chains [][]string) {
+	for idx, chain := range chains {
+		if idx > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, chain[0])
+		for depth := 1; depth < len(chain); depth++ {
+			fmt.Fprintf(w, "%s%s [%s]\n", strings.Repeat("  ", depth), chain[depth], requirementNames(g, chain[depth-1], chain[depth]))
+		}
+	}
+}
+
+func printDOT(w io.Writer, g *rpmmd.DependencyGraph, chains [][]string) {
+	edges := make(map[[2]string]bool)
+	if chains == nil {
+		for _, pkg := range g.Packages() {
+			for _, dep := range dependencies(g, pkg) {
+				edges[[2]string{pkg, dep}] = true
+			}
+		}
+	}
+	for _, chain := range chains {
+		for idx := 1; idx < len(chain); idx++ {
+			edges[[2]string{chain[idx-1], chain[idx]}] = true
+		}
+	}
+
+	nodes := make(map[string]bool)
+	sortedEdges := make([][2]string, 0, len(edges))
+	for edge := range edges {
+		nodes[edge[0]] = true
+		nodes[edge[1]] = true
+		sortedEdges = append(sortedEdges, edge)
+	}
+	for _, chain := range chains {
+		// single package chains have no edges
+		nodes[chain[0]] = true
+	}
+	slices.SortFunc(sortedEdges, func(a, b [2]string) int {
+		if c := strings.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		return strings.Compare(a[1], b[1])
+	})
+
+	fmt.Fprintln(w, "digraph dependencies {")
+	for _, pkg := range g.Packages() {
+		if !nodes[pkg] && chains != nil {
+			continue
+		}
+		if g.IsRequested(pkg) {
+			fmt.Fprintf(w, "  %q [style=bold];\n", pkg)
+		} else {
+			fmt.Fprintf(w, "  %q;\n", pkg)
+		}
+	}
+	for _, edge := range sortedEdges {
+		fmt.Fprintf(w, "  %q -> %q [label=%q];\n", edge[0], edge[1], requirementNames(g, edge[0], edge[1]))
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func run() error {
+	var distroName, archName, imageName, pipelineName string
+	var repositories, rpmCacheRoot, configFile string
+	var pkgName, format string
+
+	flag.StringVar(&distroName, "distro", "", "distribution name (required)")
+	flag.StringVar(&archName, "arch", "", "architecture name (required)")
+	flag.StringVar(&imageName, "image", "", "image type name (required)")
+	flag.StringVar(&pipelineName, "pipeline", "os", "name of the pipeline whose packages are depsolved")
+	flag.StringVar(&repositories, "repositories", "test/data/repositories", "path to repository directory")
+	flag.StringVar(&rpmCacheRoot, "rpmmd", "/tmp/rpmmd", "rpm metadata cache directory")
+	flag.StringVar(&configFile, "config", "", "build config file with the blueprint to use")
+	flag.StringVar(&pkgName, "package", "", "only explain why this package (name or NEVRA) is installed")
+	flag.StringVar(&format, "format", "tree", "output format (tree or dot)")
+	flag.Parse()
+
+	if distroName == "" || archName == "" || imageName == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if format != "tree" && format != "dot" {
+		return fmt.Errorf("invalid output format %q, must be tree or dot", format)
+	}
+
+	d := distrofactory.NewDefault().GetDistro(distroName)
+	if d == nil {
+		return fmt.Errorf("invalid or unsupported distribution: %q", distroName)
+	}
+	arch, err := d.GetArch(archName)
+	if err != nil {
+		return fmt.Errorf("invalid arch name %q for distro %q: %w", archName, distroName, err)
+	}
+	imgType, err := arch.GetImageType(imageName)
+	if err != nil {
+		return fmt.Errorf("invalid image type %q for distro %q and arch %q: %w", imageName, distroName, archName, err)
+	}
+
+	bp := &blueprint.Blueprint{}
+	var options distro.ImageOptions
+	if configFile != "" {
+		config, err := buildconfig.New(configFile)
+		if err != nil {
+			return err
+		}
+		if config.Blueprint != nil {
+			bp = config.Blueprint
+		}
+		options = config.Options
+	}
+	if imgType.OSTreeRef() != "" && options.OSTree == nil {
+		options.OSTree = &ostree.ImageOptions{
+			URL: "https://example.com", // required by some image types
+		}
+	}
+
+	reporeg, err := reporegistry.New([]string{repositories}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load repositories from %q: %w", repositories, err)
+	}
+	repos, err := reporeg.ReposByImageTypeName(distroName, archName, imageName)
+	if err != nil {
+		return err
+	}
+
+	mf, _, err := imgType.Manifest(bp, options, repos, nil)
+	if err != nil {
+		return err
+	}
+	pkgSets, ok := mf.GetPackageSetChains()[pipelineName]
+	if !ok {
+		return fmt.Errorf("image type %q has no package sets for pipeline %q", imageName, pipelineName)
+	}
+
+	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), archName, d.Name(), rpmCacheRoot)
+	solver.SetDependencyGraph(true)
+	res, err := solver.Depsolve(pkgSets, sbom.StandardTypeNone)
+	if err != nil {
+		return err
+	}
+
+	var chains [][]string
+	if pkgName != "" {
+		chains, err = res.Dependencies.Why(pkgName)
+		if err != nil {
+			return err
+		}
+		if len(chains) == 0 {
+			return fmt.Errorf("no requested package pulls in %q", pkgName)
+		}
+	}
+
+	switch {
+	case format == "dot":
+		printDOT(os.Stdout, res.Dependencies, chains)
+	case chains != nil:
+		printChains(os.Stdout, res.Dependencies, chains)
+	default:
+		printTree(os.Stdout, res.Dependencies)
+	}
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
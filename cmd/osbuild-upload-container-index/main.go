@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/osbuild/images/pkg/container"
+)
+
+// containersFlag collects the repeated oci-archives
+type containersFlag []string
+
+func (c *containersFlag) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *containersFlag) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+func main() {
+	var containers containersFlag
+	var destination string
+	var username string
+	var password string
+	var ignoreTLS bool
+
+	flag.Var(&containers, "container", "path to a single-arch oci-archive to add to the index, can be repeated (required)")
+	flag.StringVar(&destination, "destination", "", "index to create or update, e.g. registry.example.com/org/image:latest (required)")
+	flag.StringVar(&username, "username", "", "username to use for registry")
+	flag.StringVar(&password, "password", "", "password to use for registry")
+	flag.BoolVar(&ignoreTLS, "ignore-tls", false, "ignore tls verification for destination")
+	flag.Parse()
+
+	if len(containers) == 0 || destination == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	archives := make([]string, 0, len(containers))
+	for _, filename := range containers {
+		absPath, err := filepath.Abs(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Println("Container to add is:", filename)
+		archives = append(archives, absPath)
+	}
+
+	client, err := container.NewClient(destination)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating the upload client: %v\n", err)
+		os.Exit(1)
+	}
+
+	if password != "" {
+		if username == "" {
+			u, err := user.Current()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error looking up current user: %v\n", err)
+				os.Exit(1)
+			}
+			username = u.Username
+		}
+		client.SetCredentials(username, password)
+	}
+
+	if ignoreTLS {
+		client.SkipTLSVerify()
+	}
+
+	digest, err := client.PushIndex(context.Background(), archives)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error uploading: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("upload done; destination index: %s\n", digest.String())
+}
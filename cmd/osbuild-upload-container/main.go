@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
@@ -18,6 +19,12 @@ func main() {
 	var password string
 	var tag string
 	var ignoreTLS bool
+	var signSigstoreKey string
+	var signSigstorePassphraseFile string
+	var signBy string
+	var signPassphraseFile string
+	var lookasideStaging string
+	var registriesD string
 
 	flag.StringVar(&filename, "container", "", "path to the oci-archive to upload (required)")
 	flag.StringVar(&destination, "destination", "", "destination to upload to (required)")
@@ -25,6 +32,12 @@ func main() {
 	flag.StringVar(&username, "username", "", "username to use for registry")
 	flag.StringVar(&password, "password", "", "password to use for registry")
 	flag.BoolVar(&ignoreTLS, "ignore-tls", false, "ignore tls verification for destination")
+	flag.StringVar(&signSigstoreKey, "sign-by-sigstore-private-key", "", "sign the image with a sigstore private key, the signature is attached to the image in the registry")
+	flag.StringVar(&signSigstorePassphraseFile, "sign-sigstore-passphrase-file", "", "file with the passphrase of the sigstore private key")
+	flag.StringVar(&signBy, "sign-by", "", "sign the image with the GPG key with this ID (simple signing)")
+	flag.StringVar(&signPassphraseFile, "sign-passphrase-file", "", "file with the passphrase of the GPG key")
+	flag.StringVar(&lookasideStaging, "lookaside-staging", "", "directory to write the simple signing signatures to")
+	flag.StringVar(&registriesD, "registries-d", "", "containers-registries.d directory that defines where signatures are written")
 	flag.Parse()
 
 	if filename == "" || destination == "" {
@@ -63,6 +76,31 @@ func main() {
 		client.SkipTLSVerify()
 	}
 
+	if signSigstoreKey != "" || signBy != "" {
+		signOpts := &container.SignOptions{
+			SigstorePrivateKeyFile: signSigstoreKey,
+			GPGKeyID:               signBy,
+			LookasideStaging:       lookasideStaging,
+			RegistriesDirPath:      registriesD,
+		}
+		if signSigstorePassphraseFile != "" {
+			signOpts.SigstorePassphrase, err = readPassphrase(signSigstorePassphraseFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error reading the sigstore passphrase: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if signPassphraseFile != "" {
+			passphrase, err := readPassphrase(signPassphraseFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error reading the GPG passphrase: %v\n", err)
+				os.Exit(1)
+			}
+			signOpts.GPGPassphrase = string(passphrase)
+		}
+		client.SetSignOptions(signOpts)
+	}
+
 	ctx := context.Background()
 
 	from := fmt.Sprintf("oci-archive://%s", absPath)
@@ -76,3 +114,13 @@ func main() {
 
 	fmt.Printf("upload done; destination manifest: %s\n", digest.String())
 }
+
+// readPassphrase reads a passphrase from the first line of a file
+func readPassphrase(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, _, _ := bytes.Cut(data, []byte("\n"))
+	return passphrase, nil
+}
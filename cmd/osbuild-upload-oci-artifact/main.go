@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/osbuild/images/pkg/cloud/ociartifact"
+)
+
+// annotationsFlag collects repeated "key=value" annotations
+type annotationsFlag map[string]string
+
+func (a annotationsFlag) String() string {
+	return fmt.Sprintf("%v", map[string]string(a))
+}
+
+func (a annotationsFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("annotation %q is not in the key=value format", value)
+	}
+	a[key] = val
+	return nil
+}
+
+func main() {
+	var filename string
+	var destination string
+	var artifactType string
+	var mediaType string
+	var distro string
+	var arch string
+	var imageType string
+	var sbom string
+	var username string
+	var password string
+	var ignoreTLS bool
+	annotations := annotationsFlag{}
+
+	flag.StringVar(&filename, "artifact", "", "path to the image file to push (required)")
+	flag.StringVar(&destination, "destination", "", "reference to push the artifact to (required)")
+	flag.StringVar(&artifactType, "artifact-type", "", "artifact type, e.g. application/vnd.osbuild.qcow2 (required)")
+	flag.StringVar(&mediaType, "media-type", "", "media type of the file (default: application/octet-stream)")
+	flag.StringVar(&distro, "distro", "", "distro of the image, added to the annotations")
+	flag.StringVar(&arch, "arch", "", "architecture of the image, added to the annotations")
+	flag.StringVar(&imageType, "image-type", "", "type of the image, added to the annotations")
+	flag.Var(annotations, "annotation", "additional annotation of the artifact as key=value, can be repeated")
+	flag.StringVar(&sbom, "sbom", "", "SPDX SBOM of the image to attach to the artifact")
+	flag.StringVar(&username, "username", "", "username to use for registry")
+	flag.StringVar(&password, "password", "", "password to use for registry")
+	flag.BoolVar(&ignoreTLS, "ignore-tls", false, "ignore tls verification for destination")
+	flag.Parse()
+
+	if filename == "" || destination == "" || artifactType == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	opts := &ociartifact.UploaderOptions{
+		ArtifactType: artifactType,
+		MediaType:    mediaType,
+		Filename:     filepath.Base(filename),
+		Distro:       distro,
+		Arch:         arch,
+		ImageType:    imageType,
+		Annotations:  annotations,
+		SBOM:         sbom,
+		Username:     username,
+		Password:     password,
+	}
+	if ignoreTLS {
+		tlsVerify := false
+		opts.TLSVerify = &tlsVerify
+	}
+	uploader, err := ociartifact.NewUploader(destination, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating the uploader: %v\n", err)
+		os.Exit(1)
+	}
+	if err := uploader.Check(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if err := uploader.UploadAndRegister(f, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error uploading: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("upload done; artifact manifest: %s\n", ociartifact.Pushed(uploader).Digest)
+}
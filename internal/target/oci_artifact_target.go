@@ -0,0 +1,40 @@
+package target
+
+const TargetNameOCIArtifact TargetName = "org.osbuild.oci-artifact"
+
+// OCIArtifactTargetOptions pushes the image as an OCI artifact to a
+// container registry
+type OCIArtifactTargetOptions struct {
+	Reference    string `json:"reference"`
+	ArtifactType string `json:"artifact_type"`
+	MediaType    string `json:"media_type,omitempty"`
+
+	// Annotations of the artifact, in addition to the distro, arch
+	// and image type
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// AttachSBOM attaches the SBOM of the image as a referrer
+	AttachSBOM bool `json:"attach_sbom,omitempty"`
+
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	TlsVerify *bool `json:"tls_verify,omitempty"`
+}
+
+func (OCIArtifactTargetOptions) isTargetOptions() {}
+
+func NewOCIArtifactTarget(options *OCIArtifactTargetOptions) *Target {
+	return newTarget(TargetNameOCIArtifact, options)
+}
+
+type OCIArtifactTargetResultOptions struct {
+	URL    string `json:"url"`
+	Digest string `json:"digest"`
+}
+
+func (OCIArtifactTargetResultOptions) isTargetResultOptions() {}
+
+func NewOCIArtifactTargetResult(options *OCIArtifactTargetResultOptions) *TargetResult {
+	return newTargetResult(TargetNameOCIArtifact, options)
+}
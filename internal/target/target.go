@@ -89,6 +89,8 @@ func (target *Target) UnmarshalJSON(data []byte) error {
 		options = new(OCITargetOptions)
 	case TargetNameContainer:
 		options = new(ContainerTargetOptions)
+	case TargetNameOCIArtifact:
+		options = new(OCIArtifactTargetOptions)
 	case TargetNameWorkerServer:
 		options = new(WorkerServerTargetOptions)
 	default:
@@ -258,6 +260,11 @@ func (target Target) MarshalJSON() ([]byte, error) {
 			}
 			rawOptions, err = json.Marshal(compat)
 
+		case *OCIArtifactTargetOptions:
+			// The OCI artifact target was added after the incompatible
+			// change, so it has no Filename in its options.
+			rawOptions, err = json.Marshal(target.Options)
+
 		case *WorkerServerTargetOptions:
 			// WorkerServer target does not handle the backward compatibility
 			// for the Filename in target options, because it was added after
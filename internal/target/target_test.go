@@ -166,6 +166,21 @@ func TestTargetOptionsFilenameCompatibilityUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		{
+			targetJSON: []byte(`{"image_name":"my-image","name":"org.osbuild.oci-artifact","osbuild_artifact":{"export_filename":"image.qcow2"},"options":{"reference":"ref","artifact_type":"application/vnd.osbuild.qcow2","attach_sbom":true}}`),
+			expectedTarget: &Target{
+				ImageName: "my-image",
+				OsbuildArtifact: OsbuildArtifact{
+					ExportFilename: "image.qcow2",
+				},
+				Name: TargetNameOCIArtifact,
+				Options: &OCIArtifactTargetOptions{
+					Reference:    "ref",
+					ArtifactType: "application/vnd.osbuild.qcow2",
+					AttachSBOM:   true,
+				},
+			},
+		},
 		// Test that the job as Marshalled by the current compatibility code is also acceptable.
 		// Such job has Filename set in the Target options, as well in the ExportFilename.
 		{
@@ -69,6 +69,8 @@ func UnmarshalTargetResultOptions(trName TargetName, rawOptions json.RawMessage)
 		options = new(OCITargetResultOptions)
 	case TargetNameContainer:
 		options = new(ContainerTargetResultOptions)
+	case TargetNameOCIArtifact:
+		options = new(OCIArtifactTargetResultOptions)
 	default:
 		return nil, fmt.Errorf("unexpected target result name: %s", trName)
 	}
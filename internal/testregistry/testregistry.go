@@ -9,13 +9,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/opencontainers/go-digest"
 
 	"github.com/containers/image/v5/docker/reference"
 	"github.com/containers/image/v5/manifest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/arch"
@@ -102,6 +105,11 @@ type Repo struct {
 	manifests map[string]*manifest.Schema2
 	images    map[string]*manifest.Schema2List
 	tags      map[string]string
+	uploads   map[string][]byte
+
+	// id of the next blob upload, ids are not reused as uploads can run
+	// in parallel
+	nextUpload int
 }
 
 func NewRepo() *Repo {
@@ -110,6 +118,7 @@ func NewRepo() *Repo {
 		manifests: make(map[string]*manifest.Schema2),
 		tags:      make(map[string]string),
 		images:    make(map[string]*manifest.Schema2List),
+		uploads:   make(map[string][]byte),
 	}
 }
 
@@ -188,10 +197,13 @@ func (r *Repo) AddImage(layers []Blob, arches []string, comment string, ctime ti
 	return checksum
 }
 
+// AddTag tags the image, or a pushed manifest, with the checksum
 func (r *Repo) AddTag(checksum, tag string) {
 
 	if _, ok := r.images[checksum]; !ok {
-		panic("cannot tag: image not found: " + checksum)
+		if blob, ok := r.blobs[checksum]; !ok || !BlobIsManifest(blob) {
+			panic("cannot tag: image not found: " + checksum)
+		}
 	}
 
 	r.tags[tag] = checksum
@@ -213,10 +225,28 @@ func WriteBlob(blob Blob, w http.ResponseWriter) {
 
 func BlobIsManifest(blob Blob) bool {
 	mt := blob.GetMediaType()
-	return mt == manifest.DockerV2Schema2MediaType || mt == manifest.DockerV2ListMediaType
+	return mt == manifest.DockerV2Schema2MediaType || mt == manifest.DockerV2ListMediaType ||
+		mt == imgspecv1.MediaTypeImageManifest || mt == imgspecv1.MediaTypeImageIndex
+}
+
+// writeBlobOrHead writes the blob, or only its headers for HEAD requests
+func writeBlobOrHead(blob Blob, w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodHead {
+		w.Header().Add("Content-Type", blob.GetMediaType())
+		w.Header().Add("Content-Length", fmt.Sprintf("%d", blob.GetSize()))
+		w.Header().Add("Docker-Content-Digest", blob.GetDigest().String())
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	WriteBlob(blob, w)
 }
 
 func (r *Repo) ServeManifest(ref string, w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPut {
+		r.PutManifest(ref, w, req)
+		return
+	}
+
 	if checksum, ok := r.tags[ref]; ok {
 		ref = checksum
 	}
@@ -224,11 +254,43 @@ func (r *Repo) ServeManifest(ref string, w http.ResponseWriter, req *http.Reques
 	blob, ok := r.blobs[ref]
 	if !ok || !BlobIsManifest(blob) {
 		fmt.Fprintf(os.Stderr, "manifest %s not found", ref)
-		http.NotFound(w, req)
+		// the error of the distribution spec, so clients can tell
+		// missing manifests from other errors
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown","detail":%q}]}`, ref)
 		return
 	}
 
-	WriteBlob(blob, w)
+	writeBlobOrHead(blob, w, req)
+}
+
+// PutManifest stores a pushed manifest and tags it with ref, unless ref
+// is a digest
+func (r *Repo) PutManifest(ref string, w http.ResponseWriter, req *http.Request) {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	blob := dataBlob{
+		Data:      data,
+		MediaType: req.Header.Get("Content-Type"),
+	}
+	if !BlobIsManifest(blob) {
+		http.Error(w, fmt.Sprintf("unsupported manifest type %q", blob.MediaType), http.StatusBadRequest)
+		return
+	}
+	desc := r.AddBlob(blob)
+	if _, err := digest.Parse(ref); err != nil {
+		r.tags[ref] = desc.Digest.String()
+	} else if ref != desc.Digest.String() {
+		http.Error(w, fmt.Sprintf("digest mismatch: %s != %s", ref, desc.Digest), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Docker-Content-Digest", desc.Digest.String())
+	w.WriteHeader(http.StatusCreated)
 }
 
 func (r *Repo) ServeBlob(ref string, w http.ResponseWriter, req *http.Request) {
@@ -241,7 +303,112 @@ func (r *Repo) ServeBlob(ref string, w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	WriteBlob(blob, w)
+	writeBlobOrHead(blob, w, req)
+}
+
+// referrers returns the descriptors of the manifests whose subject is the
+// manifest with the given digest
+func (r *Repo) referrers(subject string) []imgspecv1.Descriptor {
+	descs := []imgspecv1.Descriptor{}
+	for _, blob := range r.blobs {
+		if blob.GetMediaType() != imgspecv1.MediaTypeImageManifest {
+			continue
+		}
+		var mf imgspecv1.Manifest
+		if err := json.NewDecoder(blob.Reader()).Decode(&mf); err != nil {
+			continue
+		}
+		if mf.Subject == nil || mf.Subject.Digest.String() != subject {
+			continue
+		}
+		descs = append(descs, imgspecv1.Descriptor{
+			MediaType:    mf.MediaType,
+			ArtifactType: mf.ArtifactType,
+			Digest:       blob.GetDigest(),
+			Size:         blob.GetSize(),
+			Annotations:  mf.Annotations,
+		})
+	}
+	return descs
+}
+
+// ServeReferrers implements the referrers API of the registry, it returns
+// an index of the manifests that refer to the subject
+func (r *Repo) ServeReferrers(subject string, w http.ResponseWriter, req *http.Request) {
+	index := imgspecv1.Index{
+		MediaType: imgspecv1.MediaTypeImageIndex,
+		Manifests: r.referrers(subject),
+	}
+	index.SchemaVersion = 2
+	w.Header().Add("Content-Type", imgspecv1.MediaTypeImageIndex)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(index); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing referrers: %v", err)
+	}
+}
+
+// ServeTags lists the tags of the repository
+func (r *Repo) ServeTags(name string, w http.ResponseWriter, req *http.Request) {
+	tags := make([]string, 0, len(r.tags))
+	for tag := range r.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}{name, tags})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing tags: %v", err)
+	}
+}
+
+// ServeUpload implements the blob upload of the registry API, the upload
+// is started with a POST request, the data is sent with PATCH requests
+// and the upload is finished with a PUT request
+func (r *Repo) ServeUpload(name, id string, w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost && id == "":
+		id = fmt.Sprintf("%d", r.nextUpload)
+		r.nextUpload++
+		r.uploads[id] = nil
+	case req.Method == http.MethodPatch || req.Method == http.MethodPut:
+		data, ok := r.uploads[id]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		chunk, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.uploads[id] = append(data, chunk...)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.Method == http.MethodPut {
+		blob := dataBlob{Data: r.uploads[id]}
+		delete(r.uploads, id)
+		if expected := req.URL.Query().Get("digest"); expected != blob.GetDigest().String() {
+			http.Error(w, fmt.Sprintf("digest mismatch: %s != %s", expected, blob.GetDigest()), http.StatusBadRequest)
+			return
+		}
+		desc := r.AddBlob(blob)
+		w.Header().Add("Docker-Content-Digest", desc.Digest.String())
+		w.Header().Add("Location", fmt.Sprintf("/v2/%s/blobs/%s", name, desc.Digest))
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	w.Header().Add("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", name, id))
+	w.Header().Add("Range", fmt.Sprintf("0-%d", max(len(r.uploads[id])-1, 0)))
+	w.Header().Add("Docker-Upload-UUID", id)
+	w.WriteHeader(http.StatusAccepted)
 }
 
 // Registry //
@@ -249,9 +416,13 @@ func (r *Repo) ServeBlob(ref string, w http.ResponseWriter, req *http.Request) {
 type Registry struct {
 	server *httptest.Server
 	repos  map[string]*Repo
+
+	mu sync.Mutex
 }
 
 func (reg *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
 
 	parts := strings.SplitN(req.URL.Path, "?", 1)
 	paths := strings.Split(strings.Trim(parts[0], "/"), "/")
@@ -260,6 +431,9 @@ func (reg *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// [1] version-check:  /v2/
 	// [2] blobs:          /v2/<repo_name>/blobs/<digest>
 	// [3] manifest:       /v2/<repo_name>/manifests/<ref>
+	// [4] blob upload:    /v2/<repo_name>/blobs/uploads/[<id>]
+	// [5] referrers:      /v2/<repo_name>/referrers/<digest>
+	// [6] tags:           /v2/<repo_name>/tags/list
 	//
 	// we need at least 4 path components and path has to start with "/v2"
 
@@ -277,6 +451,21 @@ func (reg *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// [4] blob upload, the upload id is missing when it is started
+	if paths[len(paths)-1] == "uploads" && paths[len(paths)-2] == "blobs" {
+		paths = append(paths, "")
+	}
+	if len(paths) >= 5 && paths[len(paths)-2] == "uploads" && paths[len(paths)-3] == "blobs" {
+		repoName := strings.Join(paths[1:len(paths)-3], "/")
+		repo, ok := reg.repos[repoName]
+		if !ok {
+			// pushing creates the repository
+			repo = reg.AddRepo(repoName)
+		}
+		repo.ServeUpload(repoName, paths[len(paths)-1], w, req)
+		return
+	}
+
 	// we asserted that we have at least 4 path components
 	ref := paths[len(paths)-1]
 	cmd := paths[len(paths)-2]
@@ -284,6 +473,9 @@ func (reg *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	repoName := strings.Join(paths[1:len(paths)-2], "/")
 
 	repo, ok := reg.repos[repoName]
+	if !ok && cmd == "manifests" && req.Method == http.MethodPut {
+		repo, ok = reg.AddRepo(repoName), true
+	}
 	if !ok {
 		fmt.Fprintf(os.Stderr, "repo %s not found", repoName)
 		http.NotFound(w, req)
@@ -294,6 +486,10 @@ func (reg *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		repo.ServeManifest(ref, w, req)
 	} else if cmd == "blobs" {
 		repo.ServeBlob(ref, w, req)
+	} else if cmd == "referrers" {
+		repo.ServeReferrers(ref, w, req)
+	} else if cmd == "tags" && ref == "list" {
+		repo.ServeTags(repoName, w, req)
 	} else {
 		http.NotFound(w, req)
 	}
@@ -392,6 +588,55 @@ func (reg *Registry) Resolve(target string, imgArch arch.Arch) (container.Spec,
 	}, nil
 }
 
+// GetManifest returns the manifest of a repository with the given tag or
+// digest
+func (reg *Registry) GetManifest(repoName, ref string) ([]byte, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	repo, ok := reg.repos[repoName]
+	if !ok {
+		return nil, fmt.Errorf("unknown repo")
+	}
+	if checksum, ok := repo.tags[ref]; ok {
+		ref = checksum
+	}
+	blob, ok := repo.blobs[ref]
+	if !ok || !BlobIsManifest(blob) {
+		return nil, fmt.Errorf("unknown manifest")
+	}
+	return io.ReadAll(blob.Reader())
+}
+
+// GetBlob returns the content of a blob of a repository
+func (reg *Registry) GetBlob(repoName, dgst string) ([]byte, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	repo, ok := reg.repos[repoName]
+	if !ok {
+		return nil, fmt.Errorf("unknown repo")
+	}
+	blob, ok := repo.blobs[dgst]
+	if !ok {
+		return nil, fmt.Errorf("unknown blob")
+	}
+	return io.ReadAll(blob.Reader())
+}
+
+// Referrers returns the descriptors of the manifests of a repository that
+// refer to the manifest with the given digest
+func (reg *Registry) Referrers(repoName, subject string) []imgspecv1.Descriptor {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	repo, ok := reg.repos[repoName]
+	if !ok {
+		return nil
+	}
+	return repo.referrers(subject)
+}
+
 func (reg *Registry) Close() {
 	reg.server.Close()
 }
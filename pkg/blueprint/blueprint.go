@@ -1,6 +1,13 @@
 // Package blueprint contains primitives for representing weldr blueprints
 package blueprint
 
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
 // A Blueprint is a high-level description of an image.
 type Blueprint struct {
 	Name        string    `json:"name" toml:"name"`
@@ -26,8 +33,17 @@ type Blueprint struct {
 type Package struct {
 	Name    string `json:"name" toml:"name"`
 	Version string `json:"version,omitempty" toml:"version,omitempty"`
+
+	// Source is the absolute path or the http(s) URL of an RPM file
+	// that is not available in any repository. The sha256 Checksum of
+	// the file is required with it. The manifest refers to the file by
+	// its path, so it has to stay in place until the image is built.
+	Source   string `json:"source,omitempty" toml:"source,omitempty"`
+	Checksum string `json:"checksum,omitempty" toml:"checksum,omitempty"`
 }
 
+var packageChecksumRegex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
 // A module specifies a modularity stream.
 type EnabledModule struct {
 	Name   string `json:"name" toml:"name"`
@@ -40,11 +56,45 @@ type Group struct {
 }
 
 type Container struct {
+	// Source is a registry reference, or an OCI layout ("oci:<path>")
+	// or oci-archive ("oci-archive:<path>") on the host, which requires
+	// a Name. Containers from OCI layouts and oci-archives are resolved,
+	// but cannot be built yet: the sources of osbuild only take images
+	// from a registry or the containers-storage of the host.
 	Source string `json:"source" toml:"source"`
 	Name   string `json:"name,omitempty" toml:"name,omitempty"`
 
 	TLSVerify    *bool `json:"tls-verify,omitempty" toml:"tls-verify,omitempty"`
 	LocalStorage bool  `json:"local-storage,omitempty" toml:"local-storage,omitempty"`
+
+	// Verify rejects the container unless it is signed as required
+	Verify *ContainerVerify `json:"verify,omitempty" toml:"verify,omitempty"`
+}
+
+// ContainerVerify is the signature verification policy of a container, see
+// container.SignaturePolicy
+type ContainerVerify struct {
+	// A complete containers-policy.json(5), instead of the keys
+	Policy string `json:"policy,omitempty" toml:"policy,omitempty"`
+
+	SigstoreKeys []string `json:"sigstore-keys,omitempty" toml:"sigstore-keys,omitempty"`
+	GPGKeys      []string `json:"gpg-keys,omitempty" toml:"gpg-keys,omitempty"`
+
+	Fulcio *ContainerVerifyFulcio `json:"fulcio,omitempty" toml:"fulcio,omitempty"`
+
+	// A containers-registries.d(5) configuration of the signature lookup
+	RegistriesD string `json:"registries-d,omitempty" toml:"registries-d,omitempty"`
+
+	// Install the policy into the image, in addition to the default
+	// policy of the distribution
+	Install bool `json:"install,omitempty" toml:"install,omitempty"`
+}
+
+type ContainerVerifyFulcio struct {
+	CAData         string `json:"ca-data" toml:"ca-data"`
+	OIDCIssuer     string `json:"oidc-issuer" toml:"oidc-issuer"`
+	SubjectEmail   string `json:"subject-email" toml:"subject-email"`
+	RekorPublicKey string `json:"rekor-public-key" toml:"rekor-public-key"`
 }
 
 // packages, modules, and groups all resolve to rpm packages right now. This
@@ -74,6 +124,28 @@ func (b *Blueprint) GetPackagesEx(bootable bool) []string {
 	return packages
 }
 
+// GetLocalPackages returns the packages that are supplied as RPM files
+// instead of by a repository.
+func (b *Blueprint) GetLocalPackages() ([]Package, error) {
+	var local []Package
+	for _, pkg := range b.Packages {
+		if pkg.Source == "" && pkg.Checksum == "" {
+			continue
+		}
+		if pkg.Source == "" {
+			return nil, fmt.Errorf("package %q has a checksum but no source", pkg.Name)
+		}
+		if !filepath.IsAbs(pkg.Source) && !strings.HasPrefix(pkg.Source, "http://") && !strings.HasPrefix(pkg.Source, "https://") {
+			return nil, fmt.Errorf("source of package %q must be an absolute path or a http(s) URL: %q", pkg.Name, pkg.Source)
+		}
+		if !packageChecksumRegex.MatchString(pkg.Checksum) {
+			return nil, fmt.Errorf("package %q with a source requires a sha256 checksum, got %q", pkg.Name, pkg.Checksum)
+		}
+		local = append(local, pkg)
+	}
+	return local, nil
+}
+
 func (b *Blueprint) GetEnabledModules() []string {
 	modules := []string{}
 
@@ -2,6 +2,7 @@ package blueprint
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/BurntSushi/toml"
@@ -100,6 +101,54 @@ func TestGetPackages(t *testing.T) {
 	assert.ElementsMatch(t, []string{"tmux-1.2", "openssh-server", "@anaconda-tools", "kernel"}, Received_packages)
 }
 
+func TestGetLocalPackages(t *testing.T) {
+	checksum := "sha256:" + strings.Repeat("a", 64)
+	bp := Blueprint{
+		Packages: []Package{
+			{Name: "tmux"},
+			{Name: "local", Source: "/srv/rpms/local-1.0-1.noarch.rpm", Checksum: checksum},
+			{Name: "remote", Source: "https://example.com/remote-1.0-1.noarch.rpm", Checksum: checksum},
+		},
+	}
+	local, err := bp.GetLocalPackages()
+	require.NoError(t, err)
+	assert.Equal(t, bp.Packages[1:], local)
+}
+
+func TestGetLocalPackagesErrors(t *testing.T) {
+	checksum := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		pkg Package
+		err string
+	}{
+		{
+			pkg: Package{Name: "pkg", Checksum: checksum},
+			err: `package "pkg" has a checksum but no source`,
+		},
+		{
+			pkg: Package{Name: "pkg", Source: "rpms/pkg.rpm", Checksum: checksum},
+			err: `source of package "pkg" must be an absolute path or a http(s) URL: "rpms/pkg.rpm"`,
+		},
+		{
+			pkg: Package{Name: "pkg", Source: "ftp://example.com/pkg.rpm", Checksum: checksum},
+			err: `source of package "pkg" must be an absolute path or a http(s) URL: "ftp://example.com/pkg.rpm"`,
+		},
+		{
+			pkg: Package{Name: "pkg", Source: "/srv/pkg.rpm"},
+			err: `package "pkg" with a source requires a sha256 checksum, got ""`,
+		},
+		{
+			pkg: Package{Name: "pkg", Source: "/srv/pkg.rpm", Checksum: "md5:0123"},
+			err: `package "pkg" with a source requires a sha256 checksum, got "md5:0123"`,
+		},
+	}
+	for _, tt := range tests {
+		bp := Blueprint{Packages: []Package{tt.pkg}}
+		_, err := bp.GetLocalPackages()
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
 func TestGetEnbledModules(t *testing.T) {
 	bp := Blueprint{
 		Name:        "enabled-modules-test",
@@ -0,0 +1,66 @@
+package blueprint
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/cert"
+)
+
+type BootloaderCustomization struct {
+	// Type selects the boot chain of the image: "grub2" (default) or "uki"
+	// for a Unified Kernel Image booted with systemd-boot. The latter is
+	// only supported on UEFI targets.
+	Type string `json:"type,omitempty" toml:"type,omitempty"`
+
+	// SecureBoot signs the EFI binaries and the kernel of the image with a
+	// user provided key.
+	SecureBoot *SecureBootCustomization `json:"secure_boot,omitempty" toml:"secure_boot,omitempty"`
+}
+
+// SecureBootCustomization holds the public part of the Secure Boot signing
+// key. The private key is never part of the blueprint, it is provided to
+// osbuild as a secret when the image is built.
+type SecureBootCustomization struct {
+	// PEM encoded signing certificate
+	Certificate string `json:"certificate" toml:"certificate"`
+
+	// Enrollment selects how the certificate is enrolled on the target:
+	// "mok" imports it into the shim MOK list on first boot, "db"
+	// generates authenticated variables for custom db enrollment. When
+	// empty, the certificate is expected to be enrolled already.
+	Enrollment string `json:"enrollment,omitempty" toml:"enrollment,omitempty"`
+}
+
+func (b *BootloaderCustomization) Validate() error {
+	if b == nil {
+		return nil
+	}
+	switch b.Type {
+	case "", "grub2", "uki":
+	default:
+		return fmt.Errorf("unknown bootloader type %q, must be one of: grub2, uki", b.Type)
+	}
+	return b.SecureBoot.Validate()
+}
+
+func (s *SecureBootCustomization) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Certificate == "" {
+		return fmt.Errorf("secure boot customization requires a certificate")
+	}
+	certs, err := cert.ParseCerts(s.Certificate)
+	if err != nil {
+		return fmt.Errorf("invalid secure boot certificate: %w", err)
+	}
+	if len(certs) != 1 {
+		return fmt.Errorf("secure boot certificate must contain exactly one certificate, got %d", len(certs))
+	}
+	switch s.Enrollment {
+	case "", "mok", "db":
+		return nil
+	default:
+		return fmt.Errorf("unknown secure boot enrollment %q, must be one of: mok, db", s.Enrollment)
+	}
+}
@@ -0,0 +1,30 @@
+package blueprint
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// ComposefsCustomization enables composefs for the deployments of ostree
+// based images. The commit is built with composefs metadata and the root
+// filesystem of the disk images is created with fs-verity.
+type ComposefsCustomization struct {
+	// Base64 encoded ed25519 public key. When set, composefs is configured
+	// as signed and the commit must be signed with the matching private key.
+	PublicKey string `json:"public_key,omitempty" toml:"public_key,omitempty"`
+}
+
+func (c *ComposefsCustomization) Validate() error {
+	if c == nil || c.PublicKey == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(c.PublicKey)
+	if err != nil {
+		return fmt.Errorf("composefs public key is not base64 encoded: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("composefs public key must be an ed25519 public key of %d bytes, got %d bytes", ed25519.PublicKeySize, len(key))
+	}
+	return nil
+}
@@ -34,6 +34,8 @@ type Customizations struct {
 	RPM                *RPMCustomization              `json:"rpm,omitempty" toml:"rpm,omitempty"`
 	RHSM               *RHSMCustomization             `json:"rhsm,omitempty" toml:"rhsm,omitempty"`
 	CACerts            *CACustomization               `json:"cacerts,omitempty" toml:"cacerts,omitempty"`
+	Bootloader         *BootloaderCustomization       `json:"bootloader,omitempty" toml:"bootloader,omitempty"`
+	Composefs          *ComposefsCustomization        `json:"composefs,omitempty" toml:"composefs,omitempty"`
 }
 
 type IgnitionCustomization struct {
@@ -426,6 +428,10 @@ func (c *Customizations) GetInstaller() (*InstallerCustomization, error) {
 		return nil, fmt.Errorf("blueprint contains user or group customizations but disables the required Users Anaconda module")
 	}
 
+	if c.Installer.PersistentOverlay != nil && c.Installer.PersistentOverlay.Size == 0 {
+		return nil, fmt.Errorf("installer.persistent-overlay requires a size")
+	}
+
 	return c.Installer, nil
 }
 
@@ -469,3 +475,27 @@ func (c *Customizations) GetCACerts() (*CACustomization, error) {
 
 	return c.CACerts, nil
 }
+
+func (c *Customizations) GetBootloader() (*BootloaderCustomization, error) {
+	if c == nil || c.Bootloader == nil {
+		return nil, nil
+	}
+
+	if err := c.Bootloader.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.Bootloader, nil
+}
+
+func (c *Customizations) GetComposefs() (*ComposefsCustomization, error) {
+	if c == nil || c.Composefs == nil {
+		return nil, nil
+	}
+
+	if err := c.Composefs.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.Composefs, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/customizations/anaconda"
+	"github.com/osbuild/images/pkg/datasizes"
 	"github.com/osbuild/images/pkg/rpmmd"
 )
 
@@ -480,6 +481,23 @@ func TestGetInstallerErrors(t *testing.T) {
 			},
 			expected: "blueprint contains user or group customizations but disables the required Users Anaconda module",
 		},
+		"persistent-overlay": {
+			customizations: Customizations{
+				Installer: &InstallerCustomization{
+					PersistentOverlay: &PersistentOverlay{
+						Size: 2 * datasizes.GiB,
+					},
+				},
+			},
+		},
+		"persistent-overlay-no-size": {
+			customizations: Customizations{
+				Installer: &InstallerCustomization{
+					PersistentOverlay: &PersistentOverlay{},
+				},
+			},
+			expected: "installer.persistent-overlay requires a size",
+		},
 	}
 
 	for name := range testCases {
@@ -573,3 +591,98 @@ func TestGetRepositoriesInstallFrom(t *testing.T) {
 	filtered := RepoCustomizationsInstallFromOnly(repos)
 	assert.Equal(t, expected, filtered)
 }
+
+func TestGetBootloader(t *testing.T) {
+	expected := BootloaderCustomization{
+		Type: "uki",
+	}
+
+	TestCustomizations := Customizations{
+		Bootloader: &expected,
+	}
+
+	ret, err := TestCustomizations.GetBootloader()
+	assert.NoError(t, err)
+	assert.Equal(t, &expected, ret)
+
+	TestCustomizations.Bootloader = &BootloaderCustomization{Type: "lilo"}
+	_, err = TestCustomizations.GetBootloader()
+	assert.EqualError(t, err, `unknown bootloader type "lilo", must be one of: grub2, uki`)
+}
+
+// taken from osbuild:test/data/certs/cert1.pem
+const secureBootCert = `
+-----BEGIN CERTIFICATE-----
+MIIDhTCCAm2gAwIBAgIUVya7VJ3O8W8SqwuEa0BZ4HSsXvAwDQYJKoZIhvcNAQEL
+BQAwUTELMAkGA1UEBhMCREUxDzANBgNVBAgMBkJlcmxpbjEPMA0GA1UEBwwGQmVy
+bGluMQwwCgYDVQQKDANPcmcxEjAQBgNVBAMMCWxvY2FsaG9zdDAgFw0yNDA4MjYx
+MDQyNDBaGA8yMTI0MDgwMjEwNDI0MFowUTELMAkGA1UEBhMCREUxDzANBgNVBAgM
+BkJlcmxpbjEPMA0GA1UEBwwGQmVybGluMQwwCgYDVQQKDANPcmcxEjAQBgNVBAMM
+CWxvY2FsaG9zdDCCASIwDQYJKoZIhvcNAQEBBQADggEPADCCAQoCggEBAJnGjlvN
+O3F/Z7Lr/r+6Xp2DosnNwoPHhG2e61KnFzgZfaxbklal5ORpuV/gLIg7lrbpdZe7
+WvK+16RanL6fLitis/tYVFyvz1MXqBYYrEoFGvVg9fOiis7hjpdZcpNDH9SngoAN
+O0Wvv4T6LQS0cC7ZAFZjvmJ+RiZEbzRkNG5pUddZXbotE6htNfLgA5L1wIBgllrM
+4DVkG0yNKmzqPNzfPTbdUgWCfjaQShHy1GP8KNEwFxM31F2wvQxsEb77o1S44Out
+mlsi83tti6P7KjDk7w2j2zZO1X0xI8pflv3TBkJT1Am8vnk6rVnNO4pCpop3+kma
+pDUEzBQmSQA5R1ECAwEAAaNTMFEwHQYDVR0OBBYEFDxFcFgPEsgsDixfKxB0uYGN
+aJmzMB8GA1UdIwQYMBaAFDxFcFgPEsgsDixfKxB0uYGNaJmzMA8GA1UdEwEB/wQF
+MAMBAf8wDQYJKoZIhvcNAQELBQADggEBAFih4lUbLlhKwIAV9x3/W7Mih8xUEdZr
+olquZgaHedFet+ByAHvoES3pec7AVYTOD53mjgyZubD6INnVHzKyS4AG9ydD73o4
+cmm3DKxBaesvlHeTn0MOKsoM8QCxeyFJmiUPpgDBok/PFnbGR9+JcsrlGJAnsSKD
+vWpiwYcBauZ9nnK5yDe5M9XNFPkNDZzbKvWU7Sw3ziMT/+bRJse5vTrYcyOnNGgy
+gZNz2nimKy1U8XZVAVwOV0rdGEFrfMln8DkRW86rGK/EncaVsl0SSP/rmjQgiX8Q
+3CZraQGujJP932HSwUfdCX9yh+rTjE3MEnbqMoLzJa4BXB2aDQWtywU=
+-----END CERTIFICATE-----
+`
+
+func TestGetBootloaderSecureBoot(t *testing.T) {
+	TestCustomizations := Customizations{
+		Bootloader: &BootloaderCustomization{
+			Type: "uki",
+			SecureBoot: &SecureBootCustomization{
+				Certificate: secureBootCert,
+				Enrollment:  "db",
+			},
+		},
+	}
+
+	ret, err := TestCustomizations.GetBootloader()
+	assert.NoError(t, err)
+	assert.Equal(t, TestCustomizations.Bootloader, ret)
+
+	TestCustomizations.Bootloader.SecureBoot.Enrollment = "pk"
+	_, err = TestCustomizations.GetBootloader()
+	assert.EqualError(t, err, `unknown secure boot enrollment "pk", must be one of: mok, db`)
+
+	TestCustomizations.Bootloader.SecureBoot = &SecureBootCustomization{}
+	_, err = TestCustomizations.GetBootloader()
+	assert.EqualError(t, err, "secure boot customization requires a certificate")
+
+	TestCustomizations.Bootloader.SecureBoot = &SecureBootCustomization{Certificate: "not a cert"}
+	_, err = TestCustomizations.GetBootloader()
+	assert.ErrorContains(t, err, "invalid secure boot certificate: no valid PEM certificates found")
+}
+
+func TestGetComposefs(t *testing.T) {
+	TestCustomizations := Customizations{}
+	ret, err := TestCustomizations.GetComposefs()
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+
+	// 32 bytes of an ed25519 public key
+	expected := ComposefsCustomization{
+		PublicKey: "IvOVdQ8Xn3XvBx5wMC37yq1Hm1Z8QmUp6w8rwTjQ3Pk=",
+	}
+	TestCustomizations.Composefs = &expected
+	ret, err = TestCustomizations.GetComposefs()
+	assert.NoError(t, err)
+	assert.Equal(t, &expected, ret)
+
+	TestCustomizations.Composefs = &ComposefsCustomization{PublicKey: "not base64"}
+	_, err = TestCustomizations.GetComposefs()
+	assert.ErrorContains(t, err, "composefs public key is not base64 encoded")
+
+	TestCustomizations.Composefs = &ComposefsCustomization{PublicKey: "c2VjcmV0"}
+	_, err = TestCustomizations.GetComposefs()
+	assert.EqualError(t, err, "composefs public key must be an ed25519 public key of 32 bytes, got 6 bytes")
+}
@@ -1,10 +1,13 @@
 package blueprint
 
+import "github.com/osbuild/images/pkg/datasizes"
+
 type InstallerCustomization struct {
-	Unattended   bool             `json:"unattended,omitempty" toml:"unattended,omitempty"`
-	SudoNopasswd []string         `json:"sudo-nopasswd,omitempty" toml:"sudo-nopasswd,omitempty"`
-	Kickstart    *Kickstart       `json:"kickstart,omitempty" toml:"kickstart,omitempty"`
-	Modules      *AnacondaModules `json:"modules,omitempty" toml:"modules,omitempty"`
+	Unattended        bool               `json:"unattended,omitempty" toml:"unattended,omitempty"`
+	SudoNopasswd      []string           `json:"sudo-nopasswd,omitempty" toml:"sudo-nopasswd,omitempty"`
+	Kickstart         *Kickstart         `json:"kickstart,omitempty" toml:"kickstart,omitempty"`
+	Modules           *AnacondaModules   `json:"modules,omitempty" toml:"modules,omitempty"`
+	PersistentOverlay *PersistentOverlay `json:"persistent-overlay,omitempty" toml:"persistent-overlay,omitempty"`
 }
 
 type Kickstart struct {
@@ -15,3 +18,9 @@ type AnacondaModules struct {
 	Enable  []string `json:"enable,omitempty" toml:"enable,omitempty"`
 	Disable []string `json:"disable,omitempty" toml:"disable,omitempty"`
 }
+
+// PersistentOverlay adds a writable partition to a live ISO that keeps
+// changes to the live system across reboots.
+type PersistentOverlay struct {
+	Size datasizes.Size `json:"size" toml:"size"`
+}
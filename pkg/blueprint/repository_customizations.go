@@ -19,12 +19,15 @@ type RepositoryCustomization struct {
 	Mirrorlist     string   `json:"mirrorlist,omitempty" toml:"mirrorlist,omitempty"`
 	Name           string   `json:"name,omitempty" toml:"name,omitempty"`
 	Priority       *int     `json:"priority,omitempty" toml:"priority,omitempty"`
+	Cost           *int     `json:"cost,omitempty" toml:"cost,omitempty"`
 	Enabled        *bool    `json:"enabled,omitempty" toml:"enabled,omitempty"`
 	GPGCheck       *bool    `json:"gpgcheck,omitempty" toml:"gpgcheck,omitempty"`
 	RepoGPGCheck   *bool    `json:"repo_gpgcheck,omitempty" toml:"repo_gpgcheck,omitempty"`
 	SSLVerify      *bool    `json:"sslverify,omitempty" toml:"sslverify,omitempty"`
 	ModuleHotfixes *bool    `json:"module_hotfixes,omitempty" toml:"module_hotfixes,omitempty"`
 	Filename       string   `json:"filename,omitempty" toml:"filename,omitempty"`
+	IncludePkgs    []string `json:"includepkgs,omitempty" toml:"includepkgs,omitempty"`
+	ExcludePkgs    []string `json:"excludepkgs,omitempty" toml:"excludepkgs,omitempty"`
 
 	// When set the repository will be used during the depsolve of
 	// payload repositories to install packages from it.
@@ -51,6 +54,14 @@ func validateCustomRepository(repo *RepositoryCustomization) error {
 		return fmt.Errorf("Repository gpg check is set to true but no gpg keys are provided")
 	}
 
+	for _, pkgs := range [][]string{repo.IncludePkgs, repo.ExcludePkgs} {
+		for _, pkg := range pkgs {
+			if strings.TrimSpace(pkg) == "" {
+				return fmt.Errorf("Repository includepkgs and excludepkgs must not contain empty package names")
+			}
+		}
+	}
+
 	for _, key := range repo.GPGKeys {
 		// check for a valid GPG key prefix & contains GPG suffix
 		keyIsGPGKey := strings.HasPrefix(key, "-----BEGIN PGP PUBLIC KEY BLOCK-----") && strings.Contains(key, "-----END PGP PUBLIC KEY BLOCK-----")
@@ -142,10 +153,20 @@ func (repo RepositoryCustomization) customRepoToRepoConfig() rpmmd.RepoConfig {
 		CheckGPG:       repo.GPGCheck,
 		CheckRepoGPG:   repo.RepoGPGCheck,
 		Priority:       repo.Priority,
+		Cost:           repo.Cost,
 		ModuleHotfixes: repo.ModuleHotfixes,
 		Enabled:        repo.Enabled,
 	}
 
+	if len(repo.IncludePkgs) > 0 {
+		repoConfig.IncludePackages = make([]string, len(repo.IncludePkgs))
+		copy(repoConfig.IncludePackages, repo.IncludePkgs)
+	}
+	if len(repo.ExcludePkgs) > 0 {
+		repoConfig.ExcludePackages = make([]string, len(repo.ExcludePkgs))
+		copy(repoConfig.ExcludePackages, repo.ExcludePkgs)
+	}
+
 	if repo.SSLVerify != nil {
 		repoConfig.IgnoreSSL = common.ToPtr(!*repo.SSLVerify)
 	}
@@ -79,6 +79,19 @@ func TestGetCustomRepositories(t *testing.T) {
 			},
 			wantErr: fmt.Errorf("Repository gpg key is not a valid URL or a valid gpg key"),
 		},
+		{
+			name: "Test empty excludepkgs error",
+			expectedCustomizations: Customizations{
+				Repositories: []RepositoryCustomization{
+					{
+						Id:          "example-1",
+						BaseURLs:    []string{"http://example-1.com"},
+						ExcludePkgs: []string{"kernel*", " "},
+					},
+				},
+			},
+			wantErr: fmt.Errorf("Repository includepkgs and excludepkgs must not contain empty package names"),
+		},
 		{
 			name: "Test invalid repository filename error",
 			expectedCustomizations: Customizations{
@@ -233,6 +246,31 @@ func TestCustomRepoToRepoConfigAndGPGKeys(t *testing.T) {
 			},
 			WantGPGKeys: nil,
 		},
+		{
+			Name: "Test package filters and cost",
+			Repos: []RepositoryCustomization{
+				{
+					Id:          "epel",
+					BaseURLs:    []string{"http://epel.com"},
+					Cost:        common.ToPtr(2000),
+					IncludePkgs: []string{"htop", "tmux*"},
+					ExcludePkgs: []string{"kernel*"},
+				},
+			},
+			WantRepoConfig: map[string][]rpmmd.RepoConfig{
+				"epel.repo": {
+					{
+						Id:              "epel",
+						BaseURLs:        []string{"http://epel.com"},
+						GPGKeys:         []string{},
+						Cost:            common.ToPtr(2000),
+						IncludePackages: []string{"htop", "tmux*"},
+						ExcludePackages: []string{"kernel*"},
+					},
+				},
+			},
+			WantGPGKeys: nil,
+		},
 		{
 			Name: "Test remote gpgkeys",
 			Repos: []RepositoryCustomization{
@@ -0,0 +1,161 @@
+// package ociartifact uploads images as OCI artifacts to a container
+// registry, like e.g. ORAS does.
+package ociartifact
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/osbuild/images/pkg/cloud"
+	"github.com/osbuild/images/pkg/container"
+)
+
+// SBOMArtifactType is the artifact type of the SBOMs that are attached to
+// the artifacts
+const SBOMArtifactType = "application/spdx+json"
+
+type UploaderOptions struct {
+	// ArtifactType of the artifact (required), MediaType of the file
+	ArtifactType string
+	MediaType    string
+
+	// Filename is the name of the file in the artifact
+	Filename string
+
+	// Distro, Arch and ImageType of the image are added to the
+	// annotations of the artifact
+	Distro    string
+	Arch      string
+	ImageType string
+
+	// Annotations of the artifact
+	Annotations map[string]string
+
+	// SBOM is the path of an SPDX SBOM of the image that is attached
+	// to the artifact as a referrer
+	SBOM string
+
+	// Registry authentication, if unset the default auth file is used
+	Username     string
+	Password     string
+	AuthFilePath string
+
+	TLSVerify *bool
+}
+
+type artifactUploader struct {
+	client *container.Client
+	opts   UploaderOptions
+
+	// the descriptor of the pushed artifact
+	pushed *imgspecv1.Descriptor
+}
+
+var _ cloud.Uploader = &artifactUploader{}
+
+// NewUploader returns an Uploader that pushes images as OCI artifacts to
+// the reference in a container registry
+func NewUploader(reference string, opts *UploaderOptions) (cloud.Uploader, error) {
+	if opts == nil {
+		opts = &UploaderOptions{}
+	}
+	client, err := container.NewClient(reference)
+	if err != nil {
+		return nil, err
+	}
+	client.SetTLSVerify(opts.TLSVerify)
+	if opts.AuthFilePath != "" {
+		client.SetAuthFilePath(opts.AuthFilePath)
+	}
+	if opts.Username != "" || opts.Password != "" {
+		client.SetCredentials(opts.Username, opts.Password)
+	}
+	return &artifactUploader{
+		client: client,
+		opts:   *opts,
+	}, nil
+}
+
+// annotations returns the annotations of the artifact
+func (au *artifactUploader) annotations() map[string]string {
+	annotations := make(map[string]string, len(au.opts.Annotations)+3)
+	for key, value := range au.opts.Annotations {
+		annotations[key] = value
+	}
+	for key, value := range map[string]string{
+		container.AnnotationDistro:    au.opts.Distro,
+		container.AnnotationArch:      au.opts.Arch,
+		container.AnnotationImageType: au.opts.ImageType,
+	} {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
+
+func (au *artifactUploader) Check(status io.Writer) error {
+	fmt.Fprintf(status, "Checking artifact options...\n")
+	if au.opts.ArtifactType == "" {
+		return fmt.Errorf("artifact type is required")
+	}
+	if au.opts.SBOM != "" {
+		if _, err := os.Stat(au.opts.SBOM); err != nil {
+			return fmt.Errorf("cannot attach SBOM: %w", err)
+		}
+	}
+	fmt.Fprintf(status, "Upload conditions met.\n")
+	return nil
+}
+
+func (au *artifactUploader) UploadAndRegister(f io.Reader, status io.Writer) error {
+	ctx := context.Background()
+
+	fmt.Fprintf(status, "Pushing artifact to %s...\n", au.client.Target)
+	desc, err := au.client.PushArtifact(ctx, f, container.ArtifactOptions{
+		ArtifactType: au.opts.ArtifactType,
+		MediaType:    au.opts.MediaType,
+		Filename:     au.opts.Filename,
+		Annotations:  au.annotations(),
+	})
+	if err != nil {
+		return err
+	}
+	au.pushed = &desc
+	fmt.Fprintf(status, "Pushed artifact %s\n", desc.Digest)
+
+	if au.opts.SBOM == "" {
+		return nil
+	}
+	sbom, err := os.Open(au.opts.SBOM)
+	if err != nil {
+		return err
+	}
+	defer sbom.Close()
+	fmt.Fprintf(status, "Attaching SBOM %s...\n", au.opts.SBOM)
+	sbomDesc, err := au.client.AttachArtifact(ctx, desc, sbom, container.ArtifactOptions{
+		ArtifactType: SBOMArtifactType,
+		MediaType:    SBOMArtifactType,
+		Filename:     filepath.Base(au.opts.SBOM),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot attach SBOM: %w", err)
+	}
+	fmt.Fprintf(status, "Attached SBOM %s\n", sbomDesc.Digest)
+	return nil
+}
+
+// Pushed returns the descriptor of the artifact that an Uploader of this
+// package pushed, or nil if none was pushed yet.
+func Pushed(uploader cloud.Uploader) *imgspecv1.Descriptor {
+	au, ok := uploader.(*artifactUploader)
+	if !ok {
+		return nil
+	}
+	return au.pushed
+}
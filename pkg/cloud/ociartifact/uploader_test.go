@@ -0,0 +1,76 @@
+package ociartifact_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/testregistry"
+	"github.com/osbuild/images/pkg/cloud/ociartifact"
+	"github.com/osbuild/images/pkg/container"
+)
+
+func TestUploader(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+
+	sbom := filepath.Join(t.TempDir(), "disk.spdx.json")
+	require.NoError(t, os.WriteFile(sbom, []byte(`{"spdxVersion": "SPDX-2.3"}`), 0644))
+
+	uploader, err := ociartifact.NewUploader(registry.GetRef("images/disk")+":centos-9", &ociartifact.UploaderOptions{
+		ArtifactType: "application/vnd.osbuild.qcow2",
+		Filename:     "disk.qcow2",
+		Distro:       "centos-9",
+		Arch:         "x86_64",
+		ImageType:    "qcow2",
+		Annotations:  map[string]string{"org.opencontainers.image.version": "9"},
+		SBOM:         sbom,
+		TLSVerify:    common.ToPtr(false),
+	})
+	require.NoError(t, err)
+	assert.Nil(t, ociartifact.Pushed(uploader))
+
+	var status bytes.Buffer
+	require.NoError(t, uploader.Check(&status))
+	require.NoError(t, uploader.UploadAndRegister(strings.NewReader("disk image"), &status))
+	pushed := ociartifact.Pushed(uploader)
+	require.NotNil(t, pushed)
+	assert.Contains(t, status.String(), "Pushed artifact "+pushed.Digest.String())
+
+	data, err := registry.GetManifest("images/disk", "centos-9")
+	require.NoError(t, err)
+	var mf imgspecv1.Manifest
+	require.NoError(t, json.Unmarshal(data, &mf))
+	assert.Equal(t, "application/vnd.osbuild.qcow2", mf.ArtifactType)
+	assert.Equal(t, map[string]string{
+		container.AnnotationDistro:         "centos-9",
+		container.AnnotationArch:           "x86_64",
+		container.AnnotationImageType:      "qcow2",
+		"org.opencontainers.image.version": "9",
+	}, mf.Annotations)
+
+	referrers := registry.Referrers("images/disk", pushed.Digest.String())
+	require.Len(t, referrers, 1)
+	assert.Equal(t, ociartifact.SBOMArtifactType, referrers[0].ArtifactType)
+}
+
+func TestUploaderCheck(t *testing.T) {
+	uploader, err := ociartifact.NewUploader("registry.example.com/images/disk", nil)
+	require.NoError(t, err)
+	assert.EqualError(t, uploader.Check(&bytes.Buffer{}), "artifact type is required")
+
+	uploader, err = ociartifact.NewUploader("registry.example.com/images/disk", &ociartifact.UploaderOptions{
+		ArtifactType: "application/vnd.osbuild.qcow2",
+		SBOM:         "/non-existing/sbom.json",
+	})
+	require.NoError(t, err)
+	assert.ErrorContains(t, uploader.Check(&bytes.Buffer{}), "cannot attach SBOM")
+}
@@ -0,0 +1,148 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/pkg/blobinfocache/none"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+	imgspecs "github.com/opencontainers/image-spec/specs-go"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// Annotations of the artifacts of images
+	AnnotationDistro    = "org.osbuild.distro"
+	AnnotationArch      = "org.osbuild.arch"
+	AnnotationImageType = "org.osbuild.image-type"
+
+	// DefaultArtifactMediaType is the media type of the files of
+	// artifacts without an explicit one
+	DefaultArtifactMediaType = "application/octet-stream"
+)
+
+// ArtifactOptions describe a file that is pushed as an OCI artifact, see
+// https://github.com/opencontainers/image-spec/blob/main/artifacts-guidance.md
+type ArtifactOptions struct {
+	// ArtifactType is the type of the artifact, e.g.
+	// "application/vnd.osbuild.qcow2" (required)
+	ArtifactType string
+
+	// MediaType is the media type of the file, defaults to
+	// DefaultArtifactMediaType
+	MediaType string
+
+	// Filename is the name of the file in the artifact, it is stored in
+	// the "org.opencontainers.image.title" annotation of the file
+	Filename string
+
+	// Annotations of the artifact manifest
+	Annotations map[string]string
+}
+
+// PushArtifact pushes the content of r as an OCI artifact to the Client's
+// Target and returns the descriptor of the artifact manifest.
+func (cl *Client) PushArtifact(ctx context.Context, r io.Reader, opts ArtifactOptions) (imgspecv1.Descriptor, error) {
+	return cl.pushArtifact(ctx, r, opts, nil)
+}
+
+// AttachArtifact pushes the content of r as an OCI artifact that refers
+// to the subject, e.g. the SBOM of an artifact. The artifact is not
+// tagged, registries list it as a referrer of the subject.
+func (cl *Client) AttachArtifact(ctx context.Context, subject imgspecv1.Descriptor, r io.Reader, opts ArtifactOptions) (imgspecv1.Descriptor, error) {
+	return cl.pushArtifact(ctx, r, opts, &subject)
+}
+
+func (cl *Client) pushArtifact(ctx context.Context, r io.Reader, opts ArtifactOptions, subject *imgspecv1.Descriptor) (imgspecv1.Descriptor, error) {
+	if opts.ArtifactType == "" {
+		return imgspecv1.Descriptor{}, fmt.Errorf("artifacts require an artifact type")
+	}
+	mediaType := opts.MediaType
+	if mediaType == "" {
+		mediaType = DefaultArtifactMediaType
+	}
+
+	destRef, err := docker.NewReference(cl.Target)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	dest, err := destRef.NewImageDestination(ctx, cl.sysCtx)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	defer dest.Close()
+
+	layer, err := dest.PutBlob(ctx, r, types.BlobInfo{Size: -1}, none.NoCache, false)
+	if err != nil {
+		return imgspecv1.Descriptor{}, fmt.Errorf("cannot push artifact: %w", err)
+	}
+	config := imgspecv1.DescriptorEmptyJSON
+	if _, err := dest.PutBlob(ctx, bytes.NewReader(config.Data), types.BlobInfo{Digest: config.Digest, Size: config.Size}, none.NoCache, true); err != nil {
+		return imgspecv1.Descriptor{}, fmt.Errorf("cannot push artifact config: %w", err)
+	}
+
+	layerDesc := imgspecv1.Descriptor{
+		MediaType: mediaType,
+		Digest:    layer.Digest,
+		Size:      layer.Size,
+	}
+	if opts.Filename != "" {
+		layerDesc.Annotations = map[string]string{imgspecv1.AnnotationTitle: opts.Filename}
+	}
+	config.Data = nil
+	mf := imgspecv1.Manifest{
+		Versioned:    imgspecs.Versioned{SchemaVersion: 2},
+		MediaType:    imgspecv1.MediaTypeImageManifest,
+		ArtifactType: opts.ArtifactType,
+		Config:       config,
+		Layers:       []imgspecv1.Descriptor{layerDesc},
+		Subject:      subject,
+		Annotations:  opts.Annotations,
+	}
+	data, err := json.Marshal(mf)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	desc := imgspecv1.Descriptor{
+		MediaType:    imgspecv1.MediaTypeImageManifest,
+		ArtifactType: opts.ArtifactType,
+		Digest:       digest.FromBytes(data),
+		Size:         int64(len(data)),
+		Annotations:  opts.Annotations,
+	}
+
+	// referrers are pushed by digest, without a tag
+	manifestRef := cl.Target
+	if subject != nil {
+		manifestRef, err = reference.WithDigest(reference.TrimNamed(cl.Target), desc.Digest)
+		if err != nil {
+			return imgspecv1.Descriptor{}, err
+		}
+	}
+	if err := cl.putManifest(ctx, manifestRef, data); err != nil {
+		return imgspecv1.Descriptor{}, fmt.Errorf("cannot push artifact manifest: %w", err)
+	}
+	return desc, nil
+}
+
+func (cl *Client) putManifest(ctx context.Context, ref reference.Named, data []byte) error {
+	destRef, err := docker.NewReference(ref)
+	if err != nil {
+		return err
+	}
+	dest, err := destRef.NewImageDestination(ctx, cl.sysCtx)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+	if err := dest.PutManifest(ctx, data, nil); err != nil {
+		return err
+	}
+	return dest.Commit(ctx, nil)
+}
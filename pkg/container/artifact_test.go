@@ -0,0 +1,79 @@
+package container_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/testregistry"
+	"github.com/osbuild/images/pkg/container"
+)
+
+func TestClientPushArtifact(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+
+	client, err := container.NewClient(registry.GetRef("images/disk") + ":qcow2")
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+
+	annotations := map[string]string{
+		container.AnnotationDistro:    "centos-9",
+		container.AnnotationArch:      "x86_64",
+		container.AnnotationImageType: "qcow2",
+	}
+	desc, err := client.PushArtifact(context.Background(), strings.NewReader("disk image"), container.ArtifactOptions{
+		ArtifactType: "application/vnd.osbuild.qcow2",
+		Filename:     "disk.qcow2",
+		Annotations:  annotations,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "application/vnd.osbuild.qcow2", desc.ArtifactType)
+
+	// the artifact is tagged
+	data, err := registry.GetManifest("images/disk", "qcow2")
+	require.NoError(t, err)
+	var mf imgspecv1.Manifest
+	require.NoError(t, json.Unmarshal(data, &mf))
+	assert.Equal(t, desc.Digest, digest.FromBytes(data))
+	assert.Equal(t, "application/vnd.osbuild.qcow2", mf.ArtifactType)
+	assert.Equal(t, imgspecv1.MediaTypeEmptyJSON, mf.Config.MediaType)
+	assert.Equal(t, annotations, mf.Annotations)
+	assert.Nil(t, mf.Subject)
+	require.Len(t, mf.Layers, 1)
+	assert.Equal(t, container.DefaultArtifactMediaType, mf.Layers[0].MediaType)
+	assert.Equal(t, "disk.qcow2", mf.Layers[0].Annotations[imgspecv1.AnnotationTitle])
+	content, err := registry.GetBlob("images/disk", mf.Layers[0].Digest.String())
+	require.NoError(t, err)
+	assert.Equal(t, "disk image", string(content))
+
+	// the SBOM refers to the artifact
+	sbomDesc, err := client.AttachArtifact(context.Background(), desc, strings.NewReader(`{"spdxVersion": "SPDX-2.3"}`), container.ArtifactOptions{
+		ArtifactType: "application/spdx+json",
+		MediaType:    "application/spdx+json",
+		Filename:     "disk.spdx.json",
+	})
+	require.NoError(t, err)
+	referrers := registry.Referrers("images/disk", desc.Digest.String())
+	require.Len(t, referrers, 1)
+	assert.Equal(t, sbomDesc.Digest, referrers[0].Digest)
+	assert.Equal(t, "application/spdx+json", referrers[0].ArtifactType)
+
+	// and does not replace the tag
+	data, err = registry.GetManifest("images/disk", "qcow2")
+	require.NoError(t, err)
+	assert.Equal(t, desc.Digest, digest.FromBytes(data))
+}
+
+func TestClientPushArtifactNoType(t *testing.T) {
+	client, err := container.NewClient("registry.example.com/images/disk")
+	require.NoError(t, err)
+	_, err = client.PushArtifact(context.Background(), strings.NewReader("disk image"), container.ArtifactOptions{})
+	assert.EqualError(t, err, "artifacts require an artifact type")
+}
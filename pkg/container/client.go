@@ -111,6 +111,7 @@ type Client struct {
 	// internal state
 	policy *signature.Policy
 	sysCtx *types.SystemContext
+	sign   *SignOptions
 
 	store string // another store location other than the main one, useful for testing
 }
@@ -162,6 +163,12 @@ func NewClient(target string) (*Client, error) {
 	return &client, nil
 }
 
+// SetSignOptions configures the signatures that are added to the uploaded
+// images, if nil the images are not signed.
+func (cl *Client) SetSignOptions(opts *SignOptions) {
+	cl.sign = opts
+}
+
 // SetAuthFilePath sets the location of the `containers-auth.json(5)` file.
 func (cl *Client) SetAuthFilePath(path string) {
 	cl.sysCtx.AuthFilePath = path
@@ -303,20 +310,29 @@ func (cl *Client) UploadImage(ctx context.Context, from, tag string) (digest.Dig
 		MaxRetry: cl.MaxRetries,
 	}
 
+	copyOpts := &copy.Options{
+		RemoveSignatures:      false,
+		SignBy:                "",
+		SignPassphrase:        "",
+		ReportWriter:          cl.ReportWriter,
+		SourceCtx:             cl.sysCtx,
+		DestinationCtx:        &targetCtx,
+		ForceManifestMIMEType: "",
+		ImageListSelection:    copy.CopyAllImages,
+		PreserveDigests:       false,
+	}
+	if cl.sign != nil {
+		cleanup, err := cl.sign.apply(copyOpts, &targetCtx)
+		if err != nil {
+			return "", err
+		}
+		defer cleanup()
+	}
+
 	var manifestDigest digest.Digest
 
 	err = retry.RetryIfNecessary(ctx, func() error {
-		manifestBytes, err := copy.Image(ctx, policyContext, destRef, srcRef, &copy.Options{
-			RemoveSignatures:      false,
-			SignBy:                "",
-			SignPassphrase:        "",
-			ReportWriter:          cl.ReportWriter,
-			SourceCtx:             cl.sysCtx,
-			DestinationCtx:        &targetCtx,
-			ForceManifestMIMEType: "",
-			ImageListSelection:    copy.CopyAllImages,
-			PreserveDigests:       false,
-		})
+		manifestBytes, err := copy.Image(ctx, policyContext, destRef, srcRef, copyOpts)
 
 		if err != nil {
 			return err
@@ -559,8 +575,16 @@ func (cl *Client) resolveRawManifest(ctx context.Context, rm RawManifest, local
 // Resolve the Client's Target to the manifest digest and the corresponding image id
 // which is the digest of the configuration object. It uses the architecture and
 // variant specified via SetArchitectureChoice or the corresponding defaults for
-// the host.
+// the host. Remote images are resolved from the mirrors of the registries
+// configuration if it has any for the Target.
 func (cl *Client) Resolve(ctx context.Context, name string, local bool) (Spec, error) {
+	if local {
+		return cl.resolve(ctx, name, true)
+	}
+	return cl.resolveMirrored(ctx, name)
+}
+
+func (cl *Client) resolve(ctx context.Context, name string, local bool) (Spec, error) {
 
 	raw, err := cl.GetManifest(ctx, "", local)
 	if err != nil {
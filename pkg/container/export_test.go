@@ -17,3 +17,11 @@ func NewBlockingResolverWithTestClient(arch string, f func(string) (*Client, err
 	resolver.(*blockingResolver).newClient = f
 	return resolver
 }
+
+func MockIndexHooks(read, put func()) (restore func()) {
+	savedRead, savedPut := indexRead, indexPut
+	indexRead, indexPut = read, put
+	return func() {
+		indexRead, indexPut = savedRead, savedPut
+	}
+}
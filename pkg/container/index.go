@@ -0,0 +1,296 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/opencontainers/go-digest"
+	imgspecs "github.com/opencontainers/image-spec/specs-go"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// maxIndexUpdateAttempts is how often the index is read and merged again
+// when it changed before the update was pushed
+const maxIndexUpdateAttempts = 5
+
+// indexRead and indexPut are called after the index was read for an update
+// and after the update was pushed, replaced in testing
+var (
+	indexRead = func() {}
+	indexPut  = func() {}
+)
+
+// indexLocks serializes the updates of the indexes within the process,
+// keyed by the reference of the index
+var indexLocks sync.Map
+
+// PushIndex pushes the single-arch images of the oci-archives by digest to
+// the repository of the Client's Target and adds them to the OCI index
+// (manifest list) at the Target, which is created if it does not exist.
+// Images of the index with the same platform are replaced, the others are
+// kept. Returns the digest of the index.
+//
+// Registries cannot reject outdated updates, so a concurrent update of the
+// index is detected instead of silently dropped, see updateIndex.
+func (cl *Client) PushIndex(ctx context.Context, archives []string) (digest.Digest, error) {
+	descs := make([]imgspecv1.Descriptor, 0, len(archives))
+	for _, archive := range archives {
+		desc, err := cl.pushArchive(ctx, archive)
+		if err != nil {
+			return "", fmt.Errorf("cannot push %s: %w", archive, err)
+		}
+		for _, other := range descs {
+			if platformsEqual(other.Platform, desc.Platform) {
+				return "", fmt.Errorf("cannot push %s: multiple images for platform %s", archive, platformString(desc.Platform))
+			}
+		}
+		descs = append(descs, desc)
+	}
+	return cl.updateIndex(ctx, descs)
+}
+
+// archivePlatform returns the platform of the single image of an
+// oci-archive, the variant is set to the default of the architecture if
+// the image does not have one
+func (cl *Client) archivePlatform(ctx context.Context, archive string) (*imgspecv1.Platform, error) {
+	ref, err := alltransports.ParseImageName(OCIArchiveTransport + ":" + archive)
+	if err != nil {
+		return nil, err
+	}
+	src, err := ref.NewImageSource(ctx, cl.sysCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	_, mimeType, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.MIMETypeIsMultiImage(mimeType) {
+		return nil, fmt.Errorf("archive contains a multi-image index")
+	}
+	img, err := image.FromUnparsedImage(ctx, cl.sysCtx, image.UnparsedInstance(src, nil))
+	if err != nil {
+		return nil, err
+	}
+	info, err := img.Inspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	platform := &imgspecv1.Platform{
+		OS:           info.Os,
+		Architecture: info.Architecture,
+		Variant:      info.Variant,
+	}
+	if platform.Variant == "" {
+		switch platform.Architecture {
+		case "arm64":
+			platform.Variant = "v8"
+		case "arm":
+			platform.Variant = "v7"
+		}
+	}
+	return platform, nil
+}
+
+// pushArchive pushes the image of an oci-archive by digest and returns its
+// descriptor for the index
+func (cl *Client) pushArchive(ctx context.Context, archive string) (imgspecv1.Descriptor, error) {
+	platform, err := cl.archivePlatform(ctx, archive)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+
+	srcRef, err := alltransports.ParseImageName(OCIArchiveTransport + ":" + archive)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	destRef, err := docker.NewReferenceUnknownDigest(reference.TrimNamed(cl.Target))
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	policyContext, err := signature.NewPolicyContext(cl.policy)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	defer func() {
+		_ = policyContext.Destroy()
+	}()
+
+	destCtx := *cl.sysCtx
+	destCtx.DockerRegistryPushPrecomputeDigests = cl.PrecomputeDigests
+	data, err := copy.Image(ctx, policyContext, destRef, srcRef, &copy.Options{
+		ReportWriter:    cl.ReportWriter,
+		SourceCtx:       cl.sysCtx,
+		DestinationCtx:  &destCtx,
+		PreserveDigests: true,
+	})
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	manifestDigest, err := manifest.Digest(data)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	return imgspecv1.Descriptor{
+		MediaType: manifest.GuessMIMEType(data),
+		Digest:    manifestDigest,
+		Size:      int64(len(data)),
+		Platform:  platform,
+	}, nil
+}
+
+// getIndex returns the images of the index at the Client's Target and the
+// digest of the index, or no images and an empty digest if the tag of the
+// Target does not exist
+func (cl *Client) getIndex(ctx context.Context) ([]imgspecv1.Descriptor, digest.Digest, error) {
+	tagged, ok := cl.Target.(reference.Tagged)
+	if !ok {
+		return nil, "", fmt.Errorf("%s has no tag", cl.Target)
+	}
+	ref, err := docker.NewReference(cl.Target)
+	if err != nil {
+		return nil, "", err
+	}
+	// the images were pushed to the repository already, so it exists
+	tags, err := docker.GetRepositoryTags(ctx, cl.sysCtx, ref)
+	if err != nil {
+		return nil, "", err
+	}
+	if !slices.Contains(tags, tagged.Tag()) {
+		return nil, "", nil
+	}
+
+	src, err := ref.NewImageSource(ctx, cl.sysCtx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer src.Close()
+	raw, mimeType, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if !manifest.MIMETypeIsMultiImage(mimeType) {
+		return nil, "", fmt.Errorf("%s is not an index but %s", cl.Target, mimeType)
+	}
+	list, err := manifest.ListFromBlob(raw, mimeType)
+	if err != nil {
+		return nil, "", err
+	}
+	var descs []imgspecv1.Descriptor
+	for _, instance := range list.Instances() {
+		update, err := list.Instance(instance)
+		if err != nil {
+			return nil, "", err
+		}
+		descs = append(descs, imgspecv1.Descriptor{
+			MediaType:    update.MediaType,
+			ArtifactType: update.ReadOnly.ArtifactType,
+			Digest:       update.Digest,
+			Size:         update.Size,
+			Platform:     update.ReadOnly.Platform,
+			Annotations:  update.ReadOnly.Annotations,
+		})
+	}
+	return descs, digest.FromBytes(raw), nil
+}
+
+// mergeIndex returns the images of the index with the images replaced
+// that have the same platform or digest as one of the new images
+func mergeIndex(current, images []imgspecv1.Descriptor) []imgspecv1.Descriptor {
+	merged := make([]imgspecv1.Descriptor, 0, len(current)+len(images))
+	for _, desc := range current {
+		replaced := false
+		for _, img := range images {
+			if desc.Digest == img.Digest || platformsEqual(desc.Platform, img.Platform) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, desc)
+		}
+	}
+	return append(merged, images...)
+}
+
+// updateIndex adds the images to the index at the Client's Target. The
+// index is read again right before the update is pushed and merged again if
+// it changed in the meantime. After the push the Target has to point at
+// the pushed index, otherwise another client updated it concurrently and an
+// error is returned rather than reporting an update that may have been
+// overwritten.
+func (cl *Client) updateIndex(ctx context.Context, images []imgspecv1.Descriptor) (digest.Digest, error) {
+	lock, _ := indexLocks.LoadOrStore(cl.Target.String(), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	for attempt := 0; attempt < maxIndexUpdateAttempts; attempt++ {
+		current, currentDigest, err := cl.getIndex(ctx)
+		if err != nil {
+			return "", err
+		}
+		index := imgspecv1.Index{
+			Versioned: imgspecs.Versioned{SchemaVersion: 2},
+			MediaType: imgspecv1.MediaTypeImageIndex,
+			Manifests: mergeIndex(current, images),
+		}
+		data, err := json.Marshal(index)
+		if err != nil {
+			return "", err
+		}
+		indexRead()
+
+		_, latestDigest, err := cl.getIndex(ctx)
+		if err != nil {
+			return "", err
+		}
+		if latestDigest != currentDigest {
+			continue
+		}
+		if err := cl.putManifest(ctx, cl.Target, data); err != nil {
+			return "", fmt.Errorf("cannot push index: %w", err)
+		}
+		indexPut()
+
+		indexDigest := digest.FromBytes(data)
+		_, pushedDigest, err := cl.getIndex(ctx)
+		if err != nil {
+			return "", err
+		}
+		if pushedDigest != indexDigest {
+			return "", fmt.Errorf("index %s was modified concurrently, its images may be missing: push again", cl.Target)
+		}
+		return indexDigest, nil
+	}
+	return "", fmt.Errorf("cannot update index %s: it was modified concurrently %d times", cl.Target, maxIndexUpdateAttempts)
+}
+
+func platformString(p *imgspecv1.Platform) string {
+	if p == nil {
+		return "unknown"
+	}
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+func platformsEqual(a, b *imgspecv1.Platform) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.OS == b.OS && a.Architecture == b.Architecture && a.Variant == b.Variant
+}
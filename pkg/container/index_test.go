@@ -0,0 +1,241 @@
+package container_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/testregistry"
+	"github.com/osbuild/images/pkg/container"
+)
+
+// archivesFromRegistry copies the image of each of the arches of a test
+// registry into its own oci-archive, like the container image types build.
+// Images created at different times differ.
+func archivesFromRegistry(t *testing.T, registry *testregistry.Registry, repoName string, created time.Time, arches ...string) map[string]string {
+	t.Helper()
+	repo := registry.AddRepo(repoName)
+	checksum := repo.AddImage(
+		[]testregistry.Blob{testregistry.NewDataBlobFromBase64(testregistry.RootLayer)},
+		arches,
+		"image",
+		created)
+	repo.AddTag(checksum, "latest")
+
+	policyContext, err := signature.NewPolicyContext(&signature.Policy{
+		Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = policyContext.Destroy()
+	}()
+	srcRef, err := alltransports.ParseImageName("docker://" + registry.GetRef(repoName))
+	require.NoError(t, err)
+
+	archives := make(map[string]string, len(arches))
+	for _, arch := range arches {
+		archive := filepath.Join(t.TempDir(), arch+".tar")
+		destRef, err := alltransports.ParseImageName("oci-archive:" + archive)
+		require.NoError(t, err)
+		_, err = copy.Image(context.Background(), policyContext, destRef, srcRef, &copy.Options{
+			ReportWriter:       io.Discard,
+			ImageListSelection: copy.CopySystemImage,
+			SourceCtx: &types.SystemContext{
+				DockerInsecureSkipTLSVerify: types.OptionalBoolTrue,
+				ArchitectureChoice:          arch,
+				OSChoice:                    "linux",
+			},
+		})
+		require.NoError(t, err)
+		archives[arch] = archive
+	}
+	return archives
+}
+
+func getIndex(t *testing.T, registry *testregistry.Registry, repo, ref string) (imgspecv1.Index, digest.Digest) {
+	t.Helper()
+	data, err := registry.GetManifest(repo, ref)
+	require.NoError(t, err)
+	var index imgspecv1.Index
+	require.NoError(t, json.Unmarshal(data, &index))
+	return index, digest.FromBytes(data)
+}
+
+func indexPlatforms(t *testing.T, index imgspecv1.Index) []string {
+	t.Helper()
+	var platforms []string
+	for _, desc := range index.Manifests {
+		require.NotNil(t, desc.Platform)
+		p := desc.Platform.OS + "/" + desc.Platform.Architecture
+		if desc.Platform.Variant != "" {
+			p += "/" + desc.Platform.Variant
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms
+}
+
+func TestClientPushIndex(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	archives := archivesFromRegistry(t, registry, "build/osbuild", time.Time{}, "amd64", "arm64")
+
+	client, err := container.NewClient(registry.GetRef("images/osbuild") + ":latest")
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+
+	indexDigest, err := client.PushIndex(context.Background(), []string{archives["amd64"], archives["arm64"]})
+	require.NoError(t, err)
+
+	index, checksum := getIndex(t, registry, "images/osbuild", "latest")
+	assert.Equal(t, checksum, indexDigest)
+	assert.Equal(t, imgspecv1.MediaTypeImageIndex, index.MediaType)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64/v8"}, indexPlatforms(t, index))
+
+	// the images are pushed by digest
+	for _, desc := range index.Manifests {
+		_, err := registry.GetManifest("images/osbuild", desc.Digest.String())
+		assert.NoError(t, err)
+	}
+}
+
+func TestClientPushIndexUpdate(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	archives := archivesFromRegistry(t, registry, "build/osbuild", time.Time{}, "amd64", "arm64", "ppc64le")
+	rebuilt := archivesFromRegistry(t, registry, "build/osbuild-rebuilt", time.Unix(1700000000, 0), "arm64")
+
+	client, err := container.NewClient(registry.GetRef("images/osbuild") + ":latest")
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+
+	_, err = client.PushIndex(context.Background(), []string{archives["amd64"], archives["arm64"]})
+	require.NoError(t, err)
+	first, _ := getIndex(t, registry, "images/osbuild", "latest")
+
+	// images of other platforms are kept, the ones of the same platform
+	// are replaced
+	indexDigest, err := client.PushIndex(context.Background(), []string{archives["ppc64le"], rebuilt["arm64"]})
+	require.NoError(t, err)
+	index, checksum := getIndex(t, registry, "images/osbuild", "latest")
+	assert.Equal(t, checksum, indexDigest)
+	assert.Equal(t, []string{"linux/amd64", "linux/ppc64le", "linux/arm64/v8"}, indexPlatforms(t, index))
+	assert.Equal(t, first.Manifests[0].Digest, index.Manifests[0].Digest)
+	assert.NotEqual(t, first.Manifests[1].Digest, index.Manifests[2].Digest)
+}
+
+func TestClientPushIndexModifiedConcurrently(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	archives := archivesFromRegistry(t, registry, "build/osbuild", time.Time{}, "amd64", "arm64", "ppc64le")
+	repo := registry.AddRepo("images/osbuild")
+
+	client, err := container.NewClient(registry.GetRef("images/osbuild") + ":latest")
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+
+	// two versions of the index, the second one was pushed by another
+	// client
+	amd64Digest, err := client.PushIndex(context.Background(), []string{archives["amd64"]})
+	require.NoError(t, err)
+	otherDigest, err := client.PushIndex(context.Background(), []string{archives["arm64"]})
+	require.NoError(t, err)
+
+	// the index changed after it was read, it is merged again
+	repo.AddTag(amd64Digest.String(), "latest")
+	restore := container.MockIndexHooks(func() {
+		repo.AddTag(otherDigest.String(), "latest")
+	}, func() {})
+	_, err = client.PushIndex(context.Background(), []string{archives["ppc64le"]})
+	restore()
+	require.NoError(t, err)
+	index, _ := getIndex(t, registry, "images/osbuild", "latest")
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64/v8", "linux/ppc64le"}, indexPlatforms(t, index))
+
+	// the index keeps changing
+	toggle := []digest.Digest{amd64Digest, otherDigest}
+	restore = container.MockIndexHooks(func() {
+		repo.AddTag(toggle[0].String(), "latest")
+		toggle[0], toggle[1] = toggle[1], toggle[0]
+	}, func() {})
+	_, err = client.PushIndex(context.Background(), []string{archives["ppc64le"]})
+	restore()
+	assert.ErrorContains(t, err, "it was modified concurrently 5 times")
+
+	// the index was overwritten after the update was pushed
+	restore = container.MockIndexHooks(func() {}, func() {
+		repo.AddTag(amd64Digest.String(), "latest")
+	})
+	_, err = client.PushIndex(context.Background(), []string{archives["ppc64le"]})
+	restore()
+	assert.ErrorContains(t, err, "was modified concurrently, its images may be missing")
+}
+
+func TestClientPushIndexConcurrent(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	arches := []string{"amd64", "arm64", "ppc64le", "s390x"}
+	archives := archivesFromRegistry(t, registry, "build/osbuild", time.Time{}, arches...)
+	var all []string
+	for _, arch := range arches {
+		all = append(all, archives[arch])
+	}
+
+	var wg sync.WaitGroup
+	digests := make([]digest.Digest, 3)
+	errs := make([]error, len(digests))
+	for i := range digests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := container.NewClient(registry.GetRef("images/osbuild") + ":latest")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			client.SkipTLSVerify()
+			digests[i], errs[i] = client.PushIndex(context.Background(), all)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	// every push results in the same complete index
+	index, checksum := getIndex(t, registry, "images/osbuild", "latest")
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64/v8", "linux/ppc64le", "linux/s390x"}, indexPlatforms(t, index))
+	for _, d := range digests {
+		assert.Equal(t, checksum, d)
+	}
+}
+
+func TestClientPushIndexErrors(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	archives := archivesFromRegistry(t, registry, "build/osbuild", time.Time{}, "amd64")
+
+	client, err := container.NewClient(registry.GetRef("images/osbuild") + ":latest")
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+
+	_, err = client.PushIndex(context.Background(), []string{archives["amd64"], archives["amd64"]})
+	assert.ErrorContains(t, err, "multiple images for platform linux/amd64")
+
+	missing := filepath.Join(t.TempDir(), "missing.tar")
+	_, err = client.PushIndex(context.Background(), []string{missing})
+	assert.ErrorContains(t, err, "cannot push "+missing)
+}
@@ -0,0 +1,194 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/types"
+)
+
+// RegistryMirror configures the mirrors of a registry or a repository,
+// like a [[registry]] table of containers-registries.conf(5)
+type RegistryMirror struct {
+	// Prefix is the registry or repository the configuration applies
+	// to, e.g. "quay.io" or "quay.io/centos-bootc"
+	Prefix string
+
+	// Mirrors are tried in order before the registry itself, they
+	// replace the prefix of the references
+	Mirrors []string
+
+	// MirrorByDigestOnly only uses the mirrors for references with a
+	// digest
+	MirrorByDigestOnly bool
+
+	// Insecure accesses the registry and its mirrors without TLS
+	// verification
+	Insecure bool
+
+	// Blocked rejects all pulls from the registry
+	Blocked bool
+}
+
+type registriesConfMirror struct {
+	Location string `toml:"location"`
+	Insecure bool   `toml:"insecure,omitempty"`
+}
+
+type registriesConfRegistry struct {
+	Prefix             string                 `toml:"prefix"`
+	Location           string                 `toml:"location"`
+	Insecure           bool                   `toml:"insecure,omitempty"`
+	Blocked            bool                   `toml:"blocked,omitempty"`
+	MirrorByDigestOnly bool                   `toml:"mirror-by-digest-only,omitempty"`
+	Mirrors            []registriesConfMirror `toml:"mirror,omitempty"`
+}
+
+type registriesConf struct {
+	Registries []registriesConfRegistry `toml:"registry"`
+}
+
+// WriteRegistriesConf writes the mirror configuration as a
+// containers-registries.conf(5) file to path
+func WriteRegistriesConf(path string, mirrors []RegistryMirror) error {
+	var conf registriesConf
+	for _, m := range mirrors {
+		if m.Prefix == "" {
+			return fmt.Errorf("registry mirror configuration without prefix")
+		}
+		reg := registriesConfRegistry{
+			Prefix:             m.Prefix,
+			Location:           m.Prefix,
+			Insecure:           m.Insecure,
+			Blocked:            m.Blocked,
+			MirrorByDigestOnly: m.MirrorByDigestOnly,
+		}
+		for _, location := range m.Mirrors {
+			reg.Mirrors = append(reg.Mirrors, registriesConfMirror{Location: location, Insecure: m.Insecure})
+		}
+		conf.Registries = append(conf.Registries, reg)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(f).Encode(conf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SetRegistriesConfPath sets the location of the
+// `containers-registries.conf(5)` file that configures the mirrors and
+// blocked or insecure registries.
+func (cl *Client) SetRegistriesConfPath(path string) {
+	cl.sysCtx.SystemRegistriesConfPath = path
+}
+
+// pullSources returns the locations the Client's Target is pulled from,
+// the mirrors first and the registry itself last. It returns nil if no
+// mirrors are configured for the Target.
+func (cl *Client) pullSources() ([]sysregistriesv2.PullSource, error) {
+	reg, err := sysregistriesv2.FindRegistry(cl.sysCtx, cl.Target.String())
+	if err != nil {
+		return nil, fmt.Errorf("cannot load registries configuration: %w", err)
+	}
+	if reg == nil {
+		return nil, nil
+	}
+	if reg.Blocked {
+		return nil, fmt.Errorf("registry %s is blocked in the registries configuration", reg.Prefix)
+	}
+	sources, err := reg.PullSourcesFromReference(cl.Target)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) < 2 {
+		return nil, nil
+	}
+	return sources, nil
+}
+
+// resolveMirrored resolves the Client's Target from the first of its
+// pull sources that has it and records the mirror in the Spec. If no
+// mirror is configured the Target is resolved directly.
+func (cl *Client) resolveMirrored(ctx context.Context, name string) (Spec, error) {
+	sources, err := cl.pullSources()
+	if err != nil {
+		return Spec{}, err
+	}
+	if sources == nil {
+		return cl.resolve(ctx, name, false)
+	}
+
+	var errs []string
+	for _, src := range sources {
+		sysCtx := *cl.sysCtx
+		if src.Endpoint.Insecure {
+			sysCtx.DockerInsecureSkipTLSVerify = types.OptionalBoolTrue
+		}
+		mirror := *cl
+		mirror.Target = src.Reference
+		mirror.sysCtx = &sysCtx
+
+		spec, err := mirror.resolve(ctx, name, false)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", src.Reference, err))
+			continue
+		}
+		spec.Source = cl.Target.Name()
+		if name == "" {
+			spec.LocalName = cl.Target.String()
+		}
+		// the TLS settings of insecure mirrors only apply to the mirror
+		spec.TLSVerify = cl.GetTLSVerify()
+		if src.Reference.Name() != cl.Target.Name() {
+			spec.Mirror = src.Reference.Name()
+			spec.MirrorTLSVerify = mirror.GetTLSVerify()
+		}
+		return spec, nil
+	}
+	return Spec{}, fmt.Errorf("no mirror has the image: %s", strings.Join(errs, "; "))
+}
+
+// registriesConfFile holds the registries.conf that a resolver generates
+// from inline mirror configuration
+type registriesConfFile struct {
+	dir  string
+	path string
+}
+
+// get returns the path of the configuration, which is written on the
+// first call
+func (f *registriesConfFile) get(mirrors []RegistryMirror) (string, error) {
+	if f.path != "" {
+		return f.path, nil
+	}
+	dir, err := os.MkdirTemp("", "registries.conf-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "registries.conf")
+	if err := WriteRegistriesConf(path, mirrors); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	f.dir = dir
+	f.path = path
+	return path, nil
+}
+
+func (f *registriesConfFile) cleanup() {
+	if f.dir != "" {
+		os.RemoveAll(f.dir)
+	}
+	f.dir = ""
+	f.path = ""
+}
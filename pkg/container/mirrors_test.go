@@ -0,0 +1,155 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/testregistry"
+	"github.com/osbuild/images/pkg/arch"
+	"github.com/osbuild/images/pkg/container"
+)
+
+// newMirroredRegistry returns a registry that is not reachable and a
+// mirror of it that has the library/osbuild image
+func newMirroredRegistry(t *testing.T) (primary, mirror *testregistry.Registry) {
+	t.Helper()
+	primary = testregistry.New()
+	primary.Close()
+
+	mirror = testregistry.New()
+	t.Cleanup(mirror.Close)
+	repo := mirror.AddRepo("library/osbuild")
+	checksum := repo.AddImage(
+		[]testregistry.Blob{testregistry.NewDataBlobFromBase64(testregistry.RootLayer)},
+		[]string{"amd64", "ppc64le"},
+		"image",
+		time.Time{})
+	repo.AddTag(checksum, "latest")
+	return primary, mirror
+}
+
+func TestResolverRegistryMirrors(t *testing.T) {
+	primary, mirror := newMirroredRegistry(t)
+	source := primary.GetRef("library/osbuild")
+
+	resolver := container.NewResolver("amd64")
+	resolver.RegistryMirrors = []container.RegistryMirror{
+		{
+			Prefix:   filepath.Dir(primary.GetRef("library")),
+			Mirrors:  []string{filepath.Dir(mirror.GetRef("library"))},
+			Insecure: true,
+		},
+	}
+	resolver.Add(container.SourceSpec{Source: source})
+	specs, err := resolver.Finish()
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+
+	want, err := mirror.Resolve(mirror.GetRef("library/osbuild"), arch.ARCH_X86_64)
+	require.NoError(t, err)
+	assert.Equal(t, source, specs[0].Source)
+	assert.Equal(t, source+":latest", specs[0].LocalName)
+	assert.Equal(t, mirror.GetRef("library/osbuild"), specs[0].Mirror)
+	assert.Equal(t, want.Digest, specs[0].Digest)
+	assert.Equal(t, want.ImageID, specs[0].ImageID)
+	assert.Equal(t, want.ListDigest, specs[0].ListDigest)
+	// the mirror is accessed without TLS verification, the registry
+	// itself with the settings of the source
+	assert.Equal(t, common.ToPtr(false), specs[0].MirrorTLSVerify)
+	assert.Nil(t, specs[0].TLSVerify)
+}
+
+func TestResolverRegistriesConfPath(t *testing.T) {
+	primary, mirror := newMirroredRegistry(t)
+
+	path := filepath.Join(t.TempDir(), "registries.conf")
+	err := container.WriteRegistriesConf(path, []container.RegistryMirror{
+		{
+			Prefix:   primary.GetRef("library/osbuild"),
+			Mirrors:  []string{mirror.GetRef("library/osbuild")},
+			Insecure: true,
+		},
+	})
+	require.NoError(t, err)
+
+	resolver := container.NewResolver("amd64")
+	resolver.RegistriesConfPath = path
+	resolver.Add(container.SourceSpec{Source: primary.GetRef("library/osbuild"), Name: "osbuild"})
+	specs, err := resolver.Finish()
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	assert.Equal(t, "osbuild", specs[0].LocalName)
+	assert.Equal(t, mirror.GetRef("library/osbuild"), specs[0].Mirror)
+}
+
+func TestResolverRegistryMirrorsFail(t *testing.T) {
+	primary, mirror := newMirroredRegistry(t)
+	prefix := primary.GetRef("library/osbuild")
+
+	for _, tc := range []struct {
+		name   string
+		mirror container.RegistryMirror
+		err    string
+	}{
+		{
+			name:   "blocked",
+			mirror: container.RegistryMirror{Prefix: prefix, Blocked: true},
+			err:    "is blocked in the registries configuration",
+		},
+		{
+			name:   "missing-image",
+			mirror: container.RegistryMirror{Prefix: prefix, Mirrors: []string{mirror.GetRef("library/missing")}, Insecure: true},
+			err:    "no mirror has the image",
+		},
+		{
+			name:   "no-prefix",
+			mirror: container.RegistryMirror{Mirrors: []string{mirror.GetRef("library/osbuild")}},
+			err:    "registry mirror configuration without prefix",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := container.NewResolver("amd64")
+			resolver.RegistryMirrors = []container.RegistryMirror{tc.mirror}
+			resolver.Add(container.SourceSpec{Source: prefix})
+			_, err := resolver.Finish()
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestWriteRegistriesConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registries.conf")
+	err := container.WriteRegistriesConf(path, []container.RegistryMirror{
+		{
+			Prefix:             "quay.io/centos-bootc",
+			Mirrors:            []string{"mirror.example.com/centos-bootc"},
+			MirrorByDigestOnly: true,
+		},
+		{
+			Prefix:  "docker.io",
+			Blocked: true,
+		},
+	})
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `[[registry]]
+  prefix = "quay.io/centos-bootc"
+  location = "quay.io/centos-bootc"
+  mirror-by-digest-only = true
+
+  [[registry.mirror]]
+    location = "mirror.example.com/centos-bootc"
+
+[[registry]]
+  prefix = "docker.io"
+  location = "docker.io"
+  blocked = true
+`, string(content))
+}
@@ -0,0 +1,191 @@
+package container
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+
+	"github.com/osbuild/images/pkg/arch"
+)
+
+const (
+	// Transports of containers from local files instead of a registry
+	OCILayoutTransport  = "oci"
+	OCIArchiveTransport = "oci-archive"
+)
+
+// IsOCISource returns true if the source of a container is an OCI layout
+// directory ("oci:<path>[:<reference>]") or an oci-archive file
+// ("oci-archive:<path>[:<reference>]").
+func IsOCISource(source string) bool {
+	return strings.HasPrefix(source, OCILayoutTransport+":") || strings.HasPrefix(source, OCIArchiveTransport+":")
+}
+
+// fileChecksum returns the sha256 checksum of a file in the
+// "sha256:<hex>" format of the osbuild sources
+func fileChecksum(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// packOCILayout copies the image of an OCI layout into an oci-archive in
+// archiveDir, which is reused if it exists already, and returns its path.
+func packOCILayout(ctx context.Context, src types.ImageReference, manifestDigest digest.Digest, archiveDir string) (string, error) {
+	dest := filepath.Join(archiveDir, manifestDigest.Encoded()+".tar")
+	if _, err := os.Stat(dest); err == nil {
+		return dest, nil
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return "", err
+	}
+
+	// the temporary archive is only renamed if the copy succeeds
+	tmp := dest + ".part"
+	defer os.Remove(tmp)
+	destRef, err := alltransports.ParseImageName(OCIArchiveTransport + ":" + tmp)
+	if err != nil {
+		return "", err
+	}
+	policyContext, err := signature.NewPolicyContext(&signature.Policy{
+		Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = policyContext.Destroy()
+	}()
+	if _, err := copy.Image(ctx, policyContext, destRef, src, &copy.Options{
+		ReportWriter:       io.Discard,
+		ImageListSelection: copy.CopyAllImages,
+		PreserveDigests:    true,
+	}); err != nil {
+		return "", fmt.Errorf("cannot pack oci layout: %w", err)
+	}
+	return dest, os.Rename(tmp, dest)
+}
+
+// resolveOCI resolves a container from an OCI layout or an oci-archive to
+// the manifest digest and image id of the image for arch. The images of
+// OCI layouts are packed into oci-archives in archiveDir, so they can be
+// used as sources of the build.
+func resolveOCI(ctx context.Context, src SourceSpec, archName, archiveDir string) (Spec, error) {
+	if src.Name == "" {
+		return Spec{}, fmt.Errorf("a name is required for containers from oci layouts and archives")
+	}
+	ref, err := alltransports.ParseImageName(src.Source)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	// only used for the architecture choice
+	cl := Client{sysCtx: &types.SystemContext{OSChoice: "linux"}}
+	cl.SetArchitectureChoice(archName)
+
+	imgSrc, err := ref.NewImageSource(ctx, cl.sysCtx)
+	if err != nil {
+		return Spec{}, err
+	}
+	defer imgSrc.Close()
+
+	raw, mimeType, err := imgSrc.GetManifest(ctx, nil)
+	if err != nil {
+		return Spec{}, fmt.Errorf("error getting manifest: %w", err)
+	}
+	manifestDigest, err := manifest.Digest(raw)
+	if err != nil {
+		return Spec{}, err
+	}
+	var instance *digest.Digest
+	if manifest.MIMETypeIsMultiImage(mimeType) {
+		list, err := manifest.ListFromBlob(raw, mimeType)
+		if err != nil {
+			return Spec{}, err
+		}
+		chosen, err := list.ChooseInstance(cl.sysCtx)
+		if err != nil {
+			return Spec{}, err
+		}
+		instance = &chosen
+	}
+
+	img, err := image.FromUnparsedImage(ctx, cl.sysCtx, image.UnparsedInstance(imgSrc, instance))
+	if err != nil {
+		return Spec{}, err
+	}
+	info, err := img.Inspect(ctx)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	// the path is separated from the optional reference of the image by
+	// the first colon, like containers/image does
+	archive, _, _ := strings.Cut(ref.StringWithinTransport(), ":")
+	if ref.Transport().Name() == OCILayoutTransport {
+		if archiveDir == "" {
+			archiveDir = os.TempDir()
+		}
+		archive, err = packOCILayout(ctx, ref, manifestDigest, archiveDir)
+		if err != nil {
+			return Spec{}, err
+		}
+	}
+	archive, err = filepath.Abs(archive)
+	if err != nil {
+		return Spec{}, err
+	}
+	checksum, err := fileChecksum(archive)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	spec := Spec{
+		Source:               src.Source,
+		Digest:               manifestDigest.String(),
+		ImageID:              img.ConfigInfo().Digest.String(),
+		LocalName:            src.Name,
+		LocalArchive:         archive,
+		LocalArchiveChecksum: checksum,
+		Arch:                 arch.FromString(info.Architecture),
+	}
+	if instance != nil {
+		spec.Digest = instance.String()
+	}
+	return spec, nil
+}
+
+// resolveOCISource resolves a container from an OCI layout or an
+// oci-archive for the resolvers
+func resolveOCISource(ctx context.Context, src SourceSpec, archName, archiveDir string) (Spec, error) {
+	var spec Spec
+	var err error
+	if src.Verify != nil {
+		err = fmt.Errorf("signature verification of containers from oci layouts and archives is not supported")
+	} else {
+		spec, err = resolveOCI(ctx, src, archName, archiveDir)
+	}
+	if err != nil {
+		return Spec{}, fmt.Errorf("'%s': %w", src.Source, err)
+	}
+	return spec, nil
+}
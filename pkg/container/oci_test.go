@@ -0,0 +1,148 @@
+package container_test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/testregistry"
+	"github.com/osbuild/images/pkg/arch"
+	"github.com/osbuild/images/pkg/container"
+)
+
+// copyFromRegistry copies all images of a multi-arch image of a test
+// registry to dest, e.g. an OCI layout
+func copyFromRegistry(t *testing.T, registry *testregistry.Registry, repoName, dest string) {
+	t.Helper()
+	repo := registry.AddRepo(repoName)
+	checksum := repo.AddImage(
+		[]testregistry.Blob{testregistry.NewDataBlobFromBase64(testregistry.RootLayer)},
+		[]string{"amd64", "ppc64le"},
+		"image",
+		time.Time{})
+	repo.AddTag(checksum, "latest")
+
+	srcRef, err := alltransports.ParseImageName("docker://" + registry.GetRef(repoName))
+	require.NoError(t, err)
+	destRef, err := alltransports.ParseImageName(dest)
+	require.NoError(t, err)
+	policyContext, err := signature.NewPolicyContext(&signature.Policy{
+		Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = policyContext.Destroy()
+	}()
+	_, err = copy.Image(context.Background(), policyContext, destRef, srcRef, &copy.Options{
+		ReportWriter:       io.Discard,
+		ImageListSelection: copy.CopyAllImages,
+		SourceCtx:          &types.SystemContext{DockerInsecureSkipTLSVerify: types.OptionalBoolTrue},
+	})
+	require.NoError(t, err)
+}
+
+func TestResolverOCILayout(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	layout := filepath.Join(t.TempDir(), "layout")
+	copyFromRegistry(t, registry, "library/osbuild", "oci:"+layout+":latest")
+
+	archiveDir := t.TempDir()
+	resolver := container.NewResolver("x86_64")
+	resolver.ArchiveDir = archiveDir
+	resolver.Add(container.SourceSpec{
+		Source: "oci:" + layout + ":latest",
+		Name:   "localhost/osbuild",
+	})
+	specs, err := resolver.Finish()
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	spec := specs[0]
+
+	assert.Equal(t, "oci:"+layout+":latest", spec.Source)
+	assert.Equal(t, "localhost/osbuild", spec.LocalName)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", spec.ImageID)
+	assert.Equal(t, arch.ARCH_X86_64, spec.Arch)
+	assert.False(t, spec.LocalStorage)
+	assert.Empty(t, spec.ListDigest)
+
+	// the layout is packed into an oci-archive
+	assert.Equal(t, archiveDir, filepath.Dir(spec.LocalArchive))
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", spec.LocalArchiveChecksum)
+
+	// which resolves to the same image
+	resolver = container.NewResolver("x86_64")
+	resolver.Add(container.SourceSpec{
+		Source: "oci-archive:" + spec.LocalArchive,
+		Name:   "localhost/osbuild",
+	})
+	archiveSpecs, err := resolver.Finish()
+	require.NoError(t, err)
+	require.Len(t, archiveSpecs, 1)
+	assert.Equal(t, spec.Digest, archiveSpecs[0].Digest)
+	assert.Equal(t, spec.ImageID, archiveSpecs[0].ImageID)
+	assert.Equal(t, spec.LocalArchive, archiveSpecs[0].LocalArchive)
+	assert.Equal(t, spec.LocalArchiveChecksum, archiveSpecs[0].LocalArchiveChecksum)
+}
+
+func TestResolverOCIArchive(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	copyFromRegistry(t, registry, "library/osbuild", "oci-archive:"+archive)
+
+	imageIDs := make(map[string]bool)
+	for _, archName := range []string{"x86_64", "ppc64le"} {
+		t.Run(archName, func(t *testing.T) {
+			resolver := container.NewBlockingResolver(archName)
+			resolver.Add(container.SourceSpec{
+				Source: "oci-archive:" + archive,
+				Name:   "localhost/osbuild",
+			})
+			specs, err := resolver.Finish()
+			require.NoError(t, err)
+			require.Len(t, specs, 1)
+			assert.Equal(t, archive, specs[0].LocalArchive)
+			assert.Equal(t, arch.FromString(archName), specs[0].Arch)
+			imageIDs[specs[0].ImageID] = true
+		})
+	}
+	// the image for the architecture is chosen from the index
+	assert.Len(t, imageIDs, 2)
+}
+
+func TestResolverOCIErrors(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "image.tar")
+
+	for _, tc := range []struct {
+		src container.SourceSpec
+		err string
+	}{
+		{
+			src: container.SourceSpec{Source: "oci-archive:" + archive},
+			err: "a name is required for containers from oci layouts and archives",
+		},
+		{
+			src: container.SourceSpec{Source: "oci-archive:" + archive, Name: "localhost/osbuild", Verify: &container.SignaturePolicy{}},
+			err: "signature verification of containers from oci layouts and archives is not supported",
+		},
+		{
+			src: container.SourceSpec{Source: "oci-archive:" + archive, Name: "localhost/osbuild"},
+			err: "archive file not found",
+		},
+	} {
+		resolver := container.NewBlockingResolver("x86_64")
+		resolver.Add(tc.src)
+		_, err := resolver.Finish()
+		assert.ErrorContains(t, err, tc.err)
+	}
+}
@@ -25,8 +25,19 @@ type asyncResolver struct {
 
 	Arch         string
 	AuthFilePath string
+	// ArchiveDir is where the images of OCI layouts are packed into
+	// oci-archives, defaults to the temporary directory
+	ArchiveDir string
+
+	// RegistriesConfPath is a containers-registries.conf(5) file with the
+	// mirrors of the registries, RegistryMirrors configures them inline
+	// instead
+	RegistriesConfPath string
+	RegistryMirrors    []RegistryMirror
 
 	newClient func(string) (*Client, error)
+
+	registriesConf registriesConfFile
 }
 
 type SourceSpec struct {
@@ -35,6 +46,10 @@ type SourceSpec struct {
 	Digest    *string
 	TLSVerify *bool
 	Local     bool
+
+	// Verify rejects the container unless its signatures are accepted
+	// by the policy, if nil the signatures are not verified
+	Verify *SignaturePolicy
 }
 
 // XXX: use arch.Arch here?
@@ -51,8 +66,16 @@ func NewResolver(arch string) *asyncResolver {
 }
 
 func (r *asyncResolver) Add(spec SourceSpec) {
-	client, err := r.newClient(spec.Source)
 	r.jobs += 1
+	if IsOCISource(spec.Source) {
+		go func() {
+			resolved, err := resolveOCISource(r.ctx, spec, r.Arch, r.ArchiveDir)
+			r.queue <- resolveResult{spec: resolved, err: err}
+		}()
+		return
+	}
+
+	client, err := r.newClient(spec.Source)
 
 	if err != nil {
 		r.queue <- resolveResult{err: err}
@@ -64,17 +87,22 @@ func (r *asyncResolver) Add(spec SourceSpec) {
 	if r.AuthFilePath != "" {
 		client.SetAuthFilePath(r.AuthFilePath)
 	}
+	if err := setRegistriesConf(client, r.RegistriesConfPath, r.RegistryMirrors, &r.registriesConf); err != nil {
+		r.queue <- resolveResult{err: err}
+		return
+	}
 
 	go func() {
-		spec, err := client.Resolve(r.ctx, spec.Name, spec.Local)
+		resolved, err := resolveAndVerify(r.ctx, client, spec)
 		if err != nil {
 			err = fmt.Errorf("'%s': %w", spec.Source, err)
 		}
-		r.queue <- resolveResult{spec: spec, err: err}
+		r.queue <- resolveResult{spec: resolved, err: err}
 	}()
 }
 
 func (r *asyncResolver) Finish() ([]Spec, error) {
+	defer r.registriesConf.cleanup()
 
 	specs := make([]Spec, 0, r.jobs)
 	errs := make([]string, 0, r.jobs)
@@ -103,9 +131,15 @@ func (r *asyncResolver) Finish() ([]Spec, error) {
 type blockingResolver struct {
 	Arch         string
 	AuthFilePath string
+	ArchiveDir   string
+
+	RegistriesConfPath string
+	RegistryMirrors    []RegistryMirror
 
 	newClient func(string) (*Client, error)
 
+	registriesConf registriesConfFile
+
 	results []resolveResult
 }
 
@@ -120,6 +154,12 @@ func NewBlockingResolver(arch string) Resolver {
 }
 
 func (r *blockingResolver) Add(src SourceSpec) {
+	if IsOCISource(src.Source) {
+		spec, err := resolveOCISource(context.TODO(), src, r.Arch, r.ArchiveDir)
+		r.results = append(r.results, resolveResult{spec: spec, err: err})
+		return
+	}
+
 	client, err := r.newClient(src.Source)
 	if err != nil {
 		r.results = append(r.results, resolveResult{err: err})
@@ -131,8 +171,12 @@ func (r *blockingResolver) Add(src SourceSpec) {
 	if r.AuthFilePath != "" {
 		client.SetAuthFilePath(r.AuthFilePath)
 	}
+	if err := setRegistriesConf(client, r.RegistriesConfPath, r.RegistryMirrors, &r.registriesConf); err != nil {
+		r.results = append(r.results, resolveResult{err: err})
+		return
+	}
 
-	spec, err := client.Resolve(context.TODO(), src.Name, src.Local)
+	spec, err := resolveAndVerify(context.TODO(), client, src)
 	if err != nil {
 		err = fmt.Errorf("'%s': %w", src.Source, err)
 	}
@@ -140,6 +184,7 @@ func (r *blockingResolver) Add(src SourceSpec) {
 }
 
 func (r *blockingResolver) Finish() ([]Spec, error) {
+	defer r.registriesConf.cleanup()
 	specs := make([]Spec, 0, len(r.results))
 	errs := make([]string, 0, len(r.results))
 	for _, result := range r.results {
@@ -160,3 +205,19 @@ func (r *blockingResolver) Finish() ([]Spec, error) {
 
 	return specs, nil
 }
+
+// setRegistriesConf configures the registries of a client of a resolver,
+// inline mirrors are written to the resolver's registries.conf file
+func setRegistriesConf(client *Client, path string, mirrors []RegistryMirror, file *registriesConfFile) error {
+	if len(mirrors) > 0 {
+		var err error
+		path, err = file.get(mirrors)
+		if err != nil {
+			return err
+		}
+	}
+	if path != "" {
+		client.SetRegistriesConfPath(path)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package container
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+)
+
+// SignOptions configures how the images that are uploaded by a Client are
+// signed. The signatures are created for the manifest digest that
+// UploadImage() returns, for multi-arch images it is the digest of the
+// manifest list.
+type SignOptions struct {
+	// SigstorePrivateKeyFile signs the image with a sigstore (cosign)
+	// private key, the signatures are attached to the image in the
+	// registry
+	SigstorePrivateKeyFile string
+	SigstorePassphrase     []byte
+
+	// GPGKeyID signs the image with the GPG key from the keyring of the
+	// user, as a simple signing signature. This requires the gpgme backend
+	// of containers/image, the containers_image_openpgp one cannot sign.
+	GPGKeyID      string
+	GPGPassphrase string
+
+	// LookasideStaging is the directory the simple signing signatures are
+	// written to, to be published on a lookaside server
+	LookasideStaging string
+
+	// RegistriesDirPath is a containers-registries.d(5) directory that
+	// defines where the signatures are written, it is used instead of the
+	// configuration that is derived from the options above
+	RegistriesDirPath string
+}
+
+// checkGPGSigning returns an error if simple signing signatures cannot be
+// created, e.g. because containers/image is built with the
+// containers_image_openpgp tag, which only supports verification
+func checkGPGSigning() error {
+	mech, err := signature.NewGPGSigningMechanism()
+	if err != nil {
+		return fmt.Errorf("cannot initialize GPG signing: %w", err)
+	}
+	defer mech.Close()
+	if err := mech.SupportsSigning(); err != nil {
+		return fmt.Errorf("simple signing signatures are not supported: %w", err)
+	}
+	return nil
+}
+
+// registriesD returns the registries.d configuration that writes the
+// signatures of the image
+func (o *SignOptions) registriesD() (string, error) {
+	content := "default-docker:\n"
+	if o.SigstorePrivateKeyFile != "" {
+		content += "  use-sigstore-attachments: true\n"
+	}
+	if o.LookasideStaging != "" {
+		dir, err := filepath.Abs(o.LookasideStaging)
+		if err != nil {
+			return "", err
+		}
+		content += fmt.Sprintf("  lookaside-staging: %s\n", (&url.URL{Scheme: "file", Path: dir}).String())
+	}
+	return content, nil
+}
+
+// apply adds the signing options to the options of the copy and the
+// system context of the destination. The returned cleanup function
+// has to be called after the copy.
+func (o *SignOptions) apply(opts *copy.Options, destCtx *types.SystemContext) (cleanup func(), err error) {
+	if o.SigstorePrivateKeyFile == "" && o.GPGKeyID == "" {
+		return nil, fmt.Errorf("signing requires a sigstore private key or a GPG key ID")
+	}
+	if o.GPGKeyID != "" && o.LookasideStaging == "" && o.RegistriesDirPath == "" {
+		return nil, fmt.Errorf("simple signing signatures require a lookaside staging directory")
+	}
+	if o.GPGKeyID != "" {
+		if err := checkGPGSigning(); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.SignBySigstorePrivateKeyFile = o.SigstorePrivateKeyFile
+	opts.SignSigstorePrivateKeyPassphrase = o.SigstorePassphrase
+	opts.SignBy = o.GPGKeyID
+	opts.SignPassphrase = o.GPGPassphrase
+
+	if o.RegistriesDirPath != "" {
+		destCtx.RegistriesDirPath = o.RegistriesDirPath
+		return func() {}, nil
+	}
+
+	content, err := o.registriesD()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "registries.d-")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sign.yaml"), []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	destCtx.RegistriesDirPath = dir
+	return func() { os.RemoveAll(dir) }, nil
+}
@@ -0,0 +1,175 @@
+package container_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/signature/sigstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/testregistry"
+	"github.com/osbuild/images/pkg/container"
+)
+
+func TestClientUploadImageSigned(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	repo := registry.AddRepo("library/osbuild")
+	repo.AddImage(
+		[]testregistry.Blob{testregistry.NewDataBlobFromBase64(testregistry.RootLayer)},
+		[]string{"amd64", "ppc64le"},
+		"image",
+		time.Time{})
+
+	passphrase := []byte("secret")
+	keys, err := sigstore.GenerateKeyPair(passphrase)
+	require.NoError(t, err)
+	privateKeyFile := filepath.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(privateKeyFile, keys.PrivateKey, 0600))
+
+	dest := registry.GetRef("signed/osbuild")
+	client, err := container.NewClient(dest)
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+	client.ReportWriter = io.Discard
+	client.SetSignOptions(&container.SignOptions{
+		SigstorePrivateKeyFile: privateKeyFile,
+		SigstorePassphrase:     passphrase,
+	})
+	signedDigest, err := client.UploadImage(context.Background(), "docker://"+registry.GetRef("library/osbuild"), "")
+	require.NoError(t, err)
+
+	// the uploaded image is accepted by a policy that requires the key
+	resolver := container.NewBlockingResolver("amd64")
+	resolver.Add(container.SourceSpec{
+		Source:    dest,
+		TLSVerify: common.ToPtr(false),
+		Verify:    &container.SignaturePolicy{SigstoreKeys: []string{string(keys.PublicKey)}},
+	})
+	specs, err := resolver.Finish()
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	assert.Equal(t, signedDigest.String(), specs[0].ListDigest)
+
+	// but not by a policy that requires another key
+	otherKeys, err := sigstore.GenerateKeyPair(passphrase)
+	require.NoError(t, err)
+	resolver = container.NewBlockingResolver("amd64")
+	resolver.Add(container.SourceSpec{
+		Source:    dest,
+		TLSVerify: common.ToPtr(false),
+		Verify:    &container.SignaturePolicy{SigstoreKeys: []string{string(otherKeys.PublicKey)}},
+	})
+	_, err = resolver.Finish()
+	assert.ErrorContains(t, err, "signature verification failed")
+}
+
+func TestClientUploadImageSignedGPG(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	repo := registry.AddRepo("library/osbuild")
+	repo.AddImage(
+		[]testregistry.Blob{testregistry.NewDataBlobFromBase64(testregistry.RootLayer)},
+		[]string{"amd64"},
+		"image",
+		time.Time{})
+
+	t.Setenv("GNUPGHOME", t.TempDir())
+	lookaside := t.TempDir()
+	dest := registry.GetRef("signed/osbuild")
+	client, err := container.NewClient(dest)
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+	client.ReportWriter = io.Discard
+	signOpts := &container.SignOptions{
+		GPGKeyID:         "signer@example.com",
+		LookasideStaging: lookaside,
+	}
+	client.SetSignOptions(signOpts)
+
+	mech, err := signature.NewGPGSigningMechanism()
+	require.NoError(t, err)
+	defer mech.Close()
+	if err := mech.SupportsSigning(); err != nil {
+		// the openpgp backend of containers/image can only verify
+		_, err := client.UploadImage(context.Background(), "docker://"+registry.GetRef("library/osbuild"), "v1")
+		assert.ErrorContains(t, err, "simple signing signatures are not supported: ")
+		return
+	}
+
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is required to create the signing key")
+	}
+	// #nosec G204
+	output, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", signOpts.GPGKeyID, "default", "default", "never").CombinedOutput()
+	require.NoError(t, err, string(output))
+	publicKey, err := exec.Command("gpg", "--armor", "--export", signOpts.GPGKeyID).Output()
+	require.NoError(t, err)
+
+	_, err = client.UploadImage(context.Background(), "docker://"+registry.GetRef("library/osbuild"), "v1")
+	require.NoError(t, err)
+
+	// the signature in the lookaside staging directory is accepted by a
+	// policy that requires the key
+	resolver := container.NewBlockingResolver("amd64")
+	resolver.Add(container.SourceSpec{
+		Source:    dest + ":v1",
+		TLSVerify: common.ToPtr(false),
+		Verify: &container.SignaturePolicy{
+			GPGKeys:     []string{string(publicKey)},
+			RegistriesD: fmt.Sprintf("docker:\n  %s:\n    lookaside: file://%s\n", dest, lookaside),
+		},
+	})
+	_, err = resolver.Finish()
+	assert.NoError(t, err)
+}
+
+func TestClientUploadImageUnsigned(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	repo := registry.AddRepo("library/osbuild")
+	repo.AddImage(
+		[]testregistry.Blob{testregistry.NewDataBlobFromBase64(testregistry.RootLayer)},
+		[]string{"amd64"},
+		"image",
+		time.Time{})
+
+	dest := registry.GetRef("unsigned/osbuild")
+	client, err := container.NewClient(dest)
+	require.NoError(t, err)
+	client.SkipTLSVerify()
+	client.ReportWriter = io.Discard
+	_, err = client.UploadImage(context.Background(), "docker://"+registry.GetRef("library/osbuild"), "v1")
+	require.NoError(t, err)
+
+	resolver := container.NewBlockingResolver("amd64")
+	resolver.Add(container.SourceSpec{
+		Source:    dest + ":v1",
+		TLSVerify: common.ToPtr(false),
+		Verify:    &container.SignaturePolicy{SigstoreKeys: []string{sigstorePublicKey(t)}},
+	})
+	_, err = resolver.Finish()
+	assert.ErrorContains(t, err, "A signature was required, but no signature exists")
+}
+
+func TestClientUploadImageSignOptionsErrors(t *testing.T) {
+	client, err := container.NewClient("registry.example.com/image")
+	require.NoError(t, err)
+
+	client.SetSignOptions(&container.SignOptions{})
+	_, err = client.UploadImage(context.Background(), "oci-archive:/nonexistent", "")
+	assert.EqualError(t, err, "signing requires a sigstore private key or a GPG key ID")
+
+	client.SetSignOptions(&container.SignOptions{GPGKeyID: "ABCDEF"})
+	_, err = client.UploadImage(context.Background(), "oci-archive:/nonexistent", "")
+	assert.EqualError(t, err, "simple signing signatures require a lookaside staging directory")
+}
@@ -21,6 +21,19 @@ type Spec struct {
 	ListDigest   string // digest of the list manifest at the Source (optional)
 	LocalStorage bool
 
+	// Mirror is the location of the registry mirror the container was
+	// resolved from (optional), it is fetched from there with the TLS
+	// verification of MirrorTLSVerify
+	Mirror          string
+	MirrorTLSVerify *bool
+
+	// LocalArchive is the absolute path of the oci-archive the container
+	// is taken from, LocalArchiveChecksum is the checksum of the file
+	// ("sha256:<hex>"). The sources of such containers cannot be
+	// generated yet, see osbuild.GenSources.
+	LocalArchive         string
+	LocalArchiveChecksum string
+
 	Arch arch.Arch // the architecture of the image
 }
 
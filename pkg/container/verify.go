@@ -0,0 +1,313 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	"github.com/opencontainers/go-digest"
+
+	"github.com/osbuild/images/pkg/customizations/fsnode"
+)
+
+const (
+	// Locations of the signature verification configuration in the image
+	PolicyPath        = "/etc/containers/policy.json"
+	RegistriesDirPath = "/etc/containers/registries.d"
+)
+
+// sigstoreAttachmentsRegistriesD looks up sigstore signatures as
+// attachments in the registries, see containers-registries.d(5)
+const sigstoreAttachmentsRegistriesD = `default-docker:
+  use-sigstore-attachments: true
+`
+
+// A SignaturePolicy describes how the signatures of a container image
+// are verified when it is resolved. All of the configured requirements
+// have to be met.
+type SignaturePolicy struct {
+	// Policy is a complete policy in the containers-policy.json(5)
+	// format, it is used instead of the requirements below
+	Policy string
+
+	// SigstoreKeys are PEM encoded public keys, the image needs a
+	// sigstore (cosign) signature by one of them
+	SigstoreKeys []string
+
+	// GPGKeys are ASCII armored public keys, the image needs a simple
+	// signing signature by one of them
+	GPGKeys []string
+
+	// Fulcio requires a keyless sigstore signature with a certificate
+	// issued by Fulcio, which is verified offline with the Rekor bundle
+	// embedded in the signature
+	Fulcio *FulcioPolicy
+
+	// RegistriesD is a containers-registries.d(5) configuration that
+	// defines where the signatures are looked up. If unset sigstore
+	// signatures are looked up as registry attachments and simple
+	// signing signatures with the configuration of the host.
+	RegistriesD string
+
+	// Install the policy and the registries.d configuration into the
+	// image, so the container is verified again when it is updated
+	Install bool
+}
+
+// FulcioPolicy identifies the signer of keyless sigstore signatures
+type FulcioPolicy struct {
+	CAData         string // PEM encoded Fulcio CA certificates
+	OIDCIssuer     string
+	SubjectEmail   string
+	RekorPublicKey string // PEM encoded public key of the Rekor log
+}
+
+func (p *SignaturePolicy) usesSigstore() bool {
+	return len(p.SigstoreKeys) > 0 || p.Fulcio != nil
+}
+
+// requirements returns the policy requirements that an image has to meet
+func (p *SignaturePolicy) requirements() (signature.PolicyRequirements, error) {
+	var reqs signature.PolicyRequirements
+	if len(p.SigstoreKeys) > 0 {
+		keys := make([][]byte, len(p.SigstoreKeys))
+		for idx, key := range p.SigstoreKeys {
+			keys[idx] = []byte(key)
+		}
+		req, err := signature.NewPRSigstoreSigned(signature.PRSigstoreSignedWithKeyDatas(keys), signature.PRSigstoreSignedWithSignedIdentity(signature.NewPRMMatchRepoDigestOrExact()))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sigstore keys: %w", err)
+		}
+		reqs = append(reqs, req)
+	}
+	if p.Fulcio != nil {
+		fulcio, err := signature.NewPRSigstoreSignedFulcio(
+			signature.PRSigstoreSignedFulcioWithCAData([]byte(p.Fulcio.CAData)),
+			signature.PRSigstoreSignedFulcioWithOIDCIssuer(p.Fulcio.OIDCIssuer),
+			signature.PRSigstoreSignedFulcioWithSubjectEmail(p.Fulcio.SubjectEmail),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fulcio policy: %w", err)
+		}
+		req, err := signature.NewPRSigstoreSigned(
+			signature.PRSigstoreSignedWithFulcio(fulcio),
+			signature.PRSigstoreSignedWithRekorPublicKeyData([]byte(p.Fulcio.RekorPublicKey)),
+			signature.PRSigstoreSignedWithSignedIdentity(signature.NewPRMMatchRepoDigestOrExact()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fulcio policy: %w", err)
+		}
+		reqs = append(reqs, req)
+	}
+	if len(p.GPGKeys) > 0 {
+		// a signature by any of the keys of a keyring is accepted
+		var keyring []byte
+		for _, key := range p.GPGKeys {
+			keyring = append(keyring, []byte(key+"\n")...)
+		}
+		req, err := signature.NewPRSignedByKeyData(signature.SBKeyTypeGPGKeys, keyring, signature.NewPRMMatchRepoDigestOrExact())
+		if err != nil {
+			return nil, fmt.Errorf("invalid gpg keys: %w", err)
+		}
+		reqs = append(reqs, req)
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("signature policy without requirements")
+	}
+	return reqs, nil
+}
+
+// signaturePolicy returns the policy that verifies the images
+func (p *SignaturePolicy) signaturePolicy() (*signature.Policy, error) {
+	if p.Policy != "" {
+		policy, err := signature.NewPolicyFromBytes([]byte(p.Policy))
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature policy: %w", err)
+		}
+		return policy, nil
+	}
+	reqs, err := p.requirements()
+	if err != nil {
+		return nil, err
+	}
+	return &signature.Policy{Default: reqs}, nil
+}
+
+// registriesD returns the registries.d configuration that is used to look
+// up the signatures or an empty string to use the one of the host
+func (p *SignaturePolicy) registriesD() string {
+	if p.RegistriesD == "" && p.Policy == "" && p.usesSigstore() {
+		return sigstoreAttachmentsRegistriesD
+	}
+	return p.RegistriesD
+}
+
+// VerifySignatures checks that the image of the Client's Target with the
+// given manifest digest is accepted by the signature policy. For manifest
+// lists the digest of the list has to be used.
+func (cl *Client) VerifySignatures(ctx context.Context, policy *SignaturePolicy, manifestDigest digest.Digest) error {
+	sigPolicy, err := policy.signaturePolicy()
+	if err != nil {
+		return err
+	}
+	policyContext, err := signature.NewPolicyContext(sigPolicy)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = policyContext.Destroy()
+	}()
+
+	sysCtx := *cl.sysCtx
+	if registriesD := policy.registriesD(); registriesD != "" {
+		dir, err := os.MkdirTemp("", "registries.d-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err := os.WriteFile(filepath.Join(dir, "verify.yaml"), []byte(registriesD), 0600); err != nil {
+			return err
+		}
+		sysCtx.RegistriesDirPath = dir
+	}
+
+	ref, err := docker.NewReference(cl.Target)
+	if err != nil {
+		return err
+	}
+	src, err := ref.NewImageSource(ctx, &sysCtx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	allowed, err := policyContext.IsRunningImageAllowed(ctx, image.UnparsedInstance(src, &manifestDigest))
+	if err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	if !allowed {
+		return fmt.Errorf("signature verification failed: image %s is not allowed", manifestDigest)
+	}
+	return nil
+}
+
+// resolveAndVerify resolves a container and verifies its signatures if
+// the source has a signature policy
+func resolveAndVerify(ctx context.Context, client *Client, src SourceSpec) (Spec, error) {
+	if src.Verify != nil && src.Local {
+		return Spec{}, fmt.Errorf("signature verification of containers from the local storage is not supported")
+	}
+	spec, err := client.Resolve(ctx, src.Name, src.Local)
+	if err != nil || src.Verify == nil {
+		return spec, err
+	}
+	// signatures of manifest lists cover all the images of the list
+	manifestDigest := spec.Digest
+	if spec.ListDigest != "" {
+		manifestDigest = spec.ListDigest
+	}
+	if err := client.VerifySignatures(ctx, src.Verify, digest.Digest(manifestDigest)); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}
+
+// PolicyFiles returns the containers-policy.json(5) and the
+// registries.d configuration for the containers whose signature policy
+// should be installed into the image. The requirements of these containers
+// are added to defaultPolicy, the policy that the distribution installs, so
+// its requirements stay in place. If defaultPolicy is empty, all other
+// images are accepted.
+func PolicyFiles(sources []SourceSpec, defaultPolicy string) ([]*fsnode.File, error) {
+	scopes := signature.PolicyTransportScopes{}
+	var registriesD []string
+	// sigstore attachments are enabled per repository, a default-docker
+	// section can only be defined once in registries.d
+	var sigstoreAttachments []string
+	for _, src := range sources {
+		if src.Verify == nil || !src.Verify.Install {
+			continue
+		}
+		if src.Verify.Policy != "" {
+			return nil, fmt.Errorf("container %q: complete signature policies cannot be installed into the image", src.Source)
+		}
+		reqs, err := src.Verify.requirements()
+		if err != nil {
+			return nil, fmt.Errorf("container %q: %w", src.Source, err)
+		}
+		ref, err := reference.ParseNormalizedNamed(src.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %w", src.Source, err)
+		}
+		scopes[ref.Name()] = reqs
+
+		switch {
+		case src.Verify.RegistriesD != "":
+			if !slices.Contains(registriesD, src.Verify.RegistriesD) {
+				registriesD = append(registriesD, src.Verify.RegistriesD)
+			}
+		case src.Verify.usesSigstore():
+			if !slices.Contains(sigstoreAttachments, ref.Name()) {
+				sigstoreAttachments = append(sigstoreAttachments, ref.Name())
+			}
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+
+	policy := &signature.Policy{
+		Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()},
+	}
+	if defaultPolicy != "" {
+		var err error
+		policy, err = signature.NewPolicyFromBytes([]byte(defaultPolicy))
+		if err != nil {
+			return nil, fmt.Errorf("invalid default signature policy: %w", err)
+		}
+	}
+	if policy.Transports == nil {
+		policy.Transports = map[string]signature.PolicyTransportScopes{}
+	}
+	if policy.Transports["docker"] == nil {
+		policy.Transports["docker"] = signature.PolicyTransportScopes{}
+	}
+	// the requirements of a scope that the default policy defines already
+	// have to be met as well
+	for name, reqs := range scopes {
+		policy.Transports["docker"][name] = append(policy.Transports["docker"][name], reqs...)
+	}
+
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	policyFile, err := fsnode.NewFile(PolicyPath, nil, nil, nil, data)
+	if err != nil {
+		return nil, err
+	}
+	files := []*fsnode.File{policyFile}
+
+	if len(sigstoreAttachments) > 0 {
+		content := "docker:\n"
+		for _, name := range sigstoreAttachments {
+			content += fmt.Sprintf("  %s:\n    use-sigstore-attachments: true\n", name)
+		}
+		registriesD = append(registriesD, content)
+	}
+	for idx, content := range registriesD {
+		f, err := fsnode.NewFile(filepath.Join(RegistriesDirPath, fmt.Sprintf("osbuild-%d.yaml", idx)), nil, nil, nil, []byte(content))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
@@ -0,0 +1,193 @@
+package container_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/testregistry"
+	"github.com/osbuild/images/pkg/container"
+)
+
+func sigstorePublicKey(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestResolverVerify(t *testing.T) {
+	registry := testregistry.New()
+	defer registry.Close()
+	repo := registry.AddRepo("library/osbuild")
+	checksum := repo.AddImage(
+		[]testregistry.Blob{testregistry.NewDataBlobFromBase64(testregistry.RootLayer)},
+		[]string{"amd64"},
+		"image",
+		time.Time{})
+	repo.AddTag(checksum, "latest")
+	ref := registry.GetRef("library/osbuild")
+
+	tests := []struct {
+		name   string
+		policy *container.SignaturePolicy
+		local  bool
+		err    string
+	}{
+		{
+			name:   "accept-anything",
+			policy: &container.SignaturePolicy{Policy: `{"default":[{"type":"insecureAcceptAnything"}]}`},
+		},
+		{
+			name:   "reject",
+			policy: &container.SignaturePolicy{Policy: `{"default":[{"type":"reject"}]}`},
+			err:    "signature verification failed: Running image docker://" + ref + ":latest is rejected by policy.",
+		},
+		{
+			name:   "unsigned",
+			policy: &container.SignaturePolicy{SigstoreKeys: []string{sigstorePublicKey(t)}},
+			err:    "signature verification failed: A signature was required, but no signature exists",
+		},
+		{
+			name:   "invalid-policy",
+			policy: &container.SignaturePolicy{Policy: `{}`},
+			err:    "invalid signature policy",
+		},
+		{
+			name:   "no-requirements",
+			policy: &container.SignaturePolicy{},
+			err:    "signature policy without requirements",
+		},
+		{
+			name:   "local",
+			policy: &container.SignaturePolicy{Policy: `{"default":[{"type":"insecureAcceptAnything"}]}`},
+			local:  true,
+			err:    "signature verification of containers from the local storage is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, resolver := range []container.Resolver{container.NewResolver("amd64"), container.NewBlockingResolver("amd64")} {
+				resolver.Add(container.SourceSpec{
+					Source:    ref,
+					TLSVerify: common.ToPtr(false),
+					Local:     tt.local,
+					Verify:    tt.policy,
+				})
+				specs, err := resolver.Finish()
+				if tt.err != "" {
+					assert.ErrorContains(t, err, tt.err)
+					assert.Len(t, specs, 0)
+				} else {
+					assert.NoError(t, err)
+					assert.Len(t, specs, 1)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyFiles(t *testing.T) {
+	key := sigstorePublicKey(t)
+	files, err := container.PolicyFiles([]container.SourceSpec{
+		{
+			Source: "registry.example.com/signed",
+			Verify: &container.SignaturePolicy{SigstoreKeys: []string{key}, Install: true},
+		},
+		{
+			Source: "registry.example.com/lookaside:v1",
+			Verify: &container.SignaturePolicy{
+				GPGKeys:     []string{"-----BEGIN PGP PUBLIC KEY BLOCK-----"},
+				RegistriesD: "docker:\n  registry.example.com/lookaside:\n    lookaside: https://sigs.example.com\n",
+				Install:     true,
+			},
+		},
+		{
+			Source: "registry.example.com/not-installed",
+			Verify: &container.SignaturePolicy{SigstoreKeys: []string{key}},
+		},
+		{
+			Source: "registry.example.com/unverified",
+		},
+	}, "")
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	assert.Equal(t, "/etc/containers/policy.json", files[0].Path())
+	var policy map[string]interface{}
+	require.NoError(t, json.Unmarshal(files[0].Data(), &policy))
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "insecureAcceptAnything"}}, policy["default"])
+	scopes := policy["transports"].(map[string]interface{})["docker"].(map[string]interface{})
+	assert.Len(t, scopes, 2)
+	assert.Equal(t, "sigstoreSigned", scopes["registry.example.com/signed"].([]interface{})[0].(map[string]interface{})["type"])
+	assert.Equal(t, "signedBy", scopes["registry.example.com/lookaside"].([]interface{})[0].(map[string]interface{})["type"])
+
+	assert.Equal(t, "/etc/containers/registries.d/osbuild-0.yaml", files[1].Path())
+	assert.Equal(t, "docker:\n  registry.example.com/lookaside:\n    lookaside: https://sigs.example.com\n", string(files[1].Data()))
+	assert.Equal(t, "/etc/containers/registries.d/osbuild-1.yaml", files[2].Path())
+	assert.Equal(t, "docker:\n  registry.example.com/signed:\n    use-sigstore-attachments: true\n", string(files[2].Data()))
+
+	// nothing to install
+	files, err = container.PolicyFiles([]container.SourceSpec{{Source: "registry.example.com/unverified"}}, "")
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+
+	_, err = container.PolicyFiles([]container.SourceSpec{
+		{
+			Source: "registry.example.com/policy",
+			Verify: &container.SignaturePolicy{Policy: `{"default":[{"type":"reject"}]}`, Install: true},
+		},
+	}, "")
+	assert.EqualError(t, err, `container "registry.example.com/policy": complete signature policies cannot be installed into the image`)
+}
+
+func TestPolicyFilesDefaultPolicy(t *testing.T) {
+	defaultPolicy := `{
+  "default": [{"type": "reject"}],
+  "transports": {
+    "docker": {
+      "registry.example.com/signed": [{"type": "signedBy", "keyType": "GPGKeys", "keyPath": "/etc/pki/key"}],
+      "registry.example.com/vendor": [{"type": "signedBy", "keyType": "GPGKeys", "keyPath": "/etc/pki/key"}]
+    },
+    "docker-daemon": {
+      "": [{"type": "insecureAcceptAnything"}]
+    }
+  }
+}`
+	sources := []container.SourceSpec{
+		{
+			Source: "registry.example.com/signed",
+			Verify: &container.SignaturePolicy{SigstoreKeys: []string{sigstorePublicKey(t)}, Install: true},
+		},
+	}
+	files, err := container.PolicyFiles(sources, defaultPolicy)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// the requirements of the default policy stay in place
+	var policy map[string]interface{}
+	require.NoError(t, json.Unmarshal(files[0].Data(), &policy))
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "reject"}}, policy["default"])
+	transports := policy["transports"].(map[string]interface{})
+	assert.Contains(t, transports, "docker-daemon")
+	scopes := transports["docker"].(map[string]interface{})
+	assert.Len(t, scopes, 2)
+	assert.Len(t, scopes["registry.example.com/vendor"], 1)
+	signed := scopes["registry.example.com/signed"].([]interface{})
+	require.Len(t, signed, 2)
+	assert.Equal(t, "signedBy", signed[0].(map[string]interface{})["type"])
+	assert.Equal(t, "sigstoreSigned", signed[1].(map[string]interface{})["type"])
+
+	_, err = container.PolicyFiles(sources, "{")
+	assert.ErrorContains(t, err, "invalid default signature policy")
+}
@@ -0,0 +1,42 @@
+package secureboot
+
+import (
+	"encoding/pem"
+	"fmt"
+
+	"github.com/osbuild/images/pkg/blueprint"
+)
+
+type Enrollment string
+
+const (
+	// The certificate is expected to be enrolled on the target already
+	EnrollmentNone Enrollment = ""
+	// Import the certificate into the shim MOK list on first boot
+	EnrollmentMOK Enrollment = "mok"
+	// Generate authenticated PK, KEK and db variables for custom enrollment
+	EnrollmentDB Enrollment = "db"
+)
+
+type Options struct {
+	// PEM encoded signing certificate
+	Certificate string
+	Enrollment  Enrollment
+}
+
+func FromBP(bpSecureBoot blueprint.SecureBootCustomization) *Options {
+	return &Options{
+		Certificate: bpSecureBoot.Certificate,
+		Enrollment:  Enrollment(bpSecureBoot.Enrollment),
+	}
+}
+
+// CertificateDER returns the signing certificate in DER encoding, which is
+// what mokutil expects.
+func (o *Options) CertificateDER() ([]byte, error) {
+	block, _ := pem.Decode([]byte(o.Certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("secure boot certificate is not a PEM encoded certificate")
+	}
+	return block.Bytes, nil
+}
@@ -65,6 +65,9 @@ const (
 	UsrPartitionPpc64leGUID = "15BB03AF-77E7-4D4A-B12B-C0D084F7491C" // SD_GPT_USR_PPC64_LE
 	UsrPartitionS390xGUID   = "8A4F5770-50AA-4ED3-874A-99B710DB6FEA" // SD_GPT_USR_S390X
 
+	RootVerityPartitionX86_64GUID  = "2C7357ED-EBD2-46D9-AEC1-23D437EC2BF5" // SD_GPT_ROOT_X86_64_VERITY
+	RootVerityPartitionAarch64GUID = "DF3300CE-D69F-4C92-978C-9BFB0F38D820" // SD_GPT_ROOT_ARM64_VERITY
+
 	// Partition type IDs for DOS disks
 
 	// Partition type ID for BIOS boot partition on dos.
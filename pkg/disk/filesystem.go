@@ -22,6 +22,8 @@ type Filesystem struct {
 	FSTabFreq uint64 `json:"fstab_freq,omitempty"`
 	// The sixth field of fstab(5); fs_passno
 	FSTabPassNo uint64 `json:"fstab_passno,omitempty"`
+	// Create the filesystem with fs-verity support (ext4 only)
+	FSVerity bool `json:"fs_verity,omitempty"`
 }
 
 func init() {
@@ -46,6 +48,7 @@ func (fs *Filesystem) Clone() Entity {
 		FSTabOptions: fs.FSTabOptions,
 		FSTabFreq:    fs.FSTabFreq,
 		FSTabPassNo:  fs.FSTabPassNo,
+		FSVerity:     fs.FSVerity,
 	}
 }
 
@@ -479,6 +479,12 @@ func (pt *PartitionTable) relayout(size uint64) uint64 {
 			continue
 		}
 		partition.Start = start
+		if _, isVerity := partition.Payload.(*Verity); isVerity {
+			// the hash tree grows with the data partition, which is not
+			// laid out yet; size it for the whole disk to be on the safe
+			// side
+			partition.Size = max(partition.Size, VerityHashSize(max(size, pt.Size)))
+		}
 		partition.fitTo(partition.Size)
 		partition.Size = pt.AlignUp(partition.Size)
 		start += partition.Size
@@ -635,6 +641,20 @@ func (pt *PartitionTable) FindMountable(mountpoint string) Mountable {
 	return path[0].(Mountable)
 }
 
+// EnableFSVerity marks the filesystem with the given mountpoint to be created
+// with fs-verity support. Only ext4 filesystems are supported.
+func (pt *PartitionTable) EnableFSVerity(mountpoint string) error {
+	fs, ok := pt.FindMountable(mountpoint).(*Filesystem)
+	if !ok || fs == nil {
+		return fmt.Errorf("no filesystem found for mountpoint %q", mountpoint)
+	}
+	if fs.Type != "ext4" {
+		return fmt.Errorf("fs-verity is not supported on %s filesystem for mountpoint %q", fs.Type, mountpoint)
+	}
+	fs.FSVerity = true
+	return nil
+}
+
 func clampFSSize(mountpoint string, size uint64) uint64 {
 	// set a minimum size of 1GB for all mountpoints
 	// with the exception for '/boot' (= 500 MB)
@@ -839,13 +859,15 @@ func (pt *PartitionTable) ensureBtrfs(architecture arch.Arch) error {
 }
 
 type partitionTableFeatures struct {
-	LVM   bool
-	Btrfs bool
-	XFS   bool
-	FAT   bool
-	EXT4  bool
-	LUKS  bool
-	Swap  bool
+	LVM    bool
+	Btrfs  bool
+	XFS    bool
+	FAT    bool
+	EXT4   bool
+	EROFS  bool
+	LUKS   bool
+	Swap   bool
+	Verity bool
 }
 
 // features examines all of the PartitionTable entities and returns a struct
@@ -873,11 +895,15 @@ func (pt *PartitionTable) features() partitionTableFeatures {
 				ptFeatures.XFS = true
 			case "ext4":
 				ptFeatures.EXT4 = true
+			case "erofs":
+				ptFeatures.EROFS = true
 			}
 		case *Swap:
 			ptFeatures.Swap = true
 		case *LUKSContainer:
 			ptFeatures.LUKS = true
+		case *Verity:
+			ptFeatures.Verity = true
 		case *PartitionTable, *Partition:
 			// nothing to do
 		default:
@@ -911,6 +937,9 @@ func (pt *PartitionTable) GetBuildPackages() []string {
 	if features.EXT4 {
 		packages = append(packages, "e2fsprogs")
 	}
+	if features.EROFS {
+		packages = append(packages, "erofs-utils")
+	}
 	if features.LUKS {
 		packages = append(packages,
 			"clevis",
@@ -918,6 +947,10 @@ func (pt *PartitionTable) GetBuildPackages() []string {
 			"cryptsetup",
 		)
 	}
+	if features.Verity && !features.LUKS {
+		// veritysetup
+		packages = append(packages, "cryptsetup")
+	}
 
 	return packages
 }
@@ -1078,15 +1111,56 @@ func addBootPartition(pt *PartitionTable, bootFsType FSType) error {
 	return nil
 }
 
+// UKIESPSize is the minimum size of the EFI system partition on images that
+// boot a Unified Kernel Image with systemd-boot. The ESP holds the kernel and
+// initrd of every installed UKI, so it needs to be much larger than when it
+// only holds grub2 and shim.
+const UKIESPSize uint64 = 1 * datasizes.GiB
+
+// NewUKIBasePartitionTable derives the base partition table for an image that
+// boots a Unified Kernel Image with systemd-boot from the base partition table
+// of an image type. The BIOS boot partition and the separate /boot partition
+// are dropped, since the UKI is loaded from the ESP, and the ESP is grown to
+// at least UKIESPSize.
+func NewUKIBasePartitionTable(basePT *PartitionTable) (*PartitionTable, error) {
+	pt := basePT.Clone().(*PartitionTable)
+
+	partitions := make([]Partition, 0, len(pt.Partitions))
+	var foundESP bool
+	for _, part := range pt.Partitions {
+		if part.Type == BIOSBootPartitionGUID {
+			continue
+		}
+		if fs, ok := part.Payload.(*Filesystem); ok {
+			switch fs.Mountpoint {
+			case "/boot":
+				continue
+			case "/boot/efi":
+				foundESP = true
+				if part.Size < UKIESPSize {
+					part.Size = UKIESPSize
+				}
+			}
+		}
+		partitions = append(partitions, part)
+	}
+	if !foundESP {
+		return nil, fmt.Errorf("error creating UKI partition table: base partition table has no EFI system partition")
+	}
+	pt.Partitions = partitions
+
+	return pt, nil
+}
+
 // addPartitionsForBootMode creates partitions to satisfy the boot mode requirements:
 //   - BIOS/legacy: adds a 1 MiB BIOS boot partition.
-//   - UEFI: adds a 200 MiB EFI system partition.
+//   - UEFI: adds an EFI system partition of the given size.
 //   - Hybrid: adds both.
 //
 // The function will append the new partitions to the end of the existing
 // partition table therefore it is best to call this function early to put them
 // near the front (as is conventional).
-func addPartitionsForBootMode(pt *PartitionTable, bootMode platform.BootMode) error {
+func addPartitionsForBootMode(pt *PartitionTable, bootMode platform.BootMode, espSize uint64) error {
 	switch bootMode {
 	case platform.BOOT_LEGACY:
 		// add BIOS boot partition
@@ -1098,7 +1172,7 @@ func addPartitionsForBootMode(pt *PartitionTable, bootMode platform.BootMode) er
 		return nil
 	case platform.BOOT_UEFI:
 		// add ESP
-		part, err := mkESP(200*datasizes.MiB, pt.Type)
+		part, err := mkESP(espSize, pt.Type)
 		if err != nil {
 			return err
 		}
@@ -1110,7 +1184,7 @@ func addPartitionsForBootMode(pt *PartitionTable, bootMode platform.BootMode) er
 		if err != nil {
 			return err
 		}
-		esp, err := mkESP(200*datasizes.MiB, pt.Type)
+		esp, err := mkESP(espSize, pt.Type)
 		if err != nil {
 			return err
 		}
@@ -1189,6 +1263,20 @@ type CustomPartitionTableOptions struct {
 	// enable automatic discovery. It has no effect and is not required when
 	// the PartitionTableType is PT_DOS.
 	Architecture arch.Arch
+
+	// Bootloader of the image. For platform.BOOTLOADER_UKI the EFI system
+	// partition is sized to hold the unified kernel images and no /boot
+	// partition is added automatically, since the kernel and initrd are
+	// loaded from the ESP.
+	Bootloader platform.Bootloader
+}
+
+// espSize returns the size of the automatically created EFI system partition.
+func (options *CustomPartitionTableOptions) espSize() uint64 {
+	if options.Bootloader == platform.BOOTLOADER_UKI {
+		return UKIESPSize
+	}
+	return 200 * datasizes.MiB
 }
 
 // Returns the default filesystem type if the fstype is empty. If both are
@@ -1269,12 +1357,14 @@ func NewCustomPartitionTable(customizations *blueprint.DiskCustomization, option
 	// if needed
 	//
 	// TODO: switch to ensure ESP in case customizations already include it
-	if err := addPartitionsForBootMode(pt, options.BootMode); err != nil {
+	if err := addPartitionsForBootMode(pt, options.BootMode, options.espSize()); err != nil {
 		return nil, fmt.Errorf("%s %w", errPrefix, err)
 	}
 	// add the /boot partition (if it is needed)
-	if err := maybeAddBootPartition(pt, customizations, options.DefaultFSType); err != nil {
-		return nil, fmt.Errorf("%s %w", errPrefix, err)
+	if options.Bootloader != platform.BOOTLOADER_UKI {
+		if err := maybeAddBootPartition(pt, customizations, options.DefaultFSType); err != nil {
+			return nil, fmt.Errorf("%s %w", errPrefix, err)
+		}
 	}
 	// add user customized partitions
 	for _, part := range customizations.Partitions {
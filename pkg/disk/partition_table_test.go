@@ -1118,7 +1118,7 @@ func TestAddPartitionsForBootMode(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
 			pt := tc.pt
-			err := disk.AddPartitionsForBootMode(&pt, tc.bootMode)
+			err := disk.AddPartitionsForBootMode(&pt, tc.bootMode, 200*datasizes.MiB)
 			if tc.errmsg == "" {
 				assert.NoError(err)
 				assert.Equal(tc.expected, pt)
@@ -2793,3 +2793,78 @@ func TestPartitionTableFeatures(t *testing.T) {
 		require.Equal(exp, disk.GetPartitionTableFeatures(pt))
 	}
 }
+
+func TestNewUKIBasePartitionTable(t *testing.T) {
+	basePT := testdisk.TestPartitionTables()["plain"]
+
+	pt, err := disk.NewUKIBasePartitionTable(&basePT)
+	require.NoError(t, err)
+
+	require.Len(t, pt.Partitions, 2)
+	assert.Equal(t, disk.EFISystemPartitionGUID, pt.Partitions[0].Type)
+	assert.Equal(t, disk.UKIESPSize, pt.Partitions[0].Size)
+	assert.Nil(t, pt.FindMountable("/boot"))
+	assert.NotNil(t, pt.FindMountable("/"))
+
+	// the base partition table is not modified
+	assert.Len(t, basePT.Partitions, 4)
+	assert.Equal(t, uint64(200*datasizes.MiB), basePT.Partitions[1].Size)
+}
+
+func TestNewUKIBasePartitionTableNoESP(t *testing.T) {
+	basePT := testdisk.MakeFakePartitionTable("/")
+
+	_, err := disk.NewUKIBasePartitionTable(basePT)
+	assert.EqualError(t, err, "error creating UKI partition table: base partition table has no EFI system partition")
+}
+
+func TestNewCustomPartitionTableUKI(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				Type:    "lvm",
+				MinSize: 2 * datasizes.GiB,
+				VGCustomization: blueprint.VGCustomization{
+					Name: "rootvg",
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							Name:    "rootlv",
+							MinSize: 2 * datasizes.GiB,
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType: disk.FS_XFS,
+		BootMode:      platform.BOOT_UEFI,
+		Architecture:  arch.ARCH_X86_64,
+		Bootloader:    platform.BOOTLOADER_UKI,
+	}
+
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rand.New(rand.NewSource(0))) // #nosec G404
+	require.NoError(t, err)
+
+	// the kernel and initrd are loaded from the ESP, so no /boot partition
+	// is needed even though the root filesystem is on LVM
+	assert.Nil(t, pt.FindMountable("/boot"))
+	require.Len(t, pt.Partitions, 2)
+	assert.Equal(t, disk.EFISystemPartitionGUID, pt.Partitions[0].Type)
+	assert.Equal(t, disk.UKIESPSize, pt.Partitions[0].Size)
+}
+
+func TestPartitionTableEnableFSVerity(t *testing.T) {
+	pt := testdisk.MakeFakePartitionTable("/", "/boot/efi")
+
+	require.NoError(t, pt.EnableFSVerity("/"))
+	assert.True(t, pt.FindMountable("/").(*disk.Filesystem).FSVerity)
+	assert.False(t, pt.FindMountable("/boot/efi").(*disk.Filesystem).FSVerity)
+
+	assert.EqualError(t, pt.EnableFSVerity("/boot/efi"), `fs-verity is not supported on vfat filesystem for mountpoint "/boot/efi"`)
+	assert.EqualError(t, pt.EnableFSVerity("/var"), `no filesystem found for mountpoint "/var"`)
+}
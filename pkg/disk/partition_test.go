@@ -40,6 +40,7 @@ func TestMarshalUnmarshalPartitionHappy(t *testing.T) {
 		&disk.LUKSContainer{Passphrase: "secret"},
 		&disk.Btrfs{Label: "foo"},
 		&disk.LVMVolumeGroup{Name: "bar"},
+		&disk.Verity{DataMountpoint: "/"},
 	} {
 		part.Payload = ent
 		js, err := json.Marshal(part)
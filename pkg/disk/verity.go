@@ -0,0 +1,84 @@
+package disk
+
+import (
+	"reflect"
+)
+
+const (
+	// Block and digest size of the dm-verity hash trees created for
+	// Verity payloads (veritysetup defaults with sha256)
+	verityBlockSize  = 4096
+	verityDigestSize = 32
+)
+
+// Verity defines the payload of a dm-verity hash partition. It holds the hash
+// tree that protects the read-only filesystem on another partition of the
+// same partition table. Following the Discoverable Partitions Specification,
+// the hash partition uses the "-verity" partition type matching the type of
+// the data partition, e.g. root-verity for the root partition.
+type Verity struct {
+	// Mountpoint of the filesystem protected by the hash tree
+	DataMountpoint string `json:"data_mountpoint"`
+}
+
+func init() {
+	payloadEntityMap["verity"] = reflect.TypeOf(Verity{})
+}
+
+func (v *Verity) EntityName() string {
+	return "verity"
+}
+
+func (v *Verity) Clone() Entity {
+	if v == nil {
+		return nil
+	}
+
+	return &Verity{
+		DataMountpoint: v.DataMountpoint,
+	}
+}
+
+// VerityHashSize returns the size of the hash device required for the
+// dm-verity hash tree of a data device with the given size, including the
+// verity superblock.
+func VerityHashSize(dataSize uint64) uint64 {
+	hashesPerBlock := uint64(verityBlockSize / verityDigestSize)
+
+	blocks := (dataSize + verityBlockSize - 1) / verityBlockSize
+	// one block for the superblock
+	total := uint64(1)
+	// every level hashes the blocks of the level below, up to a single
+	// block with the digests for the root hash
+	for blocks > 0 {
+		blocks = (blocks + hashesPerBlock - 1) / hashesPerBlock
+		total += blocks
+		if blocks == 1 {
+			break
+		}
+	}
+	return total * verityBlockSize
+}
+
+// FindVerity returns the data and the hash partitions of the dm-verity
+// protected filesystem with the given mountpoint. Returns nil for both if the
+// filesystem is not protected by a hash partition.
+func (pt *PartitionTable) FindVerity(mountpoint string) (data *Partition, hash *Partition) {
+	for idx := range pt.Partitions {
+		part := &pt.Partitions[idx]
+		switch payload := part.Payload.(type) {
+		case *Verity:
+			if payload.DataMountpoint == mountpoint {
+				hash = part
+			}
+		case Mountable:
+			if payload.GetMountpoint() == mountpoint {
+				data = part
+			}
+		}
+	}
+	if hash == nil || data == nil {
+		return nil, nil
+	}
+	return data, hash
+}
@@ -0,0 +1,95 @@
+package disk_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/arch"
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func testVerityPartitionTable() disk.PartitionTable {
+	return disk.PartitionTable{
+		Type: disk.PT_GPT,
+		Partitions: []disk.Partition{
+			{
+				Size: 200 * datasizes.MebiByte,
+				Type: disk.EFISystemPartitionGUID,
+				Payload: &disk.Filesystem{
+					Type:       "vfat",
+					Mountpoint: "/boot/efi",
+				},
+			},
+			{
+				Type: disk.RootVerityPartitionX86_64GUID,
+				Payload: &disk.Verity{
+					DataMountpoint: "/",
+				},
+			},
+			{
+				Size: 2 * datasizes.GibiByte,
+				Type: disk.RootPartitionX86_64GUID,
+				Payload: &disk.Filesystem{
+					Type:         "ext4",
+					Mountpoint:   "/",
+					FSTabOptions: "ro",
+				},
+			},
+		},
+	}
+}
+
+func TestVerityHashSize(t *testing.T) {
+	// superblock only
+	assert.Equal(t, uint64(4096), disk.VerityHashSize(0))
+	// one data block: superblock and the root hash block
+	assert.Equal(t, uint64(2*4096), disk.VerityHashSize(4096))
+	// 128 data blocks fit into one hash block
+	assert.Equal(t, uint64(2*4096), disk.VerityHashSize(128*4096))
+	// 129 data blocks need two leaf hash blocks and a root
+	assert.Equal(t, uint64(4*4096), disk.VerityHashSize(129*4096))
+	// 1 GiB: 262144 blocks -> 2048 + 16 + 1 hash blocks
+	assert.Equal(t, uint64((1+2048+16+1)*4096), disk.VerityHashSize(1*datasizes.GibiByte))
+}
+
+func TestFindVerity(t *testing.T) {
+	pt := testVerityPartitionTable()
+
+	data, hash := pt.FindVerity("/")
+	require.NotNil(t, data)
+	require.NotNil(t, hash)
+	assert.Equal(t, disk.RootPartitionX86_64GUID, data.Type)
+	assert.Equal(t, disk.RootVerityPartitionX86_64GUID, hash.Type)
+
+	data, hash = pt.FindVerity("/boot/efi")
+	assert.Nil(t, data)
+	assert.Nil(t, hash)
+}
+
+func TestNewPartitionTableVerity(t *testing.T) {
+	basePT := testVerityPartitionTable()
+	rng := rand.New(rand.NewSource(13)) // nolint:gosec
+
+	pt, err := disk.NewPartitionTable(&basePT, nil, 8*datasizes.GibiByte, disk.RawPartitioningMode, arch.ARCH_X86_64, nil, rng)
+	require.NoError(t, err)
+
+	data, hash := pt.FindVerity("/")
+	require.NotNil(t, data)
+	require.NotNil(t, hash)
+	// the hash partition is large enough for the grown root partition
+	assert.GreaterOrEqual(t, hash.Size, disk.VerityHashSize(data.Size))
+	assert.NotEmpty(t, hash.UUID)
+	assert.NotEqual(t, data.UUID, hash.UUID)
+	assert.Contains(t, pt.GetBuildPackages(), "cryptsetup")
+}
+
+func TestVerityClone(t *testing.T) {
+	verity := &disk.Verity{DataMountpoint: "/"}
+	clone := verity.Clone()
+	assert.Equal(t, verity, clone)
+	assert.NotSame(t, verity, clone)
+}
@@ -3,6 +3,7 @@ package defs
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -20,7 +21,7 @@ import (
 	"github.com/osbuild/images/pkg/rpmmd"
 )
 
-//go:embed */*.yaml
+//go:embed */*.yaml */*.json
 var data embed.FS
 
 var DataFS fs.FS = data
@@ -47,28 +48,18 @@ type conditions struct {
 	DistroName            map[string]packageSet `yaml:"distro_name,omitempty"`
 }
 
-// PackageSet loads the PackageSet from the yaml source file discovered via the
-// imagetype. By default the imagetype name is used to load the packageset
-// but with "overrideTypeName" this can be overriden (useful for e.g.
-// installer image types).
-func PackageSet(it distro.ImageType, overrideTypeName string, replacements map[string]string) (rpmmd.PackageSet, error) {
-	typeName := it.Name()
-	if overrideTypeName != "" {
-		typeName = overrideTypeName
-	}
-	typeName = strings.ReplaceAll(typeName, "-", "_")
-
-	arch := it.Arch()
-	archName := arch.Name()
-	distribution := arch.Distro()
-	distroNameVer := distribution.Name()
+// splitDistroName returns the name and the version of the distro, e.g.
+// "centos" and "10" for "centos-10".
+func splitDistroName(d distro.Distro) (string, string) {
+	distroNameVer := d.Name()
 	// we need to split from the right for "centos-stream-10" like
 	// distro names, sadly go has no rsplit() so we do it manually
 	// XXX: we cannot use distroidparser here because of import cycles
-	distroName := distroNameVer[:strings.LastIndex(distroNameVer, "-")]
-	distroVersion := strings.SplitN(distroNameVer, "-", 2)[1]
-	distroNameMajorVer := strings.SplitN(distroNameVer, ".", 2)[0]
+	idx := strings.LastIndex(distroNameVer, "-")
+	return distroNameVer[:idx], distroNameVer[idx+1:]
+}
 
+func loadDataFS() fs.FS {
 	// XXX: this is a short term measure, pass a set of
 	// searchPaths down the stack instead
 	var dataFS fs.FS = DataFS
@@ -76,26 +67,77 @@ func PackageSet(it distro.ImageType, overrideTypeName string, replacements map[s
 		logrus.Warnf("using experimental override dir %q", overrideDir)
 		dataFS = os.DirFS(overrideDir)
 	}
+	return dataFS
+}
+
+// distroBaseDir returns the directory of the definitions of the given
+// distro in the data FS.
+func distroBaseDir(d distro.Distro) (string, error) {
+	distroName, distroVersion := splitDistroName(d)
+	distroNameMajorVer := strings.SplitN(d.Name(), ".", 2)[0]
 
 	// XXX: this is only needed temporary until we have a "distros.yaml"
 	// that describes some high-level properties of each distro
 	// (like their yaml dirs)
-	var baseDir string
 	switch distroName {
 	case "rhel":
 		// rhel yaml files are under ./rhel-$majorVer
-		baseDir = distroNameMajorVer
+		return distroNameMajorVer, nil
 	case "centos":
 		// centos yaml is just rhel but we have (sadly) no symlinks
 		// in "go:embed" so we have to have this slightly ugly
 		// workaround
-		baseDir = fmt.Sprintf("rhel-%s", distroVersion)
+		return fmt.Sprintf("rhel-%s", distroVersion), nil
 	case "fedora", "test-distro":
 		// our other distros just have a single yaml dir per distro
 		// and use condition.version_gt etc
-		baseDir = distroName
+		return distroName, nil
 	default:
-		return rpmmd.PackageSet{}, fmt.Errorf("unsupported distro in loader %q (add to loader.go)", distroName)
+		return "", fmt.Errorf("unsupported distro in loader %q (add to loader.go)", distroName)
+	}
+}
+
+// ContainersPolicy returns the /etc/containers/policy.json that the
+// containers-common package of the given distro installs. A
+// "containers-policy-$version.json" file takes precedence over the
+// "containers-policy.json" file of the distro directory, for releases
+// whose package ships a different policy.
+func ContainersPolicy(d distro.Distro) (string, error) {
+	_, distroVersion := splitDistroName(d)
+	baseDir, err := distroBaseDir(d)
+	if err != nil {
+		return "", err
+	}
+
+	dataFS := loadDataFS()
+	data, err := fs.ReadFile(dataFS, filepath.Join(baseDir, fmt.Sprintf("containers-policy-%s.json", distroVersion)))
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = fs.ReadFile(dataFS, filepath.Join(baseDir, "containers-policy.json"))
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot load the containers policy of %s: %w", d.Name(), err)
+	}
+	return string(data), nil
+}
+
+// PackageSet loads the PackageSet from the yaml source file discovered via the
+// imagetype. By default the imagetype name is used to load the packageset
+// but with "overrideTypeName" this can be overriden (useful for e.g.
+// installer image types).
+func PackageSet(it distro.ImageType, overrideTypeName string, replacements map[string]string) (rpmmd.PackageSet, error) {
+	typeName := it.Name()
+	if overrideTypeName != "" {
+		typeName = overrideTypeName
+	}
+	typeName = strings.ReplaceAll(typeName, "-", "_")
+
+	archName := it.Arch().Name()
+	distroName, distroVersion := splitDistroName(it.Arch().Distro())
+
+	dataFS := loadDataFS()
+	baseDir, err := distroBaseDir(it.Arch().Distro())
+	if err != nil {
+		return rpmmd.PackageSet{}, err
 	}
 
 	f, err := dataFS.Open(filepath.Join(baseDir, "distro.yaml"))
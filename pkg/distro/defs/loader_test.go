@@ -2,14 +2,20 @@ package defs_test
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/containers/image/v5/signature"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distro/defs"
+	"github.com/osbuild/images/pkg/distro/fedora"
 	"github.com/osbuild/images/pkg/distro/test_distro"
 	"github.com/osbuild/images/pkg/rpmmd"
 )
@@ -177,3 +183,56 @@ image_types:
 		Exclude: []string{"from-base-condition-exc", "from-base-exc", "from-condition-exc", "from-other-type-exc", "from-type-exc"},
 	}, pkgSet)
 }
+
+func TestContainersPolicyVersionOverride(t *testing.T) {
+	d := test_distro.DistroFactory(test_distro.TestDistro1Name)
+	baseDir := makeFakePkgsSet(t, test_distro.TestDistroNameBase, "")
+	restore := defs.MockDataFS(baseDir)
+	defer restore()
+
+	_, err := defs.ContainersPolicy(d)
+	assert.ErrorContains(t, err, "cannot load the containers policy of test-distro-1")
+
+	policyPath := filepath.Join(baseDir, test_distro.TestDistroNameBase, "containers-policy.json")
+	err = os.WriteFile(policyPath, []byte(`{"default": [{"type": "reject"}]}`), 0644)
+	require.NoError(t, err)
+	policy, err := defs.ContainersPolicy(d)
+	require.NoError(t, err)
+	assert.Equal(t, `{"default": [{"type": "reject"}]}`, policy)
+
+	// the policy of a release takes precedence
+	err = os.WriteFile(filepath.Join(filepath.Dir(policyPath), "containers-policy-1.json"), []byte(`{"default": [{"type": "insecureAcceptAnything"}]}`), 0644)
+	require.NoError(t, err)
+	policy, err = defs.ContainersPolicy(d)
+	require.NoError(t, err)
+	assert.Equal(t, `{"default": [{"type": "insecureAcceptAnything"}]}`, policy)
+}
+
+func TestContainersPoliciesAreValid(t *testing.T) {
+	paths, err := fs.Glob(defs.DataFS, "*/containers-policy*.json")
+	require.NoError(t, err)
+	assert.NotEmpty(t, paths)
+	for _, path := range paths {
+		data, err := fs.ReadFile(defs.DataFS, path)
+		require.NoError(t, err)
+		_, err = signature.NewPolicyFromBytes(data)
+		assert.NoError(t, err, path)
+	}
+}
+
+// The containers-common package of Fedora installs the default-policy.json
+// of containers/image as /etc/containers/policy.json.
+func TestContainersPolicyFedoraMatchesPackage(t *testing.T) {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/containers/image/v5").Output()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		t.Skipf("cannot find the containers/image module: %v", err)
+	}
+	upstream, err := os.ReadFile(filepath.Join(strings.TrimSpace(string(out)), "default-policy.json"))
+	require.NoError(t, err)
+
+	for _, name := range []string{"fedora-41", "fedora-42", "fedora-43"} {
+		policy, err := defs.ContainersPolicy(fedora.DistroFactory(name))
+		require.NoError(t, err)
+		assert.JSONEq(t, string(upstream), policy, name)
+	}
+}
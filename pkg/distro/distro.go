@@ -4,6 +4,7 @@ import (
 	"math/rand"
 
 	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/customizations/subscription"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
@@ -169,3 +170,35 @@ func SeedFrom(p *int64) int64 {
 	}
 	return *p
 }
+
+// ContainerSources returns the container sources of the containers of a
+// blueprint
+func ContainerSources(containers []blueprint.Container) []container.SourceSpec {
+	sources := make([]container.SourceSpec, len(containers))
+	for idx, cont := range containers {
+		sources[idx] = container.SourceSpec{
+			Source:    cont.Source,
+			Name:      cont.Name,
+			TLSVerify: cont.TLSVerify,
+			Local:     cont.LocalStorage,
+		}
+		if verify := cont.Verify; verify != nil {
+			sources[idx].Verify = &container.SignaturePolicy{
+				Policy:       verify.Policy,
+				SigstoreKeys: verify.SigstoreKeys,
+				GPGKeys:      verify.GPGKeys,
+				RegistriesD:  verify.RegistriesD,
+				Install:      verify.Install,
+			}
+			if fulcio := verify.Fulcio; fulcio != nil {
+				sources[idx].Verify.Fulcio = &container.FulcioPolicy{
+					CAData:         fulcio.CAData,
+					OIDCIssuer:     fulcio.OIDCIssuer,
+					SubjectEmail:   fulcio.SubjectEmail,
+					RekorPublicKey: fulcio.RekorPublicKey,
+				}
+			}
+		}
+	}
+	return sources
+}
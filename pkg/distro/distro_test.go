@@ -1,6 +1,7 @@
 package distro_test
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -9,15 +10,19 @@ import (
 
 	"slices"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/datasizes"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distrofactory"
 	"github.com/osbuild/images/pkg/dnfjson"
+	imgmanifest "github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rpmmd"
 	testrepos "github.com/osbuild/images/test/data/repositories"
@@ -77,6 +82,17 @@ func TestImageTypePipelineNames(t *testing.T) {
 							InstallationDevice: "/dev/null",
 						}
 					}
+					if imageType.Name() == "live-installer" {
+						// the live-overlay pipeline only exists with a
+						// persistent overlay
+						customizations = &blueprint.Customizations{
+							Installer: &blueprint.InstallerCustomization{
+								PersistentOverlay: &blueprint.PersistentOverlay{
+									Size: datasizes.GiB,
+								},
+							},
+						}
+					}
 					bp := blueprint.Blueprint{
 						Customizations: customizations,
 					}
@@ -655,3 +671,209 @@ func TestOSTreeOptionsErrorForNonOSTreeImgTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestContainerSources(t *testing.T) {
+	containers := []blueprint.Container{
+		{
+			Source:    "registry.example.com/image",
+			Name:      "image",
+			TLSVerify: common.ToPtr(false),
+		},
+		{
+			Source: "registry.example.com/signed",
+			Verify: &blueprint.ContainerVerify{
+				SigstoreKeys: []string{"key"},
+				Fulcio: &blueprint.ContainerVerifyFulcio{
+					CAData:         "ca",
+					OIDCIssuer:     "https://oidc.example.com",
+					SubjectEmail:   "signer@example.com",
+					RekorPublicKey: "rekor",
+				},
+				Install: true,
+			},
+		},
+	}
+	assert.Equal(t, []container.SourceSpec{
+		{
+			Source:    "registry.example.com/image",
+			Name:      "image",
+			TLSVerify: common.ToPtr(false),
+		},
+		{
+			Source: "registry.example.com/signed",
+			Verify: &container.SignaturePolicy{
+				SigstoreKeys: []string{"key"},
+				Fulcio: &container.FulcioPolicy{
+					CAData:         "ca",
+					OIDCIssuer:     "https://oidc.example.com",
+					SubjectEmail:   "signer@example.com",
+					RekorPublicKey: "rekor",
+				},
+				Install: true,
+			},
+		},
+	}, distro.ContainerSources(containers))
+}
+
+// ostreeTestManifest holds the stage types of a serialized manifest
+type ostreeTestManifest struct {
+	Pipelines []struct {
+		Name   string `json:"name"`
+		Stages []struct {
+			Type string `json:"type"`
+		} `json:"stages"`
+	} `json:"pipelines"`
+}
+
+// serializeOSTreeTestManifest serializes a manifest of an ostree image type
+// with minimal package sets and fake checksums for the ostree commits
+func serializeOSTreeTestManifest(t *testing.T, imageType distro.ImageType, m *imgmanifest.Manifest) ([]byte, *ostreeTestManifest) {
+	t.Helper()
+	depsolvedSets := make(map[string]dnfjson.DepsolveResult)
+	for _, plName := range append(imageType.BuildPipelines(), imageType.PayloadPipelines()...) {
+		depsolvedSets[plName] = dnfjson.DepsolveResult{
+			Packages: []rpmmd.PackageSpec{
+				{Name: "kernel", Checksum: "sha256:a0c936696eb7d5ee3192bf53b9d281cecbb40ca9db520de72cb95817ad92ac72"},
+				{Name: "filesystem", Checksum: "sha256:6b4bf18ba28ccbdd49f2716c9f33c9211155ff703fa6c195c78a07bd160da0eb"},
+			},
+		}
+	}
+	commits := make(map[string][]ostree.CommitSpec)
+	for name, sources := range m.GetOSTreeSourceSpecs() {
+		for _, source := range sources {
+			commits[name] = append(commits[name], ostree.CommitSpec{
+				Ref:      source.Ref,
+				URL:      source.URL,
+				Checksum: fmt.Sprintf("%x", sha256.Sum256([]byte(source.URL+source.Ref))),
+			})
+		}
+	}
+	mf, err := m.Serialize(depsolvedSets, nil, commits, nil)
+	require.NoError(t, err)
+	pm := new(ostreeTestManifest)
+	require.NoError(t, json.Unmarshal(mf, pm))
+	return mf, pm
+}
+
+// Test that the ostree options for new commits apply to the image types that
+// build them and that the stages of the options are added to the
+// ostree-commit pipeline
+func TestOSTreeCommitOptions(t *testing.T) {
+	entity, err := openpgp.NewEntity("osbuild", "", "osbuild@example.com", nil)
+	require.NoError(t, err)
+	var publicKey bytes.Buffer
+	w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	sign := &ostree.SigningOptions{
+		Type:      ostree.SignatureTypeGPG,
+		PublicKey: publicKey.String(),
+		KeyID:     entity.PrimaryKey.KeyIdString(),
+	}
+	verify := &ostree.VerifyOptions{
+		Ed25519Keys: []string{"f0Ihp6xyuGvWiaw4FX7HtchMaSbOxl2DzS5LcWKvb8E="},
+	}
+
+	testCases := []struct {
+		name    string
+		options ostree.ImageOptions
+		// payload pipeline of the image types the options apply to
+		appliesTo string
+		// stages the options add to the ostree-commit pipeline
+		stages []string
+		check  func(t *testing.T, applies bool, m *imgmanifest.Manifest, mf []byte)
+	}{
+		{
+			name: "sign",
+			options: ostree.ImageOptions{
+				URL:    "https://example.com",
+				Sign:   sign,
+				Verify: verify,
+			},
+			appliesTo: "ostree-commit",
+			stages:    []string{"org.osbuild.ostree.sign"},
+			check: func(t *testing.T, applies bool, m *imgmanifest.Manifest, mf []byte) {
+				// the payload commits are verified, the parent commits
+				// of new commits are not
+				for _, sources := range m.GetOSTreeSourceSpecs() {
+					for _, source := range sources {
+						if applies {
+							assert.Nil(t, source.Verify)
+						} else {
+							assert.Equal(t, verify, source.Verify)
+						}
+					}
+				}
+				if applies {
+					assert.Contains(t, string(mf), fmt.Sprintf(`"key_id":%q`, sign.KeyID))
+					assert.Contains(t, string(mf), `"secrets":{"name":"org.osbuild.ostree.sign"}`)
+					assert.NotContains(t, string(mf), "PRIVATE KEY")
+				}
+			},
+		},
+		{
+			name: "static-delta",
+			options: ostree.ImageOptions{
+				URL:              "https://example.com",
+				DeltaFrom:        "previous/ref",
+				DeltaFromScratch: true,
+			},
+			appliesTo: "commit-archive",
+			stages:    []string{"org.osbuild.ostree.static-delta", "org.osbuild.ostree.summary"},
+			check: func(t *testing.T, applies bool, m *imgmanifest.Manifest, mf []byte) {
+				if applies {
+					assert.Equal(t, []ostree.SourceSpec{{URL: "https://example.com", Ref: "previous/ref"}}, m.GetOSTreeSourceSpecs()["ostree-commit"])
+				}
+			},
+		},
+	}
+
+	distroFactory := distrofactory.NewDefault()
+	for _, distroName := range listTestedDistros(t) {
+		d := distroFactory.GetDistro(distroName)
+		arch, err := d.GetArch("x86_64")
+		require.NoError(t, err)
+		for _, imageTypeName := range arch.ListImageTypes() {
+			imageType, err := arch.GetImageType(imageTypeName)
+			require.NoError(t, err)
+			if imageType.OSTreeRef() == "" {
+				continue
+			}
+			var customizations *blueprint.Customizations
+			if imageType.Name() == "edge-simplified-installer" || imageType.Name() == "iot-simplified-installer" {
+				customizations = &blueprint.Customizations{
+					InstallationDevice: "/dev/null",
+				}
+			}
+			for _, tc := range testCases {
+				t.Run(fmt.Sprintf("%s/%s/%s", distroName, imageTypeName, tc.name), func(t *testing.T) {
+					ostreeOptions := tc.options
+					options := distro.ImageOptions{OSTree: &ostreeOptions}
+					m, _, err := imageType.Manifest(&blueprint.Blueprint{Customizations: customizations}, options, nil, nil)
+					require.NoError(t, err)
+
+					mf, pm := serializeOSTreeTestManifest(t, imageType, m)
+					var stages []string
+					for _, pipeline := range pm.Pipelines {
+						for _, stage := range pipeline.Stages {
+							if slices.Contains(tc.stages, stage.Type) {
+								assert.Equal(t, "ostree-commit", pipeline.Name)
+								stages = append(stages, stage.Type)
+							}
+						}
+					}
+
+					// the options do not apply to other image types
+					applies := slices.Contains(imageType.PayloadPipelines(), tc.appliesTo)
+					if applies {
+						assert.Equal(t, tc.stages, stages)
+					} else {
+						assert.Empty(t, stages)
+					}
+					tc.check(t, applies, m, mf)
+				})
+			}
+		}
+	}
+}
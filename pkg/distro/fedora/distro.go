@@ -115,7 +115,7 @@ func mkLiveInstallerImgType(d distribution) imageType {
 		image:                  liveInstallerImage,
 		isoLabel:               getISOLabelFunc("Workstation"),
 		buildPipelines:         []string{"build"},
-		payloadPipelines:       []string{"anaconda-tree", "efiboot-tree", "bootiso-tree", "bootiso"},
+		payloadPipelines:       []string{"anaconda-tree", "efiboot-tree", "live-overlay", "bootiso-tree", "bootiso"},
 		exports:                []string{"bootiso"},
 		requiredPartitionSizes: requiredDirectorySizes,
 	}
@@ -496,6 +496,48 @@ func mkMinimalRawImgType(d distribution) imageType {
 	return it
 }
 
+// mkVerityRawImgType returns a raw disk image with a read-only ext4 root
+// filesystem protected by dm-verity. The root hash is passed to the kernel
+// with an addon to the UKI, so the image type requires the UKI boot chain.
+func mkVerityRawImgType(d distribution) imageType {
+	return imageType{
+		name:        "verity-raw",
+		filename:    "disk.raw.xz",
+		compression: "xz",
+		mimeType:    "application/xz",
+		packageSets: map[string]packageSetFunc{
+			osPkgsKey: func(t *imageType) (rpmmd.PackageSet, error) {
+				// use the minimal raw image type for the OS package set
+				return defs.PackageSet(t, "minimal-raw", VersionReplacements())
+			},
+		},
+		defaultImageConfig: &distro.ImageConfig{
+			EnabledServices: minimalServicesForVersion(&d),
+			InstallWeakDeps: common.ToPtr(common.VersionLessThan(d.osVersion, VERSION_MINIMAL_WEAKDEPS)),
+		},
+		rpmOstree:              false,
+		kernelOptions:          []string{"ro"},
+		bootable:               true,
+		defaultSize:            4 * datasizes.GibiByte,
+		image:                  diskImage,
+		buildPipelines:         []string{"build"},
+		payloadPipelines:       []string{"os", "image", "xz"},
+		exports:                []string{"xz"},
+		basePartitionTables:    verityPartitionTables,
+		requiredPartitionSizes: requiredDirectorySizes,
+	}
+}
+
+// mkVerityErofsRawImgType returns the verity-raw image type with an erofs
+// root filesystem.
+func mkVerityErofsRawImgType(d distribution) imageType {
+	it := mkVerityRawImgType(d)
+	it.name = "verity-erofs-raw"
+	it.payloadPipelines = []string{"os", "root-erofs", "image", "xz"}
+	it.basePartitionTables = verityErofsPartitionTables
+	return it
+}
+
 type distribution struct {
 	name               string
 	product            string
@@ -992,6 +1034,29 @@ func newDistro(version int) distro.Distro {
 		minimalrawZstdImgType,
 	)
 
+	x86_64.addImageTypes(
+		&platform.X86{
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_RAW,
+				Bootloader:  platform.BOOTLOADER_UKI,
+			},
+		},
+		mkVerityRawImgType(rd),
+		mkVerityErofsRawImgType(rd),
+	)
+	aarch64.addImageTypes(
+		&platform.Aarch64{
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_RAW,
+				Bootloader:  platform.BOOTLOADER_UKI,
+			},
+		},
+		mkVerityRawImgType(rd),
+		mkVerityErofsRawImgType(rd),
+	)
+
 	iotSimplifiedInstallerImgType := mkIotSimplifiedInstallerImgType(rd)
 	iotSimplifiedInstallerImgType.defaultInstallerConfig = distroInstallerConfig
 
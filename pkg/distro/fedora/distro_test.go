@@ -9,9 +9,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distro/distro_test_common"
 	"github.com/osbuild/images/pkg/distro/fedora"
+	"github.com/osbuild/images/pkg/ostree"
 )
 
 type fedoraFamilyDistro struct {
@@ -337,6 +340,8 @@ func TestImageType_Name(t *testing.T) {
 				"openstack",
 				"ova",
 				"qcow2",
+				"verity-erofs-raw",
+				"verity-raw",
 				"vhd",
 				"vmdk",
 				"wsl",
@@ -367,6 +372,8 @@ func TestImageType_Name(t *testing.T) {
 				"oci",
 				"openstack",
 				"qcow2",
+				"verity-erofs-raw",
+				"verity-raw",
 			},
 			verTypes: map[string][]string{
 				"40": {
@@ -507,7 +514,7 @@ func TestDistro_ManifestError(t *testing.T) {
 					} else if imgTypeName == "live-installer" {
 						assert.EqualError(t, err, fmt.Sprintf(distro.NoCustomizationsAllowedError, imgTypeName))
 					} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-						assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS"))
+						assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS, Composefs"))
 					} else {
 						assert.NoError(t, err)
 					}
@@ -541,6 +548,8 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 				"openstack",
 				"ova",
 				"qcow2",
+				"verity-erofs-raw",
+				"verity-raw",
 				"vhd",
 				"vmdk",
 				"wsl",
@@ -573,6 +582,8 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 				"oci",
 				"openstack",
 				"qcow2",
+				"verity-erofs-raw",
+				"verity-raw",
 			},
 			verTypes: map[string][]string{
 				"40": {
@@ -740,7 +751,7 @@ func TestDistro_CustomFileSystemManifestError(t *testing.T) {
 				if imgTypeName == "iot-commit" || imgTypeName == "iot-container" || imgTypeName == "iot-bootable-container" {
 					assert.EqualError(t, err, "Custom mountpoints and partitioning are not supported for ostree types")
 				} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS"))
+					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS, Composefs"))
 				} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" {
 					continue
 				} else if imgTypeName == "live-installer" {
@@ -774,7 +785,7 @@ func TestDistro_TestRootMountPoint(t *testing.T) {
 				if imgTypeName == "iot-commit" || imgTypeName == "iot-container" || imgTypeName == "iot-bootable-container" {
 					assert.EqualError(t, err, "Custom mountpoints and partitioning are not supported for ostree types")
 				} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS"))
+					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS, Composefs"))
 				} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" {
 					continue
 				} else if imgTypeName == "live-installer" {
@@ -922,7 +933,7 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 				if imgTypeName == "iot-commit" || imgTypeName == "iot-container" || imgTypeName == "iot-bootable-container" {
 					assert.EqualError(t, err, "Custom mountpoints and partitioning are not supported for ostree types")
 				} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS"))
+					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS, Composefs"))
 				} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" {
 					continue
 				} else if imgTypeName == "live-installer" {
@@ -967,7 +978,7 @@ func TestDistro_PartitioningConflict(t *testing.T) {
 				if imgTypeName == "iot-commit" || imgTypeName == "iot-container" || imgTypeName == "iot-bootable-container" {
 					assert.EqualError(t, err, "Custom mountpoints and partitioning are not supported for ostree types")
 				} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS"))
+					assert.EqualError(t, err, fmt.Sprintf(distro.UnsupportedCustomizationError, imgTypeName, "User, Group, Directories, Files, Services, FIPS, Composefs"))
 				} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" {
 					continue
 				} else if imgTypeName == "live-installer" {
@@ -1071,3 +1082,229 @@ func TestDistro_DiskCustomizationRunsValidateLayoutConstraints(t *testing.T) {
 		}
 	}
 }
+
+func TestDistro_BootloaderCustomization(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Bootloader: &blueprint.BootloaderCustomization{
+				Type: "uki",
+			},
+		},
+	}
+	for _, dist := range fedoraFamilyDistros {
+		fedoraDistro := dist.distro
+		for _, archName := range fedoraDistro.ListArches() {
+			arch, _ := fedoraDistro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+				switch {
+				case imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer":
+					assert.EqualError(t, err, fmt.Sprintf("boot ISO image type %q requires specifying a URL from which to retrieve the OSTree commit", imgTypeName))
+				case imgTypeName == "iot-qcow2-image" || imgTypeName == "iot-raw-image" || imgTypeName == "image-installer":
+					assert.ErrorContains(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q", imgTypeName))
+				case imgTypeName == "live-installer":
+					assert.EqualError(t, err, fmt.Sprintf("image type %q does not support customizations", imgTypeName))
+				case strings.HasPrefix(imgTypeName, "iot-") || imgTypeName == "container" || imgTypeName == "wsl":
+					assert.EqualError(t, err, fmt.Sprintf("bootloader customizations are not supported for %q", imgTypeName))
+				case archName == "ppc64le" || archName == "s390x":
+					assert.EqualError(t, err, fmt.Sprintf("bootloader \"uki\" requires a UEFI platform, %s does not support UEFI", archName))
+				default:
+					assert.NoError(t, err, "%s/%s/%s", dist.name, archName, imgTypeName)
+				}
+			}
+		}
+	}
+}
+
+// taken from osbuild:test/data/certs/cert1.pem
+const secureBootCert = `
+-----BEGIN CERTIFICATE-----
+MIIDhTCCAm2gAwIBAgIUVya7VJ3O8W8SqwuEa0BZ4HSsXvAwDQYJKoZIhvcNAQEL
+BQAwUTELMAkGA1UEBhMCREUxDzANBgNVBAgMBkJlcmxpbjEPMA0GA1UEBwwGQmVy
+bGluMQwwCgYDVQQKDANPcmcxEjAQBgNVBAMMCWxvY2FsaG9zdDAgFw0yNDA4MjYx
+MDQyNDBaGA8yMTI0MDgwMjEwNDI0MFowUTELMAkGA1UEBhMCREUxDzANBgNVBAgM
+BkJlcmxpbjEPMA0GA1UEBwwGQmVybGluMQwwCgYDVQQKDANPcmcxEjAQBgNVBAMM
+CWxvY2FsaG9zdDCCASIwDQYJKoZIhvcNAQEBBQADggEPADCCAQoCggEBAJnGjlvN
+O3F/Z7Lr/r+6Xp2DosnNwoPHhG2e61KnFzgZfaxbklal5ORpuV/gLIg7lrbpdZe7
+WvK+16RanL6fLitis/tYVFyvz1MXqBYYrEoFGvVg9fOiis7hjpdZcpNDH9SngoAN
+O0Wvv4T6LQS0cC7ZAFZjvmJ+RiZEbzRkNG5pUddZXbotE6htNfLgA5L1wIBgllrM
+4DVkG0yNKmzqPNzfPTbdUgWCfjaQShHy1GP8KNEwFxM31F2wvQxsEb77o1S44Out
+mlsi83tti6P7KjDk7w2j2zZO1X0xI8pflv3TBkJT1Am8vnk6rVnNO4pCpop3+kma
+pDUEzBQmSQA5R1ECAwEAAaNTMFEwHQYDVR0OBBYEFDxFcFgPEsgsDixfKxB0uYGN
+aJmzMB8GA1UdIwQYMBaAFDxFcFgPEsgsDixfKxB0uYGNaJmzMA8GA1UdEwEB/wQF
+MAMBAf8wDQYJKoZIhvcNAQELBQADggEBAFih4lUbLlhKwIAV9x3/W7Mih8xUEdZr
+olquZgaHedFet+ByAHvoES3pec7AVYTOD53mjgyZubD6INnVHzKyS4AG9ydD73o4
+cmm3DKxBaesvlHeTn0MOKsoM8QCxeyFJmiUPpgDBok/PFnbGR9+JcsrlGJAnsSKD
+vWpiwYcBauZ9nnK5yDe5M9XNFPkNDZzbKvWU7Sw3ziMT/+bRJse5vTrYcyOnNGgy
+gZNz2nimKy1U8XZVAVwOV0rdGEFrfMln8DkRW86rGK/EncaVsl0SSP/rmjQgiX8Q
+3CZraQGujJP932HSwUfdCX9yh+rTjE3MEnbqMoLzJa4BXB2aDQWtywU=
+-----END CERTIFICATE-----
+`
+
+func TestDistro_SecureBootCustomization(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Bootloader: &blueprint.BootloaderCustomization{
+				SecureBoot: &blueprint.SecureBootCustomization{
+					Certificate: secureBootCert,
+					Enrollment:  "mok",
+				},
+			},
+		},
+	}
+	for _, dist := range fedoraFamilyDistros {
+		fedoraDistro := dist.distro
+		for _, archName := range fedoraDistro.ListArches() {
+			arch, _ := fedoraDistro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+				switch {
+				case imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer":
+					assert.EqualError(t, err, fmt.Sprintf("boot ISO image type %q requires specifying a URL from which to retrieve the OSTree commit", imgTypeName))
+				case imgTypeName == "iot-qcow2-image" || imgTypeName == "iot-raw-image" || imgTypeName == "image-installer":
+					assert.ErrorContains(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q", imgTypeName))
+				case imgTypeName == "live-installer":
+					assert.EqualError(t, err, fmt.Sprintf("image type %q does not support customizations", imgTypeName))
+				case strings.HasPrefix(imgTypeName, "iot-") || imgTypeName == "container" || imgTypeName == "wsl":
+					assert.EqualError(t, err, fmt.Sprintf("bootloader customizations are not supported for %q", imgTypeName))
+				case archName == "ppc64le" || archName == "s390x":
+					assert.EqualError(t, err, fmt.Sprintf("secure boot signing requires a UEFI platform, %q does not support UEFI on %s", imgTypeName, archName))
+				default:
+					assert.NoError(t, err, "%s/%s/%s", dist.name, archName, imgTypeName)
+				}
+			}
+		}
+	}
+}
+
+func TestDistro_VerityRawCustomizations(t *testing.T) {
+	for _, dist := range fedoraFamilyDistros {
+		for _, archName := range []string{"x86_64", "aarch64"} {
+			for _, imgTypeName := range []string{"verity-raw", "verity-erofs-raw"} {
+				arch, err := dist.distro.GetArch(archName)
+				require.NoError(t, err)
+				imgType, err := arch.GetImageType(imgTypeName)
+				require.NoError(t, err)
+
+				_, _, err = imgType.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{}, nil, nil)
+				assert.NoError(t, err)
+
+				// extra mountpoints become plain partitions
+				bp := blueprint.Blueprint{
+					Customizations: &blueprint.Customizations{
+						Filesystem: []blueprint.FilesystemCustomization{{Mountpoint: "/home", MinSize: 1024}},
+					},
+				}
+				_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+				assert.NoError(t, err)
+				_, _, err = imgType.Manifest(&bp, distro.ImageOptions{PartitioningMode: disk.LVMPartitioningMode}, nil, nil)
+				assert.EqualError(t, err, fmt.Sprintf("partitioning mode lvm not supported for %s on %s", imgTypeName, archName))
+
+				bp = blueprint.Blueprint{
+					Customizations: &blueprint.Customizations{
+						Disk: &blueprint.DiskCustomization{},
+					},
+				}
+				_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+				assert.EqualError(t, err, fmt.Sprintf("partitioning customizations are not supported for %q", imgTypeName))
+
+				bp = blueprint.Blueprint{
+					Customizations: &blueprint.Customizations{
+						Bootloader: &blueprint.BootloaderCustomization{Type: "grub2"},
+					},
+				}
+				_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+				assert.EqualError(t, err, fmt.Sprintf("image type %q requires the uki bootloader", imgTypeName))
+			}
+		}
+	}
+}
+
+func TestDistro_LiveInstallerPersistentOverlay(t *testing.T) {
+	overlayBP := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Installer: &blueprint.InstallerCustomization{
+				PersistentOverlay: &blueprint.PersistentOverlay{
+					Size: 4 * datasizes.GiB,
+				},
+			},
+		},
+	}
+	for _, dist := range fedoraFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, err := dist.distro.GetArch(archName)
+			require.NoError(t, err)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, err := arch.GetImageType(imgTypeName)
+				require.NoError(t, err)
+				_, _, err = imgType.Manifest(&overlayBP, distro.ImageOptions{}, nil, nil)
+				switch imgTypeName {
+				case "live-installer":
+					assert.NoError(t, err)
+				default:
+					assert.Error(t, err, "%s/%s/%s", dist.name, archName, imgTypeName)
+				}
+			}
+
+			imgType, err := arch.GetImageType("live-installer")
+			if err != nil {
+				continue
+			}
+			bp := blueprint.Blueprint{
+				Customizations: &blueprint.Customizations{
+					Installer: &blueprint.InstallerCustomization{
+						Unattended: true,
+					},
+				},
+			}
+			_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+			assert.EqualError(t, err, `only installer.persistent-overlay customizations are supported for "live-installer"`)
+		}
+	}
+}
+
+func TestDistro_ComposefsCustomization(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Composefs: &blueprint.ComposefsCustomization{},
+		},
+	}
+	ostreeOptions := distro.ImageOptions{
+		OSTree: &ostree.ImageOptions{
+			URL: "https://example.com/repo",
+		},
+	}
+	for _, dist := range fedoraFamilyDistros {
+		arch, err := dist.distro.GetArch("x86_64")
+		require.NoError(t, err)
+
+		for _, imgTypeName := range []string{"iot-commit", "iot-container", "iot-raw-image", "iot-qcow2-image"} {
+			imgType, err := arch.GetImageType(imgTypeName)
+			require.NoError(t, err)
+			_, _, err = imgType.Manifest(&bp, ostreeOptions, nil, nil)
+			assert.NoError(t, err, "%s/%s", dist.name, imgTypeName)
+		}
+
+		imgType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+		_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+		assert.EqualError(t, err, `composefs customizations are not supported for "qcow2"`)
+
+		imgType, err = arch.GetImageType("iot-installer")
+		require.NoError(t, err)
+		_, _, err = imgType.Manifest(&bp, ostreeOptions, nil, nil)
+		assert.ErrorContains(t, err, `unsupported blueprint customizations found for image type "iot-installer"`)
+
+		imgType, err = arch.GetImageType("iot-raw-image")
+		require.NoError(t, err)
+		invalidBP := blueprint.Blueprint{
+			Customizations: &blueprint.Customizations{
+				Composefs: &blueprint.ComposefsCustomization{PublicKey: "c2VjcmV0"},
+			},
+		}
+		_, _, err = imgType.Manifest(&invalidBP, ostreeOptions, nil, nil)
+		assert.EqualError(t, err, "composefs public key must be an ed25519 public key of 32 bytes, got 6 bytes")
+	}
+}
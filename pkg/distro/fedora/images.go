@@ -16,8 +16,10 @@ import (
 	"github.com/osbuild/images/pkg/customizations/ignition"
 	"github.com/osbuild/images/pkg/customizations/kickstart"
 	"github.com/osbuild/images/pkg/customizations/oscap"
+	"github.com/osbuild/images/pkg/customizations/secureboot"
 	"github.com/osbuild/images/pkg/customizations/users"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/defs"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
@@ -150,6 +152,20 @@ func osCustomizations(
 		panic(fmt.Sprintf("failed to convert file customizations to fs node files: %v", err))
 	}
 
+	if len(containers) > 0 {
+		// the signature policies of the containers are added to the
+		// policy that the containers-common package of the distro installs
+		defaultPolicy, err := defs.ContainersPolicy(t.Arch().Distro())
+		if err != nil {
+			return manifest.OSCustomizations{}, err
+		}
+		policyFiles, err := container.PolicyFiles(containers, defaultPolicy)
+		if err != nil {
+			return manifest.OSCustomizations{}, err
+		}
+		osc.Files = append(osc.Files, policyFiles...)
+	}
+
 	// OSTree commits do not include data in `/var` since that is tied to the
 	// deployment, rather than the commit. Therefore the containers need to be
 	// stored in a different location, like `/usr/share`, and the container
@@ -239,6 +255,14 @@ func osCustomizations(
 		osc.CACerts = ca.PEMCerts
 	}
 
+	bootloader, err := c.GetBootloader()
+	if err != nil {
+		panic(fmt.Sprintf("unexpected error checking bootloader customizations: %v", err))
+	}
+	if bootloader != nil && bootloader.SecureBoot != nil {
+		osc.SecureBoot = secureboot.FromBP(*bootloader.SecureBoot)
+	}
+
 	if imageConfig.MachineIdUninitialized != nil {
 		osc.MachineIdUninitialized = *imageConfig.MachineIdUninitialized
 	}
@@ -334,9 +358,12 @@ func diskImage(workload workload.Workload,
 	rng *rand.Rand) (image.ImageKind, error) {
 
 	img := image.NewDiskImage()
-	img.Platform = t.platform
 
 	var err error
+	img.Platform, err = t.getPlatform(bp.Customizations)
+	if err != nil {
+		return nil, err
+	}
 	img.OSCustomizations, err = osCustomizations(t, packageSets[osPkgsKey], containers, bp.Customizations)
 	if err != nil {
 		return nil, err
@@ -439,6 +466,14 @@ func liveInstallerImage(workload workload.Workload,
 		img.AdditionalDrivers = append(img.AdditionalDrivers, installerConfig.AdditionalDrivers...)
 	}
 
+	instCust, err := bp.Customizations.GetInstaller()
+	if err != nil {
+		return nil, err
+	}
+	if instCust != nil && instCust.PersistentOverlay != nil {
+		img.PersistentOverlaySize = instCust.PersistentOverlay.Size.Uint64()
+	}
+
 	return img, nil
 }
 
@@ -534,6 +569,16 @@ func imageInstallerImage(workload workload.Workload,
 	return img, nil
 }
 
+// composefsOptions returns the composefs options of the ostree image types
+// for the blueprint customizations, nil if composefs is not enabled.
+func composefsOptions(c *blueprint.Customizations) (*ostree.ComposefsOptions, error) {
+	composefs, err := c.GetComposefs()
+	if err != nil || composefs == nil {
+		return nil, err
+	}
+	return &ostree.ComposefsOptions{PublicKey: composefs.PublicKey}, nil
+}
+
 func iotCommitImage(workload workload.Workload,
 	t *imageType,
 	bp *blueprint.Blueprint,
@@ -571,9 +616,19 @@ func iotCommitImage(workload workload.Workload,
 		},
 	}
 
+	img.Composefs, err = composefsOptions(bp.Customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.Environment = t.environment
 	img.Workload = workload
 	img.OSTreeParent = parentCommit
+	img.OSTreeDeltaFrom = makeOSTreeDeltaFrom(options.OSTree)
+	if options.OSTree != nil {
+		img.OSTreeSigning = options.OSTree.Sign
+		img.OSTreeDeltaFromScratch = options.OSTree.DeltaFromScratch
+	}
 	img.OSVersion = d.osVersion
 	img.Filename = t.Filename()
 	img.InstallWeakDeps = false
@@ -602,9 +657,17 @@ func bootableContainerImage(workload workload.Workload,
 		return nil, err
 	}
 
+	img.Composefs, err = composefsOptions(bp.Customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.Environment = t.environment
 	img.Workload = workload
 	img.OSTreeParent = parentCommit
+	if options.OSTree != nil {
+		img.OSTreeSigning = options.OSTree.Sign
+	}
 	img.OSVersion = d.osVersion
 	img.Filename = t.Filename()
 	img.InstallWeakDeps = false
@@ -652,10 +715,18 @@ func iotContainerImage(workload workload.Workload,
 		},
 	}
 
+	img.Composefs, err = composefsOptions(bp.Customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.ContainerLanguage = img.OSCustomizations.Language
 	img.Environment = t.environment
 	img.Workload = workload
 	img.OSTreeParent = parentCommit
+	if options.OSTree != nil {
+		img.OSTreeSigning = options.OSTree.Sign
+	}
 	img.OSVersion = d.osVersion
 	img.ExtraContainerPackages = packageSets[containerPkgsKey]
 	img.Filename = t.Filename()
@@ -775,6 +846,11 @@ func iotImage(workload workload.Workload,
 	}
 	img.OSTreeDeploymentCustomizations = deploymentConfig
 
+	img.Composefs, err = composefsOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	img.Platform = t.platform
 	img.Workload = workload
 
@@ -817,6 +893,11 @@ func iotSimplifiedInstallerImage(workload workload.Workload,
 	}
 	rawImg.OSTreeDeploymentCustomizations = deploymentConfig
 
+	rawImg.Composefs, err = composefsOptions(customizations)
+	if err != nil {
+		return nil, err
+	}
+
 	rawImg.Platform = t.platform
 	rawImg.Workload = workload
 	rawImg.Remote = ostree.Remote{
@@ -913,6 +994,19 @@ func makeOSTreeParentCommit(options *ostree.ImageOptions, defaultRef string) (*o
 	return parentCommit, commitRef
 }
 
+// Create an ostree SourceSpec for the commit that a static delta to the new
+// commit is generated from, if one is set in the user options.
+func makeOSTreeDeltaFrom(options *ostree.ImageOptions) *ostree.SourceSpec {
+	if options == nil || options.DeltaFrom == "" {
+		return nil
+	}
+	return &ostree.SourceSpec{
+		URL:  options.URL,
+		Ref:  options.DeltaFrom,
+		RHSM: options.RHSM,
+	}
+}
+
 // Create an ostree SourceSpec to define an ostree payload using the user options and the default ref for the image type.
 func makeOSTreePayloadCommit(options *ostree.ImageOptions, defaultRef string) (ostree.SourceSpec, error) {
 	if options == nil || options.URL == "" {
@@ -928,9 +1022,11 @@ func makeOSTreePayloadCommit(options *ostree.ImageOptions, defaultRef string) (o
 	}
 
 	return ostree.SourceSpec{
-		URL:  options.URL,
-		Ref:  commitRef,
-		RHSM: options.RHSM,
+		URL:        options.URL,
+		ContentURL: options.ContentURL,
+		Ref:        commitRef,
+		RHSM:       options.RHSM,
+		Verify:     options.Verify,
 	}, nil
 }
 
@@ -129,14 +129,39 @@ func (t *imageType) Exports() []string {
 }
 
 func (t *imageType) BootMode() platform.BootMode {
-	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
-		return platform.BOOT_HYBRID
-	} else if t.platform.GetUEFIVendor() != "" {
-		return platform.BOOT_UEFI
-	} else if t.platform.GetBIOSPlatform() != "" || t.platform.GetZiplSupport() {
-		return platform.BOOT_LEGACY
-	}
-	return platform.BOOT_NONE
+	return platform.BootModeFor(t.platform)
+}
+
+// getPlatform returns the platform of the image type with the bootloader
+// selected in the blueprint customizations applied.
+func (t *imageType) getPlatform(customizations *blueprint.Customizations) (platform.Platform, error) {
+	bpBootloader, err := customizations.GetBootloader()
+	if err != nil {
+		return nil, err
+	}
+	if bpBootloader == nil || bpBootloader.Type == "" {
+		return t.platform, nil
+	}
+
+	bootloader, err := platform.NewBootloader(bpBootloader.Type)
+	if err != nil {
+		return nil, err
+	}
+	if bootloader == t.platform.GetBootloader() {
+		return t.platform, nil
+	}
+	return platform.WithBootloader(t.platform, bootloader)
+}
+
+// verityRoot returns true if the root filesystem of the image type is
+// protected by dm-verity.
+func (t *imageType) verityRoot() bool {
+	basePartitionTable, exists := t.basePartitionTables[t.arch.Name()]
+	if !exists {
+		return false
+	}
+	data, _ := basePartitionTable.FindVerity("/")
+	return data != nil
 }
 
 func (t *imageType) getPartitionTable(
@@ -149,6 +174,18 @@ func (t *imageType) getPartitionTable(
 		return nil, fmt.Errorf("unknown arch for partition table: %s", t.arch.Name())
 	}
 
+	pf, err := t.getPlatform(customizations)
+	if err != nil {
+		return nil, err
+	}
+	if pf.GetBootloader() == platform.BOOTLOADER_UKI {
+		ukiPartitionTable, err := disk.NewUKIBasePartitionTable(&basePartitionTable)
+		if err != nil {
+			return nil, err
+		}
+		basePartitionTable = *ukiPartitionTable
+	}
+
 	imageSize := t.Size(options.Size)
 	partitioning, err := customizations.GetPartitioning()
 	if err != nil {
@@ -165,10 +202,11 @@ func (t *imageType) getPartitionTable(
 
 		partOptions := &disk.CustomPartitionTableOptions{
 			PartitionTableType: basePartitionTable.Type, // PT type is not customizable, it is determined by the base PT for an image type or architecture
-			BootMode:           t.BootMode(),
+			BootMode:           platform.BootModeFor(pf),
 			DefaultFSType:      disk.FS_EXT4, // default fs type for Fedora
 			RequiredMinSizes:   t.requiredPartitionSizes,
 			Architecture:       t.platform.GetArch(),
+			Bootloader:         pf.GetBootloader(),
 		}
 		return disk.NewCustomPartitionTable(partitioning, partOptions, rng)
 	}
@@ -183,6 +221,14 @@ func (t *imageType) getPartitionTable(
 		}
 		partitioningMode = disk.AutoLVMPartitioningMode
 	}
+	if data, _ := basePartitionTable.FindVerity("/"); data != nil {
+		// The root filesystem needs to stay on a plain partition next to
+		// its hash partition, force raw.
+		if partitioningMode != disk.DefaultPartitioningMode && partitioningMode != disk.RawPartitioningMode {
+			return nil, fmt.Errorf("partitioning mode %s not supported for %s on %s", partitioningMode, t.Name(), t.arch.Name())
+		}
+		partitioningMode = disk.RawPartitioningMode
+	}
 
 	mountpoints := customizations.GetFilesystems()
 	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, partitioningMode, t.platform.GetArch(), t.requiredPartitionSizes, rng)
@@ -293,15 +339,7 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 		}
 	}
 
-	containerSources := make([]container.SourceSpec, len(bp.Containers))
-	for idx, cont := range bp.Containers {
-		containerSources[idx] = container.SourceSpec{
-			Source:    cont.Source,
-			Name:      cont.Name,
-			TLSVerify: cont.TLSVerify,
-			Local:     cont.LocalStorage,
-		}
-	}
+	containerSources := distro.ContainerSources(bp.Containers)
 
 	source := rand.NewSource(seed)
 	// math/rand is good enough in this case
@@ -355,7 +393,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 	}
 
 	if t.name == "iot-raw-image" || t.name == "iot-qcow2-image" {
-		allowed := []string{"User", "Group", "Directories", "Files", "Services", "FIPS"}
+		allowed := []string{"User", "Group", "Directories", "Files", "Services", "FIPS", "Composefs"}
 		if err := customizations.CheckAllowed(allowed...); err != nil {
 			return warnings, fmt.Errorf(distro.UnsupportedCustomizationError, t.name, strings.Join(allowed, ", "))
 		}
@@ -366,7 +404,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 	// TODO: Support kernel name selection for image-installer
 	if t.bootISO {
 		if t.name == "iot-simplified-installer" {
-			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group", "FIPS"}
+			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group", "FIPS", "Composefs"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf(distro.UnsupportedCustomizationError, t.name, strings.Join(allowed, ", "))
 			}
@@ -417,6 +455,35 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		}
 	}
 
+	bootloader, err := customizations.GetBootloader()
+	if err != nil {
+		return warnings, err
+	}
+	if bootloader != nil && (bootloader.Type != "" || bootloader.SecureBoot != nil) {
+		if !t.bootable || t.rpmOstree || t.bootISO {
+			return warnings, fmt.Errorf("bootloader customizations are not supported for %q", t.Name())
+		}
+		pf, err := t.getPlatform(customizations)
+		if err != nil {
+			return warnings, err
+		}
+		if t.verityRoot() && pf.GetBootloader() != platform.BOOTLOADER_UKI {
+			// the root hash is passed to the kernel with a UKI addon
+			return warnings, fmt.Errorf("image type %q requires the uki bootloader", t.Name())
+		}
+		if bootloader.SecureBoot != nil && pf.GetUEFIVendor() == "" {
+			return warnings, fmt.Errorf("secure boot signing requires a UEFI platform, %q does not support UEFI on %s", t.Name(), t.Arch().Name())
+		}
+	}
+
+	composefs, err := customizations.GetComposefs()
+	if err != nil {
+		return warnings, err
+	}
+	if composefs != nil && !t.rpmOstree {
+		return warnings, fmt.Errorf("composefs customizations are not supported for %q", t.Name())
+	}
+
 	if kernelOpts := customizations.GetKernel(); kernelOpts.Append != "" && t.rpmOstree {
 		return warnings, fmt.Errorf("kernel boot parameter customizations are not supported for ostree types")
 	}
@@ -432,6 +499,9 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 	if len(mountpoints) > 0 && partitioning != nil {
 		return warnings, fmt.Errorf("partitioning customizations cannot be used with custom filesystems (mountpoints)")
 	}
+	if partitioning != nil && t.verityRoot() {
+		return warnings, fmt.Errorf("partitioning customizations are not supported for %q", t.Name())
+	}
 
 	if err := blueprint.CheckMountpointsPolicy(mountpoints, policies.MountpointPolicies); err != nil {
 		return warnings, err
@@ -498,11 +568,21 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, err
 	}
 	if instCust != nil {
-		// only supported by the Anaconda installer
-		if slices.Index([]string{"iot-installer"}, t.name) == -1 {
+		// only supported by the Anaconda installer and the live ISO
+		if slices.Index([]string{"iot-installer", "live-installer"}, t.name) == -1 {
 			return warnings, fmt.Errorf("installer customizations are not supported for %q", t.Name())
 		}
 
+		// the live ISO only supports the persistent overlay, which in turn
+		// is only supported by the live ISO
+		if t.Name() == "live-installer" &&
+			(instCust.Unattended || len(instCust.SudoNopasswd) > 0 || instCust.Kickstart != nil || instCust.Modules != nil) {
+			return warnings, fmt.Errorf("only installer.persistent-overlay customizations are supported for %q", t.Name())
+		}
+		if t.Name() != "live-installer" && instCust.PersistentOverlay != nil {
+			return warnings, fmt.Errorf("installer.persistent-overlay is not supported for %q", t.Name())
+		}
+
 		// NOTE: the image type check is redundant with the check above, but
 		// let's keep it explicit in case one of the two changes.
 		// The kickstart contents is incompatible with the users and groups
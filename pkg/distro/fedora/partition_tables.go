@@ -592,3 +592,128 @@ var iotSimplifiedInstallerPartitionTables = distro.BasePartitionTableMap{
 		},
 	},
 }
+
+// verityPartitionTables pair the read-only root filesystem with a dm-verity
+// hash partition. State that needs to be writable lives on /var.
+var verityPartitionTables = distro.BasePartitionTableMap{
+	arch.ARCH_X86_64.String(): disk.PartitionTable{
+		UUID:        "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type:        disk.PT_GPT,
+		StartOffset: 8 * datasizes.MebiByte,
+		Partitions: []disk.Partition{
+			{
+				Size: 1 * datasizes.GibiByte,
+				Type: disk.EFISystemPartitionGUID,
+				UUID: disk.EFISystemPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:         "vfat",
+					UUID:         disk.EFIFilesystemUUID,
+					Mountpoint:   "/boot/efi",
+					Label:        "EFI-SYSTEM",
+					FSTabOptions: "defaults,uid=0,gid=0,umask=077,shortname=winnt",
+					FSTabFreq:    0,
+					FSTabPassNo:  2,
+				},
+			},
+			{
+				Size: 1 * datasizes.GibiByte,
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.DataPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:         "ext4",
+					Label:        "var",
+					Mountpoint:   "/var",
+					FSTabOptions: "defaults",
+					FSTabFreq:    0,
+					FSTabPassNo:  0,
+				},
+			},
+			{
+				// sized for the root partition when laid out
+				Type: disk.RootVerityPartitionX86_64GUID,
+				Payload: &disk.Verity{
+					DataMountpoint: "/",
+				},
+			},
+			{
+				Size: 2 * datasizes.GibiByte,
+				Type: disk.RootPartitionX86_64GUID,
+				UUID: disk.RootPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:         "ext4",
+					Label:        "root",
+					Mountpoint:   "/",
+					FSTabOptions: "ro",
+					FSTabFreq:    0,
+					FSTabPassNo:  0,
+				},
+			},
+		},
+	},
+	arch.ARCH_AARCH64.String(): disk.PartitionTable{
+		UUID:        "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type:        disk.PT_GPT,
+		StartOffset: 8 * datasizes.MebiByte,
+		Partitions: []disk.Partition{
+			{
+				Size: 1 * datasizes.GibiByte,
+				Type: disk.EFISystemPartitionGUID,
+				UUID: disk.EFISystemPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:         "vfat",
+					UUID:         disk.EFIFilesystemUUID,
+					Mountpoint:   "/boot/efi",
+					Label:        "EFI-SYSTEM",
+					FSTabOptions: "defaults,uid=0,gid=0,umask=077,shortname=winnt",
+					FSTabFreq:    0,
+					FSTabPassNo:  2,
+				},
+			},
+			{
+				Size: 1 * datasizes.GibiByte,
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.DataPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:         "ext4",
+					Label:        "var",
+					Mountpoint:   "/var",
+					FSTabOptions: "defaults",
+					FSTabFreq:    0,
+					FSTabPassNo:  0,
+				},
+			},
+			{
+				// sized for the root partition when laid out
+				Type: disk.RootVerityPartitionAarch64GUID,
+				Payload: &disk.Verity{
+					DataMountpoint: "/",
+				},
+			},
+			{
+				Size: 2 * datasizes.GibiByte,
+				Type: disk.RootPartitionAarch64GUID,
+				UUID: disk.RootPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:         "ext4",
+					Label:        "root",
+					Mountpoint:   "/",
+					FSTabOptions: "ro",
+					FSTabFreq:    0,
+					FSTabPassNo:  0,
+				},
+			},
+		},
+	},
+}
+
+// verityErofsPartitionTables are the verityPartitionTables with an erofs root
+// filesystem, which is created from the tree as a whole.
+var verityErofsPartitionTables = func() distro.BasePartitionTableMap {
+	tables := make(distro.BasePartitionTableMap, len(verityPartitionTables))
+	for archName, basePT := range verityPartitionTables {
+		pt := basePT.Clone().(*disk.PartitionTable)
+		pt.FindMountable("/").(*disk.Filesystem).Type = "erofs"
+		tables[archName] = *pt
+	}
+	return tables
+}()
@@ -13,6 +13,7 @@ import (
 	"github.com/osbuild/images/pkg/distro/rhel"
 	"github.com/osbuild/images/pkg/distrofactory"
 	"github.com/osbuild/images/pkg/platform"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,3 +76,25 @@ func TestAMIHybridBoot(t *testing.T) {
 	}
 
 }
+
+func TestContainerPolicyInstall(t *testing.T) {
+	distroFactory := distrofactory.NewDefault()
+	for _, distroName := range []string{"rhel-8.10", "rhel-9.4", "rhel-10.0"} {
+		arch, err := distroFactory.GetDistro(distroName).GetArch("x86_64")
+		require.NoError(t, err)
+		imgType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+
+		// the policy is added to the default policy of RHEL
+		bp := blueprint.Blueprint{
+			Containers: []blueprint.Container{
+				{
+					Source: "registry.example.com/signed",
+					Verify: &blueprint.ContainerVerify{GPGKeys: []string{"key"}, Install: true},
+				},
+			},
+		}
+		_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+		assert.NoError(t, err, distroName)
+	}
+}
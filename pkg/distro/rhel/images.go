@@ -17,6 +17,7 @@ import (
 	"github.com/osbuild/images/pkg/customizations/subscription"
 	"github.com/osbuild/images/pkg/customizations/users"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/defs"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
@@ -164,6 +165,20 @@ func osCustomizations(
 		panic(fmt.Sprintf("failed to convert file customizations to fs node files: %v", err))
 	}
 
+	if len(containers) > 0 {
+		// the signature policies of the containers are added to the
+		// policy that the containers-common package of the distro installs
+		defaultPolicy, err := defs.ContainersPolicy(t.Arch().Distro())
+		if err != nil {
+			return manifest.OSCustomizations{}, err
+		}
+		policyFiles, err := container.PolicyFiles(containers, defaultPolicy)
+		if err != nil {
+			return manifest.OSCustomizations{}, err
+		}
+		osc.Files = append(osc.Files, policyFiles...)
+	}
+
 	// OSTree commits do not include data in `/var` since that is tied to the
 	// deployment, rather than the commit. Therefore the containers need to be
 	// stored in a different location, like `/usr/share`, and the container
@@ -435,6 +450,11 @@ func EdgeCommitImage(workload workload.Workload,
 	img.Environment = t.Environment
 	img.Workload = workload
 	img.OSTreeParent = parentCommit
+	img.OSTreeDeltaFrom = makeOSTreeDeltaFrom(options.OSTree)
+	if options.OSTree != nil {
+		img.OSTreeSigning = options.OSTree.Sign
+		img.OSTreeDeltaFromScratch = options.OSTree.DeltaFromScratch
+	}
 	img.OSVersion = t.Arch().Distro().OsVersion()
 	img.Filename = t.Filename()
 
@@ -464,6 +484,9 @@ func EdgeContainerImage(workload workload.Workload,
 	img.Environment = t.Environment
 	img.Workload = workload
 	img.OSTreeParent = parentCommit
+	if options.OSTree != nil {
+		img.OSTreeSigning = options.OSTree.Sign
+	}
 	img.OSVersion = t.Arch().Distro().OsVersion()
 	img.ExtraContainerPackages = packageSets[ContainerPkgsKey]
 	img.Filename = t.Filename()
@@ -840,6 +863,19 @@ func makeOSTreeParentCommit(options *ostree.ImageOptions, defaultRef string) (*o
 	return parentCommit, commitRef
 }
 
+// Create an ostree SourceSpec for the commit that a static delta to the new
+// commit is generated from, if one is set in the user options.
+func makeOSTreeDeltaFrom(options *ostree.ImageOptions) *ostree.SourceSpec {
+	if options == nil || options.DeltaFrom == "" {
+		return nil
+	}
+	return &ostree.SourceSpec{
+		URL:  options.URL,
+		Ref:  options.DeltaFrom,
+		RHSM: options.RHSM,
+	}
+}
+
 // Create an ostree SourceSpec to define an ostree payload using the user options and the default ref for the image type.
 func makeOSTreePayloadCommit(options *ostree.ImageOptions, defaultRef string) (ostree.SourceSpec, error) {
 	if options == nil || options.URL == "" {
@@ -855,8 +891,10 @@ func makeOSTreePayloadCommit(options *ostree.ImageOptions, defaultRef string) (o
 	}
 
 	return ostree.SourceSpec{
-		URL:  options.URL,
-		Ref:  commitRef,
-		RHSM: options.RHSM,
+		URL:        options.URL,
+		ContentURL: options.ContentURL,
+		Ref:        commitRef,
+		RHSM:       options.RHSM,
+		Verify:     options.Verify,
 	}, nil
 }
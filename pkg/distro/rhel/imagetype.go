@@ -177,14 +177,7 @@ func (t *ImageType) Exports() []string {
 }
 
 func (t *ImageType) BootMode() platform.BootMode {
-	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
-		return platform.BOOT_HYBRID
-	} else if t.platform.GetUEFIVendor() != "" {
-		return platform.BOOT_UEFI
-	} else if t.platform.GetBIOSPlatform() != "" || t.platform.GetZiplSupport() {
-		return platform.BOOT_LEGACY
-	}
-	return platform.BOOT_NONE
+	return platform.BootModeFor(t.platform)
 }
 
 func (t *ImageType) GetPartitionTable(
@@ -334,15 +327,7 @@ func (t *ImageType) Manifest(bp *blueprint.Blueprint,
 		w = cw
 	}
 
-	containerSources := make([]container.SourceSpec, len(bp.Containers))
-	for idx, cont := range bp.Containers {
-		containerSources[idx] = container.SourceSpec{
-			Source:    cont.Source,
-			Name:      cont.Name,
-			TLSVerify: cont.TLSVerify,
-			Local:     cont.LocalStorage,
-		}
-	}
+	containerSources := distro.ContainerSources(bp.Containers)
 
 	if experimentalflags.Bool("no-fstab") {
 		if t.DefaultImageConfig == nil {
@@ -396,6 +381,13 @@ func (t *ImageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return nil, fmt.Errorf("OSTree is not supported for %q", t.Name())
 	}
 
+	// the UKI boot chain and Secure Boot signing are only implemented for Fedora
+	if bootloader, err := bp.Customizations.GetBootloader(); err != nil {
+		return nil, err
+	} else if bootloader != nil && (bootloader.Type != "" || bootloader.SecureBoot != nil) {
+		return nil, fmt.Errorf("bootloader customizations are not supported for %q", t.Name())
+	}
+
 	if t.arch.distro.CheckOptions != nil {
 		return t.arch.distro.CheckOptions(t, bp, options)
 	}
@@ -45,6 +45,10 @@ type BaseSolver struct {
 	dnfJsonCmd []string
 
 	resultCache *dnfCache
+
+	// Long-lived depsolver process shared by all Solvers created from this
+	// BaseSolver (nil when every request starts a new process)
+	persistent *persistentSolver
 }
 
 // Find the osbuild-depsolve-dnf script. This checks the default location in
@@ -92,6 +96,37 @@ func (s *BaseSolver) SetDNFJSONPath(cmd string, args ...string) {
 	s.dnfJsonCmd = append([]string{cmd}, args...)
 }
 
+// SetPersistent enables or disables the persistent depsolver mode. When
+// enabled, a single osbuild-depsolve-dnf process is started in persistent mode
+// and reused for all requests of the Solvers created from this BaseSolver
+// afterwards, which keeps the loaded repository metadata in memory between
+// requests. The process is restarted if it exits unexpectedly.
+//
+// If the osbuild-depsolve-dnf found by the BaseSolver or set with
+// SetDNFJSONPath does not answer the persistent mode handshake, the Solvers
+// start a new process for each request, like without the persistent mode.
+func (bs *BaseSolver) SetPersistent(enabled bool) error {
+	if enabled {
+		if bs.persistent == nil {
+			bs.persistent = newPersistentSolver()
+		}
+		return nil
+	}
+	return bs.Close()
+}
+
+// Close stops the persistent depsolver process, if one is running. Solvers
+// created from the BaseSolver while persistent mode was enabled do not restart
+// it, they start a new process for each of their requests afterwards.
+func (bs *BaseSolver) Close() error {
+	if bs.persistent == nil {
+		return nil
+	}
+	err := bs.persistent.Close()
+	bs.persistent = nil
+	return err
+}
+
 // NewWithConfig initialises a Solver with the platform information and the
 // BaseSolver's subscription info, cache directory, and dnf-json path.
 // Also loads system subscription information.
@@ -130,6 +165,8 @@ func (bs *BaseSolver) CleanupOldCacheDirs(distros []string) {
 	CleanupOldCacheDirs(bs.cache.root, distros)
 }
 
+var _ rpmmd.MetadataReader = &Solver{}
+
 // Solver is configured with system information in order to resolve
 // dependencies for RPM packages using DNF.
 type Solver struct {
@@ -156,6 +193,18 @@ type Solver struct {
 
 	subscriptions *rhsm.Subscriptions
 
+	// Request the dependency graph of the transaction with each depsolve
+	dependencyGraph bool
+
+	// Request the updateinfo advisories with each depsolve
+	advisories bool
+
+	// Add the installed sizes to the packages of each depsolve result
+	packageSizes bool
+
+	// Add the documentation sizes to the packages of each depsolve result
+	docSizes bool
+
 	// Stderr is the stderr output from dnfjson, if unset os.Stderr
 	// will be used.
 	//
@@ -172,6 +221,14 @@ type DepsolveResult struct {
 	Repos    []rpmmd.RepoConfig
 	SBOM     *sbom.Document
 	Solver   string
+
+	// Requires edges between the depsolved packages, only set if requested
+	// with SetDependencyGraph()
+	Dependencies *rpmmd.DependencyGraph
+
+	// Advisories that affect the depsolved package versions, only set if
+	// requested with SetAdvisories()
+	Advisories []rpmmd.AdvisoryMatch
 }
 
 // Create a new Solver with the given configuration. Initialising a Solver also loads system subscription information.
@@ -187,6 +244,31 @@ func (s *Solver) SetRootDir(path string) {
 	s.rootDir = path
 }
 
+// SetDependencyGraph enables or disables returning the dependency graph of
+// the transaction with each Depsolve() result. Computing the graph makes
+// depsolving slower, so it is disabled by default.
+func (s *Solver) SetDependencyGraph(enabled bool) {
+	s.dependencyGraph = enabled
+}
+
+// SetAdvisories enables or disables annotating each Depsolve() result with
+// the advisories from the updateinfo metadata of the repositories that affect
+// the selected package versions. This requires downloading the updateinfo
+// metadata, so it is disabled by default.
+func (s *Solver) SetAdvisories(enabled bool) {
+	s.advisories = enabled
+}
+
+// SetPackageSizes enables or disables setting the installed size of the
+// packages of each Depsolve() result. The sizes are read from the primary
+// metadata in the metadata cache of the depsolver. If docs is true, the size
+// of the documentation of each package is set as well, which requires
+// downloading the headers of all packages.
+func (s *Solver) SetPackageSizes(enabled, docs bool) {
+	s.packageSizes = enabled
+	s.docSizes = enabled && docs
+}
+
 // GetCacheDir returns a distro specific rpm cache directory
 // It ensures that the distro name is below the root cache directory, and if there is
 // a problem it returns the root cache instead of an error.
@@ -222,7 +304,7 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType
 	s.cache.locker.RLock()
 	defer s.cache.locker.RUnlock()
 
-	output, err := run(s.dnfJsonCmd, req, s.Stderr)
+	output, err := s.run(req)
 	if err != nil {
 		return nil, fmt.Errorf("running osbuild-depsolve-dnf failed:\n%w", err)
 	}
@@ -240,8 +322,34 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType
 	if err := dec.Decode(&result); err != nil {
 		return nil, fmt.Errorf("decoding depsolve result failed: %w", err)
 	}
+	// a depsolver that does not know the optional arguments ignores them
+	if req.Arguments.Dependencies && result.Dependencies == nil {
+		return nil, fmt.Errorf("osbuild-depsolve-dnf did not return the dependency graph, it does not support the %q argument", "dependencies")
+	}
+	if req.Arguments.Advisories && result.Advisories == nil {
+		return nil, fmt.Errorf("osbuild-depsolve-dnf did not return the advisories, it does not support the %q argument", "advisories")
+	}
 
 	packages, modules, repos := result.toRPMMD(rhsmMap)
+	if s.packageSizes {
+		if err := s.addPackageSizes(packages, repos); err != nil {
+			return nil, fmt.Errorf("reading package sizes failed: %w", err)
+		}
+	}
+
+	// the depsolver does not know about snapshots, restore them from the
+	// request so that the result records which snapshot was used
+	snapshots := make(map[string]string)
+	for _, ps := range pkgSets {
+		for _, repo := range ps.Repositories {
+			if repo.Snapshot != "" {
+				snapshots[repo.Hash()] = repo.Snapshot
+			}
+		}
+	}
+	for idx := range repos {
+		repos[idx].Snapshot = snapshots[repos[idx].Id]
+	}
 
 	var sbomDoc *sbom.Document
 	if sbomType != sbom.StandardTypeNone {
@@ -252,11 +360,13 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType
 	}
 
 	return &DepsolveResult{
-		Packages: packages,
-		Modules:  modules,
-		Repos:    repos,
-		SBOM:     sbomDoc,
-		Solver:   result.Solver,
+		Packages:     packages,
+		Modules:      modules,
+		Repos:        repos,
+		SBOM:         sbomDoc,
+		Solver:       result.Solver,
+		Dependencies: result.Dependencies,
+		Advisories:   rpmmd.MatchAdvisories(packages, result.Advisories),
 	}, nil
 }
 
@@ -277,7 +387,7 @@ func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, err
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req, s.Stderr)
+	result, err := s.run(req)
 	if err != nil {
 		return nil, err
 	}
@@ -323,7 +433,7 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req, s.Stderr)
+	result, err := s.run(req)
 	if err != nil {
 		return nil, err
 	}
@@ -367,6 +477,9 @@ func (s *Solver) reposFromRPMMD(rpmRepos []rpmmd.RepoConfig) ([]repoConfig, erro
 			SSLCACert:      rr.SSLCACert,
 			SSLClientKey:   rr.SSLClientKey,
 			SSLClientCert:  rr.SSLClientCert,
+			IncludePkgs:    rr.IncludePackages,
+			ExcludePkgs:    rr.ExcludePackages,
+			Cost:           rr.Cost,
 			repoHash:       rr.Hash(),
 		}
 		if rr.ModuleHotfixes != nil {
@@ -421,6 +534,9 @@ type repoConfig struct {
 	SSLClientCert  string   `json:"sslclientcert,omitempty"`
 	MetadataExpire string   `json:"metadata_expire,omitempty"`
 	ModuleHotfixes *bool    `json:"module_hotfixes,omitempty"`
+	IncludePkgs    []string `json:"includepkgs,omitempty"`
+	ExcludePkgs    []string `json:"excludepkgs,omitempty"`
+	Cost           *int     `json:"cost,omitempty"`
 	// set the repo hass from `rpmmd.RepoConfig.Hash()` function
 	// rather than re-calculating it
 	repoHash string
@@ -501,6 +617,11 @@ func (s *Solver) makeDepsolveRequest(pkgSets []rpmmd.PackageSet, sbomType sbom.S
 		RootDir:          s.rootDir,
 		Transactions:     transactions,
 		OptionalMetadata: s.optionalMetadataForDistro(),
+		Dependencies:     s.dependencyGraph,
+		Advisories:       s.advisories,
+	}
+	if s.advisories {
+		args.OptionalMetadata = append(args.OptionalMetadata, "updateinfo")
 	}
 
 	req := Request{
@@ -637,21 +758,24 @@ func (result depsolveResult) toRPMMD(rhsm map[string]bool) ([]rpmmd.PackageSpec,
 			ignoreSSL = !*sslVerify
 		}
 		repoConfigs = append(repoConfigs, rpmmd.RepoConfig{
-			Id:             repo.ID,
-			Name:           repo.Name,
-			BaseURLs:       repo.BaseURLs,
-			Metalink:       repo.Metalink,
-			MirrorList:     repo.MirrorList,
-			GPGKeys:        repo.GPGKeys,
-			CheckGPG:       &repo.GPGCheck,
-			CheckRepoGPG:   &repo.RepoGPGCheck,
-			IgnoreSSL:      &ignoreSSL,
-			MetadataExpire: repo.MetadataExpire,
-			ModuleHotfixes: repo.ModuleHotfixes,
-			Enabled:        common.ToPtr(true),
-			SSLCACert:      repo.SSLCACert,
-			SSLClientKey:   repo.SSLClientKey,
-			SSLClientCert:  repo.SSLClientCert,
+			Id:              repo.ID,
+			Name:            repo.Name,
+			BaseURLs:        repo.BaseURLs,
+			Metalink:        repo.Metalink,
+			MirrorList:      repo.MirrorList,
+			GPGKeys:         repo.GPGKeys,
+			CheckGPG:        &repo.GPGCheck,
+			CheckRepoGPG:    &repo.RepoGPGCheck,
+			IgnoreSSL:       &ignoreSSL,
+			MetadataExpire:  repo.MetadataExpire,
+			ModuleHotfixes:  repo.ModuleHotfixes,
+			Enabled:         common.ToPtr(true),
+			SSLCACert:       repo.SSLCACert,
+			SSLClientKey:    repo.SSLClientKey,
+			SSLClientCert:   repo.SSLClientCert,
+			IncludePackages: repo.IncludePkgs,
+			ExcludePackages: repo.ExcludePkgs,
+			Cost:            repo.Cost,
 		})
 	}
 	return rpmDependencies, moduleSpecs, repoConfigs
@@ -723,6 +847,13 @@ type arguments struct {
 
 	// Optionally request an SBOM from depsolving
 	Sbom *sbomRequest `json:"sbom,omitempty"`
+
+	// Optionally request the requires edges between the depsolved packages
+	Dependencies bool `json:"dependencies,omitempty"`
+
+	// Optionally request the updateinfo advisories for the depsolved
+	// packages
+	Advisories bool `json:"advisories,omitempty"`
 }
 
 type searchArgs struct {
@@ -765,6 +896,13 @@ type depsolveResult struct {
 
 	// (optional) contains the SBOM for the depsolved transaction
 	SBOM json.RawMessage `json:"sbom,omitempty"`
+
+	// (optional) contains the requires edges between the depsolved packages
+	Dependencies *rpmmd.DependencyGraph `json:"dependencies,omitempty"`
+
+	// (optional) contains the advisories that list fixed versions of the
+	// depsolved packages
+	Advisories []rpmmd.Advisory `json:"advisories,omitempty"`
 }
 
 // Package specification
@@ -866,6 +1004,15 @@ func ParseError(data []byte) Error {
 	return e
 }
 
+// run sends the request to the persistent depsolver process, if enabled, or
+// to a new osbuild-depsolve-dnf process.
+func (s *Solver) run(req *Request) ([]byte, error) {
+	if s.persistent != nil {
+		return s.persistent.run(s.dnfJsonCmd, req, s.Stderr)
+	}
+	return run(s.dnfJsonCmd, req, s.Stderr)
+}
+
 func run(dnfJsonCmd []string, req *Request, stderr io.Writer) ([]byte, error) {
 	if len(dnfJsonCmd) == 0 {
 		dnfJsonCmd = []string{findDepsolveDnf()}
@@ -821,6 +821,31 @@ func TestRepoConfigHash(t *testing.T) {
 	assert.NotEqual(t, hash, rcs[1].Hash())
 }
 
+func TestReposFromRPMMDPackageFilters(t *testing.T) {
+	repos := []rpmmd.RepoConfig{
+		{
+			Id:              "epel",
+			BaseURLs:        []string{"https://example.com/epel/"},
+			IncludePackages: []string{"htop", "tmux*"},
+			ExcludePackages: []string{"kernel*"},
+			Cost:            common.ToPtr(2000),
+		},
+	}
+
+	solver := NewSolver("platform:el9", "9", "x86_64", "rhel-9", "/tmp/cache")
+	rcs, err := solver.reposFromRPMMD(repos)
+	require.NoError(t, err)
+	require.Len(t, rcs, 1)
+	assert.Equal(t, []string{"htop", "tmux*"}, rcs[0].IncludePkgs)
+	assert.Equal(t, []string{"kernel*"}, rcs[0].ExcludePkgs)
+	assert.Equal(t, common.ToPtr(2000), rcs[0].Cost)
+	assert.Equal(t, repos[0].Hash(), rcs[0].Hash())
+
+	js, err := json.Marshal(rcs[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(js), `"includepkgs":["htop","tmux*"],"excludepkgs":["kernel*"],"cost":2000`)
+}
+
 func TestRequestHash(t *testing.T) {
 	solver := NewSolver("platform:f38", "38", "x86_64", "fedora-38", "/tmp/cache")
 	repos := []rpmmd.RepoConfig{
@@ -893,6 +918,190 @@ echo '{"solver": "zypper"}'
 	assert.Equal(t, 0, len(res.Repos))
 }
 
+func TestSolverDependencyGraph(t *testing.T) {
+	tmpdir := t.TempDir()
+	fakeSolver := `#!/bin/sh -e
+cat - > "$0".stdin
+echo '{"packages": [], "repos": {}, "dependencies": {"requested": ["bash-5.2.32-1.fc41.x86_64"], "requires": {"bash-5.2.32-1.fc41.x86_64": [{"name": "libc.so.6()(64bit)", "provided_by": "glibc-2.40-1.fc41.x86_64"}]}}}'
+`
+	fakeSolverPath := filepath.Join(tmpdir, "fake-solver")
+	err := os.WriteFile(fakeSolverPath, []byte(fakeSolver), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	solver := NewSolver("platform:f41", "41", "x86_64", "fedora-41", tmpdir)
+	solver.dnfJsonCmd = []string{fakeSolverPath}
+	solver.SetDependencyGraph(true)
+	res, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	require.NoError(t, err)
+
+	stdin, err := os.ReadFile(fakeSolverPath + ".stdin")
+	require.NoError(t, err)
+	assert.Contains(t, string(stdin), `"dependencies":true`)
+
+	require.NotNil(t, res.Dependencies)
+	chains, err := res.Dependencies.Why("glibc")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"bash-5.2.32-1.fc41.x86_64", "glibc-2.40-1.fc41.x86_64"}}, chains)
+}
+
+func TestSolverDependencyGraphUnsupported(t *testing.T) {
+	tmpdir := t.TempDir()
+	fakeSolver := `#!/bin/sh -e
+cat - > /dev/null
+echo '{"packages": [], "repos": {}}'
+`
+	fakeSolverPath := filepath.Join(tmpdir, "fake-solver")
+	err := os.WriteFile(fakeSolverPath, []byte(fakeSolver), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	solver := NewSolver("platform:f41", "41", "x86_64", "fedora-41", tmpdir)
+	solver.dnfJsonCmd = []string{fakeSolverPath}
+	solver.SetDependencyGraph(true)
+	_, err = solver.Depsolve(nil, sbom.StandardTypeNone)
+	assert.EqualError(t, err, `osbuild-depsolve-dnf did not return the dependency graph, it does not support the "dependencies" argument`)
+}
+
+func TestSolverPackageSizes(t *testing.T) {
+	tmpdir := t.TempDir()
+	fakeSolver := `#!/bin/sh -e
+cat - > /dev/null
+cat <<EOF
+{
+  "packages": [
+    {"name": "ModemManager", "epoch": 0, "version": "1.18.2", "release": "3.el9", "arch": "x86_64", "repo_id": "testrepo",
+     "checksum": "sha256:159adbdd220d622a93aff1ad942936e110e7b32400620bef3b1f0b52e639c964"}
+  ],
+  "repos": {"testrepo": {"id": "testrepo", "name": "testrepo", "baseurl": ["https://example.com/testrepo"]}}
+}
+EOF
+`
+	fakeSolverPath := filepath.Join(tmpdir, "fake-solver")
+	err := os.WriteFile(fakeSolverPath, []byte(fakeSolver), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	solver := NewSolver("platform:el9", "9", "x86_64", "centos-9", filepath.Join(tmpdir, "cache"))
+	solver.dnfJsonCmd = []string{fakeSolverPath}
+	solver.SetPackageSizes(true, false)
+
+	// the metadata is not in the cache
+	_, err = solver.Depsolve(nil, sbom.StandardTypeNone)
+	assert.ErrorContains(t, err, `reading package sizes failed: cannot find the primary metadata of repository "testrepo"`)
+
+	// the metadata cache of the depsolver
+	primary, err := filepath.Glob("../../test/data/testrepo/repodata/*-primary.xml.gz")
+	require.NoError(t, err)
+	require.Len(t, primary, 1)
+	repodata := filepath.Join(solver.GetCacheDir(), "testrepo-0123456789abcdef", "repodata")
+	require.NoError(t, os.MkdirAll(repodata, 0755))
+	content, err := os.ReadFile(primary[0])
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repodata, filepath.Base(primary[0])), content, 0644))
+
+	res, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	require.NoError(t, err)
+	require.Len(t, res.Packages, 1)
+	assert.Equal(t, uint64(4272086), res.Packages[0].InstallSize)
+}
+
+func TestSolverAdvisories(t *testing.T) {
+	tmpdir := t.TempDir()
+	fakeSolver := `#!/bin/sh -e
+cat - > "$0".stdin
+cat <<EOF
+{
+  "packages": [
+    {"name": "openssl-libs", "epoch": 1, "version": "3.2.2", "release": "3.fc41", "arch": "x86_64", "repo_id": "fedora"},
+    {"name": "bash", "epoch": 0, "version": "5.2.32", "release": "1.fc41", "arch": "x86_64", "repo_id": "fedora"}
+  ],
+  "repos": {"fedora": {"id": "fedora", "name": "fedora", "baseurl": ["https://example.com/fedora"]}},
+  "advisories": [
+    {
+      "id": "FEDORA-2024-0000000001",
+      "type": "security",
+      "severity": "Critical",
+      "cves": ["CVE-2024-0001"],
+      "packages": [
+        {"name": "openssl-libs", "epoch": 1, "version": "3.2.2", "release": "5.fc41", "arch": "x86_64", "available": true}
+      ]
+    }
+  ]
+}
+EOF
+`
+	fakeSolverPath := filepath.Join(tmpdir, "fake-solver")
+	err := os.WriteFile(fakeSolverPath, []byte(fakeSolver), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	solver := NewSolver("platform:f41", "41", "x86_64", "fedora-41", tmpdir)
+	solver.dnfJsonCmd = []string{fakeSolverPath}
+	solver.SetAdvisories(true)
+	res, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	require.NoError(t, err)
+
+	stdin, err := os.ReadFile(fakeSolverPath + ".stdin")
+	require.NoError(t, err)
+	assert.Contains(t, string(stdin), `"advisories":true`)
+	assert.Contains(t, string(stdin), `"optional-metadata":["updateinfo"]`)
+
+	assert.Equal(t, []rpmmd.AdvisoryMatch{
+		{
+			Package:    "openssl-libs-1:3.2.2-3.fc41.x86_64",
+			AdvisoryID: "FEDORA-2024-0000000001",
+			Type:       "security",
+			Severity:   "Critical",
+			CVEs:       []string{"CVE-2024-0001"},
+			Status:     rpmmd.AdvisoryStatusFixAvailable,
+			FixedIn:    "openssl-libs-1:3.2.2-5.fc41.x86_64",
+		},
+	}, res.Advisories)
+}
+
+func TestSolverAdvisoriesUnsupported(t *testing.T) {
+	tmpdir := t.TempDir()
+	fakeSolver := `#!/bin/sh -e
+cat - > /dev/null
+echo '{"packages": [], "repos": {}}'
+`
+	fakeSolverPath := filepath.Join(tmpdir, "fake-solver")
+	err := os.WriteFile(fakeSolverPath, []byte(fakeSolver), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	solver := NewSolver("platform:f41", "41", "x86_64", "fedora-41", tmpdir)
+	solver.dnfJsonCmd = []string{fakeSolverPath}
+	solver.SetAdvisories(true)
+	_, err = solver.Depsolve(nil, sbom.StandardTypeNone)
+	assert.EqualError(t, err, `osbuild-depsolve-dnf did not return the advisories, it does not support the "advisories" argument`)
+}
+
+func TestSolverSnapshot(t *testing.T) {
+	repo, err := rpmmd.RepoConfig{
+		Name:        "fedora",
+		SnapshotURL: "https://snapshots.example.com/{snapshot}/fedora/",
+	}.WithSnapshot("2024-05-01")
+	require.NoError(t, err)
+
+	tmpdir := t.TempDir()
+	fakeSolver := fmt.Sprintf(`#!/bin/sh -e
+cat - > "$0".stdin
+echo '{"packages": [], "repos": {"%[1]s": {"id": "%[1]s", "name": "fedora", "baseurl": ["https://snapshots.example.com/2024-05-01/fedora/"]}}}'
+`, repo.Hash())
+	fakeSolverPath := filepath.Join(tmpdir, "fake-solver")
+	err = os.WriteFile(fakeSolverPath, []byte(fakeSolver), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	solver := NewSolver("platform:f41", "41", "x86_64", "fedora-41", tmpdir)
+	solver.dnfJsonCmd = []string{fakeSolverPath}
+	res, err := solver.Depsolve([]rpmmd.PackageSet{{Include: []string{"bash"}, Repositories: []rpmmd.RepoConfig{repo}}}, sbom.StandardTypeNone)
+	require.NoError(t, err)
+
+	stdin, err := os.ReadFile(fakeSolverPath + ".stdin")
+	require.NoError(t, err)
+	assert.Contains(t, string(stdin), `"baseurl":["https://snapshots.example.com/2024-05-01/fedora/"]`)
+
+	require.Len(t, res.Repos, 1)
+	assert.Equal(t, "2024-05-01", res.Repos[0].Snapshot)
+}
+
 func TestDepsolveResultWithModulesKey(t *testing.T) {
 	// quick test that verifies that `depsolveResult` understands JSON that contains
 	// a `modules` key
@@ -0,0 +1,284 @@
+package dnfjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+)
+
+// persistentArg is passed to osbuild-depsolve-dnf to start it in persistent
+// mode.
+const persistentArg = "--persistent"
+
+// persistentProtocol is the version of the line protocol that a persistent
+// osbuild-depsolve-dnf must report in the handshake.
+const persistentProtocol = 1
+
+const (
+	// defaultHandshakeTimeout limits how long a newly started process may
+	// take to answer the handshake. A depsolver without persistent mode
+	// support reads stdin until EOF and never answers.
+	defaultHandshakeTimeout = 10 * time.Second
+
+	// defaultRequestTimeout limits how long a single request may take,
+	// including loading the repository metadata.
+	defaultRequestTimeout = 30 * time.Minute
+
+	// defaultStopTimeout limits how long the process may take to exit after
+	// its stdin is closed.
+	defaultStopTimeout = 10 * time.Second
+)
+
+// handshakeRequest is sent to a newly started process to check that it
+// supports the persistent mode.
+type handshakeRequest struct {
+	Command string `json:"command"`
+}
+
+// handshakeResult is the result of a successful handshake.
+type handshakeResult struct {
+	Protocol int `json:"protocol"`
+}
+
+// persistentResponse is the envelope for a single response of a persistent
+// osbuild-depsolve-dnf process. Exactly one of the fields is set.
+type persistentResponse struct {
+	// Output of a successful request, the same as the stdout of a one-shot
+	// run
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Error of a failed request, the same as the stdout of a one-shot run
+	// that exits with an error
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// stderrSwitch forwards the stderr of the persistent process to the writer
+// of the request that is currently running.
+type stderrSwitch struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *stderrSwitch) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return os.Stderr.Write(p)
+	}
+	return s.w.Write(p)
+}
+
+func (s *stderrSwitch) set(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w = w
+}
+
+// persistentSolver keeps a single osbuild-depsolve-dnf process running to
+// serve all requests. Requests and responses are exchanged as one JSON
+// document per line on stdin and stdout, which allows the depsolver to reuse
+// the repository metadata it already loaded. Requests are serialized. The
+// process is restarted if it exits unexpectedly, until the persistentSolver is
+// closed. Requests after Close start a new process each, like without the
+// persistent mode.
+//
+// Each new process must answer a handshake with the supported protocol
+// version first. If it does not, the depsolver does not support the
+// persistent mode and all requests start a new process each, like after Close.
+// A process that does not answer a request in time is killed.
+type persistentSolver struct {
+	mu          sync.Mutex
+	closed      bool
+	unsupported bool
+
+	handshakeTimeout time.Duration
+	requestTimeout   time.Duration
+	stopTimeout      time.Duration
+
+	dnfJsonCmd []string
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	stdout     *bufio.Reader
+	stderr     stderrSwitch
+}
+
+func newPersistentSolver() *persistentSolver {
+	return &persistentSolver{
+		handshakeTimeout: defaultHandshakeTimeout,
+		requestTimeout:   defaultRequestTimeout,
+		stopTimeout:      defaultStopTimeout,
+	}
+}
+
+func (ps *persistentSolver) start(dnfJsonCmd []string) error {
+	ex := dnfJsonCmd[0]
+	args := append(slices.Clone(dnfJsonCmd[1:]), persistentArg)
+	cmd := exec.Command(ex, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("creating stdin pipe for %s failed: %w", ex, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("creating stdout pipe for %s failed: %w", ex, err)
+	}
+	cmd.Stderr = &ps.stderr
+	// do not wait forever for children of a killed process that still hold
+	// its stderr open
+	cmd.WaitDelay = ps.stopTimeout
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s failed: %w", ex, err)
+	}
+	ps.dnfJsonCmd = slices.Clone(dnfJsonCmd)
+	ps.cmd = cmd
+	ps.stdin = stdin
+	ps.stdout = bufio.NewReader(stdout)
+	return nil
+}
+
+// stop terminates the process, if one is running.
+func (ps *persistentSolver) stop() error {
+	if ps.cmd == nil {
+		return nil
+	}
+	// closing stdin asks the depsolver to exit cleanly
+	ps.stdin.Close()
+	timer := ps.killAfter(ps.stopTimeout)
+	err := ps.cmd.Wait()
+	timer.Stop()
+	ps.cmd = nil
+	ps.stdin = nil
+	ps.stdout = nil
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// the process already failed, nothing else to clean up
+		return nil
+	}
+	return err
+}
+
+// killAfter kills the running process when the timeout expires, unless the
+// returned timer is stopped first. Killing the process closes its stdout, which
+// unblocks a pending read.
+func (ps *persistentSolver) killAfter(timeout time.Duration) *time.Timer {
+	proc := ps.cmd.Process
+	return time.AfterFunc(timeout, func() {
+		_ = proc.Kill()
+	})
+}
+
+// roundtrip sends the request to the running process and reads its response.
+// The process is killed if the response does not arrive within the timeout.
+func (ps *persistentSolver) roundtrip(req any, timeout time.Duration) (*persistentResponse, error) {
+	timer := ps.killAfter(timeout)
+	defer timer.Stop()
+
+	if err := json.NewEncoder(ps.stdin).Encode(req); err != nil {
+		return nil, fmt.Errorf("sending request failed: %w", err)
+	}
+	line, err := ps.stdout.ReadBytes('\n')
+	if err != nil {
+		if !timer.Stop() {
+			return nil, fmt.Errorf("no response within %s", timeout)
+		}
+		return nil, fmt.Errorf("reading response failed: %w", err)
+	}
+	var resp persistentResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("decoding response %q failed: %w", string(line), err)
+	}
+	return &resp, nil
+}
+
+// handshake checks that the newly started process supports the persistent
+// mode.
+func (ps *persistentSolver) handshake() error {
+	resp, err := ps.roundtrip(&handshakeRequest{Command: "handshake"}, ps.handshakeTimeout)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("handshake failed: %s", string(resp.Error))
+	}
+	var result handshakeResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return fmt.Errorf("decoding handshake result %q failed: %w", string(resp.Result), err)
+	}
+	if result.Protocol != persistentProtocol {
+		return fmt.Errorf("unsupported protocol version %d", result.Protocol)
+	}
+	return nil
+}
+
+// run sends the request to the persistent process, starting it if needed. If
+// the process died, it is restarted and the request is retried once.
+func (ps *persistentSolver) run(dnfJsonCmd []string, req *Request, stderr io.Writer) ([]byte, error) {
+	if len(dnfJsonCmd) == 0 {
+		dnfJsonCmd = []string{findDepsolveDnf()}
+	}
+	if len(dnfJsonCmd) == 0 || dnfJsonCmd[0] == "" {
+		return nil, fmt.Errorf("osbuild-depsolve-dnf command undefined")
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.closed || ps.unsupported {
+		return run(dnfJsonCmd, req, stderr)
+	}
+
+	ps.stderr.set(stderr)
+	defer ps.stderr.set(nil)
+
+	// the command changed since the process was started
+	if ps.cmd != nil && !slices.Equal(ps.dnfJsonCmd, dnfJsonCmd) {
+		_ = ps.stop()
+	}
+
+	var resp *persistentResponse
+	for attempt := 0; attempt < 2; attempt++ {
+		if ps.cmd == nil {
+			if err := ps.start(dnfJsonCmd); err != nil {
+				return nil, err
+			}
+			if err := ps.handshake(); err != nil {
+				// the depsolver does not support the persistent mode
+				_ = ps.stop()
+				ps.unsupported = true
+				return run(dnfJsonCmd, req, stderr)
+			}
+		}
+		var err error
+		resp, err = ps.roundtrip(req, ps.requestTimeout)
+		if err == nil {
+			break
+		}
+		// the process crashed or sent garbage, restart it
+		_ = ps.stop()
+		if attempt == 1 {
+			return nil, fmt.Errorf("persistent %s failed: %w", dnfJsonCmd[0], err)
+		}
+	}
+
+	if resp.Error != nil {
+		return nil, parseError(resp.Error, req.Arguments.Repos)
+	}
+	return resp.Result, nil
+}
+
+// Close stops the persistent process. It is not started again.
+func (ps *persistentSolver) Close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.closed = true
+	return ps.stop()
+}
@@ -0,0 +1,174 @@
+package dnfjson
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/sbom"
+)
+
+const fakePersistentSolver = `#!/bin/sh -e
+if [ "$1" != "--persistent" ]; then
+	cat > /dev/null
+	echo started >> "$0".oneshot
+	echo '{"solver": "fake"}'
+	exit 0
+fi
+echo started >> "$0".starts
+while read -r line; do
+	if [ "$line" = '{"command":"handshake"}' ]; then
+		echo '{"result": {"protocol": 1}}'
+		continue
+	fi
+	echo "$line" >> "$0".stdin
+	if [ -e "$0".hang ]; then
+		rm "$0".hang
+		sleep 60 > /dev/null 2>&1
+	fi
+	if [ -e "$0".crash ]; then
+		rm "$0".crash
+		exit 1
+	fi
+	if [ -e "$0".fail ]; then
+		echo '{"error": {"kind": "MarkingErrors", "reason": "no package matched"}}'
+		continue
+	fi
+	echo '{"result": {"solver": "fake"}}'
+done
+`
+
+// fakeOneshotSolver does not support the persistent mode and reads stdin
+// until EOF, like osbuild-depsolve-dnf without persistent mode support.
+const fakeOneshotSolver = `#!/bin/sh -e
+cat > /dev/null
+echo started >> "$0".oneshot
+echo '{"solver": "fake"}'
+`
+
+func makeFakePersistentSolver(t *testing.T) (*BaseSolver, string) {
+	return makeFakeSolver(t, fakePersistentSolver)
+}
+
+func makeFakeSolver(t *testing.T, script string) (*BaseSolver, string) {
+	fakeSolverPath := filepath.Join(t.TempDir(), "fake-solver")
+	err := os.WriteFile(fakeSolverPath, []byte(script), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	bs := NewBaseSolver(t.TempDir())
+	bs.SetDNFJSONPath(fakeSolverPath)
+	require.NoError(t, bs.SetPersistent(true))
+	t.Cleanup(func() {
+		assert.NoError(t, bs.Close())
+	})
+	return bs, fakeSolverPath
+}
+
+func countStarts(t *testing.T, path string) int {
+	starts, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Count(string(starts), "started")
+}
+
+func TestPersistentSolverReusesProcess(t *testing.T) {
+	bs, fakeSolverPath := makeFakePersistentSolver(t)
+
+	// solvers created from the same base solver share the process
+	for _, distro := range []string{"fedora-40", "fedora-41"} {
+		solver := bs.NewWithConfig("platform:f40", "40", "x86_64", distro)
+		for i := 0; i < 2; i++ {
+			res, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+			require.NoError(t, err)
+			assert.Equal(t, 0, len(res.Packages))
+		}
+	}
+	assert.Equal(t, 1, countStarts(t, fakeSolverPath+".starts"))
+
+	stdin, err := os.ReadFile(fakeSolverPath + ".stdin")
+	require.NoError(t, err)
+	requests := strings.Split(strings.TrimSpace(string(stdin)), "\n")
+	assert.Len(t, requests, 4)
+	for _, req := range requests {
+		assert.Contains(t, req, `"command":"depsolve"`)
+	}
+}
+
+func TestPersistentSolverError(t *testing.T) {
+	bs, fakeSolverPath := makeFakePersistentSolver(t)
+	solver := bs.NewWithConfig("platform:f40", "40", "x86_64", "fedora-40")
+
+	require.NoError(t, os.WriteFile(fakeSolverPath+".fail", nil, 0600))
+	_, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	assert.EqualError(t, err, "running osbuild-depsolve-dnf failed:\nDNF error occurred: MarkingErrors: no package matched")
+
+	// an error response does not terminate the process
+	require.NoError(t, os.Remove(fakeSolverPath+".fail"))
+	_, err = solver.Depsolve(nil, sbom.StandardTypeNone)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, countStarts(t, fakeSolverPath+".starts"))
+}
+
+func TestPersistentSolverRestart(t *testing.T) {
+	bs, fakeSolverPath := makeFakePersistentSolver(t)
+	solver := bs.NewWithConfig("platform:f40", "40", "x86_64", "fedora-40")
+
+	_, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	require.NoError(t, err)
+
+	// the process crashes on the next request, which is retried with a new
+	// process
+	require.NoError(t, os.WriteFile(fakeSolverPath+".crash", nil, 0600))
+	_, err = solver.Depsolve(nil, sbom.StandardTypeNone)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, countStarts(t, fakeSolverPath+".starts"))
+}
+
+func TestPersistentSolverClose(t *testing.T) {
+	bs, fakeSolverPath := makeFakePersistentSolver(t)
+	solver := bs.NewWithConfig("platform:f40", "40", "x86_64", "fedora-40")
+
+	_, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	require.NoError(t, err)
+	require.NoError(t, bs.SetPersistent(false))
+
+	// the solver does not restart the persistent process but starts one
+	// process per request
+	for i := 0; i < 2; i++ {
+		_, err = solver.Depsolve(nil, sbom.StandardTypeNone)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, countStarts(t, fakeSolverPath+".starts"))
+	assert.Equal(t, 2, countStarts(t, fakeSolverPath+".oneshot"))
+}
+
+func TestPersistentSolverUnsupported(t *testing.T) {
+	bs, fakeSolverPath := makeFakeSolver(t, fakeOneshotSolver)
+	bs.persistent.handshakeTimeout = 100 * time.Millisecond
+	solver := bs.NewWithConfig("platform:f40", "40", "x86_64", "fedora-40")
+
+	// the handshake times out and the solver falls back to one process per
+	// request
+	for i := 0; i < 2; i++ {
+		_, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, countStarts(t, fakeSolverPath+".oneshot"))
+}
+
+func TestPersistentSolverRequestTimeout(t *testing.T) {
+	bs, fakeSolverPath := makeFakePersistentSolver(t)
+	bs.persistent.requestTimeout = 100 * time.Millisecond
+	solver := bs.NewWithConfig("platform:f40", "40", "x86_64", "fedora-40")
+
+	// the process hangs on the next request and is killed, the retry uses a
+	// new process
+	require.NoError(t, os.WriteFile(fakeSolverPath+".hang", nil, 0600))
+	_, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, countStarts(t, fakeSolverPath+".starts"))
+}
@@ -0,0 +1,117 @@
+package dnfjson
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/osbuild/images/pkg/repomd"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// headerFetchWorkers is the number of package headers that are downloaded in
+// parallel to compute the documentation sizes.
+const headerFetchWorkers = 8
+
+// findPrimaryMetadata returns the path of the primary metadata of a repository
+// in the metadata cache of the depsolver. If there is more than one, e.g.
+// because the cache of a changed mirror was not cleaned yet, the newest one is
+// used.
+func (s *Solver) findPrimaryMetadata(repoID string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.GetCacheDir(), repoID+"-*", "repodata", "*primary.xml*"))
+	if err != nil {
+		return "", err
+	}
+	var newest string
+	var newestInfo os.FileInfo
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return "", err
+		}
+		if newestInfo == nil || info.ModTime().After(newestInfo.ModTime()) {
+			newest = match
+			newestInfo = info
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("cannot find the primary metadata of repository %q in %q", repoID, s.GetCacheDir())
+	}
+	return newest, nil
+}
+
+// addPackageSizes sets the installed size of the packages from the primary
+// metadata of their repositories in the metadata cache and, if enabled, the
+// size of their documentation from their headers.
+func (s *Solver) addPackageSizes(pkgs []rpmmd.PackageSpec, repos []rpmmd.RepoConfig) error {
+	checksums := make(map[string]map[string]bool)
+	for _, pkg := range pkgs {
+		if checksums[pkg.RepoID] == nil {
+			checksums[pkg.RepoID] = make(map[string]bool)
+		}
+		checksums[pkg.RepoID][pkg.Checksum] = true
+	}
+
+	sizes := make(map[string]repomd.PackageSize, len(pkgs))
+	for repoID, repoChecksums := range checksums {
+		path, err := s.findPrimaryMetadata(repoID)
+		if err != nil {
+			return err
+		}
+		repoSizes, err := repomd.ReadPackageSizes(path, repoChecksums)
+		if err != nil {
+			return err
+		}
+		for checksum, size := range repoSizes {
+			sizes[checksum] = size
+		}
+	}
+	for idx, pkg := range pkgs {
+		size, ok := sizes[pkg.Checksum]
+		if !ok {
+			return fmt.Errorf("package %s not found in the primary metadata of repository %q", pkg.GetNEVRA(), pkg.RepoID)
+		}
+		pkgs[idx].InstallSize = size.InstallSize
+	}
+
+	if !s.docSizes {
+		return nil
+	}
+
+	repoConfigs := make(map[string]rpmmd.RepoConfig, len(repos))
+	for _, repo := range repos {
+		repoConfigs[repo.Id] = repo
+	}
+	reader := repomd.NewReader()
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, headerFetchWorkers)
+	errs := make([]error, len(pkgs))
+	for idx := range pkgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pkg *rpmmd.PackageSpec, errp *error) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			header, err := reader.ReadHeader(repoConfigs[pkg.RepoID], pkg.RemoteLocation, sizes[pkg.Checksum])
+			if err != nil {
+				*errp = err
+				return
+			}
+			docSize, err := repomd.HeaderDocSize(header)
+			if err != nil {
+				*errp = fmt.Errorf("cannot parse the header of package %s: %w", pkg.GetNEVRA(), err)
+				return
+			}
+			pkg.DocSize = docSize
+		}(&pkgs[idx], &errs[idx])
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
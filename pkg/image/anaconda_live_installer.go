@@ -9,6 +9,7 @@ import (
 	"github.com/osbuild/images/pkg/arch"
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -44,6 +45,10 @@ type AnacondaLiveInstaller struct {
 
 	AdditionalDracutModules []string
 	AdditionalDrivers       []string
+
+	// Size of a writable partition appended to the hybrid ISO that keeps
+	// changes to the live system. If zero, no partition is added.
+	PersistentOverlaySize uint64
 }
 
 func NewAnacondaLiveInstaller() *AnacondaLiveInstaller {
@@ -52,6 +57,25 @@ func NewAnacondaLiveInstaller() *AnacondaLiveInstaller {
 	}
 }
 
+func liveOverlayPartitionTable(size uint64, rng *rand.Rand) *disk.PartitionTable {
+	pt := &disk.PartitionTable{
+		Size: size,
+		Partitions: []disk.Partition{
+			{
+				Start: 0,
+				Size:  size,
+				Payload: &disk.Filesystem{
+					Type:       "ext4",
+					Label:      manifest.LiveOverlayLabel,
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+	pt.GenerateUUIDs(rng)
+	return pt
+}
+
 func (img *AnacondaLiveInstaller) InstantiateManifest(m *manifest.Manifest,
 	repos []rpmmd.RepoConfig,
 	runner runner.Runner,
@@ -104,6 +128,12 @@ func (img *AnacondaLiveInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	kernelOpts = append(kernelOpts, img.AdditionalKernelOpts...)
 
+	var overlayPipeline *manifest.LiveOverlay
+	if img.PersistentOverlaySize > 0 {
+		overlayPipeline = manifest.NewLiveOverlay(buildPipeline, liveOverlayPartitionTable(img.PersistentOverlaySize, rng))
+		kernelOpts = append(kernelOpts, overlayPipeline.KernelOpts()...)
+	}
+
 	bootTreePipeline.KernelOpts = kernelOpts
 
 	isoTreePipeline := manifest.NewAnacondaInstallerISOTree(buildPipeline, livePipeline, rootfsImagePipeline, bootTreePipeline)
@@ -119,6 +149,7 @@ func (img *AnacondaLiveInstaller) InstantiateManifest(m *manifest.Manifest,
 	isoPipeline := manifest.NewISO(buildPipeline, isoTreePipeline, img.ISOLabel)
 	isoPipeline.SetFilename(img.Filename)
 	isoPipeline.ISOBoot = img.ISOBoot
+	isoPipeline.PersistentOverlay = overlayPipeline
 
 	artifact := isoPipeline.Export()
 
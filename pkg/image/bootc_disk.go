@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"math/rand"
 
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/customizations/bootc"
 	"github.com/osbuild/images/pkg/customizations/fsnode"
 	"github.com/osbuild/images/pkg/customizations/users"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/pathpolicy"
 	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/policies"
 	"github.com/osbuild/images/pkg/runner"
 )
 
@@ -20,6 +25,15 @@ type BootcDiskImage struct {
 	Platform       platform.Platform
 	PartitionTable *disk.PartitionTable
 
+	// DiskCustomization, when set, creates the partition table of the image
+	// from the blueprint disk customizations with NewBootcPartitionTable,
+	// the PartitionTable is only used for its type and size then
+	DiskCustomization *blueprint.DiskCustomization
+
+	// BootcConfig is the bootc install config of the container, its root
+	// filesystem type is the default for the DiskCustomization
+	BootcConfig *bootc.Config
+
 	Filename string
 
 	ContainerSource *container.SourceSpec
@@ -39,6 +53,10 @@ type BootcDiskImage struct {
 	// SELinux policy, when set it enables the labeling of the tree with the
 	// selected profile
 	SELinux string
+
+	// Enable fs-verity on the root filesystem for a container that mounts
+	// its deployments with composefs
+	Composefs *ostree.ComposefsOptions
 }
 
 func NewBootcDiskImage(container container.SourceSpec) *BootcDiskImage {
@@ -48,11 +66,116 @@ func NewBootcDiskImage(container container.SourceSpec) *BootcDiskImage {
 	}
 }
 
+// rootFSType returns the filesystem type of the root filesystem of the
+// customizations, or an empty string if it is not customized or has no type
+func rootFSType(customizations *blueprint.DiskCustomization) string {
+	for _, part := range customizations.Partitions {
+		switch part.Type {
+		case "plain", "":
+			if part.Mountpoint == "/" {
+				return part.FSType
+			}
+		case "lvm":
+			for _, lv := range part.LogicalVolumes {
+				if lv.Mountpoint == "/" {
+					return lv.FSType
+				}
+			}
+		case "btrfs":
+			for _, subvol := range part.Subvolumes {
+				if subvol.Mountpoint == "/" {
+					return "btrfs"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// NewBootcPartitionTable creates the partition table of a bootc disk image
+// from the blueprint disk customizations with disk.NewCustomPartitionTable,
+// like for package-based images. The type and size of the basePT are used
+// as defaults. The mountpoints must not conflict with the ostree deployment
+// of bootc and the root filesystem type must match the one of the bootc
+// install config of the container, if set, which is also the default
+// filesystem type.
+func NewBootcPartitionTable(customizations *blueprint.DiskCustomization, basePT *disk.PartitionTable, cfg *bootc.Config, pf platform.Platform, rng *rand.Rand) (*disk.PartitionTable, error) {
+	for _, policy := range []*pathpolicy.PathPolicies{
+		policies.MountpointPolicies,
+		policies.OstreeMountpointPolicies,
+		policies.BootcMountpointPolicies,
+	} {
+		if err := blueprint.CheckDiskMountpointsPolicy(customizations, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	var defaultFSType disk.FSType
+	if cfg != nil {
+		fsType, err := disk.NewFSType(cfg.RootFilesystemType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root filesystem type in bootc install config: %w", err)
+		}
+		if rootType := rootFSType(customizations); rootType != "" && fsType != disk.FS_NONE && rootType != fsType.String() {
+			return nil, fmt.Errorf("root filesystem type %q does not match the root filesystem type %q of the bootc install config", rootType, fsType)
+		}
+		defaultFSType = fsType
+	}
+	if defaultFSType == disk.FS_NONE && basePT != nil {
+		if fs, ok := basePT.FindMountable("/").(*disk.Filesystem); ok {
+			fsType, err := disk.NewFSType(fs.Type)
+			if err != nil {
+				return nil, err
+			}
+			defaultFSType = fsType
+		}
+	}
+
+	options := &disk.CustomPartitionTableOptions{
+		BootMode:      platform.BootModeFor(pf),
+		DefaultFSType: defaultFSType,
+		Architecture:  pf.GetArch(),
+		Bootloader:    pf.GetBootloader(),
+	}
+	if basePT != nil {
+		options.PartitionTableType = basePT.Type
+		if customizations.MinSize < basePT.Size {
+			withSize := *customizations
+			withSize.MinSize = basePT.Size
+			customizations = &withSize
+		}
+	}
+	return disk.NewCustomPartitionTable(customizations, options, rng)
+}
+
+// partitionTable returns the partition table of the image, created from the
+// DiskCustomization if set and with fs-verity enabled on the root filesystem
+// for composefs
+func (img *BootcDiskImage) partitionTable(rng *rand.Rand) (*disk.PartitionTable, error) {
+	pt := img.PartitionTable
+	if img.DiskCustomization != nil {
+		var err error
+		pt, err = NewBootcPartitionTable(img.DiskCustomization, img.PartitionTable, img.BootcConfig, img.Platform, rng)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if img.Composefs != nil {
+		return composefsPartitionTable(pt)
+	}
+	return pt, nil
+}
+
 func (img *BootcDiskImage) InstantiateManifestFromContainers(m *manifest.Manifest,
 	containers []container.SourceSpec,
 	runner runner.Runner,
 	rng *rand.Rand) error {
 
+	pt, err := img.partitionTable(rng)
+	if err != nil {
+		return err
+	}
+
 	buildPipeline := manifest.NewBuildFromContainer(m, runner, containers, &manifest.BuildOptions{ContainerBuildable: true})
 	buildPipeline.Checkpoint()
 
@@ -61,7 +184,7 @@ func (img *BootcDiskImage) InstantiateManifestFromContainers(m *manifest.Manifes
 	var hostPipeline manifest.Build
 
 	rawImage := manifest.NewRawBootcImage(buildPipeline, containers, img.Platform)
-	rawImage.PartitionTable = img.PartitionTable
+	rawImage.PartitionTable = pt
 	rawImage.Users = img.Users
 	rawImage.Groups = img.Groups
 	rawImage.Files = img.Files
@@ -34,6 +34,11 @@ func (img *BootcLegacyDiskImage) InstantiateManifestFromContainers(m *manifest.M
 	runner runner.Runner,
 	rng *rand.Rand) error {
 
+	pt, err := img.bootcImg.partitionTable(rng)
+	if err != nil {
+		return err
+	}
+
 	// XXX: hardcoded for now
 	ref := "ostree/1/1/0"
 	ostreeImg := &OSTreeDiskImage{
@@ -43,7 +48,7 @@ func (img *BootcLegacyDiskImage) InstantiateManifestFromContainers(m *manifest.M
 		OSName:          "default",
 	}
 	ostreeImg.Platform = img.bootcImg.Platform
-	ostreeImg.PartitionTable = img.bootcImg.PartitionTable
+	ostreeImg.PartitionTable = pt
 	ostreeImg.OSTreeDeploymentCustomizations.KernelOptionsAppend = img.bootcImg.KernelOptionsAppend
 	ostreeImg.OSTreeDeploymentCustomizations.Users = img.bootcImg.Users
 	ostreeImg.OSTreeDeploymentCustomizations.Groups = img.bootcImg.Groups
@@ -62,7 +67,10 @@ func (img *BootcLegacyDiskImage) InstantiateManifestFromContainers(m *manifest.M
 	// In BIB, we export multiple images from the same pipeline so we use the
 	// filename as the basename for each export and set the extensions based on
 	// each file format.
-	baseImage := baseRawOstreeImage(ostreeImg, buildPipeline, opts)
+	baseImage, err := baseRawOstreeImage(ostreeImg, buildPipeline, opts)
+	if err != nil {
+		return err
+	}
 	baseImage.SetFilename(fmt.Sprintf("%s.raw", fileBasename))
 
 	qcow2Pipeline := manifest.NewQCOW2(hostPipeline, baseImage)
@@ -4,17 +4,24 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/internal/testdisk"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/customizations/bootc"
 	"github.com/osbuild/images/pkg/customizations/fsnode"
 	"github.com/osbuild/images/pkg/customizations/users"
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/runner"
 )
@@ -308,3 +315,197 @@ func TestBootcDiskImageInstantiateDirs(t *testing.T) {
 		}
 	}
 }
+
+func TestNewBootcPartitionTable(t *testing.T) {
+	pf := &platform.X86{
+		BasePlatform: platform.BasePlatform{
+			ImageFormat: platform.FORMAT_QCOW2,
+		},
+		UEFIVendor: "fedora",
+		BIOS:       true,
+	}
+	basePT := testdisk.MakeFakePartitionTable("/", "/boot", "/boot/efi")
+	basePT.Size = 10 * datasizes.GiB
+	cfg := &bootc.Config{RootFilesystemType: "xfs"}
+	rng := mathrand.New(mathrand.NewSource(0)) // nolint:gosec
+
+	customizations := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				Type:    "lvm",
+				MinSize: 5 * datasizes.GiB,
+				VGCustomization: blueprint.VGCustomization{
+					Name: "vg0",
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							Name:    "rootlv",
+							MinSize: 2 * datasizes.GiB,
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+						{
+							Name:    "varloglv",
+							MinSize: 1 * datasizes.GiB,
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/var/log",
+								FSType:     "ext4",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	pt, err := image.NewBootcPartitionTable(customizations, basePT, cfg, pf, rng)
+	require.NoError(t, err)
+	assert.Equal(t, basePT.Type, pt.Type)
+	assert.GreaterOrEqual(t, pt.Size, basePT.Size)
+	assert.Equal(t, "xfs", pt.FindMountable("/").(*disk.Filesystem).Type)
+	assert.Equal(t, "ext4", pt.FindMountable("/var/log").(*disk.Filesystem).Type)
+	// the root filesystem is on LVM, so a /boot partition is added
+	assert.NotNil(t, pt.FindMountable("/boot"))
+	assert.NotNil(t, pt.FindMountable("/boot/efi"))
+	// the base is not modified
+	assert.Equal(t, uint64(0), customizations.MinSize)
+
+	for _, mountpoint := range []string{"/usr", "/usr/local", "/etc", "/sysroot", "/home"} {
+		_, err := image.NewBootcPartitionTable(&blueprint.DiskCustomization{
+			Partitions: []blueprint.PartitionCustomization{
+				{
+					FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+						Mountpoint: mountpoint,
+						FSType:     "xfs",
+					},
+				},
+			},
+		}, basePT, cfg, pf, rng)
+		assert.ErrorContains(t, err, fmt.Sprintf("path %q is not allowed", mountpoint))
+	}
+
+	_, err = image.NewBootcPartitionTable(&blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/",
+					FSType:     "ext4",
+				},
+			},
+		},
+	}, basePT, cfg, pf, rng)
+	assert.EqualError(t, err, `root filesystem type "ext4" does not match the root filesystem type "xfs" of the bootc install config`)
+
+	// without an install config the root filesystem type can be chosen
+	pt, err = image.NewBootcPartitionTable(&blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				Type: "btrfs",
+				BtrfsVolumeCustomization: blueprint.BtrfsVolumeCustomization{
+					Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+						{Name: "root", Mountpoint: "/"},
+						{Name: "var", Mountpoint: "/var"},
+					},
+				},
+			},
+		},
+	}, basePT, nil, pf, rng)
+	require.NoError(t, err)
+	assert.IsType(t, &disk.BtrfsSubvolume{}, pt.FindMountable("/"))
+}
+
+func TestBootcDiskImageInstantiateDiskCustomization(t *testing.T) {
+	containerSource := container.SourceSpec{
+		Source: "some-src",
+		Name:   "name",
+	}
+	img := image.NewBootcDiskImage(containerSource)
+	img.Filename = "fake-disk"
+	img.Platform = makeFakePlatform(&bootcDiskImageTestOpts{ImageFormat: platform.FORMAT_QCOW2, BIOS: true})
+	img.PartitionTable = testdisk.MakeFakePartitionTable("/", "/boot", "/boot/efi")
+	img.SELinux = "targeted"
+	img.BootcConfig = &bootc.Config{RootFilesystemType: "ext4"}
+	img.DiskCustomization = &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 1 * datasizes.GiB,
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/var/lib/containers",
+					FSType:     "ext4",
+				},
+			},
+		},
+	}
+
+	m := &manifest.Manifest{}
+	rng := mathrand.New(mathrand.NewSource(0)) // nolint:gosec
+	err := img.InstantiateManifestFromContainers(m, []container.SourceSpec{containerSource}, &runner.Fedora{}, rng)
+	require.NoError(t, err)
+
+	fakeSourceSpecs := map[string][]container.Spec{
+		"build": []container.Spec{{Source: "some-src", Digest: makeFakeDigest(t), ImageID: makeFakeDigest(t)}},
+		"image": []container.Spec{{Source: "other-src", Digest: makeFakeDigest(t), ImageID: makeFakeDigest(t)}},
+	}
+	osbuildManifest, err := m.Serialize(nil, fakeSourceSpecs, nil, nil)
+	require.NoError(t, err)
+
+	// the customizations are mounted for the customization stages
+	imagePipeline := findPipelineFromOsbuildManifest(t, osbuildManifest, "image")
+	require.NotNil(t, imagePipeline)
+	selinuxStage := findStageFromOsbuildPipeline(t, imagePipeline, "org.osbuild.selinux")
+	require.NotNil(t, selinuxStage)
+	var targets []string
+	for _, mnt := range selinuxStage["mounts"].([]interface{}) {
+		if target, ok := mnt.(map[string]interface{})["target"].(string); ok {
+			targets = append(targets, target)
+		}
+	}
+	assert.Contains(t, targets, "/var/lib/containers")
+
+	// the customizations are validated
+	img.DiskCustomization.Partitions[0].Mountpoint = "/usr/lib"
+	err = img.InstantiateManifestFromContainers(&manifest.Manifest{}, []container.SourceSpec{containerSource}, &runner.Fedora{}, rng)
+	assert.ErrorContains(t, err, `path "/usr/lib" is not allowed`)
+}
+
+func TestBootcDiskImageInstantiateComposefs(t *testing.T) {
+	containerSource := container.SourceSpec{
+		Source: "some-src",
+		Name:   "name",
+	}
+	img := image.NewBootcDiskImage(containerSource)
+	img.Filename = "fake-disk"
+	img.Platform = makeFakePlatform(&bootcDiskImageTestOpts{ImageFormat: platform.FORMAT_QCOW2})
+	img.PartitionTable = testdisk.MakeFakePartitionTable("/", "/boot", "/boot/efi")
+	img.Composefs = &ostree.ComposefsOptions{}
+
+	m := &manifest.Manifest{}
+	err := img.InstantiateManifestFromContainers(m, []container.SourceSpec{containerSource}, &runner.Fedora{}, nil)
+	require.NoError(t, err)
+
+	fakeSourceSpecs := map[string][]container.Spec{
+		"build": []container.Spec{{Source: "some-src", Digest: makeFakeDigest(t), ImageID: makeFakeDigest(t)}},
+		"image": []container.Spec{{Source: "other-src", Digest: makeFakeDigest(t), ImageID: makeFakeDigest(t)}},
+	}
+	osbuildManifest, err := m.Serialize(nil, fakeSourceSpecs, nil, nil)
+	require.NoError(t, err)
+
+	// only the root filesystem gets fs-verity enabled
+	imagePipeline := findPipelineFromOsbuildManifest(t, osbuildManifest, "image")
+	require.NotNil(t, imagePipeline)
+	var verity []bool
+	for _, stageIf := range imagePipeline["stages"].([]interface{}) {
+		stage := stageIf.(map[string]interface{})
+		if stage["type"].(string) == "org.osbuild.mkfs.ext4" {
+			options := stage["options"].(map[string]interface{})
+			verity = append(verity, options["verity"] == true)
+		}
+	}
+	assert.ElementsMatch(t, []bool{true, false}, verity)
+	assert.False(t, img.PartitionTable.FindMountable("/").(*disk.Filesystem).FSVerity)
+
+	// fs-verity is not available on all filesystems
+	img.PartitionTable.FindMountable("/").(*disk.Filesystem).Type = "xfs"
+	err = img.InstantiateManifestFromContainers(&manifest.Manifest{}, []container.SourceSpec{containerSource}, &runner.Fedora{}, nil)
+	assert.EqualError(t, err, `composefs requires fs-verity on the root filesystem: fs-verity is not supported on xfs filesystem for mountpoint "/"`)
+}
@@ -69,8 +69,14 @@ func (img *DiskImage) InstantiateManifest(m *manifest.Manifest,
 		osPipeline.InstallWeakDeps = *img.InstallWeakDeps
 	}
 
+	var rootErofsPipeline *manifest.Erofs
+	if root := img.PartitionTable.FindMountable("/"); root != nil && root.GetFSType() == "erofs" {
+		rootErofsPipeline = manifest.NewErofs(buildPipeline, osPipeline)
+	}
+
 	rawImagePipeline := manifest.NewRawImage(buildPipeline, osPipeline)
 	rawImagePipeline.PartTool = img.PartTool
+	rawImagePipeline.RootErofs = rootErofsPipeline
 
 	var imagePipeline manifest.FilePipeline
 	switch img.Platform.GetImageFormat() {
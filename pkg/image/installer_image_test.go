@@ -10,6 +10,7 @@ import (
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/customizations/anaconda"
 	"github.com/osbuild/images/pkg/customizations/kickstart"
+	"github.com/osbuild/images/pkg/datasizes"
 	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
@@ -365,6 +366,42 @@ func TestLiveInstallerSquashfsRootfs(t *testing.T) {
 	assert.NotContains(t, mfs, `"name:rootfs-image"`)
 }
 
+func TestLiveInstallerPersistentOverlay(t *testing.T) {
+	img := image.NewAnacondaLiveInstaller()
+	assert.NotNil(t, img)
+
+	img.Product = product
+	img.OSVersion = osversion
+	img.ISOLabel = isolabel
+	img.RootfsType = manifest.SquashfsRootfs
+	img.Platform = testPlatform
+	img.PersistentOverlaySize = 2 * datasizes.GibiByte
+
+	mfs := instantiateAndSerialize(t, img, mockPackageSets(), nil, nil)
+	assert.Contains(t, mfs, `"name":"live-overlay"`)
+	assert.Contains(t, mfs, `"size":"2147483648"`)
+	assert.Contains(t, mfs, `"label":"LIVE-OVERLAY"`)
+	assert.Contains(t, mfs, `"append_partitions":[{"number":3,"type":"0x83","path":"input://overlay/overlay.img"}]`)
+	assert.Contains(t, mfs, `"overlay":{"type":"org.osbuild.tree","origin":"org.osbuild.pipeline","references":["name:live-overlay"]}`)
+	// the grub2 and isolinux menu entries use the overlay
+	assert.Contains(t, mfs, `"rd.live.overlay=LABEL=LIVE-OVERLAY","rd.live.overlay.overlayfs"`)
+}
+
+func TestLiveInstallerNoPersistentOverlay(t *testing.T) {
+	img := image.NewAnacondaLiveInstaller()
+	assert.NotNil(t, img)
+
+	img.Product = product
+	img.OSVersion = osversion
+	img.ISOLabel = isolabel
+	img.Platform = testPlatform
+
+	mfs := instantiateAndSerialize(t, img, mockPackageSets(), nil, nil)
+	assert.NotContains(t, mfs, `"name":"live-overlay"`)
+	assert.NotContains(t, mfs, "append_partitions")
+	assert.NotContains(t, mfs, "rd.live.overlay")
+}
+
 func instantiateAndSerialize(t *testing.T, img image.ImageKind, depsolved map[string]dnfjson.DepsolveResult, containers map[string][]container.Spec, commits map[string][]ostree.CommitSpec) string {
 	source := rand.NewSource(int64(0))
 	// math/rand is good enough in this case
@@ -1,6 +1,7 @@
 package image
 
 import (
+	"fmt"
 	"math/rand"
 
 	"github.com/osbuild/images/internal/environment"
@@ -28,6 +29,17 @@ type OSTreeArchive struct {
 	// OSTreeRef is the ref of the commit that will be built.
 	OSTreeRef string
 
+	// OSTreeSigning signs the commit that will be built (optional).
+	OSTreeSigning *ostree.SigningOptions
+
+	// OSTreeDeltaFrom specifies the source for an optional commit that a
+	// static delta to the new commit is generated from.
+	OSTreeDeltaFrom *ostree.SourceSpec
+
+	// OSTreeDeltaFromScratch generates a static delta from scratch for the
+	// new commit.
+	OSTreeDeltaFromScratch bool
+
 	OSVersion string
 	Filename  string
 
@@ -39,6 +51,9 @@ type OSTreeArchive struct {
 	// kernel arguments for bootable containers.
 	// This is ignored if BootContainer = false.
 	BootcConfig *bootc.Config
+
+	// Configure the commit to be deployed with composefs (optional).
+	Composefs *ostree.ComposefsOptions
 }
 
 func NewOSTreeArchive(ref string) *OSTreeArchive {
@@ -63,18 +78,25 @@ func (img *OSTreeArchive) InstantiateManifest(m *manifest.Manifest,
 	osPipeline.OSTreeParent = img.OSTreeParent
 	osPipeline.OSTreeRef = img.OSTreeRef
 	osPipeline.InstallWeakDeps = img.InstallWeakDeps
+	osPipeline.Composefs = img.Composefs
 
 	ostreeCommitPipeline := manifest.NewOSTreeCommit(buildPipeline, osPipeline, img.OSTreeRef)
 	ostreeCommitPipeline.OSVersion = img.OSVersion
+	ostreeCommitPipeline.Signing = img.OSTreeSigning
 
 	var artifact *artifact.Artifact
 	if img.BootContainer {
+		if img.OSTreeDeltaFrom != nil || img.OSTreeDeltaFromScratch {
+			return nil, fmt.Errorf("ostree static deltas are not supported for bootable containers")
+		}
 		osPipeline.Bootupd = true
 		osPipeline.BootcConfig = img.BootcConfig
 		encapsulatePipeline := manifest.NewOSTreeEncapsulate(buildPipeline, ostreeCommitPipeline, "ostree-encapsulate")
 		encapsulatePipeline.SetFilename(img.Filename)
 		artifact = encapsulatePipeline.Export()
 	} else {
+		ostreeCommitPipeline.StaticDeltaFrom = img.OSTreeDeltaFrom
+		ostreeCommitPipeline.StaticDeltaFromScratch = img.OSTreeDeltaFromScratch
 		tarPipeline := manifest.NewTar(buildPipeline, ostreeCommitPipeline, "commit-archive")
 		tarPipeline.SetFilename(img.Filename)
 		artifact = tarPipeline.Export()
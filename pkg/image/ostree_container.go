@@ -27,6 +27,12 @@ type OSTreeContainer struct {
 	// OSTreeRef is the ref of the commit that will be built.
 	OSTreeRef string
 
+	// OSTreeSigning signs the commit that will be built (optional).
+	OSTreeSigning *ostree.SigningOptions
+
+	// Configure the commit to be deployed with composefs (optional).
+	Composefs *ostree.ComposefsOptions
+
 	OSVersion              string
 	ExtraContainerPackages rpmmd.PackageSet // FIXME: this is never read
 	ContainerLanguage      string
@@ -53,9 +59,11 @@ func (img *OSTreeContainer) InstantiateManifest(m *manifest.Manifest,
 	osPipeline.Workload = img.Workload
 	osPipeline.OSTreeRef = img.OSTreeRef
 	osPipeline.OSTreeParent = img.OSTreeParent
+	osPipeline.Composefs = img.Composefs
 
 	commitPipeline := manifest.NewOSTreeCommit(buildPipeline, osPipeline, img.OSTreeRef)
 	commitPipeline.OSVersion = img.OSVersion
+	commitPipeline.Signing = img.OSTreeSigning
 
 	nginxConfigPath := "/etc/nginx.conf"
 	listenPort := "8080"
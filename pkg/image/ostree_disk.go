@@ -31,6 +31,11 @@ type OSTreeDiskImage struct {
 	OSName string
 	Ref    string
 
+	// Composefs enables fs-verity on the root filesystem and requires the
+	// deployed commit to carry composefs metadata. The commit needs to be
+	// built with composefs enabled, see OSTreeArchive.Composefs.
+	Composefs *ostree.ComposefsOptions
+
 	Filename string
 
 	Compression string
@@ -59,11 +64,19 @@ type baseRawOstreeImageOpts struct {
 	useBootupd bool
 }
 
-func baseRawOstreeImage(img *OSTreeDiskImage, buildPipeline manifest.Build, opts *baseRawOstreeImageOpts) *manifest.RawOSTreeImage {
+func baseRawOstreeImage(img *OSTreeDiskImage, buildPipeline manifest.Build, opts *baseRawOstreeImageOpts) (*manifest.RawOSTreeImage, error) {
 	if opts == nil {
 		opts = &baseRawOstreeImageOpts{}
 	}
 
+	pt := img.PartitionTable
+	if img.Composefs != nil {
+		var err error
+		if pt, err = composefsPartitionTable(pt); err != nil {
+			return nil, err
+		}
+	}
+
 	var osPipeline *manifest.OSTreeDeployment
 	switch {
 	case img.CommitSource != nil:
@@ -74,9 +87,11 @@ func baseRawOstreeImage(img *OSTreeDiskImage, buildPipeline manifest.Build, opts
 		panic("no content source defined for ostree image")
 	}
 
-	osPipeline.PartitionTable = img.PartitionTable
+	osPipeline.PartitionTable = pt
 	osPipeline.Remote = img.Remote
 	osPipeline.OSTreeDeploymentCustomizations = img.OSTreeDeploymentCustomizations
+	// set after the customizations, which carry a Composefs field too
+	osPipeline.Composefs = img.Composefs
 	osPipeline.UseBootupd = opts.useBootupd
 
 	// other image types (e.g. live) pass the workload to the pipeline.
@@ -84,7 +99,21 @@ func baseRawOstreeImage(img *OSTreeDiskImage, buildPipeline manifest.Build, opts
 		osPipeline.EnabledServices = img.Workload.GetServices()
 		osPipeline.DisabledServices = img.Workload.GetDisabledServices()
 	}
-	return manifest.NewRawOStreeImage(buildPipeline, osPipeline, img.Platform)
+	return manifest.NewRawOStreeImage(buildPipeline, osPipeline, img.Platform), nil
+}
+
+// composefsPartitionTable returns a copy of the partition table with fs-verity
+// enabled on the root filesystem, which composefs needs to verify the
+// deployment.
+func composefsPartitionTable(pt *disk.PartitionTable) (*disk.PartitionTable, error) {
+	if pt == nil {
+		return nil, fmt.Errorf("composefs requires a partition table")
+	}
+	pt = pt.Clone().(*disk.PartitionTable)
+	if err := pt.EnableFSVerity("/"); err != nil {
+		return nil, fmt.Errorf("composefs requires fs-verity on the root filesystem: %w", err)
+	}
+	return pt, nil
 }
 
 // replaced in testing
@@ -107,7 +136,10 @@ func (img *OSTreeDiskImage) InstantiateManifest(m *manifest.Manifest,
 		panic(fmt.Sprintf("no compression is allowed with %q format for %q", imgFormat, img.name))
 	}
 
-	baseImage := baseRawOstreeImage(img, buildPipeline, nil)
+	baseImage, err := baseRawOstreeImage(img, buildPipeline, nil)
+	if err != nil {
+		return nil, err
+	}
 	switch img.Platform.GetImageFormat() {
 	case platform.FORMAT_VMDK:
 		vmdkPipeline := manifest.NewVMDK(buildPipeline, baseImage)
@@ -4,12 +4,15 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/osbuild/images/internal/testdisk"
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/runner"
@@ -59,3 +62,49 @@ func TestOSTreeDiskImageManifestSetsContainerBuildable(t *testing.T) {
 		require.Equal(t, buildOpts[0].ContainerBuildable, containerBuildable)
 	}
 }
+
+func TestOSTreeDiskImageComposefs(t *testing.T) {
+	commitSource := ostree.SourceSpec{
+		URL: "https://example.com/repo",
+		Ref: "fedora/x86_64/iot",
+	}
+	img := image.NewOSTreeDiskImageFromCommit(commitSource)
+	img.Platform = &platform.X86{
+		BasePlatform: platform.BasePlatform{
+			ImageFormat: platform.FORMAT_RAW,
+		},
+		UEFIVendor: "fedora",
+	}
+	img.Workload = &workload.BaseWorkload{}
+	img.OSName = "fedora-iot"
+	img.Filename = "disk.raw"
+	img.PartitionTable = testdisk.MakeFakePartitionTable("/", "/boot", "/boot/efi")
+	img.OSTreeDeploymentCustomizations.Keyboard = "us"
+	img.Composefs = &ostree.ComposefsOptions{}
+
+	mf := manifest.New()
+	_, err := img.InstantiateManifest(&mf, nil, &runner.Fedora{Version: 41}, rand.New(rand.NewSource(0))) // nolint:gosec
+	require.NoError(t, err)
+
+	// the deployed commit must carry composefs metadata
+	sources := mf.GetOSTreeSourceSpecs()["ostree-deployment"]
+	require.Len(t, sources, 1)
+	assert.True(t, sources[0].RequireComposefs)
+
+	commits := map[string][]ostree.CommitSpec{
+		"ostree-deployment": {{
+			Ref:      commitSource.Ref,
+			URL:      commitSource.URL,
+			Checksum: "7f7e4ceff1726cb986eafd0230e2e1b0e5ebe590d0498a9f7c370c8ec3797deb",
+		}},
+	}
+	osbuildManifest, err := mf.Serialize(fakeDepsolve, nil, commits, nil)
+	require.NoError(t, err)
+
+	deploymentPipeline := findPipelineFromOsbuildManifest(t, osbuildManifest, "ostree-deployment")
+	require.NotNil(t, deploymentPipeline)
+	configStage := findStageFromOsbuildPipeline(t, deploymentPipeline, "org.osbuild.ostree.config")
+	require.NotNil(t, configStage)
+	config := configStage["options"].(map[string]interface{})["config"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"composefs": true}, config["integrity"])
+}
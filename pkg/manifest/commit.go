@@ -2,6 +2,7 @@ package manifest
 
 import (
 	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
 )
 
 // OSTreeCommit represents an ostree with one commit.
@@ -9,8 +10,22 @@ type OSTreeCommit struct {
 	Base
 	OSVersion string
 
+	// Signing signs the commit with the key of the
+	// osbuild.OSTreeSigningKeySecret secret (optional)
+	Signing *ostree.SigningOptions
+
+	// StaticDeltaFrom is the commit that a static delta to the new commit
+	// is generated from (optional)
+	StaticDeltaFrom *ostree.SourceSpec
+
+	// StaticDeltaFromScratch generates a static delta that contains the
+	// whole commit
+	StaticDeltaFromScratch bool
+
 	treePipeline *OS
 	ref          string
+
+	deltaFromSpec *ostree.CommitSpec
 }
 
 // NewOSTreeCommit creates a new OSTree commit pipeline. The
@@ -30,9 +45,42 @@ func (p *OSTreeCommit) getBuildPackages(Distro) []string {
 	packages := []string{
 		"rpm-ostree",
 	}
+	if p.Signing != nil && p.Signing.Type == ostree.SignatureTypeGPG {
+		packages = append(packages, "gnupg2")
+	}
 	return packages
 }
 
+func (p *OSTreeCommit) getOSTreeCommitSources() []ostree.SourceSpec {
+	if p.StaticDeltaFrom == nil {
+		return nil
+	}
+	return []ostree.SourceSpec{*p.StaticDeltaFrom}
+}
+
+func (p *OSTreeCommit) getOSTreeCommits() []ostree.CommitSpec {
+	if p.deltaFromSpec == nil {
+		return nil
+	}
+	return []ostree.CommitSpec{*p.deltaFromSpec}
+}
+
+func (p *OSTreeCommit) serializeStart(inputs Inputs) {
+	if p.deltaFromSpec != nil {
+		panic("double call to serializeStart()")
+	}
+	if len(inputs.Commits) > 1 {
+		panic("pipeline supports at most one ostree commit")
+	}
+	if len(inputs.Commits) > 0 {
+		p.deltaFromSpec = &inputs.Commits[0]
+	}
+}
+
+func (p *OSTreeCommit) serializeEnd() {
+	p.deltaFromSpec = nil
+}
+
 func (p *OSTreeCommit) serialize() osbuild.Pipeline {
 	pipeline := p.Base.serialize()
 
@@ -61,5 +109,48 @@ func (p *OSTreeCommit) serialize() osbuild.Pipeline {
 		p.treePipeline.Name()),
 	)
 
+	if p.Signing != nil {
+		pipeline.AddStage(osbuild.NewOSTreeSignStage(
+			&osbuild.OSTreeSignStageOptions{
+				Repo:    "/repo",
+				Ref:     p.ref,
+				Type:    p.Signing.Type,
+				KeyID:   p.Signing.KeyID,
+				Secrets: &osbuild.OSTreeSigningKeySecrets{Name: osbuild.OSTreeSigningKeySecret},
+			},
+			p.Signing.PublicKey),
+		)
+	}
+
+	if p.StaticDeltaFrom != nil && p.deltaFromSpec == nil {
+		panic("serialization not started")
+	}
+	if p.deltaFromSpec != nil || p.StaticDeltaFromScratch {
+		var inputs *osbuild.OSTreeStaticDeltaStageInputs
+		if p.deltaFromSpec != nil {
+			inputs = osbuild.NewOSTreeStaticDeltaStageInputs(p.deltaFromSpec.Checksum)
+		}
+		pipeline.AddStage(osbuild.NewOSTreeStaticDeltaStage(
+			&osbuild.OSTreeStaticDeltaStageOptions{
+				Repo:        "/repo",
+				Ref:         p.ref,
+				FromScratch: p.StaticDeltaFromScratch,
+			},
+			inputs),
+		)
+		// the summary lists the deltas for the clients
+		pipeline.AddStage(osbuild.NewOSTreeSummaryStage(&osbuild.OSTreeSummaryStageOptions{Repo: "/repo"}))
+	}
+
 	return pipeline
 }
+
+func (p *OSTreeCommit) getInline() []string {
+	inlineData := []string{}
+	// inline data for the public key that checks the signature, the
+	// secret key is never part of the manifest
+	if p.Signing != nil {
+		inlineData = append(inlineData, p.Signing.PublicKey)
+	}
+	return inlineData
+}
@@ -0,0 +1,105 @@
+package manifest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
+)
+
+func TestOSTreeCommitSigning(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		signing  *ostree.SigningOptions
+		packages []string
+	}{
+		{"unsigned", nil, []string{"rpm-ostree"}},
+		{"ed25519", &ostree.SigningOptions{Type: ostree.SignatureTypeEd25519, PublicKey: "public"}, []string{"rpm-ostree"}},
+		{"gpg", &ostree.SigningOptions{Type: ostree.SignatureTypeGPG, PublicKey: "public", KeyID: "1234ABCD"}, []string{"rpm-ostree", "gnupg2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			os := manifest.NewTestOS()
+			os.OSTreeRef = "test/ostree/ref"
+			build := manifest.NewBuild(&manifest.Manifest{}, nil, nil, nil)
+			commit := manifest.NewOSTreeCommit(build, os, "test/ostree/ref")
+			commit.Signing = tc.signing
+
+			assert.Equal(t, tc.packages, commit.GetBuildPackages(manifest.DISTRO_FEDORA))
+
+			stage := manifest.FindStage("org.osbuild.ostree.sign", commit.Serialize().Stages)
+			if tc.signing == nil {
+				assert.Nil(t, stage)
+				assert.Empty(t, commit.GetInline())
+				return
+			}
+			require.NotNil(t, stage)
+			assert.Equal(t, &osbuild.OSTreeSignStageOptions{
+				Repo:    "/repo",
+				Ref:     "test/ostree/ref",
+				Type:    tc.signing.Type,
+				KeyID:   tc.signing.KeyID,
+				Secrets: &osbuild.OSTreeSigningKeySecrets{Name: osbuild.OSTreeSigningKeySecret},
+			}, stage.Options)
+			// only the public key is part of the manifest
+			assert.Equal(t, []string{"public"}, commit.GetInline())
+		})
+	}
+}
+
+func TestOSTreeCommitStaticDeltas(t *testing.T) {
+	checksum := "5330bb1b8820944567f519de66ad6354c729b6b490dea1c5a7ba320c9f147c58"
+	for _, tc := range []struct {
+		name        string
+		from        *ostree.SourceSpec
+		fromScratch bool
+	}{
+		{"none", nil, false},
+		{"from-commit", &ostree.SourceSpec{URL: "https://example.com/repo", Ref: "test/ostree/ref"}, false},
+		{"from-scratch", nil, true},
+		{"both", &ostree.SourceSpec{URL: "https://example.com/repo", Ref: "test/ostree/ref"}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			os := manifest.NewTestOS()
+			os.OSTreeRef = "test/ostree/ref"
+			build := manifest.NewBuild(&manifest.Manifest{}, nil, nil, nil)
+			commit := manifest.NewOSTreeCommit(build, os, "test/ostree/ref")
+			commit.StaticDeltaFrom = tc.from
+			commit.StaticDeltaFromScratch = tc.fromScratch
+
+			var inputs manifest.Inputs
+			if tc.from != nil {
+				assert.Equal(t, []ostree.SourceSpec{*tc.from}, commit.GetOSTreeCommitSources())
+				inputs.Commits = []ostree.CommitSpec{{Ref: tc.from.Ref, URL: tc.from.URL, Checksum: checksum}}
+			} else {
+				assert.Empty(t, commit.GetOSTreeCommitSources())
+			}
+			stages := commit.SerializeWith(inputs).Stages
+
+			deltaStage := manifest.FindStage("org.osbuild.ostree.static-delta", stages)
+			summaryStage := manifest.FindStage("org.osbuild.ostree.summary", stages)
+			if tc.from == nil && !tc.fromScratch {
+				assert.Nil(t, deltaStage)
+				assert.Nil(t, summaryStage)
+				return
+			}
+			require.NotNil(t, deltaStage)
+			require.NotNil(t, summaryStage)
+			assert.Equal(t, &osbuild.OSTreeStaticDeltaStageOptions{
+				Repo:        "/repo",
+				Ref:         "test/ostree/ref",
+				FromScratch: tc.fromScratch,
+			}, deltaStage.Options)
+			if tc.from != nil {
+				assert.Equal(t, osbuild.NewOSTreeStaticDeltaStageInputs(checksum), deltaStage.Inputs)
+			} else {
+				assert.Nil(t, deltaStage.Inputs)
+			}
+			// the summary is updated after the deltas are generated
+			assert.Equal(t, "org.osbuild.ostree.summary", stages[len(stages)-1].Type)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package manifest
+
+import (
+	"os"
+	"path"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/customizations/fsnode"
+	"github.com/osbuild/images/pkg/ostree"
+)
+
+// composefsFiles returns the prepare-root.conf that enables composefs and,
+// for signed composefs, the public key used to verify the deployed commit.
+// They need to be added to the tree of the commit before the initramfs is
+// generated.
+func composefsFiles(options *ostree.ComposefsOptions) []*fsnode.File {
+	if options == nil {
+		return nil
+	}
+
+	conf, err := fsnode.NewFile(ostree.PrepareRootConfPath, common.ToPtr(os.FileMode(0644)), "root", "root", []byte(options.PrepareRootConf()))
+	if err != nil {
+		panic(err)
+	}
+	files := []*fsnode.File{conf}
+
+	if options.Signed() {
+		key, err := fsnode.NewFile(ostree.ComposefsPublicKeyPath, common.ToPtr(os.FileMode(0644)), "root", "root", []byte(options.PublicKey+"\n"))
+		if err != nil {
+			panic(err)
+		}
+		files = append(files, key)
+	}
+	return files
+}
+
+// composefsDirectories returns the directories of the composefs files, which
+// are created if they do not exist yet.
+func composefsDirectories(files []*fsnode.File) []*fsnode.Directory {
+	var dirs []*fsnode.Directory
+	seen := map[string]bool{}
+	for _, file := range files {
+		dirPath := path.Dir(file.Path())
+		if seen[dirPath] {
+			continue
+		}
+		seen[dirPath] = true
+		dir, err := fsnode.NewDirectory(dirPath, nil, nil, nil, true)
+		if err != nil {
+			panic(err)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
@@ -0,0 +1,51 @@
+package manifest
+
+import (
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// The Erofs pipeline creates an erofs image of an OS tree. It is used for
+// disk images with a read-only erofs root filesystem, which the RawImage
+// pipeline writes to the root partition as a whole.
+type Erofs struct {
+	Base
+	filename string
+
+	treePipeline *OS
+}
+
+func (p Erofs) Filename() string {
+	return p.filename
+}
+
+// NewErofs creates a new Erofs pipeline. treePipeline is the pipeline
+// producing the tree of the root filesystem.
+func NewErofs(buildPipeline Build, treePipeline *OS) *Erofs {
+	p := &Erofs{
+		Base:         NewBase("root-erofs", buildPipeline),
+		filename:     "root.erofs",
+		treePipeline: treePipeline,
+	}
+	buildPipeline.addDependent(p)
+	return p
+}
+
+func (p *Erofs) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	options := &osbuild.ErofsStageOptions{
+		Filename: p.Filename(),
+		// favour decompression speed for a filesystem that is read at
+		// runtime
+		Compression: &osbuild.ErofsCompression{
+			Method: "lz4hc",
+		},
+	}
+	pipeline.AddStage(osbuild.NewErofsStage(options, p.treePipeline.Name()))
+
+	return pipeline
+}
+
+func (p *Erofs) getBuildPackages(Distro) []string {
+	return []string{"erofs-utils"}
+}
@@ -84,3 +84,37 @@ func (p *OSTreeDeployment) Serialize() osbuild.Pipeline {
 	})
 	return p.serialize()
 }
+
+func (p *OS) GetInline() []string {
+	return p.getInline()
+}
+
+func (p *OSTreeDeployment) GetOSTreeCommitSources() []ostree.SourceSpec {
+	return p.getOSTreeCommitSources()
+}
+
+func (p *OSTreeDeployment) GetInline() []string {
+	return p.getInline()
+}
+
+func (p *OSTreeCommit) Serialize() osbuild.Pipeline {
+	return p.serialize()
+}
+
+func (p *OSTreeCommit) GetInline() []string {
+	return p.getInline()
+}
+
+func (p *OSTreeCommit) GetBuildPackages(d Distro) []string {
+	return p.getBuildPackages(d)
+}
+
+func (p *OSTreeCommit) SerializeWith(inputs Inputs) osbuild.Pipeline {
+	p.serializeStart(inputs)
+	defer p.serializeEnd()
+	return p.serialize()
+}
+
+func (p *OSTreeCommit) GetOSTreeCommitSources() []ostree.SourceSpec {
+	return p.getOSTreeCommitSources()
+}
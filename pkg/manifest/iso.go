@@ -1,6 +1,8 @@
 package manifest
 
 import (
+	"fmt"
+
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/osbuild"
 )
@@ -14,6 +16,10 @@ type ISO struct {
 
 	treePipeline Pipeline
 	isoLabel     string
+
+	// Writable partition appended to the hybrid image. If nil, the image
+	// is read-only.
+	PersistentOverlay *LiveOverlay
 }
 
 func (p ISO) Filename() string {
@@ -45,7 +51,25 @@ func (p *ISO) getBuildPackages(Distro) []string {
 func (p *ISO) serialize() osbuild.Pipeline {
 	pipeline := p.Base.serialize()
 
-	pipeline.AddStage(osbuild.NewXorrisofsStage(xorrisofsStageOptions(p.Filename(), p.isoLabel, p.ISOBoot), p.treePipeline.Name()))
+	options := xorrisofsStageOptions(p.Filename(), p.isoLabel, p.ISOBoot)
+	xorrisofsStage := osbuild.NewXorrisofsStage(options, p.treePipeline.Name())
+	if p.PersistentOverlay != nil {
+		inputName := "overlay"
+		options.AppendPartitions = []osbuild.XorrisofsPartition{
+			{
+				// partitions 1 and 2 are used by the ISO filesystem and
+				// the EFI boot image
+				Number: 3,
+				Type:   "0x83",
+				Path:   fmt.Sprintf("input://%s/%s", inputName, p.PersistentOverlay.Filename()),
+			},
+		}
+		xorrisofsStage.Inputs = osbuild.PipelineTreeInputs{
+			"tree":    *osbuild.NewTreeInput("name:" + p.treePipeline.Name()),
+			inputName: *osbuild.NewTreeInput("name:" + p.PersistentOverlay.Name()),
+		}
+	}
+	pipeline.AddStage(xorrisofsStage)
 	pipeline.AddStage(osbuild.NewImplantisomd5Stage(&osbuild.Implantisomd5StageOptions{Filename: p.Filename()}))
 
 	return pipeline
@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// LiveOverlayLabel is the filesystem label of the persistent overlay
+// partition. dmsquash-live finds the partition by this label.
+const LiveOverlayLabel = "LIVE-OVERLAY"
+
+// A LiveOverlay represents an empty filesystem image that is appended to a
+// hybrid ISO as a writable partition. dmsquash-live uses it as a persistent
+// overlay for the read-only live root filesystem.
+type LiveOverlay struct {
+	Base
+	filename string
+
+	// Partition table describing the filesystem of the overlay image. It
+	// must contain a single filesystem labelled LiveOverlayLabel.
+	PartitionTable *disk.PartitionTable
+}
+
+func (p LiveOverlay) Filename() string {
+	return p.filename
+}
+
+func NewLiveOverlay(buildPipeline Build, pt *disk.PartitionTable) *LiveOverlay {
+	p := &LiveOverlay{
+		Base:           NewBase("live-overlay", buildPipeline),
+		filename:       "overlay.img",
+		PartitionTable: pt,
+	}
+	buildPipeline.addDependent(p)
+	return p
+}
+
+// KernelOpts returns the kernel options that make dmsquash-live use the
+// overlay partition.
+func (p *LiveOverlay) KernelOpts() []string {
+	return []string{
+		fmt.Sprintf("rd.live.overlay=LABEL=%s", LiveOverlayLabel),
+		"rd.live.overlay.overlayfs",
+	}
+}
+
+func (p *LiveOverlay) getBuildPackages(Distro) []string {
+	return p.PartitionTable.GetBuildPackages()
+}
+
+func (p *LiveOverlay) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	if p.PartitionTable == nil {
+		panic("no partition table for live overlay")
+	}
+
+	pipeline.AddStage(osbuild.NewTruncateStage(&osbuild.TruncateStageOptions{
+		Filename: p.Filename(),
+		Size:     fmt.Sprintf("%d", p.PartitionTable.Size),
+	}))
+	for _, stage := range osbuild.GenFsStages(p.PartitionTable, p.Filename()) {
+		pipeline.AddStage(stage)
+	}
+
+	return pipeline
+}
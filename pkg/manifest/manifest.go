@@ -114,6 +114,18 @@ func (m Manifest) GetPackageSetChains() map[string][]rpmmd.PackageSet {
 	return chains
 }
 
+// GetExcludeDocsPipelines returns the names of the pipelines that install
+// their packages without documentation.
+func (m Manifest) GetExcludeDocsPipelines() []string {
+	var names []string
+	for _, pipeline := range m.pipelines {
+		if os, ok := pipeline.(*OS); ok && os.ExcludeDocs {
+			names = append(names, pipeline.Name())
+		}
+	}
+	return names
+}
+
 func (m Manifest) GetContainerSourceSpecs() map[string][]container.SourceSpec {
 	// Containers should only appear in the payload pipeline.
 	// Let's iterate over all pipelines to avoid assuming pipeline names, but
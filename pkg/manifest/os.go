@@ -3,6 +3,7 @@ package manifest
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
@@ -15,6 +16,7 @@ import (
 	"github.com/osbuild/images/pkg/customizations/bootc"
 	"github.com/osbuild/images/pkg/customizations/fsnode"
 	"github.com/osbuild/images/pkg/customizations/oscap"
+	"github.com/osbuild/images/pkg/customizations/secureboot"
 	"github.com/osbuild/images/pkg/customizations/shell"
 	"github.com/osbuild/images/pkg/customizations/subscription"
 	"github.com/osbuild/images/pkg/customizations/users"
@@ -143,6 +145,10 @@ type OSCustomizations struct {
 
 	CACerts []string
 
+	// SecureBoot signs the EFI binaries and the kernel of the image with a
+	// user provided key and optionally sets up enrollment of the certificate
+	SecureBoot *secureboot.Options
+
 	FIPS bool
 
 	// NoBLS configures the image bootloader with traditional menu entries
@@ -186,6 +192,11 @@ type OS struct {
 	// Add a bootc config file to the image (for bootable containers)
 	BootcConfig *bootc.Config
 
+	// Configure ostree-prepare-root to mount deployments of the commit with
+	// composefs. The configuration is added before rpm-ostree generates the
+	// initramfs. Only works with ostree-based images.
+	Composefs *ostree.ComposefsOptions
+
 	// Partition table, if nil the tree cannot be put on a partitioned disk
 	PartitionTable *disk.PartitionTable
 
@@ -199,6 +210,9 @@ type OS struct {
 	platform  platform.Platform
 	kernelVer string
 
+	// files for the MOK enrollment of the Secure Boot certificate
+	mokFiles []*fsnode.File
+
 	OSProduct string
 	OSVersion string
 	OSNick    string
@@ -278,6 +292,10 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 		customizationPackages = append(customizationPackages, "firewalld")
 	}
 
+	if p.SecureBoot != nil && p.SecureBoot.Enrollment == secureboot.EnrollmentMOK {
+		customizationPackages = append(customizationPackages, "mokutil")
+	}
+
 	osRepos := append(p.repos, p.ExtraBaseRepos...)
 
 	// merge all package lists for the pipeline
@@ -390,6 +408,13 @@ func (p *OS) getBuildPackages(distro Distro) []string {
 		packages = append(packages, tomlPkgsFor(distro)...)
 	}
 
+	if p.SecureBoot != nil {
+		packages = append(packages, "sbsigntools")
+		if p.SecureBoot.Enrollment == secureboot.EnrollmentDB {
+			packages = append(packages, "efitools")
+		}
+	}
+
 	return packages
 }
 
@@ -437,6 +462,14 @@ func (p *OS) serializeStart(inputs Inputs) {
 		p.kernelVer = rpmmd.GetVerStrFromPackageSpecListPanic(p.packageSpecs, p.KernelName)
 	}
 
+	if p.SecureBoot != nil && p.SecureBoot.Enrollment == secureboot.EnrollmentMOK {
+		_, mokFiles, err := mokEnrollStages(p.SecureBoot)
+		if err != nil {
+			panic(err)
+		}
+		p.mokFiles = mokFiles
+	}
+
 	p.repos = append(p.repos, inputs.Depsolved.Repos...)
 }
 
@@ -448,6 +481,7 @@ func (p *OS) serializeEnd() {
 	p.packageSpecs = nil
 	p.containerSpecs = nil
 	p.ostreeParentSpec = nil
+	p.mokFiles = nil
 }
 
 func (p *OS) serialize() osbuild.Pipeline {
@@ -487,7 +521,7 @@ func (p *OS) serialize() osbuild.Pipeline {
 	}
 	pipeline.AddStage(osbuild.NewRPMStage(rpmOptions, osbuild.NewRpmStageSourceFilesInputs(p.packageSpecs)))
 
-	if !p.NoBLS {
+	if !p.NoBLS && p.platform.GetBootloader() != platform.BOOTLOADER_UKI {
 		// If the /boot is on a separate partition, the prefix for the BLS stage must be ""
 		if p.PartitionTable == nil || p.PartitionTable.FindMountable("/boot") == nil {
 			pipeline.AddStage(osbuild.NewFixBLSStage(&osbuild.FixBLSStageOptions{}))
@@ -689,10 +723,35 @@ func (p *OS) serialize() osbuild.Pipeline {
 		}
 		pipeline.AddStages(fsCfgStages...)
 
+		if data, _ := pt.FindVerity("/"); data != nil && p.platform.GetBootloader() != platform.BOOTLOADER_UKI {
+			// the root hash is passed to the kernel with a UKI addon
+			panic("dm-verity protected root filesystem requires the UKI bootloader")
+		}
+
 		var bootloader *osbuild.Stage
-		switch p.platform.GetArch() {
-		case arch.ARCH_S390X:
+		switch {
+		case p.platform.GetArch() == arch.ARCH_S390X:
 			bootloader = osbuild.NewZiplStage(new(osbuild.ZiplStageOptions))
+		case p.platform.GetBootloader() == platform.BOOTLOADER_UKI:
+			// The UKI carries the kernel command line, so the root
+			// filesystem needs to be spelled out explicitly
+			root := "root=UUID=" + rootUUID
+			if data, _ := pt.FindVerity("/"); data != nil {
+				// set up by systemd-veritysetup-generator
+				root = "root=/dev/mapper/root"
+			}
+			cmdline := append([]string{root}, kernelOptions...)
+			pipeline.AddStage(osbuild.NewUkifyStage(osbuild.NewUkifyStageOptions(p.kernelVer, strings.Join(cmdline, " "))))
+
+			options := &osbuild.SystemdBootStageOptions{
+				ESPPath: "/boot/efi",
+			}
+			if cfg := p.Grub2Config; cfg != nil && cfg.Timeout != 0 {
+				options.Config = &osbuild.SystemdBootLoaderConfig{
+					Timeout: common.ToPtr(cfg.Timeout),
+				}
+			}
+			bootloader = osbuild.NewSystemdBootStage(options)
 		default:
 			if p.NoBLS {
 				// BLS entries not supported: use grub2.legacy
@@ -744,6 +803,10 @@ func (p *OS) serialize() osbuild.Pipeline {
 
 		pipeline.AddStage(bootloader)
 
+		if p.SecureBoot != nil {
+			pipeline.AddStages(p.secureBootStages()...)
+		}
+
 		if !p.KernelOptionsBootloader || p.platform.GetArch() == arch.ARCH_S390X {
 			pipeline = prependKernelCmdlineStage(pipeline, rootUUID, kernelOptions)
 		}
@@ -822,6 +885,15 @@ func (p *OS) serialize() osbuild.Pipeline {
 	disabledServices := []string{}
 	maskedServices := []string{}
 	enabledServices = append(enabledServices, p.EnabledServices...)
+
+	if p.SecureBoot != nil && p.SecureBoot.Enrollment == secureboot.EnrollmentMOK {
+		mokStages, _, err := mokEnrollStages(p.SecureBoot)
+		if err != nil {
+			panic(err)
+		}
+		pipeline.AddStages(mokStages...)
+		enabledServices = append(enabledServices, mokEnrollService)
+	}
 	disabledServices = append(disabledServices, p.DisabledServices...)
 	maskedServices = append(maskedServices, p.MaskedServices...)
 	if p.Environment != nil {
@@ -903,6 +975,10 @@ func (p *OS) serialize() osbuild.Pipeline {
 	}
 
 	if p.OSTreeRef != "" {
+		if files := composefsFiles(p.Composefs); len(files) > 0 {
+			pipeline.AddStages(osbuild.GenDirectoryNodesStages(composefsDirectories(files))...)
+			pipeline.AddStages(osbuild.GenFileNodesStages(files)...)
+		}
 		pipeline.AddStage(osbuild.NewOSTreePrepTreeStage(&osbuild.OSTreePrepTreeStageOptions{
 			EtcGroupMembers: []string{
 				// NOTE: We may want to make this configurable.
@@ -924,6 +1000,9 @@ func (p *OS) serialize() osbuild.Pipeline {
 		if p.BootcConfig != nil {
 			panic("bootc config is only compatible with ostree-based images, this is a programming error")
 		}
+		if p.Composefs != nil {
+			panic("composefs is only compatible with ostree-based images, this is a programming error")
+		}
 	}
 
 	return pipeline
@@ -978,9 +1057,14 @@ func (p *OS) getInline() []string {
 	inlineData := []string{}
 
 	// inline data for custom files
-	for _, file := range p.Files {
+	for _, file := range slices.Concat(p.Files, p.mokFiles, composefsFiles(p.Composefs)) {
 		inlineData = append(inlineData, string(file.Data()))
 	}
 
+	// the signing certificate, the key is passed as a secret
+	if p.SecureBoot != nil {
+		inlineData = append(inlineData, p.SecureBoot.Certificate)
+	}
+
 	return inlineData
 }
@@ -12,11 +12,16 @@ import (
 	"github.com/osbuild/images/internal/testdisk"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/customizations/bootc"
+	"github.com/osbuild/images/pkg/customizations/secureboot"
 	"github.com/osbuild/images/pkg/customizations/subscription"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
 )
 
 func findStages(name string, stages []*osbuild.Stage) []*osbuild.Stage {
@@ -166,6 +171,66 @@ func TestBootupdStage(t *testing.T) {
 	require.NotNil(t, st)
 }
 
+func TestOSPipelineComposefs(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		options         *ostree.ComposefsOptions
+		expectedTargets []string
+		expectedInline  []string
+	}{
+		{
+			"unsigned",
+			&ostree.ComposefsOptions{},
+			[]string{"tree:///usr/lib/ostree/prepare-root.conf"},
+			[]string{"[composefs]\nenabled = yes\n"},
+		},
+		{
+			"signed",
+			&ostree.ComposefsOptions{PublicKey: "c2VjcmV0"},
+			[]string{"tree:///usr/lib/ostree/prepare-root.conf", "tree:///etc/ostree/initramfs-root-binding.key"},
+			[]string{"[composefs]\nenabled = signed\nkeypath = /etc/ostree/initramfs-root-binding.key\n", "c2VjcmV0\n"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			os := manifest.NewTestOS()
+			os.OSTreeRef = "some/ref"
+			os.Composefs = tc.options
+			stages := os.Serialize().Stages
+
+			// the configuration has to be in place when rpm-ostree
+			// generates the initramfs
+			copyIdx, prepTreeIdx := -1, -1
+			for idx, stage := range stages {
+				switch stage.Type {
+				case "org.osbuild.copy":
+					copyIdx = idx
+				case "org.osbuild.ostree.preptree":
+					prepTreeIdx = idx
+				}
+			}
+			require.NotEqual(t, -1, copyIdx)
+			require.NotEqual(t, -1, prepTreeIdx)
+			assert.Less(t, copyIdx, prepTreeIdx)
+
+			var targets []string
+			for _, path := range stages[copyIdx].Options.(*osbuild.CopyStageOptions).Paths {
+				targets = append(targets, path.To)
+			}
+			assert.Equal(t, tc.expectedTargets, targets)
+			assert.Equal(t, tc.expectedInline, os.GetInline())
+		})
+	}
+}
+
+func TestOSPipelineComposefsRequiresOSTree(t *testing.T) {
+	os := manifest.NewTestOS()
+	os.Composefs = &ostree.ComposefsOptions{}
+
+	assert.PanicsWithValue(t, "composefs is only compatible with ostree-based images, this is a programming error", func() {
+		os.Serialize()
+	})
+}
+
 func TestTomlLibUsedNoneByDefault(t *testing.T) {
 	os := manifest.NewTestOS()
 	buildPkgs := os.GetBuildPackages(manifest.DISTRO_FEDORA)
@@ -369,3 +434,209 @@ func TestHostnameDoesNotIncludeHostnameStage(t *testing.T) {
 	st := manifest.FindStage("org.osbuild.hostname", pipeline.Stages)
 	require.Nil(t, st)
 }
+
+func TestOSPipelineUKIBootloader(t *testing.T) {
+	repos := []rpmmd.RepoConfig{}
+	m := manifest.New()
+	build := manifest.NewBuild(&m, &runner.Fedora{Version: 41}, repos, nil)
+	build.Checkpoint()
+
+	pf := &platform.X86{
+		UEFIVendor: "fedora",
+		BasePlatform: platform.BasePlatform{
+			Bootloader: platform.BOOTLOADER_UKI,
+		},
+	}
+	os := manifest.NewOS(build, pf, repos)
+	os.PartitionTable = testdisk.MakeFakePartitionTable("/", "/boot/efi")
+	os.KernelName = "kernel"
+	os.KernelOptionsAppend = []string{"ro"}
+
+	pipeline := os.SerializeWith(manifest.Inputs{
+		Depsolved: dnfjson.DepsolveResult{
+			Packages: []rpmmd.PackageSpec{
+				{Name: "kernel", Version: "6.11.4", Release: "301.fc41", Arch: "x86_64", Checksum: "sha256:da1a5ea0c44a3b5e8bd0bb9d1a0ad79ee4d1ed1b19bd4c8cbc2dc8a4a7c2b8e1"},
+			},
+		},
+	})
+
+	assert.Nil(t, manifest.FindStage("org.osbuild.grub2", pipeline.Stages))
+
+	ukify := manifest.FindStage("org.osbuild.ukify", pipeline.Stages)
+	require.NotNil(t, ukify)
+	ukifyOptions := ukify.Options.(*osbuild.UkifyStageOptions)
+	assert.Equal(t, "6.11.4-301.fc41.x86_64", ukifyOptions.Kernel)
+	assert.Regexp(t, "^root=UUID=[0-9a-fA-F-]+ ro$", ukifyOptions.Cmdline)
+	assert.Equal(t, "/boot/efi/EFI/Linux/linux-6.11.4-301.fc41.x86_64.efi", ukifyOptions.Output)
+
+	sdboot := manifest.FindStage("org.osbuild.systemd-boot", pipeline.Stages)
+	require.NotNil(t, sdboot)
+	assert.Equal(t, "/boot/efi", sdboot.Options.(*osbuild.SystemdBootStageOptions).ESPPath)
+
+	assert.Contains(t, os.GetBuildPackages(manifest.DISTRO_FEDORA), "systemd-ukify")
+	CheckPkgSetInclude(t, os.GetPackageSetChain(manifest.DISTRO_FEDORA), []string{"systemd-boot-unsigned"})
+}
+
+// not a real certificate, the manifest only needs the PEM framing
+const testSecureBootCert = `-----BEGIN CERTIFICATE-----
+MTIz
+-----END CERTIFICATE-----
+`
+
+func newSecureBootTestOS(pf platform.Platform, enrollment secureboot.Enrollment) (*manifest.OS, osbuild.Pipeline) {
+	repos := []rpmmd.RepoConfig{}
+	m := manifest.New()
+	build := manifest.NewBuild(&m, &runner.Fedora{Version: 41}, repos, nil)
+	build.Checkpoint()
+
+	os := manifest.NewOS(build, pf, repos)
+	os.PartitionTable = testdisk.MakeFakePartitionTable("/", "/boot/efi")
+	os.KernelName = "kernel"
+	os.SecureBoot = &secureboot.Options{
+		Certificate: testSecureBootCert,
+		Enrollment:  enrollment,
+	}
+
+	pipeline := os.SerializeWith(manifest.Inputs{
+		Depsolved: dnfjson.DepsolveResult{
+			Packages: []rpmmd.PackageSpec{
+				{Name: "kernel", Version: "6.11.4", Release: "301.fc41", Arch: "x86_64", Checksum: "sha256:da1a5ea0c44a3b5e8bd0bb9d1a0ad79ee4d1ed1b19bd4c8cbc2dc8a4a7c2b8e1"},
+			},
+		},
+	})
+	return os, pipeline
+}
+
+func TestOSPipelineSecureBootUKI(t *testing.T) {
+	pf := &platform.X86{
+		UEFIVendor: "fedora",
+		BasePlatform: platform.BasePlatform{
+			Bootloader: platform.BOOTLOADER_UKI,
+		},
+	}
+	os, pipeline := newSecureBootTestOS(pf, secureboot.EnrollmentDB)
+
+	sbsign := manifest.FindStage("org.osbuild.sbsign", pipeline.Stages)
+	require.NotNil(t, sbsign)
+	options := sbsign.Options.(*osbuild.SbsignStageOptions)
+	assert.Equal(t, []string{
+		"/boot/efi/EFI/Linux/linux-6.11.4-301.fc41.x86_64.efi",
+		"/boot/efi/EFI/systemd/systemd-bootx64.efi",
+		"/boot/efi/EFI/BOOT/BOOTX64.EFI",
+	}, options.Paths)
+	assert.Equal(t, "org.osbuild.secureboot", options.Secrets.Name)
+
+	// signing has to happen after the UKI and the bootloader are in place
+	var ukifyIdx, sdbootIdx, sbsignIdx int
+	for idx, stage := range pipeline.Stages {
+		switch stage.Type {
+		case "org.osbuild.ukify":
+			ukifyIdx = idx
+		case "org.osbuild.systemd-boot":
+			sdbootIdx = idx
+		case "org.osbuild.sbsign":
+			sbsignIdx = idx
+		}
+	}
+	assert.Greater(t, sbsignIdx, ukifyIdx)
+	assert.Greater(t, sbsignIdx, sdbootIdx)
+
+	authVars := manifest.FindStage("org.osbuild.secureboot.auth-vars", pipeline.Stages)
+	require.NotNil(t, authVars)
+	assert.Equal(t, "/boot/efi/loader/keys/auto", authVars.Options.(*osbuild.SecureBootAuthVarsStageOptions).Target)
+
+	// the certificate is inlined, the key never is
+	assert.Contains(t, os.GetInline(), testSecureBootCert)
+	assert.Nil(t, manifest.FindStage("org.osbuild.systemd.unit.create", pipeline.Stages))
+	assert.Contains(t, os.GetBuildPackages(manifest.DISTRO_FEDORA), "sbsigntools")
+	assert.Contains(t, os.GetBuildPackages(manifest.DISTRO_FEDORA), "efitools")
+}
+
+func TestOSPipelineSecureBootGrubMOK(t *testing.T) {
+	pf := &platform.X86{
+		UEFIVendor: "fedora",
+	}
+	os, pipeline := newSecureBootTestOS(pf, secureboot.EnrollmentMOK)
+
+	sbsign := manifest.FindStage("org.osbuild.sbsign", pipeline.Stages)
+	require.NotNil(t, sbsign)
+	assert.Equal(t, []string{
+		"/boot/efi/EFI/fedora/shimx64.efi",
+		"/boot/efi/EFI/fedora/grubx64.efi",
+		"/boot/efi/EFI/BOOT/BOOTX64.EFI",
+		"/boot/vmlinuz-6.11.4-301.fc41.x86_64",
+	}, sbsign.Options.(*osbuild.SbsignStageOptions).Paths)
+	assert.Nil(t, manifest.FindStage("org.osbuild.secureboot.auth-vars", pipeline.Stages))
+
+	unit := manifest.FindStage("org.osbuild.systemd.unit.create", pipeline.Stages)
+	require.NotNil(t, unit)
+	unitOptions := unit.Options.(*osbuild.SystemdUnitCreateStageOptions)
+	assert.Equal(t, "osbuild-secureboot-mok-enroll.service", unitOptions.Filename)
+	assert.Contains(t, unitOptions.Config.Service.ExecStart, "/usr/bin/mokutil --import /etc/pki/secureboot/osbuild-mok.der --root-pw")
+
+	systemd := manifest.FindStage("org.osbuild.systemd", pipeline.Stages)
+	require.NotNil(t, systemd)
+	assert.Contains(t, systemd.Options.(*osbuild.SystemdStageOptions).EnabledServices, "osbuild-secureboot-mok-enroll.service")
+
+	// the DER encoded certificate is installed into the tree, without
+	// changing the customized files of the pipeline
+	assert.Contains(t, os.GetInline(), "123")
+	assert.Empty(t, os.Files)
+	assert.NotContains(t, os.GetBuildPackages(manifest.DISTRO_FEDORA), "efitools")
+	CheckPkgSetInclude(t, os.GetPackageSetChain(manifest.DISTRO_FEDORA), []string{"mokutil"})
+}
+
+func newVerityTestPartitionTable() *disk.PartitionTable {
+	pt := testdisk.MakeFakePartitionTable("/", "/boot/efi")
+	for idx := range pt.Partitions {
+		if pt.Partitions[idx].Payload.(disk.Mountable).GetMountpoint() == "/" {
+			pt.Partitions[idx].UUID = disk.RootPartitionUUID
+		}
+	}
+	pt.Partitions = append(pt.Partitions, disk.Partition{
+		UUID: "F6A1DC8F-1B50-4A2B-8C4B-3D6F2E4C8A11",
+		Payload: &disk.Verity{
+			DataMountpoint: "/",
+		},
+	})
+	return pt
+}
+
+func TestOSPipelineVerityUKI(t *testing.T) {
+	pf := &platform.X86{
+		UEFIVendor: "fedora",
+		BasePlatform: platform.BasePlatform{
+			Bootloader: platform.BOOTLOADER_UKI,
+		},
+	}
+	repos := []rpmmd.RepoConfig{}
+	m := manifest.New()
+	build := manifest.NewBuild(&m, &runner.Fedora{Version: 41}, repos, nil)
+	build.Checkpoint()
+
+	os := manifest.NewOS(build, pf, repos)
+	os.PartitionTable = newVerityTestPartitionTable()
+	os.KernelName = "kernel"
+	os.KernelOptionsAppend = []string{"ro"}
+
+	pipeline := os.SerializeWith(manifest.Inputs{
+		Depsolved: dnfjson.DepsolveResult{
+			Packages: []rpmmd.PackageSpec{
+				{Name: "kernel", Version: "6.11.4", Release: "301.fc41", Arch: "x86_64", Checksum: "sha256:da1a5ea0c44a3b5e8bd0bb9d1a0ad79ee4d1ed1b19bd4c8cbc2dc8a4a7c2b8e1"},
+			},
+		},
+	})
+
+	ukify := manifest.FindStage("org.osbuild.ukify", pipeline.Stages)
+	require.NotNil(t, ukify)
+	assert.Regexp(t, "^root=/dev/mapper/root systemd.verity_root_data=/dev/disk/by-partuuid/6264d520-3fb9-423f-8ab8-7a0a8e3d3562 systemd.verity_root_hash=/dev/disk/by-partuuid/f6a1dc8f-1b50-4a2b-8c4b-3d6f2e4c8a11 ro$", ukify.Options.(*osbuild.UkifyStageOptions).Cmdline)
+}
+
+func TestOSPipelineVerityRequiresUKI(t *testing.T) {
+	os := manifest.NewTestOS()
+	os.PartitionTable = newVerityTestPartitionTable()
+
+	assert.PanicsWithValue(t, "dm-verity protected root filesystem requires the UKI bootloader", func() {
+		os.Serialize()
+	})
+}
@@ -46,6 +46,12 @@ type OSTreeDeploymentCustomizations struct {
 	// MountUnits creates systemd .mount units to describe the filesystem
 	// instead of writing to /etc/fstab
 	MountUnits bool
+
+	// Mount the deployment with composefs. The root filesystem is created
+	// with fs-verity and the deployed commit must carry composefs metadata.
+	// ostree-prepare-root is configured by the commit itself, which has to
+	// be built with composefs enabled (see OS.Composefs).
+	Composefs *ostree.ComposefsOptions
 }
 
 // OSTreeDeployment represents the filesystem tree of a target image based
@@ -143,8 +149,12 @@ func (p *OSTreeDeployment) getOSTreeCommitSources() []ostree.SourceSpec {
 	if p.commitSource == nil {
 		return []ostree.SourceSpec{}
 	}
+	source := *p.commitSource
+	if p.Composefs != nil {
+		source.RequireComposefs = true
+	}
 	return []ostree.SourceSpec{
-		*p.commitSource,
+		source,
 	}
 }
 
@@ -322,17 +332,21 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		panic("no content source defined for ostree deployment")
 	}
 
-	configStage := osbuild.NewOSTreeConfigStage(
-		&osbuild.OSTreeConfigStageOptions{
-			Repo: repoPath,
-			Config: &osbuild.OSTreeConfig{
-				Sysroot: &osbuild.SysrootOptions{
-					ReadOnly:   &p.SysrootReadOnly,
-					Bootloader: "none",
-				},
+	configOptions := &osbuild.OSTreeConfigStageOptions{
+		Repo: repoPath,
+		Config: &osbuild.OSTreeConfig{
+			Sysroot: &osbuild.SysrootOptions{
+				ReadOnly:   &p.SysrootReadOnly,
+				Bootloader: "none",
 			},
 		},
-	)
+	}
+	if p.Composefs != nil {
+		configOptions.Config.Integrity = &osbuild.IntegrityOptions{
+			Composefs: common.ToPtr(true),
+		}
+	}
+	configStage := osbuild.NewOSTreeConfigStage(configOptions)
 	configStage.MountOSTree(p.osName, ref, 0)
 	pipeline.AddStage(configStage)
 
@@ -3,8 +3,12 @@ package manifest_test
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/osbuild/images/internal/testdisk"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -47,3 +51,39 @@ func TestOSTreeDeploymentPipelineMountUnitStages(t *testing.T) {
 
 	checkStagesForMountUnits(t, pipeline.Serialize().Stages, expectedUnits)
 }
+
+func TestOSTreeDeploymentPipelineComposefs(t *testing.T) {
+	pipeline := NewTestOSTreeDeployment()
+	pipeline.PartitionTable = testdisk.MakeFakePartitionTable("/")
+	pipeline.Composefs = &ostree.ComposefsOptions{PublicKey: "c2VjcmV0"}
+
+	sources := pipeline.GetOSTreeCommitSources()
+	require.Len(t, sources, 1)
+	assert.True(t, sources[0].RequireComposefs)
+
+	stages := pipeline.Serialize().Stages
+	configStage := manifest.FindStage("org.osbuild.ostree.config", stages)
+	require.NotNil(t, configStage)
+	config := configStage.Options.(*osbuild.OSTreeConfigStageOptions).Config
+	require.NotNil(t, config.Integrity)
+	assert.True(t, *config.Integrity.Composefs)
+
+	// prepare-root.conf is part of the commit, the deployment does not
+	// change it
+	assert.Nil(t, manifest.FindStage("org.osbuild.copy", stages))
+	assert.Empty(t, pipeline.GetInline())
+}
+
+func TestOSTreeDeploymentPipelineNoComposefs(t *testing.T) {
+	pipeline := NewTestOSTreeDeployment()
+	pipeline.PartitionTable = testdisk.MakeFakePartitionTable("/")
+
+	sources := pipeline.GetOSTreeCommitSources()
+	require.Len(t, sources, 1)
+	assert.False(t, sources[0].RequireComposefs)
+
+	configStage := manifest.FindStage("org.osbuild.ostree.config", pipeline.Serialize().Stages)
+	require.NotNil(t, configStage)
+	assert.Nil(t, configStage.Options.(*osbuild.OSTreeConfigStageOptions).Config.Integrity)
+	assert.Empty(t, pipeline.GetInline())
+}
@@ -15,6 +15,10 @@ type RawImage struct {
 	treePipeline *OS
 	filename     string
 	PartTool     osbuild.PartTool
+
+	// RootErofs is the pipeline creating the erofs image of the root
+	// filesystem, for partition tables with an erofs root filesystem
+	RootErofs *Erofs
 }
 
 func (p RawImage) Filename() string {
@@ -56,6 +60,13 @@ func (p *RawImage) serialize() osbuild.Pipeline {
 		pipeline.AddStage(stage)
 	}
 
+	if root := pt.FindMountable("/"); root != nil && root.GetFSType() == "erofs" {
+		if p.RootErofs == nil {
+			panic("erofs root filesystem requires an erofs pipeline; this is a programming error")
+		}
+		pipeline.AddStages(osbuild.GenWriteErofsStages(pt, p.Filename(), p.RootErofs.Name(), p.RootErofs.Filename())...)
+	}
+
 	inputName := "root-tree"
 	copyOptions, copyDevices, copyMounts := osbuild.GenCopyFSTreeOptions(inputName, p.treePipeline.Name(), p.Filename(), pt)
 	copyInputs := osbuild.NewPipelineTreeInputs(inputName, p.treePipeline.Name())
@@ -93,6 +104,13 @@ func (p *RawImage) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewCopyStage(bootCopyOptions, bootCopyInputs, bootCopyDevices, bootCopyMounts))
 	}
 
+	// the hash trees can only be created once the filesystems are final
+	pipeline.AddStages(osbuild.GenDMVerityStages(pt, p.Filename())...)
+	if data, _ := pt.FindVerity("/"); data != nil && p.treePipeline.SecureBoot != nil {
+		// the certificate is part of the inline data of the tree pipeline
+		pipeline.AddStage(osbuild.GenVerityRootHashAddonSignStage(pt, p.Filename(), p.treePipeline.SecureBoot.Certificate))
+	}
+
 	for _, stage := range osbuild.GenImageFinishStages(pt, p.Filename()) {
 		pipeline.AddStage(stage)
 	}
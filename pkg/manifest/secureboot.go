@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/osbuild/images/pkg/arch"
+	"github.com/osbuild/images/pkg/customizations/fsnode"
+	"github.com/osbuild/images/pkg/customizations/secureboot"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
+)
+
+const (
+	mokEnrollService  = "osbuild-secureboot-mok-enroll.service"
+	mokEnrollCertPath = "/etc/pki/secureboot/osbuild-mok.der"
+)
+
+// efiArch returns the architecture suffix used in the names of EFI binaries
+// on the ESP, e.g. BOOTX64.EFI or shimaa64.efi.
+func efiArch(a arch.Arch) string {
+	switch a {
+	case arch.ARCH_X86_64:
+		return "x64"
+	case arch.ARCH_AARCH64:
+		return "aa64"
+	case arch.ARCH_RISCV64:
+		return "riscv64"
+	default:
+		panic(fmt.Sprintf("no EFI architecture name for %s", a))
+	}
+}
+
+// secureBootSignPaths returns the EFI binaries and kernels in the tree that
+// are loaded by the firmware or the bootloader and need to be signed for the
+// boot chain of the platform.
+func (p *OS) secureBootSignPaths() []string {
+	if p.platform.GetUEFIVendor() == "" {
+		panic("secure boot signing requires a UEFI platform")
+	}
+	suffix := efiArch(p.platform.GetArch())
+	fallback := filepath.Join("/boot/efi/EFI/BOOT", fmt.Sprintf("BOOT%s.EFI", strings.ToUpper(suffix)))
+
+	switch p.platform.GetBootloader() {
+	case platform.BOOTLOADER_UKI:
+		// the kernel is embedded in the UKI and covered by its signature
+		return []string{
+			osbuild.NewUkifyStageOptions(p.kernelVer, "").Output,
+			filepath.Join("/boot/efi/EFI/systemd", fmt.Sprintf("systemd-boot%s.efi", suffix)),
+			fallback,
+		}
+	default:
+		vendorDir := filepath.Join("/boot/efi/EFI", p.platform.GetUEFIVendor())
+		paths := []string{}
+		// there is no shim on riscv64, grub is loaded directly
+		if p.platform.GetArch() != arch.ARCH_RISCV64 {
+			paths = append(paths, filepath.Join(vendorDir, fmt.Sprintf("shim%s.efi", suffix)))
+		}
+		return append(paths,
+			filepath.Join(vendorDir, fmt.Sprintf("grub%s.efi", suffix)),
+			fallback,
+			fmt.Sprintf("/boot/vmlinuz-%s", p.kernelVer),
+		)
+	}
+}
+
+// secureBootStages returns the stages that sign the boot chain and, for db
+// enrollment, generate the authenticated variables on the ESP. They need to
+// run after the bootloader has been installed.
+func (p *OS) secureBootStages() []*osbuild.Stage {
+	stages := []*osbuild.Stage{
+		osbuild.NewSbsignStage(osbuild.NewSbsignStageOptions(p.secureBootSignPaths()), p.SecureBoot.Certificate),
+	}
+	if p.SecureBoot.Enrollment == secureboot.EnrollmentDB {
+		stages = append(stages, osbuild.NewSecureBootAuthVarsStage(&osbuild.SecureBootAuthVarsStageOptions{
+			Target:  osbuild.SecureBootAutoEnrollDir,
+			Secrets: &osbuild.SecureBootKeySecrets{Name: osbuild.SecureBootKeySecret},
+		}, p.SecureBoot.Certificate))
+	}
+	return stages
+}
+
+// mokEnrollStages installs the signing certificate into the tree and creates
+// a first boot service that queues it for enrollment into the MOK list. The
+// enrollment is confirmed in MokManager on the next boot using the root
+// password of the system.
+func mokEnrollStages(options *secureboot.Options) ([]*osbuild.Stage, []*fsnode.File, error) {
+	der, err := options.CertificateDER()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certDir, err := fsnode.NewDirectory(filepath.Dir(mokEnrollCertPath), nil, nil, nil, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	certFile, err := fsnode.NewFile(mokEnrollCertPath, nil, nil, nil, der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stages := osbuild.GenDirectoryNodesStages([]*fsnode.Directory{certDir})
+	stages = append(stages, osbuild.GenFileNodesStages([]*fsnode.File{certFile})...)
+	stages = append(stages, osbuild.NewSystemdUnitCreateStage(&osbuild.SystemdUnitCreateStageOptions{
+		Filename: mokEnrollService,
+		UnitType: "system",
+		UnitPath: osbuild.EtcUnitPath,
+		Config: osbuild.SystemdUnit{
+			Unit: &osbuild.UnitSection{
+				Description:         "First-boot service for enrolling the Secure Boot signing certificate as a MOK",
+				ConditionPathExists: []string{mokEnrollCertPath},
+			},
+			Service: &osbuild.ServiceSection{
+				Type: osbuild.OneshotServiceType,
+				ExecStart: []string{
+					fmt.Sprintf("/usr/bin/mokutil --import %s --root-pw", mokEnrollCertPath),
+					fmt.Sprintf("/usr/bin/rm %s", mokEnrollCertPath),
+				},
+			},
+			Install: &osbuild.InstallSection{
+				WantedBy: []string{"default.target"},
+			},
+		},
+	}))
+
+	return stages, []*fsnode.File{certFile}, nil
+}
@@ -0,0 +1,55 @@
+package manifestgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// AdvisoryPolicyError is returned by the Generator if a selected package is
+// affected by a critical advisory and a fixed version is available.
+type AdvisoryPolicyError struct {
+	Advisories []rpmmd.AdvisoryMatch
+}
+
+func (e *AdvisoryPolicyError) Error() string {
+	lines := make([]string, 0, len(e.Advisories))
+	for _, adv := range e.Advisories {
+		lines = append(lines, adv.String())
+	}
+	return fmt.Sprintf("selected packages are affected by critical advisories with available fixes:\n%s", strings.Join(lines, "\n"))
+}
+
+// checkAdvisoryPolicy returns an *AdvisoryPolicyError if any of the
+// depsolved packages is affected by a critical advisory that is fixed by an
+// available package version.
+func checkAdvisoryPolicy(depsolved map[string]dnfjson.DepsolveResult) error {
+	names := make([]string, 0, len(depsolved))
+	for name := range depsolved {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var critical []rpmmd.AdvisoryMatch
+	for _, name := range names {
+		for _, adv := range depsolved[name].Advisories {
+			if !strings.EqualFold(adv.Severity, "critical") || adv.Status != rpmmd.AdvisoryStatusFixAvailable {
+				continue
+			}
+			// the same package can be part of more than one pipeline
+			if slices.ContainsFunc(critical, func(m rpmmd.AdvisoryMatch) bool {
+				return m.Package == adv.Package && m.AdvisoryID == adv.AdvisoryID
+			}) {
+				continue
+			}
+			critical = append(critical, adv)
+		}
+	}
+	if len(critical) > 0 {
+		return &AdvisoryPolicyError{Advisories: critical}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package manifestgen_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distrofactory"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/imagefilter"
+	"github.com/osbuild/images/pkg/manifestgen"
+	"github.com/osbuild/images/pkg/rpmmd"
+	testrepos "github.com/osbuild/images/test/data/repositories"
+)
+
+func fakeDepsolveWithAdvisory(adv rpmmd.AdvisoryMatch) manifestgen.DepsolveFunc {
+	return func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+		depsolved, err := fakeDepsolve(cacheDir, packageSets, d, arch)
+		if err != nil {
+			return nil, err
+		}
+		for name, res := range depsolved {
+			res.Advisories = []rpmmd.AdvisoryMatch{adv}
+			depsolved[name] = res
+		}
+		return depsolved, nil
+	}
+}
+
+func TestManifestGeneratorAdvisoryPolicy(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	filter, err := imagefilter.New(distrofactory.NewDefault(), repos)
+	require.NoError(t, err)
+	res, err := filter.Filter("distro:centos-9", "type:qcow2", "arch:x86_64")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+
+	critical := rpmmd.AdvisoryMatch{
+		Package:    "kernel-5.14.0-500.el9.x86_64",
+		AdvisoryID: "RHSA-2024:0001",
+		Type:       "security",
+		Severity:   "Critical",
+		CVEs:       []string{"CVE-2024-0001"},
+		Status:     rpmmd.AdvisoryStatusFixAvailable,
+		FixedIn:    "kernel-5.14.0-501.el9.x86_64",
+	}
+	unfixed := critical
+	unfixed.Status = rpmmd.AdvisoryStatusAffected
+	important := critical
+	important.Severity = "Important"
+
+	type testCase struct {
+		adv         rpmmd.AdvisoryMatch
+		failOn      bool
+		expectedErr bool
+	}
+	testCases := map[string]testCase{
+		"critical-fix-available": {adv: critical, failOn: true, expectedErr: true},
+		"critical-no-fix":        {adv: unfixed, failOn: true},
+		"important-fix":          {adv: important, failOn: true},
+		"policy-disabled":        {adv: critical},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var osbuildManifest bytes.Buffer
+			mg, err := manifestgen.New(repos, &manifestgen.Options{
+				Output:                   &osbuildManifest,
+				Depsolver:                fakeDepsolveWithAdvisory(tc.adv),
+				CommitResolver:           panicCommitResolver,
+				ContainerResolver:        panicContainerResolver,
+				FailOnCriticalAdvisories: tc.failOn,
+			})
+			require.NoError(t, err)
+			err = mg.Generate(&blueprint.Blueprint{}, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+			if !tc.expectedErr {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, osbuildManifest.String())
+				return
+			}
+			var policyErr *manifestgen.AdvisoryPolicyError
+			require.ErrorAs(t, err, &policyErr)
+			// reported once, even though the package is in several pipelines
+			assert.Equal(t, []rpmmd.AdvisoryMatch{tc.adv}, policyErr.Advisories)
+			assert.EqualError(t, err, "selected packages are affected by critical advisories with available fixes:\nkernel-5.14.0-500.el9.x86_64: RHSA-2024:0001 (security, Critical) CVE-2024-0001, fixed in kernel-5.14.0-501.el9.x86_64 (fix-available)")
+			assert.Empty(t, osbuildManifest.String())
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package manifestgen
+
+import (
+	"archive/tar"
+	"crypto/md5"  // #nosec G501
+	"crypto/sha1" // #nosec G505
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// Layout of an offline bundle directory, see Options.OfflineBundleDir
+const (
+	BundleManifestFilename = "manifest.json"
+	BundlePackagesDir      = "rpms"
+	BundleContainersDir    = "containers"
+	BundleOSTreeRepoDir    = "ostree/repo"
+)
+
+// fileURL returns the file:// URL of an absolute path
+func fileURL(p string) string {
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+func newChecksumHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case "md5":
+		return md5.New(), nil // #nosec G401
+	case "sha1":
+		return sha1.New(), nil // #nosec G401
+	case "sha256":
+		return sha256.New(), nil
+	case "sha384":
+		return sha512.New384(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum type %q", algo)
+}
+
+// verifyFileChecksum verifies a file against a checksum in the
+// "<algorithm>:<hex digest>" format of the depsolved packages.
+func verifyFileChecksum(filename, checksum string) error {
+	algo, expected, ok := strings.Cut(checksum, ":")
+	if !ok {
+		return fmt.Errorf("invalid checksum %q", checksum)
+	}
+	h, err := newChecksumHash(algo)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return fmt.Errorf("checksum mismatch for %q: expected %s, got %s:%s", filename, checksum, algo, actual)
+	}
+	return nil
+}
+
+// DefaultPackageDownloader downloads a package from its remote location
+// with the TLS settings of its repository.
+func DefaultPackageDownloader(pkg rpmmd.PackageSpec, repo *rpmmd.RepoConfig, dest string) error {
+	tlsConf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// #nosec G402
+		InsecureSkipVerify: pkg.IgnoreSSL,
+	}
+	if repo != nil && repo.SSLCACert != "" {
+		caCertPEM, err := os.ReadFile(repo.SSLCACert)
+		if err != nil {
+			return fmt.Errorf("cannot read ca certificate of repository %q: %w", repo.Id, err)
+		}
+		tlsConf.RootCAs = x509.NewCertPool()
+		if ok := tlsConf.RootCAs.AppendCertsFromPEM(caCertPEM); !ok {
+			return fmt.Errorf("cannot add ca certificate of repository %q", repo.Id)
+		}
+	}
+	if repo != nil && repo.SSLClientCert != "" && repo.SSLClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(repo.SSLClientCert, repo.SSLClientKey)
+		if err != nil {
+			return fmt.Errorf("cannot load client certificate of repository %q: %w", repo.Id, err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConf
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   300 * time.Second,
+	}
+
+	resp, err := client.Get(pkg.RemoteLocation)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot download %q: %s", pkg.RemoteLocation, resp.Status)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DefaultContainerExporter copies a container image to an oci-archive with
+// skopeo.
+func DefaultContainerExporter(spec container.Spec, dest string) error {
+	args := []string{"copy", "--preserve-digests"}
+	if spec.TLSVerify != nil && !*spec.TLSVerify {
+		args = append(args, "--src-tls-verify=false")
+	}
+	args = append(args, fmt.Sprintf("docker://%s@%s", spec.Source, spec.Digest), "oci-archive:"+dest)
+	// #nosec G204
+	if output, err := exec.Command("skopeo", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot export container %q: %w, output:\n%s", spec.Source, err, output)
+	}
+	return nil
+}
+
+// DefaultCommitMirror pulls an ostree commit into an archive repository
+// with the ostree command line tool.
+func DefaultCommitMirror(spec ostree.CommitSpec, repoPath string) error {
+	if spec.Secrets != "" {
+		return fmt.Errorf("cannot mirror commit %q: secrets %q are not supported", spec.Checksum, spec.Secrets)
+	}
+	remoteURL := spec.URL
+	if spec.ContentURL != "" {
+		remoteURL = spec.ContentURL
+	}
+	cmds := [][]string{
+		{"ostree", "init", "--mode=archive", "--repo=" + repoPath},
+		{"ostree", "remote", "add", "--force", "--no-gpg-verify", "--repo=" + repoPath, "bundle", remoteURL},
+		{"ostree", "pull", "--repo=" + repoPath, "bundle", spec.Checksum},
+	}
+	for _, cmd := range cmds {
+		// #nosec G204
+		if output, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("cannot mirror commit %q: %s failed: %w, output:\n%s", spec.Checksum, strings.Join(cmd[:2], " "), err, output)
+		}
+	}
+	return nil
+}
+
+// bundleLocation returns the location of a file in the bundle directory on
+// the build host.
+func (mg *Generator) bundleLocation(elem ...string) string {
+	return filepath.Join(append([]string{mg.offlineBundlePath}, elem...)...)
+}
+
+// packageFilename returns the filename of a package in the bundle
+func packageFilename(pkg rpmmd.PackageSpec) (string, error) {
+	filename := path.Base(pkg.Path)
+	if pkg.IsLocalFile() {
+		filename = filepath.Base(pkg.LocalSource)
+	} else if pkg.Path == "" {
+		u, err := url.Parse(pkg.RemoteLocation)
+		if err != nil {
+			return "", err
+		}
+		filename = path.Base(u.Path)
+	}
+	if !strings.HasSuffix(filename, ".rpm") {
+		return "", fmt.Errorf("cannot determine the filename of package %q", pkg.GetNEVRA())
+	}
+	return filename, nil
+}
+
+// bundlePackage downloads a package, or copies a local package file, into
+// the bundle, unless it was fetched before, and returns its filename
+func (mg *Generator) bundlePackage(pkg rpmmd.PackageSpec, repos []rpmmd.RepoConfig) (string, error) {
+	filename, err := packageFilename(pkg)
+	if err != nil {
+		return "", err
+	}
+	dest := filepath.Join(mg.offlineBundleDir, BundlePackagesDir, filename)
+	if _, err := os.Stat(dest); err == nil {
+		if err := verifyFileChecksum(dest, pkg.Checksum); err == nil {
+			return filename, nil
+		}
+	}
+
+	var repo *rpmmd.RepoConfig
+	if idx := slices.IndexFunc(repos, func(r rpmmd.RepoConfig) bool { return r.Id == pkg.RepoID }); idx >= 0 {
+		repo = &repos[idx]
+	}
+	tmp := dest + ".part"
+	defer os.Remove(tmp)
+	if pkg.IsLocalFile() {
+		err = copyFile(pkg.LocalSource, tmp)
+	} else {
+		err = mg.packageDownloader(pkg, repo, tmp)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot download package %q: %w", pkg.GetNEVRA(), err)
+	}
+	if err := verifyFileChecksum(tmp, pkg.Checksum); err != nil {
+		return "", fmt.Errorf("cannot download package %q: %w", pkg.GetNEVRA(), err)
+	}
+	return filename, os.Rename(tmp, dest)
+}
+
+// ociArchiveHasManifest verifies that the index of an oci-archive refers to
+// the image manifest with the given digest.
+func ociArchiveHasManifest(filename, manifestDigest string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%q is not an oci-archive: no index.json", filename)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read %q: %w", filename, err)
+		}
+		if path.Clean(hdr.Name) != "index.json" {
+			continue
+		}
+		var index imgspecv1.Index
+		if err := json.NewDecoder(tr).Decode(&index); err != nil {
+			return fmt.Errorf("cannot read the index of %q: %w", filename, err)
+		}
+		for _, desc := range index.Manifests {
+			if desc.Digest.String() == manifestDigest {
+				return nil
+			}
+		}
+		return fmt.Errorf("oci-archive %q does not contain the manifest %s", filename, manifestDigest)
+	}
+}
+
+// bundleContainer exports a container, or copies the oci-archive it was
+// resolved from, into the bundle, unless the archive is there already.
+func (mg *Generator) bundleContainer(spec container.Spec) error {
+	filename := strings.TrimPrefix(spec.ImageID, "sha256:") + ".tar"
+	dest := filepath.Join(mg.offlineBundleDir, BundleContainersDir, filename)
+	verify := func(filename string) error {
+		return ociArchiveHasManifest(filename, spec.Digest)
+	}
+	if spec.LocalArchive != "" {
+		verify = func(filename string) error {
+			return verifyFileChecksum(filename, spec.LocalArchiveChecksum)
+		}
+	}
+	if _, err := os.Stat(dest); err == nil {
+		if err := verify(dest); err == nil {
+			return nil
+		}
+	}
+
+	tmp := dest + ".part"
+	defer os.Remove(tmp)
+	if spec.LocalArchive != "" {
+		// oci-archives are copied into the bundle as they are
+		if err := copyFile(spec.LocalArchive, tmp); err != nil {
+			return fmt.Errorf("cannot copy container %q: %w", spec.Source, err)
+		}
+	} else if err := mg.containerExporter(spec, tmp); err != nil {
+		return err
+	}
+	if err := verify(tmp); err != nil {
+		return fmt.Errorf("cannot export container %q: %w", spec.Source, err)
+	}
+	return os.Rename(tmp, dest)
+}
+
+// writeBundle fetches all packages, containers and commits into the
+// offline bundle directory and returns copies of the inputs that point to
+// the content of the bundle.
+func (mg *Generator) writeBundle(depsolved map[string]dnfjson.DepsolveResult, containerSpecs map[string][]container.Spec, commitSpecs map[string][]ostree.CommitSpec) (map[string]dnfjson.DepsolveResult, map[string][]container.Spec, map[string][]ostree.CommitSpec, error) {
+	for _, dir := range []string{BundlePackagesDir, BundleContainersDir} {
+		if err := os.MkdirAll(filepath.Join(mg.offlineBundleDir, dir), 0755); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	bundleDepsolved := make(map[string]dnfjson.DepsolveResult, len(depsolved))
+	for _, name := range sortedKeys(depsolved) {
+		res := depsolved[name]
+		pkgs := make([]rpmmd.PackageSpec, len(res.Packages))
+		for idx, pkg := range res.Packages {
+			filename, err := mg.bundlePackage(pkg, res.Repos)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			// locally supplied packages are fetched from the bundle
+			// like all others
+			pkg.LocalSource = ""
+			pkg.RemoteLocation = fileURL(mg.bundleLocation(BundlePackagesDir, filename))
+			pkg.Secrets = ""
+			pkg.IgnoreSSL = false
+			pkgs[idx] = pkg
+		}
+		res.Packages = pkgs
+		bundleDepsolved[name] = res
+	}
+
+	bundleContainers := make(map[string][]container.Spec, len(containerSpecs))
+	for _, name := range sortedKeys(containerSpecs) {
+		for _, spec := range containerSpecs[name] {
+			if !spec.LocalStorage {
+				if err := mg.bundleContainer(spec); err != nil {
+					return nil, nil, nil, err
+				}
+				// the sources of osbuild cannot take oci-archives,
+				// the archives are loaded into the containers-storage
+				// of the build host, which keeps their image IDs
+				spec.LocalStorage = true
+				spec.LocalArchive = ""
+				spec.LocalArchiveChecksum = ""
+				spec.ListDigest = ""
+				spec.Mirror = ""
+				spec.MirrorTLSVerify = nil
+				spec.TLSVerify = nil
+			}
+			bundleContainers[name] = append(bundleContainers[name], spec)
+		}
+	}
+
+	bundleCommits := make(map[string][]ostree.CommitSpec, len(commitSpecs))
+	if len(commitSpecs) > 0 {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(mg.offlineBundleDir, BundleOSTreeRepoDir)), 0755); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	for _, name := range sortedKeys(commitSpecs) {
+		for _, spec := range commitSpecs[name] {
+			if err := mg.commitMirror(spec, filepath.Join(mg.offlineBundleDir, BundleOSTreeRepoDir)); err != nil {
+				return nil, nil, nil, err
+			}
+			spec.URL = fileURL(mg.bundleLocation(BundleOSTreeRepoDir))
+			spec.ContentURL = ""
+			spec.Secrets = ""
+			bundleCommits[name] = append(bundleCommits[name], spec)
+		}
+	}
+
+	return bundleDepsolved, bundleContainers, bundleCommits, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
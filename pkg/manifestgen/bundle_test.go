@@ -0,0 +1,348 @@
+package manifestgen_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distrofactory"
+	"github.com/osbuild/images/pkg/imagefilter"
+	"github.com/osbuild/images/pkg/manifestgen"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/reporegistry"
+	"github.com/osbuild/images/pkg/rpmmd"
+	testrepos "github.com/osbuild/images/test/data/repositories"
+)
+
+// fakePackageDownloader writes the package name as the content of the
+// package, which matches the checksums of fakeDepsolve
+func fakePackageDownloader(downloads *[]string) manifestgen.PackageDownloaderFunc {
+	return func(pkg rpmmd.PackageSpec, repo *rpmmd.RepoConfig, dest string) error {
+		*downloads = append(*downloads, pkg.Name)
+		return os.WriteFile(dest, []byte(pkg.Name), 0644)
+	}
+}
+
+// fakeContainerExporter writes an oci-archive that only has an index with
+// the manifest of the container
+func fakeContainerExporter(exported *[]string) manifestgen.ContainerExporterFunc {
+	return func(spec container.Spec, dest string) error {
+		*exported = append(*exported, spec.Source)
+		index := fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q,"size":1}]}`, spec.Digest)
+		f, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		tw := tar.NewWriter(f)
+		if err := tw.WriteHeader(&tar.Header{Name: "index.json", Mode: 0644, Size: int64(len(index))}); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(index)); err != nil {
+			return err
+		}
+		return tw.Close()
+	}
+}
+
+func filterOne(t *testing.T, repos *reporegistry.RepoRegistry, query ...string) imagefilter.Result {
+	t.Helper()
+	filter, err := imagefilter.New(distrofactory.NewDefault(), repos)
+	require.NoError(t, err)
+	res, err := filter.Filter(query...)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	return res[0]
+}
+
+func TestManifestGeneratorOfflineBundle(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+
+	bundleDir := t.TempDir()
+	var downloads []string
+	var osbuildManifest bytes.Buffer
+	opts := &manifestgen.Options{
+		Output:            &osbuildManifest,
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+		RpmDownloader:     osbuild.RpmDownloaderLibrepo,
+		OfflineBundleDir:  bundleDir,
+		OfflineBundlePath: "/run/bundle",
+		PackageDownloader: fakePackageDownloader(&downloads),
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	var bp blueprint.Blueprint
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, downloads, "kernel")
+	content, err := os.ReadFile(filepath.Join(bundleDir, manifestgen.BundlePackagesDir, "kernel.rpm"))
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", string(content))
+
+	// the manifest in the bundle is the one that was written to the output
+	mf, err := os.ReadFile(filepath.Join(bundleDir, manifestgen.BundleManifestFilename))
+	require.NoError(t, err)
+	assert.Equal(t, osbuildManifest.String(), string(mf)+"\n")
+	assert.Contains(t, string(mf), `"file:///run/bundle/rpms/kernel.rpm"`)
+	assert.Contains(t, string(mf), sha256For("kernel"))
+	// librepo would download from the repositories
+	assert.NotContains(t, string(mf), "org.osbuild.librepo")
+
+	// packages that are already part of the bundle are not downloaded again
+	downloads = nil
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	require.NoError(t, err)
+	assert.Empty(t, downloads)
+}
+
+func TestManifestGeneratorOfflineBundleChecksumMismatch(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+
+	bundleDir := t.TempDir()
+	opts := &manifestgen.Options{
+		Output:            &bytes.Buffer{},
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+		OfflineBundleDir:  bundleDir,
+		PackageDownloader: func(pkg rpmmd.PackageSpec, repo *rpmmd.RepoConfig, dest string) error {
+			return os.WriteFile(dest, []byte("corrupted"), 0644)
+		},
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	var bp blueprint.Blueprint
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	// no broken packages are left in the bundle
+	entries, err := os.ReadDir(filepath.Join(bundleDir, manifestgen.BundlePackagesDir))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestManifestGeneratorOfflineBundleLocalPackages(t *testing.T) {
+	restore := manifestgen.MockCreateRepo(func(dir string) error { return nil })
+	defer restore()
+
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+
+	localRPM := filepath.Join(t.TempDir(), "local-1.0-1.noarch.rpm")
+	require.NoError(t, os.WriteFile(localRPM, []byte("local"), 0644))
+
+	bundleDir := t.TempDir()
+	var osbuildManifest bytes.Buffer
+	opts := &manifestgen.Options{
+		Output:            &osbuildManifest,
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+		OfflineBundleDir:  bundleDir,
+		OfflineBundlePath: "/run/bundle",
+		PackageDownloader: fakePackageDownloader(new([]string)),
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	bp := blueprint.Blueprint{
+		Packages: []blueprint.Package{
+			{Name: "local", Source: localRPM, Checksum: sha256For("local")},
+		},
+	}
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	require.NoError(t, err)
+
+	// the local package is copied into the bundle and fetched from there
+	content, err := os.ReadFile(filepath.Join(bundleDir, manifestgen.BundlePackagesDir, "local-1.0-1.noarch.rpm"))
+	require.NoError(t, err)
+	assert.Equal(t, "local", string(content))
+	assert.Contains(t, osbuildManifest.String(), `"`+sha256For("local")+`":{"url":"file:///run/bundle/rpms/local-1.0-1.noarch.rpm"}`)
+	assert.NotContains(t, osbuildManifest.String(), localRPM)
+}
+
+func TestManifestGeneratorOfflineBundleContainers(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+
+	bundleDir := t.TempDir()
+	var exported []string
+	var osbuildManifest bytes.Buffer
+	opts := &manifestgen.Options{
+		Output:            &osbuildManifest,
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: fakeContainerResolver,
+		OfflineBundleDir:  bundleDir,
+		OfflineBundlePath: "/run/bundle",
+		PackageDownloader: fakePackageDownloader(new([]string)),
+		ContainerExporter: fakeContainerExporter(&exported),
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{
+				Source: "registry.example.com/image",
+			},
+		},
+	}
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"resolved-cnt-registry.example.com/image"}, exported)
+	entries, err := os.ReadDir(filepath.Join(bundleDir, manifestgen.BundleContainersDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	// the archive is loaded into the containers-storage of the build host
+	assert.Contains(t, osbuildManifest.String(), "org.osbuild.containers-storage")
+	assert.NotContains(t, osbuildManifest.String(), `"org.osbuild.skopeo":{"items"`)
+}
+
+func TestManifestGeneratorOfflineBundleContainersVerified(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{
+				Source: "registry.example.com/image",
+			},
+		},
+	}
+
+	bundleDir := t.TempDir()
+	var exported []string
+	exporter := fakeContainerExporter(&exported)
+	generate := func(exporter manifestgen.ContainerExporterFunc) error {
+		opts := &manifestgen.Options{
+			Output:            &bytes.Buffer{},
+			Depsolver:         fakeDepsolve,
+			CommitResolver:    panicCommitResolver,
+			ContainerResolver: fakeContainerResolver,
+			OfflineBundleDir:  bundleDir,
+			PackageDownloader: fakePackageDownloader(new([]string)),
+			ContainerExporter: exporter,
+		}
+		mg, err := manifestgen.New(repos, opts)
+		require.NoError(t, err)
+		return mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	}
+
+	// an export that fails leaves no archive behind
+	err = generate(func(spec container.Spec, dest string) error {
+		require.NoError(t, os.WriteFile(dest, []byte("truncated"), 0644))
+		return fmt.Errorf("export failed")
+	})
+	assert.EqualError(t, err, "export failed")
+	entries, err := os.ReadDir(filepath.Join(bundleDir, manifestgen.BundleContainersDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	// archives without the manifest of the container are not kept
+	err = generate(func(spec container.Spec, dest string) error {
+		return os.WriteFile(dest, []byte("truncated"), 0644)
+	})
+	assert.ErrorContains(t, err, `cannot export container "resolved-cnt-registry.example.com/image": `)
+	assert.ErrorContains(t, err, "unexpected EOF")
+	entries, err = os.ReadDir(filepath.Join(bundleDir, manifestgen.BundleContainersDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	require.NoError(t, generate(exporter))
+	assert.Len(t, exported, 1)
+	entries, err = os.ReadDir(filepath.Join(bundleDir, manifestgen.BundleContainersDir))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// a verified archive is reused
+	require.NoError(t, generate(exporter))
+	assert.Len(t, exported, 1)
+
+	// a damaged archive is exported again
+	archive := filepath.Join(bundleDir, manifestgen.BundleContainersDir, entries[0].Name())
+	require.NoError(t, os.WriteFile(archive, []byte("truncated"), 0644))
+	require.NoError(t, generate(exporter))
+	assert.Len(t, exported, 2)
+	content, err := os.ReadFile(archive)
+	require.NoError(t, err)
+	assert.NotEqual(t, "truncated", string(content))
+}
+
+func TestManifestGeneratorOfflineBundleCommits(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:edge-ami", "arch:x86_64")
+
+	bundleDir := t.TempDir()
+	var mirrored []string
+	var osbuildManifest bytes.Buffer
+	opts := &manifestgen.Options{
+		Output:            &osbuildManifest,
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    fakeCommitResolver,
+		ContainerResolver: panicContainerResolver,
+		OfflineBundleDir:  bundleDir,
+		OfflineBundlePath: "/run/bundle",
+		PackageDownloader: fakePackageDownloader(new([]string)),
+		CommitMirror: func(spec ostree.CommitSpec, repoPath string) error {
+			mirrored = append(mirrored, spec.URL)
+			assert.Equal(t, filepath.Join(bundleDir, manifestgen.BundleOSTreeRepoDir), repoPath)
+			return nil
+		},
+	}
+	imageOpts := &distro.ImageOptions{
+		OSTree: &ostree.ImageOptions{
+			URL: "http://example.com/",
+		},
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	var bp blueprint.Blueprint
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, imageOpts)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"resolved-url-for-centos/9/x86_64/edge"}, mirrored)
+	assert.Contains(t, osbuildManifest.String(), `{"url":"file:///run/bundle/ostree/repo"}`)
+}
+
+func TestDefaultPackageDownloader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Packages/kernel.rpm" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "kernel")
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "kernel.rpm")
+	err := manifestgen.DefaultPackageDownloader(rpmmd.PackageSpec{Name: "kernel", RemoteLocation: srv.URL + "/Packages/kernel.rpm"}, nil, dest)
+	require.NoError(t, err)
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", string(content))
+
+	err = manifestgen.DefaultPackageDownloader(rpmmd.PackageSpec{Name: "missing", RemoteLocation: srv.URL + "/Packages/missing.rpm"}, nil, dest)
+	assert.ErrorContains(t, err, "404 Not Found")
+}
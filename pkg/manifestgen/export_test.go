@@ -0,0 +1,9 @@
+package manifestgen
+
+func MockCreateRepo(f func(dir string) error) (restore func()) {
+	saved := createRepo
+	createRepo = f
+	return func() {
+		createRepo = saved
+	}
+}
@@ -0,0 +1,144 @@
+package manifestgen
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// localPackagesRepoID is the ID of the temporary repository that makes
+// the local packages of a blueprint available to the depsolver
+const localPackagesRepoID = "blueprint-local-packages"
+
+// createRepo generates the repository metadata of a directory of packages
+var createRepo = func(dir string) error {
+	// #nosec G204
+	if output, err := exec.Command("createrepo_c", dir).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot create repository of local packages: %w, output:\n%s", err, output)
+	}
+	return nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// prepareLocalPackages copies or downloads the local packages of a
+// blueprint into dir, verifies their checksums and returns a repository of
+// them for the payload package sets of the image type. The copies in dir are
+// only used for depsolving, the manifest refers to the packages by their
+// Source (see markLocalPackages), which has to stay valid until the build.
+func (mg *Generator) prepareLocalPackages(dir string, pkgs []blueprint.Package, imgType distro.ImageType) (rpmmd.RepoConfig, error) {
+	for _, pkg := range pkgs {
+		dest := filepath.Join(dir, strings.TrimPrefix(pkg.Checksum, "sha256:")+".rpm")
+		var err error
+		if filepath.IsAbs(pkg.Source) {
+			err = copyFile(pkg.Source, dest)
+		} else {
+			err = mg.packageDownloader(rpmmd.PackageSpec{Name: pkg.Name, RemoteLocation: pkg.Source}, nil, dest)
+		}
+		if err != nil {
+			return rpmmd.RepoConfig{}, fmt.Errorf("cannot fetch local package %q: %w", pkg.Name, err)
+		}
+		if err := verifyFileChecksum(dest, pkg.Checksum); err != nil {
+			return rpmmd.RepoConfig{}, fmt.Errorf("cannot use local package %q: %w", pkg.Name, err)
+		}
+	}
+	if err := createRepo(dir); err != nil {
+		return rpmmd.RepoConfig{}, err
+	}
+
+	checkGPG := false
+	return rpmmd.RepoConfig{
+		Id:          localPackagesRepoID,
+		Name:        localPackagesRepoID,
+		BaseURLs:    []string{fileURL(dir)},
+		CheckGPG:    &checkGPG,
+		PackageSets: imgType.PayloadPackageSets(),
+	}, nil
+}
+
+// markLocalPackages points the depsolved packages that were supplied
+// locally to their source and drops the temporary repository of them.
+// The local packages are requested by name only, so the depsolver picks a
+// package of a repository instead if it has a higher version. Every local
+// package has to be in the depsolved packages of one of the payload
+// pipelines, otherwise an error is returned.
+func markLocalPackages(depsolved map[string]dnfjson.DepsolveResult, pkgs []blueprint.Package, payloadPipelines []string) error {
+	sources := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		sources[pkg.Checksum] = pkg.Source
+	}
+
+	installed := make(map[string]bool, len(pkgs))
+	for _, name := range payloadPipelines {
+		for _, pkg := range depsolved[name].Packages {
+			if _, ok := sources[pkg.Checksum]; ok {
+				installed[pkg.Checksum] = true
+			}
+		}
+	}
+	for _, pkg := range pkgs {
+		if !installed[pkg.Checksum] {
+			return fmt.Errorf("local package %q from %q is not installed, the depsolver selected another package with the same name, e.g. a higher version from a repository", pkg.Name, pkg.Source)
+		}
+	}
+
+	for name, res := range depsolved {
+		marked := make(map[string]bool)
+		for idx := range res.Packages {
+			pkg := &res.Packages[idx]
+			source, ok := sources[pkg.Checksum]
+			if !ok {
+				continue
+			}
+			pkg.LocalSource = source
+			pkg.RemoteLocation = ""
+			if !pkg.IsLocalFile() {
+				pkg.RemoteLocation = source
+			}
+			pkg.Secrets = ""
+			marked[pkg.Checksum] = true
+		}
+
+		// the depsolver replaces the repository IDs with hashes
+		var repos []rpmmd.RepoConfig
+		for _, repo := range res.Repos {
+			if repo.Name != localPackagesRepoID {
+				repos = append(repos, repo)
+			}
+		}
+		res.Repos = repos
+
+		if res.SBOM != nil {
+			for _, checksum := range sortedKeys(marked) {
+				if err := res.SBOM.MarkLocalPackage(checksum, sources[checksum]); err != nil {
+					return err
+				}
+			}
+		}
+		depsolved[name] = res
+	}
+	return nil
+}
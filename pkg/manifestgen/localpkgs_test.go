@@ -0,0 +1,155 @@
+package manifestgen_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/manifestgen"
+	"github.com/osbuild/images/pkg/rpmmd"
+	testrepos "github.com/osbuild/images/test/data/repositories"
+)
+
+func TestManifestGeneratorLocalPackages(t *testing.T) {
+	var createdRepos []string
+	restore := manifestgen.MockCreateRepo(func(dir string) error {
+		createdRepos = append(createdRepos, dir)
+		return nil
+	})
+	defer restore()
+
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+
+	// the content of the packages matches the checksums of fakeDepsolve
+	localRPM := filepath.Join(t.TempDir(), "local-1.0-1.noarch.rpm")
+	require.NoError(t, os.WriteFile(localRPM, []byte("local"), 0644))
+
+	var localRepo *rpmmd.RepoConfig
+	var downloads []string
+	var osbuildManifest bytes.Buffer
+	opts := &manifestgen.Options{
+		Output: &osbuildManifest,
+		Depsolver: func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+			for _, pkgSet := range packageSets["os"] {
+				for _, repo := range pkgSet.Repositories {
+					if repo.Name == "blueprint-local-packages" {
+						localRepo = &repo
+						// the packages are available when depsolving
+						entries, err := os.ReadDir(strings.TrimPrefix(repo.BaseURLs[0], "file://"))
+						require.NoError(t, err)
+						assert.Len(t, entries, 2)
+					}
+				}
+			}
+			return fakeDepsolve(cacheDir, packageSets, d, arch)
+		},
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+		PackageDownloader: fakePackageDownloader(&downloads),
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	bp := blueprint.Blueprint{
+		Packages: []blueprint.Package{
+			{Name: "local", Source: localRPM, Checksum: sha256For("local")},
+			{Name: "remote", Source: "https://example.com/remote-1.0-1.noarch.rpm", Checksum: sha256For("remote")},
+		},
+	}
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	require.NoError(t, err)
+
+	require.NotNil(t, localRepo)
+	assert.Equal(t, res.ImgType.PayloadPackageSets(), localRepo.PackageSets)
+	assert.Equal(t, []string{"remote"}, downloads)
+	require.Len(t, createdRepos, 1)
+	// the temporary repository is removed again
+	assert.NoDirExists(t, createdRepos[0])
+
+	// both packages are downloaded from their source, not from the
+	// temporary repository
+	assert.Contains(t, osbuildManifest.String(), `"`+sha256For("local")+`":{"url":"file://`+localRPM+`"}`)
+	assert.Contains(t, osbuildManifest.String(), `"`+sha256For("remote")+`":{"url":"https://example.com/remote-1.0-1.noarch.rpm"}`)
+}
+
+func TestManifestGeneratorLocalPackagesChecksumMismatch(t *testing.T) {
+	restore := manifestgen.MockCreateRepo(func(dir string) error {
+		panic("unexpected call to createRepo")
+	})
+	defer restore()
+
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+
+	localRPM := filepath.Join(t.TempDir(), "local-1.0-1.noarch.rpm")
+	require.NoError(t, os.WriteFile(localRPM, []byte("corrupted"), 0644))
+
+	opts := &manifestgen.Options{
+		Output:            &bytes.Buffer{},
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	bp := blueprint.Blueprint{
+		Packages: []blueprint.Package{
+			{Name: "local", Source: localRPM, Checksum: sha256For("local")},
+		},
+	}
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	assert.ErrorContains(t, err, `cannot use local package "local": checksum mismatch`)
+}
+
+func TestManifestGeneratorLocalPackagesNotSelected(t *testing.T) {
+	restore := manifestgen.MockCreateRepo(func(dir string) error { return nil })
+	defer restore()
+
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:qcow2", "arch:x86_64")
+
+	localRPM := filepath.Join(t.TempDir(), "local-1.0-1.noarch.rpm")
+	require.NoError(t, os.WriteFile(localRPM, []byte("local"), 0644))
+
+	opts := &manifestgen.Options{
+		Output: &bytes.Buffer{},
+		// a repository has a newer package with the same name, which is
+		// selected instead of the local one
+		Depsolver: func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+			depsolved, err := fakeDepsolve(cacheDir, packageSets, d, arch)
+			if err != nil {
+				return nil, err
+			}
+			pkgs := depsolved["os"].Packages
+			for idx := range pkgs {
+				if pkgs[idx].Name == "local" {
+					pkgs[idx].Version = "2.0"
+					pkgs[idx].Checksum = sha256For("local-2.0")
+				}
+			}
+			return depsolved, nil
+		},
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	bp := blueprint.Blueprint{
+		Packages: []blueprint.Package{
+			{Name: "local", Source: localRPM, Checksum: sha256For("local")},
+		},
+	}
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	assert.EqualError(t, err, `local package "local" from "`+localRPM+`" is not installed, the depsolver selected another package with the same name, e.g. a higher version from a repository`)
+}
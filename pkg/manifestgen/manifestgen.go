@@ -57,15 +57,85 @@ type Options struct {
 	// This is mostly useful for testing
 	OverrideRepos []rpmmd.RepoConfig
 
+	// RepoSnapshot pins all repositories to the given snapshot date
+	// or ID, see rpmmd.RepoConfig.WithSnapshot(). Repositories
+	// without snapshot_url are not pinned, which is reported to
+	// WarningsOutput. The snapshots that were used are recorded in
+	// the comment and the annotations of the SBOMs.
+	RepoSnapshot string
+
 	// Custom "solver" functions, if unset the defaults will be
 	// used. Only needed for specialized use-cases.
 	Depsolver         DepsolveFunc
 	ContainerResolver ContainerResolverFunc
 	CommitResolver    CommitResolverFunc
 
+	// OSTreeParentChecker checks that the ostree parent of the
+	// image options exists before the packages are depsolved, if
+	// unset the default will be used. Callers that access the
+	// repository with MTLS or a proxy need to set one that passes
+	// them to ostree.ImageOptions.CheckParent.
+	OSTreeParentChecker OSTreeParentCheckerFunc
+
 	// Use the a bootstrap container to buildroot (useful for e.g.
 	// cross-arch or cross-distro builds)
 	UseBootstrapContainer bool
+
+	// SizeReportWriter will be called with the package size report
+	// of the image (as JSON), the filename contains the suggested
+	// filename string. The default depsolver reads the installed
+	// sizes from the repository metadata, a custom Depsolver must
+	// set rpmmd.PackageSpec.InstallSize itself.
+	SizeReportWriter SizeReportWriterFunc
+
+	// PreviousSizeReport is compared to the size report of the
+	// image and the differences are added to it
+	PreviousSizeReport *SizeReport
+
+	// SizeReportExcludeSavings depsolves all package sets a second
+	// time without their excludes to estimate how much they save.
+	// The default depsolver also downloads the package headers to
+	// report the size of the documentation that pipelines with
+	// ExcludeDocs do not install, a custom Depsolver must set
+	// rpmmd.PackageSpec.DocSize itself.
+	SizeReportExcludeSavings bool
+
+	// SizeBudgets is the maximum installed size of the payload
+	// packages, keyed by image type name. Generate() returns a
+	// *SizeBudgetError if it is exceeded, after the size report but
+	// before the manifest, the SBOMs or the offline bundle are
+	// written.
+	SizeBudgets map[string]uint64
+
+	// FailOnCriticalAdvisories makes Generate() return an
+	// *AdvisoryPolicyError if a selected package is affected by a
+	// critical advisory and a fixed version is available. The
+	// default depsolver requests the advisories when this is set,
+	// a custom Depsolver must set DepsolveResult.Advisories itself.
+	FailOnCriticalAdvisories bool
+
+	// OfflineBundleDir makes Generate() download all packages,
+	// containers and ostree commits of the image into the directory
+	// and write a manifest that only uses these local copies to
+	// BundleManifestFilename in it. The sources of the manifest use
+	// file:// URLs, packages are always fetched with curl. Container
+	// images are stored as oci-archives in BundleContainersDir, the
+	// manifest takes them from the containers-storage of the build
+	// host, so they must be loaded into it before building, e.g. with
+	// "podman load". Containers from the containers-storage of the
+	// host are not part of the bundle.
+	OfflineBundleDir string
+
+	// OfflineBundlePath is the absolute path of the bundle directory
+	// on the build host, the file:// URLs of the manifest point
+	// there. Defaults to the absolute path of OfflineBundleDir.
+	OfflineBundlePath string
+
+	// Custom functions to fetch the content of the offline bundle,
+	// if unset the defaults will be used
+	PackageDownloader PackageDownloaderFunc
+	ContainerExporter ContainerExporterFunc
+	CommitMirror      CommitMirrorFunc
 }
 
 // Generator can generate an osbuild manifest from a given repository
@@ -77,6 +147,7 @@ type Generator struct {
 	depsolver         DepsolveFunc
 	containerResolver ContainerResolverFunc
 	commitResolver    CommitResolverFunc
+	parentChecker     OSTreeParentCheckerFunc
 	sbomWriter        SBOMWriterFunc
 	warningsOutput    io.Writer
 
@@ -86,8 +157,22 @@ type Generator struct {
 
 	customSeed    *int64
 	overrideRepos []rpmmd.RepoConfig
+	repoSnapshot  string
 
 	useBootstrapContainer bool
+
+	sizeReportWriter         SizeReportWriterFunc
+	previousSizeReport       *SizeReport
+	sizeReportExcludeSavings bool
+	sizeBudgets              map[string]uint64
+
+	failOnCriticalAdvisories bool
+
+	offlineBundleDir  string
+	offlineBundlePath string
+	packageDownloader PackageDownloaderFunc
+	containerExporter ContainerExporterFunc
+	commitMirror      CommitMirrorFunc
 }
 
 // New will create a new manifest generator
@@ -103,18 +188,40 @@ func New(reporegistry *reporegistry.RepoRegistry, opts *Options) (*Generator, er
 		depsolver:             opts.Depsolver,
 		containerResolver:     opts.ContainerResolver,
 		commitResolver:        opts.CommitResolver,
+		parentChecker:         opts.OSTreeParentChecker,
 		rpmDownloader:         opts.RpmDownloader,
 		sbomWriter:            opts.SBOMWriter,
 		warningsOutput:        opts.WarningsOutput,
 		customSeed:            opts.CustomSeed,
 		overrideRepos:         opts.OverrideRepos,
+		repoSnapshot:          opts.RepoSnapshot,
 		useBootstrapContainer: opts.UseBootstrapContainer,
+
+		sizeReportWriter:         opts.SizeReportWriter,
+		previousSizeReport:       opts.PreviousSizeReport,
+		sizeReportExcludeSavings: opts.SizeReportExcludeSavings,
+		sizeBudgets:              opts.SizeBudgets,
+
+		failOnCriticalAdvisories: opts.FailOnCriticalAdvisories,
+
+		offlineBundleDir:  opts.OfflineBundleDir,
+		offlineBundlePath: opts.OfflineBundlePath,
+		packageDownloader: opts.PackageDownloader,
+		containerExporter: opts.ContainerExporter,
+		commitMirror:      opts.CommitMirror,
 	}
 	if mg.out == nil {
 		mg.out = os.Stdout
 	}
 	if mg.depsolver == nil {
-		mg.depsolver = DefaultDepsolver
+		dopts := depsolveOptions{
+			advisories:   mg.failOnCriticalAdvisories,
+			packageSizes: mg.sizeReportWriter != nil || len(mg.sizeBudgets) > 0,
+			docSizes:     mg.sizeReportExcludeSavings,
+		}
+		mg.depsolver = func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+			return depsolve(cacheDir, packageSets, d, arch, dopts)
+		}
 	}
 	if mg.containerResolver == nil {
 		mg.containerResolver = DefaultContainerResolver
@@ -122,6 +229,25 @@ func New(reporegistry *reporegistry.RepoRegistry, opts *Options) (*Generator, er
 	if mg.commitResolver == nil {
 		mg.commitResolver = DefaultCommitResolver
 	}
+	if mg.parentChecker == nil {
+		mg.parentChecker = DefaultOSTreeParentChecker
+	}
+	if mg.offlineBundleDir != "" && mg.offlineBundlePath == "" {
+		path, err := filepath.Abs(mg.offlineBundleDir)
+		if err != nil {
+			return nil, err
+		}
+		mg.offlineBundlePath = path
+	}
+	if mg.packageDownloader == nil {
+		mg.packageDownloader = DefaultPackageDownloader
+	}
+	if mg.containerExporter == nil {
+		mg.containerExporter = DefaultContainerExporter
+	}
+	if mg.commitMirror == nil {
+		mg.commitMirror = DefaultCommitMirror
+	}
 
 	return mg, nil
 }
@@ -143,6 +269,32 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 			return err
 		}
 	}
+	if mg.repoSnapshot != "" {
+		var unpinned []string
+		repos, unpinned, err = rpmmd.ApplySnapshot(repos, mg.repoSnapshot)
+		if err != nil {
+			return err
+		}
+		if len(unpinned) > 0 && mg.warningsOutput != nil {
+			fmt.Fprintf(mg.warningsOutput, "repositories without snapshot_url are not pinned to snapshot %q: %s\n", mg.repoSnapshot, strings.Join(unpinned, ", "))
+		}
+	}
+	localPkgs, err := bp.GetLocalPackages()
+	if err != nil {
+		return err
+	}
+	if len(localPkgs) > 0 {
+		localPkgsDir, err := os.MkdirTemp("", "local-packages-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(localPkgsDir)
+		localRepo, err := mg.prepareLocalPackages(localPkgsDir, localPkgs, imgType)
+		if err != nil {
+			return err
+		}
+		repos = append(slices.Clone(repos), localRepo)
+	}
 	// To support "user" a.k.a. "3rd party" repositories, these
 	// will have to be added to the repos with
 	// <repo_item>.PackageSets set to the "payload" pipeline names
@@ -159,10 +311,38 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 			return fmt.Errorf("Warnings during manifest creation:\n%v", warn)
 		}
 	}
+	// a missing parent would only fail the commit resolving after
+	// the depsolving
+	if imgOpts.OSTree != nil {
+		if err := mg.parentChecker(*imgOpts.OSTree); err != nil {
+			return err
+		}
+	}
 	depsolved, err := mg.depsolver(mg.cacheDir, preManifest.GetPackageSetChains(), dist, a.Name())
 	if err != nil {
 		return err
 	}
+	if len(localPkgs) > 0 {
+		if err := markLocalPackages(depsolved, localPkgs, imgType.PayloadPipelines()); err != nil {
+			return err
+		}
+	}
+	if mg.failOnCriticalAdvisories {
+		if err := checkAdvisoryPolicy(depsolved); err != nil {
+			return err
+		}
+	}
+	// the report is written and the budget is checked before anything
+	// else is written, so no manifest is output for an image over budget
+	if mg.sizeReportWriter != nil || mg.sizeBudgets[imgType.Name()] > 0 {
+		sizeReport, err := mg.sizeReport(preManifest.GetPackageSetChains(), preManifest.GetExcludeDocsPipelines(), depsolved, dist, imgType, a)
+		if err != nil {
+			return err
+		}
+		if err := mg.writeSizeReport(sizeReport); err != nil {
+			return err
+		}
+	}
 	containerSpecs, err := mg.containerResolver(preManifest.GetContainerSourceSpecs(), a.Name())
 	if err != nil {
 		return err
@@ -174,11 +354,25 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 	opts := &manifest.SerializeOptions{
 		RpmDownloader: mg.rpmDownloader,
 	}
-	mf, err := preManifest.Serialize(depsolved, containerSpecs, commitSpecs, opts)
+	mfDepsolved := depsolved
+	if mg.offlineBundleDir != "" {
+		mfDepsolved, containerSpecs, commitSpecs, err = mg.writeBundle(depsolved, containerSpecs, commitSpecs)
+		if err != nil {
+			return err
+		}
+		// librepo sources download from the repositories
+		opts.RpmDownloader = osbuild.RpmDownloaderCurl
+	}
+	mf, err := preManifest.Serialize(mfDepsolved, containerSpecs, commitSpecs, opts)
 	if err != nil {
 		return err
 	}
 	fmt.Fprintf(mg.out, "%s\n", mf)
+	if mg.offlineBundleDir != "" {
+		if err := os.WriteFile(filepath.Join(mg.offlineBundleDir, BundleManifestFilename), mf, 0644); err != nil {
+			return err
+		}
+	}
 
 	if mg.sbomWriter != nil {
 		// XXX: this is very similar to
@@ -196,6 +390,17 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 			imageName := fmt.Sprintf("%s-%s-%s", dist.Name(), imgType.Name(), a.Name())
 			sbomDocOutputFilename := fmt.Sprintf("%s.%s-%s.%s", imageName, pipelinePurpose, plName, defaultSBOMExt)
 
+			if snapshots := repoSnapshots(depsolvedPipeline.Repos); len(snapshots) > 0 {
+				if err := depsolvedPipeline.SBOM.AddComment("repository snapshots: " + strings.Join(snapshots, ", ")); err != nil {
+					return err
+				}
+				for _, snapshot := range snapshots {
+					if err := depsolvedPipeline.SBOM.AddAnnotation("repository snapshot: " + snapshot); err != nil {
+						return err
+					}
+				}
+			}
+
 			var buf bytes.Buffer
 			enc := json.NewEncoder(&buf)
 			if err := enc.Encode(depsolvedPipeline.SBOM.Document); err != nil {
@@ -210,6 +415,88 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 	return nil
 }
 
+// sizeReport creates the size report for the depsolved package sets
+func (mg *Generator) sizeReport(pkgSetChains map[string][]rpmmd.PackageSet, excludeDocsPipelines []string, depsolved map[string]dnfjson.DepsolveResult, dist distro.Distro, imgType distro.ImageType, a distro.Arch) (*SizeReport, error) {
+	report := NewSizeReport(dist.Name(), a.Name(), imgType.Name(), depsolved, imgType.PayloadPipelines())
+
+	if mg.sizeReportExcludeSavings {
+		withoutExcludes := make(map[string][]rpmmd.PackageSet, len(pkgSetChains))
+		for name, chain := range pkgSetChains {
+			withoutExcludes[name] = make([]rpmmd.PackageSet, len(chain))
+			for idx, pkgSet := range chain {
+				pkgSet.Exclude = nil
+				withoutExcludes[name][idx] = pkgSet
+			}
+		}
+		depsolvedWithoutExcludes, err := mg.depsolver(mg.cacheDir, withoutExcludes, dist, a.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error depsolving without excludes: %w", err)
+		}
+		for name, ps := range report.PackageSets {
+			unexcluded := newPackageSetSize(depsolvedWithoutExcludes[name].Packages)
+			savings := int64(unexcluded.InstallSize) - int64(ps.InstallSize)
+			ps.ExcludeSavings = &savings
+			if slices.Contains(excludeDocsPipelines, name) {
+				var docSavings uint64
+				for _, pkg := range ps.Packages {
+					docSavings += pkg.DocSize
+				}
+				ps.ExcludeDocsSavings = &docSavings
+			}
+			report.PackageSets[name] = ps
+		}
+	}
+
+	if mg.previousSizeReport != nil {
+		report.Diff = report.Compare(mg.previousSizeReport)
+	}
+	return report, nil
+}
+
+// writeSizeReport passes the report to the SizeReportWriter and checks it
+// against the size budget of the image type
+func (mg *Generator) writeSizeReport(report *SizeReport) error {
+	if mg.sizeReportWriter != nil {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+		filename := fmt.Sprintf("%s-%s-%s.size-report.json", report.Distro, report.ImageType, report.Arch)
+		if err := mg.sizeReportWriter(filename, &buf); err != nil {
+			return err
+		}
+	}
+
+	if budget := mg.sizeBudgets[report.ImageType]; budget > 0 && report.PayloadSize > budget {
+		return &SizeBudgetError{
+			ImageType:   report.ImageType,
+			PayloadSize: report.PayloadSize,
+			Budget:      budget,
+		}
+	}
+	return nil
+}
+
+// repoSnapshots describes the snapshots of the given repositories as
+// "<name>=<snapshot>", sorted by name
+func repoSnapshots(repos []rpmmd.RepoConfig) []string {
+	var snapshots []string
+	for _, repo := range repos {
+		if repo.Snapshot == "" {
+			continue
+		}
+		name := repo.Name
+		if name == "" {
+			name = repo.Id
+		}
+		snapshots = append(snapshots, fmt.Sprintf("%s=%s", name, repo.Snapshot))
+	}
+	slices.Sort(snapshots)
+	return slices.Compact(snapshots)
+}
+
 func xdgCacheHome() (string, error) {
 	xdgCacheHome := os.Getenv("XDG_CACHE_HOME")
 	if xdgCacheHome != "" {
@@ -226,6 +513,24 @@ func xdgCacheHome() (string, error) {
 // It should rarely be necessary to use it directly and will be used
 // by default by manifestgen (unless overriden)
 func DefaultDepsolver(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+	return depsolve(cacheDir, packageSets, d, arch, depsolveOptions{})
+}
+
+// depsolveOptions select the optional data that the default depsolver adds to
+// the results.
+type depsolveOptions struct {
+	// annotate the results with the advisories that affect the selected
+	// packages
+	advisories bool
+
+	// set the installed size of the packages
+	packageSizes bool
+
+	// set the documentation size of the packages, requires packageSizes
+	docSizes bool
+}
+
+func depsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string, opts depsolveOptions) (map[string]dnfjson.DepsolveResult, error) {
 	if cacheDir == "" {
 		xdgCacheHomeDir, err := xdgCacheHome()
 		if err != nil {
@@ -235,6 +540,8 @@ func DefaultDepsolver(cacheDir string, packageSets map[string][]rpmmd.PackageSet
 	}
 
 	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), arch, d.Name(), cacheDir)
+	solver.SetAdvisories(opts.advisories)
+	solver.SetPackageSizes(opts.packageSizes, opts.docSizes)
 	depsolvedSets := make(map[string]dnfjson.DepsolveResult)
 	for name, pkgSet := range packageSets {
 		// Always generate Spdx SBOMs for now, this makes the
@@ -297,6 +604,18 @@ func DefaultCommitResolver(commitSources map[string][]ostree.SourceSpec) (map[st
 	return commits, nil
 }
 
+// DefaultOSTreeParentChecker provides a default implementation for
+// checking the ostree parent of the image options, see
+// ostree.ImageOptions.CheckParent.
+// It should rarely be necessary to use it directly and will be used
+// by default by manifestgen (unless overriden)
+func DefaultOSTreeParentChecker(options ostree.ImageOptions) error {
+	if err := options.CheckParent(nil, ""); err != nil {
+		return fmt.Errorf("error checking the ostree parent: %w", err)
+	}
+	return nil
+}
+
 type (
 	DepsolveFunc func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error)
 
@@ -304,5 +623,21 @@ type (
 
 	CommitResolverFunc func(commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error)
 
+	OSTreeParentCheckerFunc func(options ostree.ImageOptions) error
+
 	SBOMWriterFunc func(filename string, content io.Reader, docType sbom.StandardType) error
+
+	SizeReportWriterFunc func(filename string, content io.Reader) error
+
+	// PackageDownloaderFunc downloads a package to dest, the repo is
+	// the repository of the package if it is known
+	PackageDownloaderFunc func(pkg rpmmd.PackageSpec, repo *rpmmd.RepoConfig, dest string) error
+
+	// ContainerExporterFunc exports a container image to an
+	// oci-archive at dest
+	ContainerExporterFunc func(spec container.Spec, dest string) error
+
+	// CommitMirrorFunc pulls an ostree commit into the archive
+	// repository at repoPath, creating it if needed
+	CommitMirrorFunc func(spec ostree.CommitSpec, repoPath string) error
 )
@@ -23,6 +23,7 @@ import (
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/osbuild/manifesttest"
 	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/ostree/mock_ostree_repo"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/sbom"
 	testrepos "github.com/osbuild/images/test/data/repositories"
@@ -248,6 +249,86 @@ func TestManifestGeneratorContainers(t *testing.T) {
 	assert.Contains(t, osbuildManifest.String(), "resolved-cnt-"+fakeContainerSource)
 }
 
+func TestManifestGeneratorOSTreeParent(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:centos-9", "type:edge-commit", "arch:x86_64")
+
+	repo := mock_ostree_repo.Setup("centos/9/x86_64/edge")
+	defer repo.TearDown()
+
+	for _, tc := range []struct {
+		parent string
+		err    string
+	}{
+		{"centos/9/x86_64/edge", ""},
+		{"centos/9/x86_64/missing", fmt.Sprintf(`error checking the ostree parent: ostree parent ref or commit "centos/9/x86_64/missing" does not exist in %q`, repo.Server.URL)},
+	} {
+		t.Run(tc.parent, func(t *testing.T) {
+			depsolved := false
+			opts := &manifestgen.Options{
+				Output: io.Discard,
+				Depsolver: func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+					depsolved = true
+					return fakeDepsolve(cacheDir, packageSets, d, arch)
+				},
+				CommitResolver:    fakeCommitResolver,
+				ContainerResolver: panicContainerResolver,
+			}
+			imageOpts := &distro.ImageOptions{
+				OSTree: &ostree.ImageOptions{
+					URL:       repo.Server.URL,
+					ParentRef: tc.parent,
+				},
+			}
+			mg, err := manifestgen.New(repos, opts)
+			require.NoError(t, err)
+			err = mg.Generate(&blueprint.Blueprint{}, res.Distro, res.ImgType, res.Arch, imageOpts)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				assert.True(t, depsolved)
+				return
+			}
+			// the missing parent fails before the depsolving
+			assert.EqualError(t, err, tc.err)
+			assert.False(t, depsolved)
+		})
+	}
+}
+
+func TestManifestGeneratorContainersInstallPolicy(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	res := filterOne(t, repos, "distro:fedora-41", "type:qcow2", "arch:x86_64")
+
+	var osbuildManifest bytes.Buffer
+	opts := &manifestgen.Options{
+		Output:            &osbuildManifest,
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: fakeContainerResolver,
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{
+				Source: "registry.example.com/signed",
+				Verify: &blueprint.ContainerVerify{
+					SigstoreKeys: []string{"-----BEGIN PUBLIC KEY-----"},
+					Install:      true,
+				},
+			},
+		},
+	}
+	err = mg.Generate(&bp, res.Distro, res.ImgType, res.Arch, nil)
+	require.NoError(t, err)
+
+	// the policy and the registries.d configuration are part of the image
+	assert.Contains(t, osbuildManifest.String(), `"to":"tree:///etc/containers/policy.json"`)
+	assert.Contains(t, osbuildManifest.String(), `"to":"tree:///etc/containers/registries.d/osbuild-0.yaml"`)
+}
+
 func TestManifestGeneratorDepsolveWithSbomWriter(t *testing.T) {
 	repos, err := testrepos.New()
 	assert.NoError(t, err)
@@ -374,6 +455,83 @@ func TestManifestGeneratorOverrideRepos(t *testing.T) {
 	}
 }
 
+func TestManifestGeneratorRepoSnapshot(t *testing.T) {
+	repos, err := testrepos.New()
+	assert.NoError(t, err)
+	fac := distrofactory.NewDefault()
+
+	filter, err := imagefilter.New(fac, repos)
+	assert.NoError(t, err)
+	res, err := filter.Filter("distro:centos-9", "type:qcow2", "arch:x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(res))
+
+	// like the real depsolver, return the repositories that were used
+	depsolver := func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+		depsolved, err := fakeDepsolve(cacheDir, packageSets, d, arch)
+		if err != nil {
+			return nil, err
+		}
+		for name, res := range depsolved {
+			res.Repos = packageSets[name][0].Repositories
+			depsolved[name] = res
+		}
+		return depsolved, nil
+	}
+
+	var osbuildManifest bytes.Buffer
+	generatedSboms := map[string]string{}
+	opts := &manifestgen.Options{
+		Output:    &osbuildManifest,
+		Depsolver: depsolver,
+		OverrideRepos: []rpmmd.RepoConfig{
+			{
+				Name:        "snapshot_repo",
+				Metalink:    "http://example.com/metalink",
+				SnapshotURL: "http://example.com/snapshots/{snapshot}/repo",
+			},
+		},
+		RepoSnapshot: "2024-05-01",
+		SBOMWriter: func(filename string, content io.Reader, docType sbom.StandardType) error {
+			b, err := io.ReadAll(content)
+			assert.NoError(t, err)
+			generatedSboms[filename] = strings.TrimSpace(string(b))
+			return nil
+		},
+	}
+	mg, err := manifestgen.New(repos, opts)
+	assert.NoError(t, err)
+
+	var bp blueprint.Blueprint
+	err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	require.NoError(t, err)
+	assert.Contains(t, osbuildManifest.String(), "http://example.com/snapshots/2024-05-01/repo/kernel.rpm")
+	assert.NotContains(t, osbuildManifest.String(), "http://example.com/metalink")
+	var osSbom struct {
+		Comment     string `json:"comment"`
+		Annotations []struct {
+			AnnotationType string `json:"annotationType"`
+			Comment        string `json:"comment"`
+		} `json:"annotations"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(generatedSboms["centos-9-qcow2-x86_64.image-os.spdx.json"]), &osSbom))
+	assert.Equal(t, "repository snapshots: snapshot_repo=2024-05-01", osSbom.Comment)
+	require.Len(t, osSbom.Annotations, 1)
+	assert.Equal(t, "OTHER", osSbom.Annotations[0].AnnotationType)
+	assert.Equal(t, "repository snapshot: snapshot_repo=2024-05-01", osSbom.Annotations[0].Comment)
+
+	// repositories without snapshot_url are not pinned
+	var warnings bytes.Buffer
+	osbuildManifest.Reset()
+	opts.WarningsOutput = &warnings
+	opts.OverrideRepos = append(opts.OverrideRepos, rpmmd.RepoConfig{Name: "live_repo", BaseURLs: []string{"http://example.com/live"}})
+	mg, err = manifestgen.New(repos, opts)
+	assert.NoError(t, err)
+	err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "repositories without snapshot_url are not pinned to snapshot \"2024-05-01\": live_repo\n", warnings.String())
+}
+
 func TestManifestGeneratorUseBootstrapContainer(t *testing.T) {
 	repos, err := testrepos.New()
 	assert.NoError(t, err)
@@ -0,0 +1,293 @@
+package manifestgen
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// PackageSize is the installed size of a single depsolved package
+type PackageSize struct {
+	Name        string `json:"name"`
+	Arch        string `json:"arch"`
+	EVR         string `json:"evr"`
+	InstallSize uint64 `json:"install_size"`
+
+	// Size of the documentation of the package, only set if requested
+	// with Options.SizeReportExcludeSavings
+	DocSize uint64 `json:"doc_size,omitempty"`
+}
+
+func (p PackageSize) key() string {
+	return p.Name + "." + p.Arch
+}
+
+// PackageSetSize summarizes the estimated installed size of the packages of
+// one pipeline.
+type PackageSetSize struct {
+	// Estimated installed size of all packages
+	InstallSize uint64 `json:"install_size"`
+
+	// All packages, sorted by size (largest first) and name
+	Packages []PackageSize `json:"packages"`
+
+	// Additional installed size the packages would have without the excludes
+	// of the package sets. Only set if requested with
+	// Options.SizeReportExcludeSavings.
+	ExcludeSavings *int64 `json:"exclude_savings,omitempty"`
+
+	// Size of the documentation that is not installed because the
+	// pipeline excludes documentation (ExcludeDocs). Only set if
+	// requested with Options.SizeReportExcludeSavings and the pipeline
+	// excludes documentation.
+	ExcludeDocsSavings *uint64 `json:"exclude_docs_savings,omitempty"`
+}
+
+// Top returns the n largest packages of the package set.
+func (ps PackageSetSize) Top(n int) []PackageSize {
+	return ps.Packages[:min(n, len(ps.Packages))]
+}
+
+// SizeReport is an estimate of the installed size of the packages of an
+// image, based on the installed sizes found in the repository metadata. It
+// does not take files that are removed or added during the build into
+// account, the documentation that is excluded during package installation is
+// only reported as PackageSetSize.ExcludeDocsSavings.
+type SizeReport struct {
+	Distro    string `json:"distro"`
+	Arch      string `json:"arch"`
+	ImageType string `json:"image_type"`
+
+	// Installed size of the packages of each pipeline, keyed by the name
+	// of the pipeline
+	PackageSets map[string]PackageSetSize `json:"package_sets"`
+
+	// Total installed size of the packages of the payload pipelines
+	PayloadSize uint64 `json:"payload_size"`
+
+	// Differences to a previous report, only set if Options.PreviousSizeReport
+	// is set
+	Diff *SizeReportDiff `json:"diff,omitempty"`
+}
+
+// PackageSizeChange is the size change of a package between two reports
+type PackageSizeChange struct {
+	Name   string `json:"name"`
+	Arch   string `json:"arch"`
+	OldEVR string `json:"old_evr"`
+	NewEVR string `json:"new_evr"`
+	Delta  int64  `json:"delta"`
+}
+
+// PackageSetSizeDiff are the differences of a package set between two reports
+type PackageSetSizeDiff struct {
+	// Change of the total installed size
+	Delta int64 `json:"delta"`
+
+	Added   []PackageSize       `json:"added,omitempty"`
+	Removed []PackageSize       `json:"removed,omitempty"`
+	Changed []PackageSizeChange `json:"changed,omitempty"`
+}
+
+// SizeReportDiff are the differences between two reports, keyed by the name
+// of the pipeline
+type SizeReportDiff struct {
+	PackageSets map[string]PackageSetSizeDiff `json:"package_sets"`
+
+	// Change of the total installed size of the payload pipelines
+	PayloadDelta int64 `json:"payload_delta"`
+}
+
+// SizeBudgetError is returned by the Generator if the payload of an image
+// exceeds its size budget.
+type SizeBudgetError struct {
+	ImageType   string
+	PayloadSize uint64
+	Budget      uint64
+}
+
+func (e *SizeBudgetError) Error() string {
+	return fmt.Sprintf("installed size of the %s payload packages (%s) exceeds the budget of %s", e.ImageType, formatSize(e.PayloadSize), formatSize(e.Budget))
+}
+
+func formatSize(size uint64) string {
+	if size >= datasizes.MiB {
+		return fmt.Sprintf("%.1f MiB", float64(size)/datasizes.MiB)
+	}
+	return fmt.Sprintf("%.1f KiB", float64(size)/datasizes.KiB)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func newPackageSetSize(pkgs []rpmmd.PackageSpec) PackageSetSize {
+	var ps PackageSetSize
+	ps.Packages = make([]PackageSize, 0, len(pkgs))
+	seen := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		// count packages that appear in more than one transaction only once
+		if seen[pkg.GetNEVRA()] {
+			continue
+		}
+		seen[pkg.GetNEVRA()] = true
+		evr := fmt.Sprintf("%s-%s", pkg.Version, pkg.Release)
+		if pkg.Epoch != 0 {
+			evr = fmt.Sprintf("%d:%s", pkg.Epoch, evr)
+		}
+		ps.Packages = append(ps.Packages, PackageSize{
+			Name:        pkg.Name,
+			Arch:        pkg.Arch,
+			EVR:         evr,
+			InstallSize: pkg.InstallSize,
+			DocSize:     pkg.DocSize,
+		})
+		ps.InstallSize += pkg.InstallSize
+	}
+	slices.SortFunc(ps.Packages, func(a, b PackageSize) int {
+		if c := cmp.Compare(b.InstallSize, a.InstallSize); c != 0 {
+			return c
+		}
+		return strings.Compare(a.key(), b.key())
+	})
+	return ps
+}
+
+// NewSizeReport creates a size report from the depsolved package sets of an
+// image. The payload size is the sum of the sizes of the given payload
+// pipelines.
+func NewSizeReport(distroName, archName, imgTypeName string, depsolved map[string]dnfjson.DepsolveResult, payloadPipelines []string) *SizeReport {
+	report := &SizeReport{
+		Distro:      distroName,
+		Arch:        archName,
+		ImageType:   imgTypeName,
+		PackageSets: make(map[string]PackageSetSize, len(depsolved)),
+	}
+	for name, res := range depsolved {
+		ps := newPackageSetSize(res.Packages)
+		report.PackageSets[name] = ps
+		if slices.Contains(payloadPipelines, name) {
+			report.PayloadSize += ps.InstallSize
+		}
+	}
+	return report
+}
+
+// LoadSizeReport reads a size report that was written by the Generator.
+func LoadSizeReport(path string) (*SizeReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report SizeReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("cannot parse size report %q: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Compare returns the differences between the report and a previous report.
+func (r *SizeReport) Compare(prev *SizeReport) *SizeReportDiff {
+	diff := &SizeReportDiff{
+		PackageSets:  make(map[string]PackageSetSizeDiff),
+		PayloadDelta: int64(r.PayloadSize) - int64(prev.PayloadSize),
+	}
+
+	names := make(map[string]bool)
+	for name := range r.PackageSets {
+		names[name] = true
+	}
+	for name := range prev.PackageSets {
+		names[name] = true
+	}
+	for name := range names {
+		cur := r.PackageSets[name]
+		old := prev.PackageSets[name]
+		psDiff := PackageSetSizeDiff{
+			Delta: int64(cur.InstallSize) - int64(old.InstallSize),
+		}
+
+		oldPkgs := make(map[string]PackageSize, len(old.Packages))
+		for _, pkg := range old.Packages {
+			oldPkgs[pkg.key()] = pkg
+		}
+		for _, pkg := range cur.Packages {
+			oldPkg, ok := oldPkgs[pkg.key()]
+			if !ok {
+				psDiff.Added = append(psDiff.Added, pkg)
+				continue
+			}
+			delete(oldPkgs, pkg.key())
+			if oldPkg.InstallSize != pkg.InstallSize || oldPkg.EVR != pkg.EVR {
+				psDiff.Changed = append(psDiff.Changed, PackageSizeChange{
+					Name:   pkg.Name,
+					Arch:   pkg.Arch,
+					OldEVR: oldPkg.EVR,
+					NewEVR: pkg.EVR,
+					Delta:  int64(pkg.InstallSize) - int64(oldPkg.InstallSize),
+				})
+			}
+		}
+		// keep the order of the previous report for removed packages
+		for _, pkg := range old.Packages {
+			if _, ok := oldPkgs[pkg.key()]; ok {
+				psDiff.Removed = append(psDiff.Removed, pkg)
+			}
+		}
+		slices.SortFunc(psDiff.Changed, func(a, b PackageSizeChange) int {
+			if c := cmp.Compare(abs(b.Delta), abs(a.Delta)); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Name+"."+a.Arch, b.Name+"."+b.Arch)
+		})
+		diff.PackageSets[name] = psDiff
+	}
+	return diff
+}
+
+// WriteSummary writes a human readable summary of the report with the n
+// largest packages of each package set.
+func (r *SizeReport) WriteSummary(w io.Writer, n int) error {
+	names := make([]string, 0, len(r.PackageSets))
+	for name := range r.PackageSets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s: payload %s", r.Distro, r.Arch, r.ImageType, formatSize(r.PayloadSize))
+	if r.Diff != nil {
+		fmt.Fprintf(&b, " (%+d bytes)", r.Diff.PayloadDelta)
+	}
+	fmt.Fprintln(&b)
+	for _, name := range names {
+		ps := r.PackageSets[name]
+		fmt.Fprintf(&b, "  %s: %d packages, %s\n", name, len(ps.Packages), formatSize(ps.InstallSize))
+		if ps.ExcludeSavings != nil {
+			fmt.Fprintf(&b, "    excludes save %d bytes\n", *ps.ExcludeSavings)
+		}
+		if ps.ExcludeDocsSavings != nil {
+			fmt.Fprintf(&b, "    excluding documentation saves %d bytes\n", *ps.ExcludeDocsSavings)
+		}
+		for _, pkg := range ps.Top(n) {
+			fmt.Fprintf(&b, "    %-40s %12s\n", pkg.key(), formatSize(pkg.InstallSize))
+		}
+		if r.Diff != nil {
+			psDiff := r.Diff.PackageSets[name]
+			fmt.Fprintf(&b, "    changed by %+d bytes: %d added, %d removed, %d changed\n", psDiff.Delta, len(psDiff.Added), len(psDiff.Removed), len(psDiff.Changed))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
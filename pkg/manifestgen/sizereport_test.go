@@ -0,0 +1,194 @@
+package manifestgen_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distrofactory"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/imagefilter"
+	"github.com/osbuild/images/pkg/manifestgen"
+	"github.com/osbuild/images/pkg/rpmmd"
+	testrepos "github.com/osbuild/images/test/data/repositories"
+)
+
+// fakeDepsolveWithSizes sets the install size of each package to 1000 times
+// the length of its name and its documentation size to 100, and pulls in an
+// additional "unexcluded" package for package sets without excludes.
+func fakeDepsolveWithSizes(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+	depsolved, err := fakeDepsolve(cacheDir, packageSets, d, arch)
+	if err != nil {
+		return nil, err
+	}
+	for name, res := range depsolved {
+		for idx := range res.Packages {
+			res.Packages[idx].InstallSize = uint64(len(res.Packages[idx].Name) * 1000)
+			res.Packages[idx].DocSize = 100
+		}
+		if len(packageSets[name][0].Exclude) == 0 {
+			unexcluded := res.Packages[0]
+			unexcluded.Name = "unexcluded"
+			unexcluded.Checksum = sha256For("unexcluded")
+			unexcluded.InstallSize = 5000
+			res.Packages = append(res.Packages, unexcluded)
+		}
+		depsolved[name] = res
+	}
+	return depsolved, nil
+}
+
+func generateSizeReport(t *testing.T, opts *manifestgen.Options) (*manifestgen.SizeReport, error) {
+	return generateSizeReportFor(t, opts, "centos-9", "qcow2")
+}
+
+func generateSizeReportFor(t *testing.T, opts *manifestgen.Options, distroName, imgTypeName string) (*manifestgen.SizeReport, error) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	filter, err := imagefilter.New(distrofactory.NewDefault(), repos)
+	require.NoError(t, err)
+	res, err := filter.Filter("distro:"+distroName, "type:"+imgTypeName, "arch:x86_64")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+
+	var report *manifestgen.SizeReport
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+	opts.Depsolver = fakeDepsolveWithSizes
+	opts.CommitResolver = panicCommitResolver
+	opts.ContainerResolver = panicContainerResolver
+	opts.SizeReportWriter = func(filename string, content io.Reader) error {
+		assert.Equal(t, distroName+"-"+imgTypeName+"-x86_64.size-report.json", filename)
+		return json.NewDecoder(content).Decode(&report)
+	}
+	mg, err := manifestgen.New(repos, opts)
+	require.NoError(t, err)
+	err = mg.Generate(&blueprint.Blueprint{}, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	return report, err
+}
+
+func TestManifestGeneratorSizeReport(t *testing.T) {
+	report, err := generateSizeReport(t, &manifestgen.Options{
+		SizeReportExcludeSavings: true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, report)
+
+	assert.Equal(t, "centos-9", report.Distro)
+	assert.Equal(t, "x86_64", report.Arch)
+	assert.Equal(t, "qcow2", report.ImageType)
+	assert.Contains(t, report.PackageSets, "build")
+	require.Contains(t, report.PackageSets, "os")
+
+	osSet := report.PackageSets["os"]
+	assert.Equal(t, osSet.InstallSize, report.PayloadSize)
+	var total uint64
+	for idx, pkg := range osSet.Packages {
+		total += pkg.InstallSize
+		if idx > 0 {
+			assert.LessOrEqual(t, pkg.InstallSize, osSet.Packages[idx-1].InstallSize)
+		}
+	}
+	assert.Equal(t, total, osSet.InstallSize)
+	assert.Len(t, osSet.Top(3), 3)
+	assert.Equal(t, osSet.Packages[0], osSet.Top(3)[0])
+
+	// the os package set of qcow2 has excludes
+	require.NotNil(t, osSet.ExcludeSavings)
+	assert.Equal(t, int64(5000), *osSet.ExcludeSavings)
+	// but does not exclude documentation
+	assert.Nil(t, osSet.ExcludeDocsSavings)
+	assert.Nil(t, report.Diff)
+
+	var summary bytes.Buffer
+	require.NoError(t, report.WriteSummary(&summary, 5))
+	assert.Contains(t, summary.String(), "centos-9 x86_64 qcow2: payload")
+}
+
+func TestManifestGeneratorSizeReportExcludeDocs(t *testing.T) {
+	report, err := generateSizeReportFor(t, &manifestgen.Options{
+		SizeReportExcludeSavings: true,
+	}, "fedora-41", "container")
+	require.NoError(t, err)
+	require.NotNil(t, report)
+
+	// the os pipeline of the container excludes documentation, the build
+	// pipeline does not
+	osSet := report.PackageSets["os"]
+	require.NotNil(t, osSet.ExcludeDocsSavings)
+	assert.Equal(t, uint64(len(osSet.Packages)*100), *osSet.ExcludeDocsSavings)
+	assert.Nil(t, report.PackageSets["build"].ExcludeDocsSavings)
+
+	var summary bytes.Buffer
+	require.NoError(t, report.WriteSummary(&summary, 5))
+	assert.Contains(t, summary.String(), fmt.Sprintf("excluding documentation saves %d bytes", *osSet.ExcludeDocsSavings))
+}
+
+func TestManifestGeneratorSizeReportDiff(t *testing.T) {
+	prev, err := generateSizeReport(t, &manifestgen.Options{})
+	require.NoError(t, err)
+
+	// simulate an older report with a smaller kernel and without a package
+	osSize := prev.PackageSets["os"]
+	var removed manifestgen.PackageSize
+	for idx, pkg := range osSize.Packages {
+		if pkg.Name == "kernel" {
+			osSize.Packages[idx].InstallSize -= 1000
+			osSize.Packages[idx].EVR = "5.14-1"
+		}
+	}
+	removed, osSize.Packages = osSize.Packages[0], osSize.Packages[1:]
+	osSize.InstallSize -= 1000 + removed.InstallSize
+	osSize.Packages = append(osSize.Packages, manifestgen.PackageSize{Name: "old-package", Arch: "noarch", EVR: "1-1", InstallSize: 3000})
+	osSize.InstallSize += 3000
+	prev.PackageSets["os"] = osSize
+	prev.PayloadSize = osSize.InstallSize
+
+	report, err := generateSizeReport(t, &manifestgen.Options{
+		PreviousSizeReport: prev,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, report.Diff)
+
+	osDiff := report.Diff.PackageSets["os"]
+	assert.Equal(t, int64(1000+removed.InstallSize-3000), osDiff.Delta)
+	assert.Equal(t, osDiff.Delta, report.Diff.PayloadDelta)
+	assert.Equal(t, []manifestgen.PackageSize{removed}, osDiff.Added)
+	assert.Equal(t, []manifestgen.PackageSize{{Name: "old-package", Arch: "noarch", EVR: "1-1", InstallSize: 3000}}, osDiff.Removed)
+	assert.Equal(t, []manifestgen.PackageSizeChange{
+		{Name: "kernel", OldEVR: "5.14-1", NewEVR: "-", Delta: 1000},
+	}, osDiff.Changed)
+	assert.Equal(t, manifestgen.PackageSetSizeDiff{}, report.Diff.PackageSets["build"])
+}
+
+func TestManifestGeneratorSizeBudget(t *testing.T) {
+	var output bytes.Buffer
+	report, err := generateSizeReport(t, &manifestgen.Options{
+		Output:      &output,
+		SizeBudgets: map[string]uint64{"qcow2": 1000},
+	})
+	// the report is written even if the budget is exceeded, the manifest
+	// is not
+	require.NotNil(t, report)
+	var budgetErr *manifestgen.SizeBudgetError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, "qcow2", budgetErr.ImageType)
+	assert.Equal(t, report.PayloadSize, budgetErr.PayloadSize)
+	assert.Equal(t, uint64(1000), budgetErr.Budget)
+	assert.Empty(t, output.String())
+
+	_, err = generateSizeReport(t, &manifestgen.Options{
+		Output:      &output,
+		SizeBudgets: map[string]uint64{"qcow2": report.PayloadSize},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, output.String())
+}
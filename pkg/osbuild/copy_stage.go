@@ -2,6 +2,9 @@ package osbuild
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/osbuild/images/pkg/disk"
 )
@@ -61,6 +64,10 @@ func GenCopyFSTreeOptions(inputName, inputPipeline, filename string, pt *disk.Pa
 	[]Mount,
 ) {
 
+	if root := pt.FindMountable("/"); root != nil && root.GetFSType() == "erofs" {
+		return genCopyReadOnlyRootOptions(inputName, filename, pt)
+	}
+
 	fsRootMntName, mounts, devices, err := GenMountsDevicesFromPT(filename, pt)
 	if err != nil {
 		panic(err)
@@ -77,3 +84,57 @@ func GenCopyFSTreeOptions(inputName, inputPipeline, filename string, pt *disk.Pa
 
 	return &options, devices, mounts
 }
+
+// genCopyReadOnlyRootOptions creates the options, devices, and mounts for an
+// org.osbuild.copy stage for a partition table with an erofs root filesystem.
+// The root filesystem is written as a whole, see GenWriteErofsStages, so only
+// the trees of the other mountpoints are copied to their filesystems. Their
+// content is also part of the root filesystem, which keeps the mountpoints on
+// the read-only root, but is hidden by the mounts at runtime.
+func genCopyReadOnlyRootOptions(inputName, filename string, pt *disk.PartitionTable) (
+	*CopyStageOptions,
+	map[string]Device,
+	[]Mount,
+) {
+	devices := make(map[string]Device, len(pt.Partitions))
+	mounts := make([]Mount, 0, len(pt.Partitions))
+	_ = pt.ForEachMountable(func(mnt disk.Mountable, path []disk.Entity) error {
+		if mnt.GetFSType() == "erofs" {
+			return nil
+		}
+		stageDevices, leafDeviceName := getDevices(path, filename, false)
+		mount, err := genOsbuildMount(leafDeviceName, mnt)
+		if err != nil {
+			panic(err)
+		}
+		mounts = append(mounts, *mount)
+		for devName, device := range stageDevices {
+			devices[devName] = device
+		}
+		return nil
+	})
+
+	// parents are sorted before their children, see GenMountsDevicesFromPT
+	sort.Slice(mounts, func(i, j int) bool {
+		return mounts[i].Target < mounts[j].Target
+	})
+
+	options := CopyStageOptions{}
+	var copied []string
+	for _, mount := range mounts {
+		nested := slices.ContainsFunc(copied, func(target string) bool {
+			return strings.HasPrefix(mount.Target, target+"/")
+		})
+		if nested {
+			// copied with the tree of the parent mountpoint
+			continue
+		}
+		copied = append(copied, mount.Target)
+		options.Paths = append(options.Paths, CopyStagePath{
+			From: fmt.Sprintf("input://%s%s/", inputName, mount.Target),
+			To:   fmt.Sprintf("mount://%s/", mount.Name),
+		})
+	}
+
+	return &options, devices, mounts
+}
@@ -2,6 +2,7 @@ package osbuild
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 
@@ -143,6 +144,16 @@ func GenImageKernelOptions(pt *disk.PartitionTable, mountUnits bool) (string, []
 		case *disk.LUKSContainer:
 			karg := "luks.uuid=" + ent.UUID
 			cmdline = append(cmdline, karg)
+		case *disk.Verity:
+			// the root hash is only known once the hash tree has been
+			// formatted and is added separately, see GenDMVerityStages
+			if ent.DataMountpoint == "/" {
+				data, hash := pt.FindVerity("/")
+				cmdline = append(cmdline,
+					"systemd.verity_root_data=/dev/disk/by-partuuid/"+strings.ToLower(data.UUID),
+					"systemd.verity_root_hash=/dev/disk/by-partuuid/"+strings.ToLower(hash.UUID),
+				)
+			}
 		case *disk.BtrfsSubvolume:
 			if ent.Mountpoint == "/" && !mountUnits {
 				// if we're using mount units, the rootflags will be added
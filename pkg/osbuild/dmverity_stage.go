@@ -0,0 +1,122 @@
+package osbuild
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/disk"
+)
+
+// VerityRootHashAddon is the path of the UKI addon with the root hash of the
+// dm-verity protected root filesystem, relative to the ESP. systemd-stub
+// loads global addons from this directory for every UKI on the ESP.
+const VerityRootHashAddon = "/loader/addons/verity-roothash.addon.efi"
+
+// DMVerityStageOptions describe the dm-verity hash tree to format on the hash
+// device for the data device. The filesystem on the data device must not be
+// modified after the stage ran.
+type DMVerityStageOptions struct {
+	// Location of a UKI addon to create that adds the root hash of the
+	// hash tree to the kernel command line (roothash=). It is a mount URL,
+	// e.g. mount://boot-efi/loader/addons/verity-roothash.addon.efi. Note
+	// that with Secure Boot enabled, systemd-stub only loads signed addons,
+	// see GenVerityRootHashAddonSignStage.
+	RootHashAddon string `json:"root_hash_addon,omitempty"`
+}
+
+func (DMVerityStageOptions) isStageOptions() {}
+
+// NewDMVerityStage creates a new org.osbuild.dmverity stage. The devices map
+// must contain a "data_device" and a "hash_device".
+func NewDMVerityStage(options *DMVerityStageOptions, devices map[string]Device, mounts []Mount) *Stage {
+	for _, name := range []string{"data_device", "hash_device"} {
+		if _, ok := devices[name]; !ok {
+			panic(fmt.Sprintf("dmverity stage requires a %q", name))
+		}
+	}
+	return &Stage{
+		Type:    "org.osbuild.dmverity",
+		Options: options,
+		Devices: devices,
+		Mounts:  mounts,
+	}
+}
+
+// GenDMVerityStages generates a org.osbuild.dmverity stage for each dm-verity
+// hash partition in the partition table. For the root filesystem, the root
+// hash is written as a UKI addon to the ESP, which therefore has to be part
+// of the partition table.
+func GenDMVerityStages(pt *disk.PartitionTable, filename string) []*Stage {
+	var stages []*Stage
+
+	for idx := range pt.Partitions {
+		verity, ok := pt.Partitions[idx].Payload.(*disk.Verity)
+		if !ok {
+			continue
+		}
+		data, hash := pt.FindVerity(verity.DataMountpoint)
+		if data == nil {
+			panic(fmt.Sprintf("no data partition for the dm-verity hash partition of %q; this is a programming error", verity.DataMountpoint))
+		}
+
+		// the devices are named after the role they have for the stage,
+		// see the stage schema for reference
+		devices := make(map[string]Device)
+		dataDevices, dataName := getDevices([]disk.Entity{pt, data}, filename, true)
+		devices["data_device"] = dataDevices[dataName]
+		hashDevices, hashName := getDevices([]disk.Entity{pt, hash}, filename, true)
+		devices["hash_device"] = hashDevices[hashName]
+
+		options := &DMVerityStageOptions{}
+		var mounts []Mount
+		if verity.DataMountpoint == "/" {
+			espDevices, esp := genESPMount(pt, filename)
+			for name, device := range espDevices {
+				devices[name] = device
+			}
+			mounts = append(mounts, *esp)
+			options.RootHashAddon = fmt.Sprintf("mount://%s%s", esp.Name, VerityRootHashAddon)
+		}
+
+		stages = append(stages, NewDMVerityStage(options, devices, mounts))
+	}
+
+	return stages
+}
+
+// GenVerityRootHashAddonSignStage generates an org.osbuild.sbsign stage that
+// signs the root hash addon written by GenDMVerityStages. With Secure Boot
+// enabled, systemd-stub only loads addons signed by a trusted key. The
+// certificate data must be added to the inline data of the manifest by the
+// caller.
+func GenVerityRootHashAddonSignStage(pt *disk.PartitionTable, filename string, certData string) *Stage {
+	if data, _ := pt.FindVerity("/"); data == nil {
+		panic("no dm-verity protected root filesystem in the partition table; this is a programming error")
+	}
+	devices, esp := genESPMount(pt, filename)
+	options := NewSbsignStageOptions([]string{fmt.Sprintf("mount://%s%s", esp.Name, VerityRootHashAddon)})
+	stage := NewSbsignStage(options, certData)
+	stage.Devices = devices
+	stage.Mounts = []Mount{*esp}
+	return stage
+}
+
+// genESPMount returns the devices and the mount for the ESP of the partition
+// table.
+func genESPMount(pt *disk.PartitionTable, filename string) (map[string]Device, *Mount) {
+	var espPath []disk.Entity
+	_ = pt.ForEachMountable(func(mnt disk.Mountable, path []disk.Entity) error {
+		if mnt.GetMountpoint() == "/boot/efi" {
+			espPath = path
+		}
+		return nil
+	})
+	if espPath == nil {
+		panic("dm-verity protected root filesystem requires an ESP for the root hash; this is a programming error")
+	}
+	devices, espName := getDevices(espPath, filename, false)
+	esp, err := genOsbuildMount(espName, espPath[len(espPath)-1].(disk.Mountable))
+	if err != nil {
+		panic(err)
+	}
+	return devices, esp
+}
@@ -0,0 +1,118 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func testVerityPartitionTable() *disk.PartitionTable {
+	return &disk.PartitionTable{
+		Type: disk.PT_GPT,
+		Partitions: []disk.Partition{
+			{
+				Start: 1 * datasizes.MebiByte,
+				Size:  200 * datasizes.MebiByte,
+				Type:  disk.EFISystemPartitionGUID,
+				UUID:  disk.EFISystemPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:       "vfat",
+					UUID:       disk.EFIFilesystemUUID,
+					Mountpoint: "/boot/efi",
+				},
+			},
+			{
+				Start: 201 * datasizes.MebiByte,
+				Size:  20 * datasizes.MebiByte,
+				Type:  disk.RootVerityPartitionX86_64GUID,
+				UUID:  "F6A1DC8F-1B50-4A2B-8C4B-3D6F2E4C8A11",
+				Payload: &disk.Verity{
+					DataMountpoint: "/",
+				},
+			},
+			{
+				Start: 221 * datasizes.MebiByte,
+				Size:  2 * datasizes.GibiByte,
+				Type:  disk.RootPartitionX86_64GUID,
+				UUID:  disk.RootPartitionUUID,
+				Payload: &disk.Filesystem{
+					Type:         "ext4",
+					UUID:         "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75",
+					Mountpoint:   "/",
+					FSTabOptions: "ro",
+				},
+			},
+		},
+	}
+}
+
+func TestGenDMVerityStages(t *testing.T) {
+	pt := testVerityPartitionTable()
+
+	stages := GenDMVerityStages(pt, "disk.img")
+	require.Len(t, stages, 1)
+	stage := stages[0]
+
+	assert.Equal(t, "org.osbuild.dmverity", stage.Type)
+	assert.Equal(t, &DMVerityStageOptions{
+		RootHashAddon: "mount://boot-efi/loader/addons/verity-roothash.addon.efi",
+	}, stage.Options)
+
+	dataDevice := stage.Devices["data_device"].Options.(*LoopbackDeviceOptions)
+	assert.Equal(t, pt.BytesToSectors(221*datasizes.MebiByte), dataDevice.Start)
+	hashDevice := stage.Devices["hash_device"].Options.(*LoopbackDeviceOptions)
+	assert.Equal(t, pt.BytesToSectors(201*datasizes.MebiByte), hashDevice.Start)
+	assert.Contains(t, stage.Devices, "boot-efi")
+
+	require.Len(t, stage.Mounts, 1)
+	assert.Equal(t, "/boot/efi", stage.Mounts[0].Target)
+}
+
+func TestGenDMVerityStagesNoVerity(t *testing.T) {
+	pt := testVerityPartitionTable()
+	pt.Partitions = append(pt.Partitions[:1], pt.Partitions[2])
+
+	assert.Empty(t, GenDMVerityStages(pt, "disk.img"))
+}
+
+func TestGenImageKernelOptionsVerity(t *testing.T) {
+	pt := testVerityPartitionTable()
+
+	rootUUID, cmdline, err := GenImageKernelOptions(pt, false)
+	require.NoError(t, err)
+	assert.Equal(t, "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75", rootUUID)
+	assert.Equal(t, []string{
+		"systemd.verity_root_data=/dev/disk/by-partuuid/6264d520-3fb9-423f-8ab8-7a0a8e3d3562",
+		"systemd.verity_root_hash=/dev/disk/by-partuuid/f6a1dc8f-1b50-4a2b-8c4b-3d6f2e4c8a11",
+	}, cmdline)
+}
+
+func TestNewDMVerityStageMissingDevice(t *testing.T) {
+	assert.PanicsWithValue(t, `dmverity stage requires a "hash_device"`, func() {
+		NewDMVerityStage(&DMVerityStageOptions{}, map[string]Device{"data_device": {}}, nil)
+	})
+}
+
+func TestGenVerityRootHashAddonSignStage(t *testing.T) {
+	pt := testVerityPartitionTable()
+
+	stage := GenVerityRootHashAddonSignStage(pt, "disk.img", "42\n")
+	assert.Equal(t, "org.osbuild.sbsign", stage.Type)
+	assert.Equal(t, NewSbsignStageOptions([]string{"mount://boot-efi/loader/addons/verity-roothash.addon.efi"}), stage.Options)
+	assert.Contains(t, stage.Devices, "boot-efi")
+	require.Len(t, stage.Mounts, 1)
+	assert.Equal(t, "/boot/efi", stage.Mounts[0].Target)
+}
+
+func TestGenVerityRootHashAddonSignStageNoVerity(t *testing.T) {
+	pt := testVerityPartitionTable()
+	pt.Partitions = append(pt.Partitions[:1], pt.Partitions[2])
+
+	assert.PanicsWithValue(t, "no dm-verity protected root filesystem in the partition table; this is a programming error", func() {
+		GenVerityRootHashAddonSignStage(pt, "disk.img", "42\n")
+	})
+}
@@ -3,6 +3,9 @@ package osbuild
 type MkfsExt4StageOptions struct {
 	UUID  string `json:"uuid"`
 	Label string `json:"label,omitempty"`
+
+	// Enable fs-verity support on the filesystem
+	Verity *bool `json:"verity,omitempty"`
 }
 
 func (MkfsExt4StageOptions) isStageOptions() {}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/disk"
 )
 
@@ -12,6 +13,8 @@ import (
 //   - org.osbuild.mkfs.*: for all filesystems and btrfs volumes
 //   - org.osbuild.btrfs.subvol: for all btrfs subvolumes
 //   - org.osbuild.mkswap: for swap areas
+//
+// No stages are created for erofs filesystems, see GenWriteErofsStages.
 func GenFsStages(pt *disk.PartitionTable, filename string) []*Stage {
 	stages := make([]*Stage, 0, len(pt.Partitions))
 
@@ -45,7 +48,13 @@ func GenFsStages(pt *disk.PartitionTable, filename string) []*Stage {
 					UUID:  e.UUID,
 					Label: e.Label,
 				}
+				if e.FSVerity {
+					options.Verity = common.ToPtr(true)
+				}
 				stages = append(stages, NewMkfsExt4Stage(options, stageDevices))
+			case "erofs":
+				// created from the tree and written to the device as a
+				// whole, see GenWriteErofsStages
 			default:
 				panic(fmt.Sprintf("unknown fs type: %s", e.GetFSType()))
 			}
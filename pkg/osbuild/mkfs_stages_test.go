@@ -148,6 +148,20 @@ func TestGenFsStages(t *testing.T) {
 	}, stages)
 }
 
+func TestGenFsStagesFSVerity(t *testing.T) {
+	pt := testdisk.MakeFakePartitionTable("/", "/boot")
+	assert.NoError(t, pt.EnableFSVerity("/"))
+	stages := GenFsStages(pt, "file.img")
+	assert.Len(t, stages, 2)
+	assert.Equal(t, &MkfsExt4StageOptions{
+		UUID:   disk.RootPartitionUUID,
+		Verity: common.ToPtr(true),
+	}, stages[0].Options)
+	assert.Equal(t, &MkfsExt4StageOptions{
+		UUID: disk.DataPartitionUUID,
+	}, stages[1].Options)
+}
+
 func TestGenFsStagesBtrfs(t *testing.T) {
 	// Let's put there /extra to make sure that / and /extra creates only one btrfs partition
 	pt := testdisk.MakeFakeBtrfsPartitionTable("/", "/boot", "/boot/efi", "/extra", "swap")
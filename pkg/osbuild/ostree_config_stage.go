@@ -13,6 +13,9 @@ func (OSTreeConfigStageOptions) isStageOptions() {}
 type OSTreeConfig struct {
 	// Options concerning the sysroot
 	Sysroot *SysrootOptions `json:"sysroot,omitempty"`
+
+	// Options concerning the integrity of deployments
+	Integrity *IntegrityOptions `json:"integrity,omitempty"`
 }
 
 type SysrootOptions struct {
@@ -20,6 +23,11 @@ type SysrootOptions struct {
 	Bootloader string `json:"bootloader,omitempty"`
 }
 
+type IntegrityOptions struct {
+	// Generate a composefs image for each deployment
+	Composefs *bool `json:"composefs,omitempty"`
+}
+
 // A new org.osbuild.ostree.config stage to configure an OSTree repository
 func NewOSTreeConfigStage(options *OSTreeConfigStageOptions) *Stage {
 	return &Stage{
@@ -0,0 +1,79 @@
+package osbuild
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/osbuild/images/pkg/ostree"
+)
+
+// OSTreeSigningKeySecret is the name of the osbuild secret that provides
+// the secret key that signs ostree commits. The key is handed to the stage
+// by osbuild at build time and is never part of the manifest.
+const OSTreeSigningKeySecret = "org.osbuild.ostree.sign"
+
+type OSTreeSigningKeySecrets struct {
+	Name string `json:"name"`
+}
+
+// Options for the org.osbuild.ostree.sign stage.
+type OSTreeSignStageOptions struct {
+	// Location of the ostree repo
+	Repo string `json:"repo"`
+
+	// Ref of the commit to sign
+	Ref string `json:"ref"`
+
+	// Type of the signature, "ed25519" or "gpg"
+	Type string `json:"type"`
+
+	// ID of the GPG key to sign with (gpg only)
+	KeyID string `json:"key_id,omitempty"`
+
+	Secrets *OSTreeSigningKeySecrets `json:"secrets"`
+}
+
+func (OSTreeSignStageOptions) isStageOptions() {}
+
+func (o *OSTreeSignStageOptions) validate() error {
+	switch o.Type {
+	case ostree.SignatureTypeEd25519:
+		if o.KeyID != "" {
+			return fmt.Errorf("org.osbuild.ostree.sign: key_id is only supported for gpg signatures")
+		}
+	case ostree.SignatureTypeGPG:
+		if o.KeyID == "" {
+			return fmt.Errorf("org.osbuild.ostree.sign: key_id is required for gpg signatures")
+		}
+	default:
+		return fmt.Errorf("org.osbuild.ostree.sign: unknown signature type %q", o.Type)
+	}
+	if o.Secrets == nil || o.Secrets.Name == "" {
+		return fmt.Errorf("org.osbuild.ostree.sign: a key secret is required")
+	}
+	return nil
+}
+
+type OSTreeSignStageInputs struct {
+	PublicKey *FilesInput `json:"public-key"`
+}
+
+func (OSTreeSignStageInputs) isStageInputs() {}
+
+// NewOSTreeSignStage creates a new org.osbuild.ostree.sign stage that signs
+// the commit of a ref with the secret key of the options' secret and checks
+// the signature against the public key. The public key must be added to the
+// inline data of the manifest by the caller.
+func NewOSTreeSignStage(options *OSTreeSignStageOptions, publicKey string) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+	input := NewFilesInput(NewFilesInputSourcePlainRef([]string{
+		fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(publicKey))),
+	}))
+	return &Stage{
+		Type:    "org.osbuild.ostree.sign",
+		Options: options,
+		Inputs:  &OSTreeSignStageInputs{PublicKey: input},
+	}
+}
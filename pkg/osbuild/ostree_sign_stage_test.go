@@ -0,0 +1,58 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOSTreeSignStage(t *testing.T) {
+	stage := NewOSTreeSignStage(&OSTreeSignStageOptions{
+		Repo:    "/repo",
+		Ref:     "fedora/x86_64/iot",
+		Type:    "ed25519",
+		Secrets: &OSTreeSigningKeySecrets{Name: OSTreeSigningKeySecret},
+	}, "42\n")
+
+	data, err := json.Marshal(stage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "org.osbuild.ostree.sign",
+		"inputs": {
+			"public-key": {
+				"type": "org.osbuild.files",
+				"origin": "org.osbuild.source",
+				"references": ["sha256:084c799cd551dd1d8d5c5f9a5d593b2e931f5e36122ee5c793c1d08a19839cc0"]
+			}
+		},
+		"options": {
+			"repo": "/repo",
+			"ref": "fedora/x86_64/iot",
+			"type": "ed25519",
+			"secrets": {
+				"name": "org.osbuild.ostree.sign"
+			}
+		}
+	}`, string(data))
+}
+
+func TestNewOSTreeSignStageValidation(t *testing.T) {
+	secrets := &OSTreeSigningKeySecrets{Name: OSTreeSigningKeySecret}
+	assert.NotPanics(t, func() {
+		NewOSTreeSignStage(&OSTreeSignStageOptions{Repo: "/repo", Ref: "ref", Type: "gpg", KeyID: "1234ABCD", Secrets: secrets}, "key")
+	})
+	assert.PanicsWithError(t, "org.osbuild.ostree.sign: key_id is required for gpg signatures", func() {
+		NewOSTreeSignStage(&OSTreeSignStageOptions{Repo: "/repo", Ref: "ref", Type: "gpg", Secrets: secrets}, "key")
+	})
+	assert.PanicsWithError(t, "org.osbuild.ostree.sign: key_id is only supported for gpg signatures", func() {
+		NewOSTreeSignStage(&OSTreeSignStageOptions{Repo: "/repo", Ref: "ref", Type: "ed25519", KeyID: "1234ABCD", Secrets: secrets}, "key")
+	})
+	assert.PanicsWithError(t, `org.osbuild.ostree.sign: unknown signature type "x509"`, func() {
+		NewOSTreeSignStage(&OSTreeSignStageOptions{Repo: "/repo", Ref: "ref", Type: "x509", Secrets: secrets}, "key")
+	})
+	assert.PanicsWithError(t, "org.osbuild.ostree.sign: a key secret is required", func() {
+		NewOSTreeSignStage(&OSTreeSignStageOptions{Repo: "/repo", Ref: "ref", Type: "ed25519"}, "key")
+	})
+}
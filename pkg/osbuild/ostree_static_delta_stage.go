@@ -0,0 +1,72 @@
+package osbuild
+
+// Options for the org.osbuild.ostree.static-delta stage.
+type OSTreeStaticDeltaStageOptions struct {
+	// Location of the ostree repo
+	Repo string `json:"repo"`
+
+	// Ref of the commit the deltas are generated to
+	Ref string `json:"ref"`
+
+	// Generate a delta from scratch, which contains the whole commit
+	FromScratch bool `json:"from_scratch,omitempty"`
+}
+
+func (OSTreeStaticDeltaStageOptions) isStageOptions() {}
+
+type OSTreeStaticDeltaStageInput struct {
+	inputCommon
+	References OSTreeCheckoutReferences `json:"references"`
+}
+
+func (OSTreeStaticDeltaStageInput) isStageInput() {}
+
+type OSTreeStaticDeltaStageInputs struct {
+	// Commits that deltas are generated from, they are not added to the
+	// repo
+	Commits *OSTreeStaticDeltaStageInput `json:"commits,omitempty"`
+}
+
+func (OSTreeStaticDeltaStageInputs) isStageInputs() {}
+
+// NewOSTreeStaticDeltaStage creates a new org.osbuild.ostree.static-delta
+// stage that generates static deltas to the commit of a ref, from each of
+// the commits of the inputs and optionally from scratch. The inputs may be
+// nil for deltas from scratch only.
+func NewOSTreeStaticDeltaStage(options *OSTreeStaticDeltaStageOptions, inputs *OSTreeStaticDeltaStageInputs) *Stage {
+	stage := &Stage{
+		Type:    "org.osbuild.ostree.static-delta",
+		Options: options,
+	}
+	if inputs != nil {
+		stage.Inputs = inputs
+	}
+	return stage
+}
+
+// NewOSTreeStaticDeltaStageInputs returns the inputs for the commits with
+// the given checksums from the ostree source.
+func NewOSTreeStaticDeltaStageInputs(checksums ...string) *OSTreeStaticDeltaStageInputs {
+	input := new(OSTreeStaticDeltaStageInput)
+	input.Type = "org.osbuild.ostree"
+	input.Origin = "org.osbuild.source"
+	input.References = checksums
+	return &OSTreeStaticDeltaStageInputs{Commits: input}
+}
+
+type OSTreeStaticDeltaStageMetadata struct {
+	Deltas []OSTreeStaticDeltaMetadata `json:"deltas"`
+}
+
+type OSTreeStaticDeltaMetadata struct {
+	// Commit the delta is generated from, empty for deltas from scratch
+	From string `json:"from,omitempty"`
+
+	// Commit the delta is generated to
+	To string `json:"to"`
+
+	// Size of the delta in bytes
+	Size int64 `json:"size"`
+}
+
+func (OSTreeStaticDeltaStageMetadata) isStageMetadata() {}
@@ -0,0 +1,62 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOSTreeStaticDeltaStage(t *testing.T) {
+	checksum := "5330bb1b8820944567f519de66ad6354c729b6b490dea1c5a7ba320c9f147c58"
+	stage := NewOSTreeStaticDeltaStage(&OSTreeStaticDeltaStageOptions{
+		Repo:        "/repo",
+		Ref:         "fedora/x86_64/iot",
+		FromScratch: true,
+	}, NewOSTreeStaticDeltaStageInputs(checksum))
+
+	data, err := json.Marshal(stage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "org.osbuild.ostree.static-delta",
+		"inputs": {
+			"commits": {
+				"type": "org.osbuild.ostree",
+				"origin": "org.osbuild.source",
+				"references": ["`+checksum+`"]
+			}
+		},
+		"options": {
+			"repo": "/repo",
+			"ref": "fedora/x86_64/iot",
+			"from_scratch": true
+		}
+	}`, string(data))
+}
+
+func TestOSTreeStaticDeltaStageMetadata(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "result",
+		"success": true,
+		"log": {},
+		"metadata": {
+			"ostree-commit": {
+				"org.osbuild.ostree.static-delta": {
+					"deltas": [
+						{"from": "5330bb1b", "to": "9c1a2c1b", "size": 1024},
+						{"to": "9c1a2c1b", "size": 4096}
+					]
+				}
+			}
+		}
+	}`), &result))
+
+	md, ok := result.Metadata["ostree-commit"]["org.osbuild.ostree.static-delta"].(*OSTreeStaticDeltaStageMetadata)
+	require.True(t, ok)
+	assert.Equal(t, []OSTreeStaticDeltaMetadata{
+		{From: "5330bb1b", To: "9c1a2c1b", Size: 1024},
+		{To: "9c1a2c1b", Size: 4096},
+	}, md.Deltas)
+}
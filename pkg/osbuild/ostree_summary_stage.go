@@ -0,0 +1,18 @@
+package osbuild
+
+// Options for the org.osbuild.ostree.summary stage.
+type OSTreeSummaryStageOptions struct {
+	// Location of the ostree repo
+	Repo string `json:"repo"`
+}
+
+func (OSTreeSummaryStageOptions) isStageOptions() {}
+
+// NewOSTreeSummaryStage creates a new org.osbuild.ostree.summary stage that
+// updates the summary of a repo, which lists its refs and static deltas.
+func NewOSTreeSummaryStage(options *OSTreeSummaryStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.ostree.summary",
+		Options: options,
+	}
+}
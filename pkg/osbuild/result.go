@@ -90,6 +90,11 @@ func (md *PipelineMetadata) UnmarshalJSON(data []byte) error {
 			if err := json.Unmarshal(rawStageData, metadata); err != nil {
 				return err
 			}
+		case "org.osbuild.ostree.static-delta":
+			metadata = new(OSTreeStaticDeltaStageMetadata)
+			if err := json.Unmarshal(rawStageData, metadata); err != nil {
+				return err
+			}
 		default:
 			metadata = RawStageMetadata(rawStageData)
 		}
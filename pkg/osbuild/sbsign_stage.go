@@ -0,0 +1,82 @@
+package osbuild
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SecureBootKeySecret is the name of the osbuild secret that provides the
+// private Secure Boot signing key. The key is handed to the stages by osbuild
+// at build time and is never part of the manifest.
+const SecureBootKeySecret = "org.osbuild.secureboot"
+
+type SecureBootKeySecrets struct {
+	Name string `json:"name"`
+}
+
+// SbsignStageOptions describe the EFI binaries in the tree or on the mounts of
+// the stage to sign with sbsign. Each file is signed in place.
+type SbsignStageOptions struct {
+	// Absolute paths of the EFI binaries and kernels in the tree to sign, or
+	// mount URLs for files on the mounts of the stage, e.g.
+	// mount://boot-efi/loader/addons/verity-roothash.addon.efi
+	Paths []string `json:"paths"`
+
+	Secrets *SecureBootKeySecrets `json:"secrets"`
+}
+
+func (SbsignStageOptions) isStageOptions() {}
+
+func (o SbsignStageOptions) validate() error {
+	if len(o.Paths) == 0 {
+		return fmt.Errorf("sbsign stage requires at least one path")
+	}
+	for _, path := range o.Paths {
+		if !filepath.IsAbs(path) && !strings.HasPrefix(path, "mount://") {
+			return fmt.Errorf("sbsign stage path %q must be an absolute path or a mount URL", path)
+		}
+	}
+	if o.Secrets == nil || o.Secrets.Name == "" {
+		return fmt.Errorf("sbsign stage requires a key secret")
+	}
+	return nil
+}
+
+// SecureBootStageInputs provide the signing certificate to the Secure Boot
+// stages.
+type SecureBootStageInputs struct {
+	Certificate *FilesInput `json:"certificate"`
+}
+
+func (SecureBootStageInputs) isStageInputs() {}
+
+func newSecureBootStageInputs(certData string) *SecureBootStageInputs {
+	input := NewFilesInput(NewFilesInputSourcePlainRef([]string{
+		fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(certData))),
+	}))
+	return &SecureBootStageInputs{Certificate: input}
+}
+
+// NewSbsignStage creates a new org.osbuild.sbsign stage. The certificate data
+// must be added to the inline data of the manifest by the caller.
+func NewSbsignStage(options *SbsignStageOptions, certData string) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+	return &Stage{
+		Type:    "org.osbuild.sbsign",
+		Options: options,
+		Inputs:  newSecureBootStageInputs(certData),
+	}
+}
+
+// NewSbsignStageOptions returns the options to sign the given paths with the
+// key provided through the default Secure Boot key secret.
+func NewSbsignStageOptions(paths []string) *SbsignStageOptions {
+	return &SbsignStageOptions{
+		Paths:   paths,
+		Secrets: &SecureBootKeySecrets{Name: SecureBootKeySecret},
+	}
+}
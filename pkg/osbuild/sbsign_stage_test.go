@@ -0,0 +1,37 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSbsignStage(t *testing.T) {
+	options := NewSbsignStageOptions([]string{"/boot/efi/EFI/Linux/linux-6.11.4-301.fc41.x86_64.efi"})
+	stage := NewSbsignStage(options, "42\n")
+
+	assert.Equal(t, "org.osbuild.sbsign", stage.Type)
+	assert.Equal(t, options, stage.Options)
+
+	inputs := stage.Inputs.(*SecureBootStageInputs)
+	refs := inputs.Certificate.References.(*FilesInputSourcePlainRef)
+	assert.Equal(t, FilesInputSourcePlainRef{"sha256:084c799cd551dd1d8d5c5f9a5d593b2e931f5e36122ee5c793c1d08a19839cc0"}, *refs)
+
+	data, err := json.Marshal(stage.Options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"paths":["/boot/efi/EFI/Linux/linux-6.11.4-301.fc41.x86_64.efi"],"secrets":{"name":"org.osbuild.secureboot"}}`, string(data))
+}
+
+func TestNewSbsignStageValidation(t *testing.T) {
+	assert.PanicsWithError(t, "sbsign stage requires at least one path", func() {
+		NewSbsignStage(NewSbsignStageOptions(nil), "42\n")
+	})
+	assert.PanicsWithError(t, `sbsign stage path "vmlinuz" must be an absolute path or a mount URL`, func() {
+		NewSbsignStage(NewSbsignStageOptions([]string{"vmlinuz"}), "42\n")
+	})
+	assert.PanicsWithError(t, "sbsign stage requires a key secret", func() {
+		NewSbsignStage(&SbsignStageOptions{Paths: []string{"/boot/vmlinuz"}}, "42\n")
+	})
+}
@@ -0,0 +1,50 @@
+package osbuild
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// SecureBootAutoEnrollDir is the directory on the ESP from which systemd-boot
+// enrolls Secure Boot keys when the firmware is in setup mode.
+const SecureBootAutoEnrollDir = "/boot/efi/loader/keys/auto"
+
+// SecureBootAuthVarsStageOptions describe the authenticated EFI variables
+// (PK.auth, KEK.auth and db.auth) to generate from the signing certificate.
+// The variables are signed with the private key, which makes the certificate
+// the platform key of the target.
+type SecureBootAuthVarsStageOptions struct {
+	// Directory in the tree the .auth files are written to
+	Target string `json:"target"`
+
+	// GUID identifying the owner of the signature lists
+	Owner string `json:"owner,omitempty"`
+
+	Secrets *SecureBootKeySecrets `json:"secrets"`
+}
+
+func (SecureBootAuthVarsStageOptions) isStageOptions() {}
+
+func (o SecureBootAuthVarsStageOptions) validate() error {
+	if !filepath.IsAbs(o.Target) {
+		return fmt.Errorf("secureboot auth vars stage target %q must be an absolute path", o.Target)
+	}
+	if o.Secrets == nil || o.Secrets.Name == "" {
+		return fmt.Errorf("secureboot auth vars stage requires a key secret")
+	}
+	return nil
+}
+
+// NewSecureBootAuthVarsStage creates a new org.osbuild.secureboot.auth-vars
+// stage. The certificate data must be added to the inline data of the
+// manifest by the caller.
+func NewSecureBootAuthVarsStage(options *SecureBootAuthVarsStageOptions, certData string) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+	return &Stage{
+		Type:    "org.osbuild.secureboot.auth-vars",
+		Options: options,
+		Inputs:  newSecureBootStageInputs(certData),
+	}
+}
@@ -0,0 +1,31 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSecureBootAuthVarsStage(t *testing.T) {
+	options := &SecureBootAuthVarsStageOptions{
+		Target:  SecureBootAutoEnrollDir,
+		Secrets: &SecureBootKeySecrets{Name: SecureBootKeySecret},
+	}
+	stage := NewSecureBootAuthVarsStage(options, "42\n")
+
+	assert.Equal(t, "org.osbuild.secureboot.auth-vars", stage.Type)
+	assert.Equal(t, options, stage.Options)
+
+	inputs := stage.Inputs.(*SecureBootStageInputs)
+	refs := inputs.Certificate.References.(*FilesInputSourcePlainRef)
+	assert.Equal(t, FilesInputSourcePlainRef{"sha256:084c799cd551dd1d8d5c5f9a5d593b2e931f5e36122ee5c793c1d08a19839cc0"}, *refs)
+}
+
+func TestNewSecureBootAuthVarsStageValidation(t *testing.T) {
+	assert.PanicsWithError(t, `secureboot auth vars stage target "keys" must be an absolute path`, func() {
+		NewSecureBootAuthVarsStage(&SecureBootAuthVarsStageOptions{Target: "keys"}, "42\n")
+	})
+	assert.PanicsWithError(t, "secureboot auth vars stage requires a key secret", func() {
+		NewSecureBootAuthVarsStage(&SecureBootAuthVarsStageOptions{Target: SecureBootAutoEnrollDir}, "42\n")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/dnfjson"
@@ -101,19 +102,56 @@ func (sources Sources) addPackagesLibrepo(packages []rpmmd.PackageSpec, rpmRepos
 	return nil
 }
 
+// fileURL returns the file:// URL of an absolute path
+func fileURL(p string) string {
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// addLocalPackages adds locally supplied packages, which are downloaded with
+// curl from their URL or, for local files, from a file:// URL. Local files
+// are read on the host when the manifest is built, so they have to exist
+// until then.
+func (sources Sources) addLocalPackages(packages []rpmmd.PackageSpec) error {
+	curl, ok := sources[SourceNameCurl].(*CurlSource)
+	if !ok {
+		curl = NewCurlSource()
+		sources[SourceNameCurl] = curl
+	}
+	for _, pkg := range packages {
+		pkg.RemoteLocation = pkg.LocalSource
+		if pkg.IsLocalFile() {
+			pkg.RemoteLocation = fileURL(pkg.LocalSource)
+		}
+		if err := curl.AddPackage(pkg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GenSources generates the Sources from the given inputs. Note that
 // the packages and rpmRepos need to come from the *resolved* set.
 func GenSources(inputs SourceInputs, rpmDownloader RpmDownloader) (Sources, error) {
 	sources := Sources{}
 
+	// locally supplied packages are not part of any repository
+	var repoPackages, localPackages []rpmmd.PackageSpec
+	for _, pkg := range inputs.Depsolved.Packages {
+		if pkg.LocalSource != "" {
+			localPackages = append(localPackages, pkg)
+		} else {
+			repoPackages = append(repoPackages, pkg)
+		}
+	}
+
 	// collect rpm package sources
-	if len(inputs.Depsolved.Packages) > 0 {
+	if len(repoPackages) > 0 {
 		var err error
 		switch rpmDownloader {
 		case RpmDownloaderCurl:
-			err = sources.addPackagesCurl(inputs.Depsolved.Packages)
+			err = sources.addPackagesCurl(repoPackages)
 		case RpmDownloaderLibrepo:
-			err = sources.addPackagesLibrepo(inputs.Depsolved.Packages, inputs.Depsolved.Repos)
+			err = sources.addPackagesLibrepo(repoPackages, inputs.Depsolved.Repos)
 		default:
 			err = fmt.Errorf("unknown rpm downloader %v", rpmDownloader)
 		}
@@ -143,19 +181,37 @@ func GenSources(inputs SourceInputs, rpmDownloader RpmDownloader) (Sources, erro
 		sources[SourceNameInline] = ils
 	}
 
+	// collect locally supplied packages
+	if len(localPackages) > 0 {
+		if err := sources.addLocalPackages(localPackages); err != nil {
+			return nil, err
+		}
+	}
+
 	// collect skopeo and local container sources
 	if len(inputs.Containers) > 0 {
 		skopeo := NewSkopeoSource()
 		skopeoIndex := NewSkopeoIndexSource()
 		localContainers := NewContainersStorageSource()
 		for _, c := range inputs.Containers {
-			if c.LocalStorage {
+			if c.LocalArchive != "" {
+				// the org.osbuild.skopeo source only fetches images
+				// from a registry, and the containers-storage source
+				// only takes images from the storage of the host
+				return nil, fmt.Errorf("container %q: building containers from oci-archives is not supported, load the archive into the containers-storage of the build host and use it from there", c.Source)
+			} else if c.LocalStorage {
 				localContainers.AddItem(c.ImageID)
 			} else {
-				skopeo.AddItem(c.Source, c.Digest, c.ImageID, c.TLSVerify)
+				// containers that were resolved from a mirror are
+				// fetched from there, the digests are the same
+				source, tlsVerify := c.Source, c.TLSVerify
+				if c.Mirror != "" {
+					source, tlsVerify = c.Mirror, c.MirrorTLSVerify
+				}
+				skopeo.AddItem(source, c.Digest, c.ImageID, tlsVerify)
 				// if we have a list digest, add a skopeo-index source as well
 				if c.ListDigest != "" {
-					skopeoIndex.AddItem(c.Source, c.ListDigest, c.TLSVerify)
+					skopeoIndex.AddItem(source, c.ListDigest, tlsVerify)
 				}
 			}
 		}
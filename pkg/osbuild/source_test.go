@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -173,6 +174,19 @@ func TestGenSourcesContainerStorage(t *testing.T) {
 }`)
 }
 
+func TestGenSourcesOCIArchive(t *testing.T) {
+	containers := []container.Spec{
+		{
+			Source:               "oci-archive:/srv/image.tar",
+			ImageID:              "sha256:c2ecf25cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f",
+			LocalArchive:         "/srv/image.tar",
+			LocalArchiveChecksum: "sha256:aabbcc5cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f",
+		},
+	}
+	_, err := GenSources(SourceInputs{Containers: containers}, 0)
+	assert.EqualError(t, err, `container "oci-archive:/srv/image.tar": building containers from oci-archives is not supported, load the archive into the containers-storage of the build host and use it from there`)
+}
+
 func TestGenSourcesSkopeo(t *testing.T) {
 	imageID := "sha256:c2ecf25cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f"
 	digest := "sha256:aabbcc5cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f"
@@ -242,6 +256,52 @@ func TestGenSourcesWithSkopeoIndex(t *testing.T) {
 }`)
 }
 
+func TestGenSourcesSkopeoMirror(t *testing.T) {
+	imageID := "sha256:c2ecf25cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f"
+	digest := "sha256:aabbcc5cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f"
+	listDigest := "sha256:ffeeaabbcc90e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f"
+	containers := []container.Spec{
+		{
+			Source:          "quay.io/some-source",
+			Mirror:          "mirror.example.com/some-source",
+			MirrorTLSVerify: common.ToPtr(false),
+			Digest:          digest,
+			ListDigest:      listDigest,
+			ImageID:         imageID,
+		},
+	}
+	sources, err := GenSources(SourceInputs{Containers: containers}, 0)
+	assert.NoError(t, err)
+
+	// the container is fetched from the mirror it was resolved from, with
+	// the TLS settings of the mirror
+	jsonOutput, err := json.MarshalIndent(sources, "", "  ")
+	assert.NoError(t, err)
+	assert.Equal(t, string(jsonOutput), `{
+  "org.osbuild.skopeo": {
+    "items": {
+      "sha256:c2ecf25cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f": {
+        "image": {
+          "name": "mirror.example.com/some-source",
+          "digest": "sha256:aabbcc5cf190e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f",
+          "tls-verify": false
+        }
+      }
+    }
+  },
+  "org.osbuild.skopeo-index": {
+    "items": {
+      "sha256:ffeeaabbcc90e76b12b07436ad5140d4ba53d8a136d498705e57a006837a720f": {
+        "image": {
+          "name": "mirror.example.com/some-source",
+          "tls-verify": false
+        }
+      }
+    }
+  }
+}`)
+}
+
 // TODO: move into a common "rpmtest" package
 var opensslPkg = rpmmd.PackageSpec{
 	Name:           "openssl-libs",
@@ -338,3 +398,45 @@ func TestGenSourcesRpmBad(t *testing.T) {
 	_, err := GenSources(inputs, 99)
 	assert.EqualError(t, err, "unknown rpm downloader 99")
 }
+
+func TestGenSourcesLocalPackages(t *testing.T) {
+	localRPM := "/path/to/local 1.0.rpm"
+	localChecksum := "sha256:25bf8e1a2393f1108d37029b3df5593236c755742ec93465bbafa9b290bddcf6"
+	remoteChecksum := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	inputs := SourceInputs{
+		Depsolved: dnfjson.DepsolveResult{
+			Packages: []rpmmd.PackageSpec{
+				opensslPkg,
+				{
+					Name:        "remote",
+					Checksum:    remoteChecksum,
+					LocalSource: "https://example.com/remote.rpm",
+				},
+				{
+					Name:        "local",
+					Checksum:    localChecksum,
+					LocalSource: localRPM,
+				},
+			},
+			Repos: fakeRepos,
+		},
+	}
+	sources, err := GenSources(inputs, RpmDownloaderLibrepo)
+	assert.NoError(t, err)
+
+	// local packages are not part of the librepo mirrors
+	librepo := sources[SourceNameLibrepo].(*LibrepoSource)
+	assert.Len(t, librepo.Items, 1)
+	curl := sources[SourceNameCurl].(*CurlSource)
+	assert.Equal(t, map[string]CurlSourceItem{
+		remoteChecksum: &CurlSourceOptions{URL: "https://example.com/remote.rpm"},
+		localChecksum:  &CurlSourceOptions{URL: "file:///path/to/local%201.0.rpm"},
+	}, curl.Items)
+	// the content of local packages is not inlined
+	assert.NotContains(t, sources, SourceNameInline)
+
+	inputs.Depsolved.Packages[2].Checksum = "local"
+	_, err = GenSources(inputs, RpmDownloaderLibrepo)
+	assert.EqualError(t, err, `curl package source item with name "local" has invalid digest "local"`)
+}
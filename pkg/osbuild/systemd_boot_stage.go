@@ -0,0 +1,31 @@
+package osbuild
+
+// SystemdBootStageOptions describe how to install systemd-boot into the EFI
+// system partition of a tree. The stage runs `bootctl install` without
+// touching EFI variables, which is how images are expected to boot through
+// the removable media path (EFI/BOOT/BOOT<arch>.EFI).
+type SystemdBootStageOptions struct {
+	// Mountpoint of the EFI system partition in the tree
+	ESPPath string `json:"esp_path"`
+
+	// Settings written to loader/loader.conf on the ESP
+	Config *SystemdBootLoaderConfig `json:"config,omitempty"`
+}
+
+func (SystemdBootStageOptions) isStageOptions() {}
+
+type SystemdBootLoaderConfig struct {
+	// Menu timeout in seconds
+	Timeout *int `json:"timeout,omitempty"`
+
+	// Glob pattern of the default entry
+	Default string `json:"default,omitempty"`
+}
+
+// NewSystemdBootStage creates a new org.osbuild.systemd-boot stage.
+func NewSystemdBootStage(options *SystemdBootStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.systemd-boot",
+		Options: options,
+	}
+}
@@ -0,0 +1,23 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestNewSystemdBootStage(t *testing.T) {
+	options := &SystemdBootStageOptions{
+		ESPPath: "/boot/efi",
+		Config: &SystemdBootLoaderConfig{
+			Timeout: common.ToPtr(5),
+		},
+	}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.systemd-boot",
+		Options: options,
+	}
+	assert.Equal(t, expectedStage, NewSystemdBootStage(options))
+}
@@ -0,0 +1,66 @@
+package osbuild
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// UKIDir is the directory on the EFI system partition in which systemd-boot
+// discovers Unified Kernel Images (type #2 entries of the Boot Loader
+// Specification).
+const UKIDir = "/boot/efi/EFI/Linux"
+
+// UkifyStageOptions describe how to build a Unified Kernel Image with
+// ukify. The UKI combines the kernel, the initrd, the kernel command line and
+// the os-release file of the tree into a single EFI binary.
+type UkifyStageOptions struct {
+	// Version of the kernel in the tree to build the UKI for
+	Kernel string `json:"kernel"`
+
+	// Kernel command line embedded into the .cmdline section
+	Cmdline string `json:"cmdline"`
+
+	// Path of the initrd in the tree. Defaults to
+	// /boot/initramfs-<kernel>.img
+	Initrd string `json:"initrd,omitempty"`
+
+	// Path of the os-release file in the tree. Defaults to
+	// /usr/lib/os-release
+	OSRelease string `json:"os_release,omitempty"`
+
+	// Path in the tree the UKI is written to
+	Output string `json:"output"`
+}
+
+func (UkifyStageOptions) isStageOptions() {}
+
+func (o UkifyStageOptions) validate() error {
+	if o.Kernel == "" {
+		return fmt.Errorf("ukify stage requires a kernel version")
+	}
+	if !filepath.IsAbs(o.Output) {
+		return fmt.Errorf("ukify stage output %q must be an absolute path", o.Output)
+	}
+	return nil
+}
+
+// NewUkifyStage creates a new org.osbuild.ukify stage.
+func NewUkifyStage(options *UkifyStageOptions) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+	return &Stage{
+		Type:    "org.osbuild.ukify",
+		Options: options,
+	}
+}
+
+// NewUkifyStageOptions returns the options to build a UKI for the given kernel
+// version and command line into the systemd-boot UKI directory of the ESP.
+func NewUkifyStageOptions(kernelVer, cmdline string) *UkifyStageOptions {
+	return &UkifyStageOptions{
+		Kernel:  kernelVer,
+		Cmdline: cmdline,
+		Output:  filepath.Join(UKIDir, fmt.Sprintf("linux-%s.efi", kernelVer)),
+	}
+}
@@ -0,0 +1,29 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUkifyStage(t *testing.T) {
+	options := NewUkifyStageOptions("6.11.4-301.fc41.x86_64", "root=UUID=6e4ff95f-f662-45ee-a82a-bdf44a2d0b75 ro")
+	expectedStage := &Stage{
+		Type: "org.osbuild.ukify",
+		Options: &UkifyStageOptions{
+			Kernel:  "6.11.4-301.fc41.x86_64",
+			Cmdline: "root=UUID=6e4ff95f-f662-45ee-a82a-bdf44a2d0b75 ro",
+			Output:  "/boot/efi/EFI/Linux/linux-6.11.4-301.fc41.x86_64.efi",
+		},
+	}
+	assert.Equal(t, expectedStage, NewUkifyStage(options))
+}
+
+func TestNewUkifyStageValidation(t *testing.T) {
+	assert.PanicsWithError(t, "ukify stage requires a kernel version", func() {
+		NewUkifyStage(&UkifyStageOptions{Output: "/boot/efi/EFI/Linux/linux.efi"})
+	})
+	assert.PanicsWithError(t, `ukify stage output "linux.efi" must be an absolute path`, func() {
+		NewUkifyStage(&UkifyStageOptions{Kernel: "6.11.4-301.fc41.x86_64", Output: "linux.efi"})
+	})
+}
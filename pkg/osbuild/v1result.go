@@ -118,6 +118,11 @@ func convertStageResult(sr1 *v1StageResult) (*StageResult, StageMetadata, error)
 		if err := json.Unmarshal(sr1.Metadata, metadata); err != nil {
 			return nil, nil, err
 		}
+	case "org.osbuild.ostree.static-delta":
+		metadata = new(OSTreeStaticDeltaStageMetadata)
+		if err := json.Unmarshal(sr1.Metadata, metadata); err != nil {
+			return nil, nil, err
+		}
 	default:
 		metadata = RawStageMetadata(sr1.Metadata)
 	}
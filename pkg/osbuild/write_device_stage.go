@@ -0,0 +1,67 @@
+package osbuild
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/disk"
+)
+
+// WriteDeviceStageOptions describe the file to write to the device of the
+// stage, starting at its first byte.
+type WriteDeviceStageOptions struct {
+	// Input URL of the file to write, e.g. input://tree/root.erofs
+	From string `json:"from"`
+}
+
+func (WriteDeviceStageOptions) isStageOptions() {}
+
+// NewWriteDeviceStage creates a new org.osbuild.write-device stage. The
+// devices map must contain a "device" to write to.
+func NewWriteDeviceStage(options *WriteDeviceStageOptions, inputs Inputs, devices map[string]Device) *Stage {
+	if _, ok := devices["device"]; !ok {
+		panic("write-device stage requires a \"device\"")
+	}
+	return &Stage{
+		Type:    "org.osbuild.write-device",
+		Options: options,
+		Inputs:  inputs,
+		Devices: devices,
+	}
+}
+
+// GenWriteErofsStages generates an org.osbuild.write-device stage for each
+// erofs filesystem in the partition table. An erofs filesystem is read-only
+// and is created from the tree in one go instead of being formatted and
+// filled like the other filesystems. The erofs image of the root filesystem is
+// taken from the file with the given name in the tree of the inputPipeline.
+// Only the root filesystem can be an erofs filesystem.
+func GenWriteErofsStages(pt *disk.PartitionTable, filename, inputPipeline, erofsFilename string) []*Stage {
+	var stages []*Stage
+
+	genStage := func(ent disk.Entity, path []disk.Entity) error {
+		fs, ok := ent.(*disk.Filesystem)
+		if !ok || fs.GetFSType() != "erofs" {
+			return nil
+		}
+		if fs.Mountpoint != "/" {
+			panic(fmt.Sprintf("erofs filesystem for mountpoint %q is not supported, only the root filesystem can be erofs; this is a programming error", fs.Mountpoint))
+		}
+
+		stageDevices, lastName := getDevices(path, filename, true)
+		// The last device in the chain must be named "device", because
+		// that's the device that the stage writes to. See the stage schema
+		// for reference.
+		lastDevice := stageDevices[lastName]
+		delete(stageDevices, lastName)
+		stageDevices["device"] = lastDevice
+
+		options := &WriteDeviceStageOptions{
+			From: fmt.Sprintf("input://tree/%s", erofsFilename),
+		}
+		stages = append(stages, NewWriteDeviceStage(options, NewPipelineTreeInputs("tree", inputPipeline), stageDevices))
+		return nil
+	}
+
+	_ = pt.ForEachEntity(genStage) // genStage always returns nil
+	return stages
+}
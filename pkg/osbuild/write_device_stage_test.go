@@ -0,0 +1,74 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func testErofsPartitionTable() *disk.PartitionTable {
+	pt := testVerityPartitionTable()
+	pt.FindMountable("/").(*disk.Filesystem).Type = "erofs"
+	pt.Partitions = append(pt.Partitions, disk.Partition{
+		Start: 2269 * datasizes.MebiByte,
+		Size:  1 * datasizes.GibiByte,
+		Type:  disk.FilesystemDataGUID,
+		UUID:  "0E6A4C54-C0C5-4D0B-A3C4-73DBC2B8E8E7",
+		Payload: &disk.Filesystem{
+			Type:       "ext4",
+			UUID:       "ab8f8d8c-0b13-4c4c-bb4a-1d7f0e7b4a58",
+			Mountpoint: "/var",
+		},
+	})
+	return pt
+}
+
+func TestGenWriteErofsStages(t *testing.T) {
+	pt := testErofsPartitionTable()
+
+	stages := GenWriteErofsStages(pt, "disk.img", "root-erofs", "root.erofs")
+	require.Len(t, stages, 1)
+	stage := stages[0]
+
+	assert.Equal(t, "org.osbuild.write-device", stage.Type)
+	assert.Equal(t, &WriteDeviceStageOptions{From: "input://tree/root.erofs"}, stage.Options)
+	assert.Equal(t, NewPipelineTreeInputs("tree", "root-erofs"), stage.Inputs)
+	require.Contains(t, stage.Devices, "device")
+	device := stage.Devices["device"].Options.(*LoopbackDeviceOptions)
+	assert.Equal(t, pt.BytesToSectors(221*datasizes.MebiByte), device.Start)
+
+	// no filesystem is created for the erofs root
+	for _, stage := range GenFsStages(pt, "disk.img") {
+		assert.NotEqual(t, device.Start, stage.Devices["device"].Options.(*LoopbackDeviceOptions).Start)
+	}
+}
+
+func TestGenWriteErofsStagesNoErofs(t *testing.T) {
+	assert.Empty(t, GenWriteErofsStages(testVerityPartitionTable(), "disk.img", "root-erofs", "root.erofs"))
+}
+
+func TestGenCopyFSTreeOptionsErofsRoot(t *testing.T) {
+	pt := testErofsPartitionTable()
+
+	options, devices, mounts := GenCopyFSTreeOptions("root-tree", "os", "disk.img", pt)
+	assert.Equal(t, &CopyStageOptions{
+		Paths: []CopyStagePath{
+			{From: "input://root-tree/boot/efi/", To: "mount://boot-efi/"},
+			{From: "input://root-tree/var/", To: "mount://var/"},
+		},
+	}, options)
+	require.Len(t, mounts, 2)
+	assert.Equal(t, "/boot/efi", mounts[0].Target)
+	assert.Equal(t, "/var", mounts[1].Target)
+	assert.Len(t, devices, 2)
+}
+
+func TestNewWriteDeviceStageMissingDevice(t *testing.T) {
+	assert.PanicsWithValue(t, `write-device stage requires a "device"`, func() {
+		NewWriteDeviceStage(&WriteDeviceStageOptions{From: "input://tree/root.erofs"}, nil, map[string]Device{})
+	})
+}
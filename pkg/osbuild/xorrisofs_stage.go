@@ -24,6 +24,19 @@ type XorrisofsStageOptions struct {
 	// This will cause the created iso to use grub2 instead of syslinux/isolinux
 	// when booting on BIOS systems.
 	Grub2MBR string `json:"grub2mbr,omitempty"`
+
+	// Partition images to append to the hybrid image after the ISO
+	// filesystem
+	AppendPartitions []XorrisofsPartition `json:"append_partitions,omitempty"`
+}
+
+type XorrisofsPartition struct {
+	// Number of the partition in the hybrid partition table
+	Number uint `json:"number"`
+	// MBR partition type code
+	Type string `json:"type"`
+	// Location of the partition image, e.g. input://name/file.img
+	Path string `json:"path"`
 }
 
 type XorrisofsBoot struct {
@@ -17,6 +17,8 @@ type YumRepository struct {
 	BaseURLs       []string `json:"baseurl,omitempty"`
 	Cost           *int     `json:"cost,omitempty"`
 	Enabled        *bool    `json:"enabled,omitempty"`
+	ExcludePkgs    []string `json:"excludepkgs,omitempty"`
+	IncludePkgs    []string `json:"includepkgs,omitempty"`
 	Priority       *int     `json:"priority,omitempty"`
 	GPGKey         []string `json:"gpgkey,omitempty"`
 	Metalink       string   `json:"metalink,omitempty"`
@@ -53,6 +55,18 @@ func (r YumRepository) validate() error {
 		}
 	}
 
+	for idx, pkg := range r.IncludePkgs {
+		if pkg == "" {
+			return fmt.Errorf("includepkgs must not contain an empty string (idx %d)", idx)
+		}
+	}
+
+	for idx, pkg := range r.ExcludePkgs {
+		if pkg == "" {
+			return fmt.Errorf("excludepkgs must not contain an empty string (idx %d)", idx)
+		}
+	}
+
 	for idx, gpgkey := range r.GPGKey {
 		if gpgkey == "" {
 			return fmt.Errorf("gpgkey must not be an empty string (idx %d)", idx)
@@ -109,9 +123,18 @@ func repoConfigToYumRepository(repo rpmmd.RepoConfig) YumRepository {
 		RepoGPGCheck:   repo.CheckRepoGPG,
 		Enabled:        repo.Enabled,
 		Priority:       repo.Priority,
+		Cost:           repo.Cost,
 		SSLVerify:      sslVerify,
 		ModuleHotfixes: repo.ModuleHotfixes,
 	}
+	if len(repo.IncludePackages) > 0 {
+		yumRepo.IncludePkgs = make([]string, len(repo.IncludePackages))
+		copy(yumRepo.IncludePkgs, repo.IncludePackages)
+	}
+	if len(repo.ExcludePackages) > 0 {
+		yumRepo.ExcludePkgs = make([]string, len(repo.ExcludePackages))
+		copy(yumRepo.ExcludePkgs, repo.ExcludePackages)
+	}
 
 	return yumRepo
 }
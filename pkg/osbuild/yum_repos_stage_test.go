@@ -107,6 +107,34 @@ func TestYumReposStageOptionsValidate(t *testing.T) {
 			},
 			err: true,
 		},
+		{
+			name: "includepkgs-empty-string",
+			options: YumReposStageOptions{
+				Filename: "test.repo",
+				Repos: []YumRepository{
+					{
+						Id:          "cool-id",
+						BaseURLs:    []string{"http://example.org/repo"},
+						IncludePkgs: []string{"cool-*", ""},
+					},
+				},
+			},
+			err: true,
+		},
+		{
+			name: "excludepkgs-empty-string",
+			options: YumReposStageOptions{
+				Filename: "test.repo",
+				Repos: []YumRepository{
+					{
+						Id:          "cool-id",
+						BaseURLs:    []string{"http://example.org/repo"},
+						ExcludePkgs: []string{""},
+					},
+				},
+			},
+			err: true,
+		},
 		{
 			name: "invalid-repo-id",
 			options: YumReposStageOptions{
@@ -160,6 +188,22 @@ func TestYumReposStageOptionsValidate(t *testing.T) {
 			},
 			err: false,
 		},
+		{
+			name: "good-options-pkg-filters",
+			options: YumReposStageOptions{
+				Filename: "test.repo",
+				Repos: []YumRepository{
+					{
+						Id:          "cool-id",
+						BaseURLs:    []string{"http://example.org/repo"},
+						Cost:        common.ToPtr(2000),
+						IncludePkgs: []string{"cool-*", "other-pkg"},
+						ExcludePkgs: []string{"kernel*"},
+					},
+				},
+			},
+			err: false,
+		},
 		{
 			name: "good-options-metalink",
 			options: YumReposStageOptions{
@@ -187,3 +231,21 @@ func TestYumReposStageOptionsValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestRepoConfigToYumRepositoryPackageFilters(t *testing.T) {
+	repo := rpmmd.RepoConfig{
+		Id:              "epel",
+		BaseURLs:        []string{"http://example.org/epel"},
+		Cost:            common.ToPtr(2000),
+		IncludePackages: []string{"htop", "tmux*"},
+		ExcludePackages: []string{"kernel*", "glibc*"},
+	}
+	yumRepo := repoConfigToYumRepository(repo)
+	assert.Equal(t, common.ToPtr(2000), yumRepo.Cost)
+	assert.Equal(t, []string{"htop", "tmux*"}, yumRepo.IncludePkgs)
+	assert.Equal(t, []string{"kernel*", "glibc*"}, yumRepo.ExcludePkgs)
+
+	// the stage options must not share the slices of the repo config
+	yumRepo.ExcludePkgs[0] = "changed"
+	assert.Equal(t, "kernel*", repo.ExcludePackages[0])
+}
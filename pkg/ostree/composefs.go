@@ -0,0 +1,66 @@
+package ostree
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	// PrepareRootConfPath is the location of the ostree-prepare-root
+	// configuration in the tree of a commit. ostree-prepare-root reads it
+	// from the initramfs, so it has to be in place when the initramfs is
+	// generated.
+	PrepareRootConfPath = "/usr/lib/ostree/prepare-root.conf"
+
+	// ComposefsPublicKeyPath is the default location ostree-prepare-root
+	// reads the ed25519 public key from when composefs is set to signed. The
+	// ostree dracut module adds it to the initramfs.
+	ComposefsPublicKeyPath = "/etc/ostree/initramfs-root-binding.key"
+
+	// composefsDigestKey is the commit metadata key that holds the composefs
+	// digest of the tree. Commits without it can not be mounted with
+	// composefs.
+	composefsDigestKey = "ostree.composefs.digest.v0"
+)
+
+// ComposefsOptions configures the deployment root to be mounted with
+// composefs by ostree-prepare-root.
+type ComposefsOptions struct {
+	// Base64 encoded ed25519 public key used to verify the composefs image
+	// of the deployed commit. When set, composefs is configured as signed
+	// and the commit must be signed with the matching private key.
+	PublicKey string
+}
+
+// Signed returns true if the composefs image must be signed.
+func (o *ComposefsOptions) Signed() bool {
+	return o != nil && o.PublicKey != ""
+}
+
+// PrepareRootConf returns the content of the prepare-root.conf file that
+// enables composefs for the deployment.
+func (o *ComposefsOptions) PrepareRootConf() string {
+	if o.Signed() {
+		return fmt.Sprintf("[composefs]\nenabled = signed\nkeypath = %s\n", ComposefsPublicKeyPath)
+	}
+	return "[composefs]\nenabled = yes\n"
+}
+
+// verifyComposefs fetches the commit object for the given checksum from the
+// repository at ss.URL and checks that its metadata carries the composefs
+// digest. If there is an error, it will be of type ResolveRefError.
+func verifyComposefs(ss SourceSpec, checksum string) error {
+	body, err := fetchObject(ss, checksum, "commit", false)
+	if err != nil {
+		return err
+	}
+
+	// The commit object is a GVariant whose first member is the a{sv}
+	// metadata dictionary. Keys of the dictionary are stored as plain
+	// nul-terminated strings, so a lookup of the key is enough to know if
+	// the digest is present without decoding the whole variant.
+	if !bytes.Contains(body, append([]byte(composefsDigestKey), 0)) {
+		return NewResolveRefError("ostree commit %s in %q does not carry composefs metadata", checksum, ss.URL)
+	}
+	return nil
+}
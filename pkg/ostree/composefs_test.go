@@ -0,0 +1,65 @@
+package ostree
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposefsPrepareRootConf(t *testing.T) {
+	unsigned := &ComposefsOptions{}
+	assert.False(t, unsigned.Signed())
+	assert.Equal(t, "[composefs]\nenabled = yes\n", unsigned.PrepareRootConf())
+
+	signed := &ComposefsOptions{PublicKey: "c2VjcmV0"}
+	assert.True(t, signed.Signed())
+	assert.Equal(t, "[composefs]\nenabled = signed\nkeypath = /etc/ostree/initramfs-root-binding.key\n", signed.PrepareRootConf())
+}
+
+func TestResolveRequireComposefs(t *testing.T) {
+	withDigest := "5330bb1b8820944567f519de66ad6354c729b6b490dea1c5a7ba320c9f147c58"
+	withoutDigest := "9c1a2c1b8820944567f519de66ad6354c729b6b490dea1c5a7ba320c9f147c58"
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/refs/heads/composefs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, withDigest)
+	})
+	handler.HandleFunc("/refs/heads/plain", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, withoutDigest)
+	})
+	handler.HandleFunc(fmt.Sprintf("/objects/%s/%s.commit", withDigest[:2], withDigest[2:]), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "\x00\x00ostree.composefs.digest.v0\x00\x00ay\x00")
+	})
+	handler.HandleFunc(fmt.Sprintf("/objects/%s/%s.commit", withoutDigest[:2], withoutDigest[2:]), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "\x00\x00ostree.ref-binding\x00\x00as\x00")
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	commit, err := Resolve(SourceSpec{URL: srv.URL, Ref: "composefs", RequireComposefs: true})
+	require.NoError(t, err)
+	assert.Equal(t, withDigest, commit.Checksum)
+
+	// checksums are verified too
+	commit, err = Resolve(SourceSpec{URL: srv.URL, Ref: withDigest, RequireComposefs: true})
+	require.NoError(t, err)
+	assert.Equal(t, withDigest, commit.Checksum)
+
+	_, err = Resolve(SourceSpec{URL: srv.URL, Ref: "plain", RequireComposefs: true})
+	assert.EqualError(t, err, fmt.Sprintf("ostree commit %s in %q does not carry composefs metadata", withoutDigest, srv.URL))
+
+	// not verified unless required
+	commit, err = Resolve(SourceSpec{URL: srv.URL, Ref: "plain"})
+	require.NoError(t, err)
+	assert.Equal(t, withoutDigest, commit.Checksum)
+
+	_, err = Resolve(SourceSpec{URL: srv.URL, Ref: "missing/ref", RequireComposefs: true})
+	assert.Error(t, err)
+
+	_, err = Resolve(SourceSpec{Ref: "composefs", RequireComposefs: true})
+	assert.EqualError(t, err, `composefs verification of ostree ref "composefs" requires a URL`)
+}
@@ -45,3 +45,20 @@ func (e ParameterComboError) Error() string {
 func NewParameterComboError(msg string, args ...interface{}) ParameterComboError {
 	return ParameterComboError{msg: fmt.Sprintf(msg, args...)}
 }
+
+// SignatureError is returned when the signature of a resolved commit can not
+// be verified, e.g. when the commit is not signed or not signed by any of
+// the trusted keys.
+type SignatureError struct {
+	msg string
+}
+
+func (e SignatureError) Error() string {
+	return e.msg
+}
+
+// NewSignatureError creates and returns a new SignatureError with a given
+// formatted message.
+func NewSignatureError(msg string, args ...interface{}) SignatureError {
+	return SignatureError{msg: fmt.Sprintf(msg, args...)}
+}
@@ -0,0 +1,159 @@
+package ostree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// The following functions decode the parts of the GVariant serialization
+// format that the ostree objects use, see
+// https://docs.gtk.org/glib/struct.Variant.html#serialization
+
+// gvariantOffsetSize returns the size of the framing offsets of a container
+// of the given size
+func gvariantOffsetSize(size int) int {
+	switch {
+	case size == 0:
+		return 0
+	case size <= 0xff:
+		return 1
+	case size <= 0xffff:
+		return 2
+	case size <= 0xffffffff:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func gvariantOffset(data []byte) int {
+	switch len(data) {
+	case 1:
+		return int(data[0])
+	case 2:
+		return int(binary.LittleEndian.Uint16(data))
+	case 4:
+		return int(binary.LittleEndian.Uint32(data))
+	default:
+		return int(binary.LittleEndian.Uint64(data))
+	}
+}
+
+func gvariantAlign(n, alignment int) int {
+	return (n + alignment - 1) &^ (alignment - 1)
+}
+
+// gvariantArray splits an array of variable-size elements with the given
+// alignment into its elements
+func gvariantArray(data []byte, alignment int) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	size := gvariantOffsetSize(len(data))
+	lastEnd := gvariantOffset(data[len(data)-size:])
+	if lastEnd > len(data) || (len(data)-lastEnd)%size != 0 {
+		return nil, fmt.Errorf("invalid array framing")
+	}
+	offsets := data[lastEnd:]
+	elements := make([][]byte, 0, len(offsets)/size)
+	start := 0
+	for i := 0; i < len(offsets); i += size {
+		end := gvariantOffset(offsets[i : i+size])
+		if end < start || end > lastEnd {
+			return nil, fmt.Errorf("invalid array framing")
+		}
+		elements = append(elements, data[start:end])
+		start = gvariantAlign(end, alignment)
+	}
+	return elements, nil
+}
+
+// gvariantDictEntry splits a dictionary entry of type {sv} into its key and
+// the serialized variant
+func gvariantDictEntry(data []byte) (string, []byte, error) {
+	size := gvariantOffsetSize(len(data))
+	if len(data) <= size {
+		return "", nil, fmt.Errorf("invalid dictionary entry")
+	}
+	keyEnd := gvariantOffset(data[len(data)-size:])
+	valueStart := gvariantAlign(keyEnd, 8)
+	if keyEnd == 0 || valueStart > len(data)-size || data[keyEnd-1] != 0 {
+		return "", nil, fmt.Errorf("invalid dictionary entry")
+	}
+	return string(data[:keyEnd-1]), data[valueStart : len(data)-size], nil
+}
+
+// gvariantVariant splits a variant into its type string and value
+func gvariantVariant(data []byte) (string, []byte, error) {
+	sep := bytes.LastIndexByte(data, 0)
+	if sep < 0 {
+		return "", nil, fmt.Errorf("invalid variant")
+	}
+	return string(data[sep+1:]), data[:sep], nil
+}
+
+// gvariantMember describes a member of a tuple by its alignment and its
+// size, which is 0 for variable-size members
+type gvariantMember struct {
+	alignment int
+	size      int
+}
+
+// gvariantTuple splits a tuple (or structure) into its members. The end
+// offsets of all variable-size members but the last are stored in reverse
+// order at the end of the tuple.
+func gvariantTuple(data []byte, members []gvariantMember) ([][]byte, error) {
+	size := gvariantOffsetSize(len(data))
+	frameEnd := len(data)
+	values := make([][]byte, 0, len(members))
+	pos := 0
+	for i, member := range members {
+		start := gvariantAlign(pos, member.alignment)
+		var end int
+		switch {
+		case member.size > 0:
+			end = start + member.size
+		case i == len(members)-1:
+			end = frameEnd
+		default:
+			frameEnd -= size
+			if frameEnd < 0 {
+				return nil, fmt.Errorf("invalid tuple framing")
+			}
+			end = gvariantOffset(data[frameEnd : frameEnd+size])
+		}
+		if start > end || end > frameEnd {
+			return nil, fmt.Errorf("invalid tuple framing")
+		}
+		values = append(values, data[start:end])
+		pos = end
+	}
+	return values, nil
+}
+
+// gvariantDict splits a dictionary of type a{sv} into its keys and the
+// serialized variants
+func gvariantDict(data []byte) (map[string][]byte, error) {
+	entries, err := gvariantArray(data, 8)
+	if err != nil {
+		return nil, err
+	}
+	dict := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		key, value, err := gvariantDictEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+	return dict, nil
+}
+
+// gvariantString decodes a nul-terminated string
+func gvariantString(data []byte) (string, error) {
+	if len(data) == 0 || data[len(data)-1] != 0 {
+		return "", fmt.Errorf("invalid string")
+	}
+	return string(data[:len(data)-1]), nil
+}
@@ -2,14 +2,36 @@ package mock_ostree_repo
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 type OSTreeTestRepo struct {
 	OSTreeRef string
 	Server    *httptest.Server
+
+	mu      sync.Mutex
+	refs    map[string]string
+	objects map[string][]byte
+}
+
+// Commit is a commit object that is served by the repository
+type Commit struct {
+	Parent    string
+	Subject   string
+	Body      string
+	Timestamp time.Time
+	Version   string
+
+	// KernelVersion marks the commit as bootable if set
+	KernelVersion string
 }
 
 func (repo *OSTreeTestRepo) TearDown() {
@@ -22,19 +44,187 @@ func (repo *OSTreeTestRepo) TearDown() {
 func Setup(ref string) *OSTreeTestRepo {
 	repo := new(OSTreeTestRepo)
 	repo.OSTreeRef = ref
+	repo.refs = make(map[string]string)
+	repo.objects = make(map[string][]byte)
 
 	mux := http.NewServeMux()
 	repo.Server = httptest.NewServer(mux)
 
 	checksum := fmt.Sprintf("%x", sha256.Sum256([]byte(repo.Server.URL+ref)))
 	fmt.Printf("Creating repo with %s %s %s\n", ref, repo.Server.URL, checksum)
-	mux.HandleFunc("/refs/heads/"+ref, func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprint(w, checksum)
-	})
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// catch-all handler, return 404
-		http.NotFound(w, r)
-	})
+	repo.refs[ref] = checksum
+	mux.Handle("/", repo)
 
 	return repo
 }
+
+// AddCommit adds the commit object to the repository and points the ref at
+// it. Returns the checksum of the commit.
+func (repo *OSTreeTestRepo) AddCommit(ref string, commit Commit) string {
+	data := commitObject(commit)
+	checksum := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.objects[checksum[:2]+"/"+checksum[2:]+".commit"] = data
+	repo.refs[ref] = checksum
+	return checksum
+}
+
+// RemoveCommit removes the commit object from the repository, e.g. to
+// truncate the history of a ref.
+func (repo *OSTreeTestRepo) RemoveCommit(checksum string) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	delete(repo.objects, checksum[:2]+"/"+checksum[2:]+".commit")
+}
+
+func (repo *OSTreeTestRepo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/refs/heads/"):
+		if checksum, ok := repo.refs[strings.TrimPrefix(r.URL.Path, "/refs/heads/")]; ok {
+			fmt.Fprint(w, checksum)
+			return
+		}
+	case strings.HasPrefix(r.URL.Path, "/objects/"):
+		if data, ok := repo.objects[strings.TrimPrefix(r.URL.Path, "/objects/")]; ok {
+			w.Write(data) // nolint:errcheck
+			return
+		}
+	case r.URL.Path == "/summary":
+		w.Write(summary(repo.refs)) // nolint:errcheck
+		return
+	}
+	// catch-all, return 404
+	http.NotFound(w, r)
+}
+
+// The following functions serialize the ostree objects in the GVariant
+// format, see https://docs.gtk.org/glib/struct.Variant.html#serialization
+
+func checksumBytes(checksum string) []byte {
+	data, err := hex.DecodeString(checksum)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func str(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func variant(value []byte, typ string) []byte {
+	return append(append(value, 0), typ...)
+}
+
+func pad(data []byte, alignment int) []byte {
+	for len(data)%alignment != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+// frame appends the framing offsets to a serialized container, using the
+// smallest offset size that fits the whole container
+func frame(body []byte, offsets []int) []byte {
+	if len(offsets) == 0 {
+		return body
+	}
+	for _, size := range []int{1, 2, 4, 8} {
+		total := len(body) + size*len(offsets)
+		if size < 8 && total > 1<<(8*size)-1 {
+			continue
+		}
+		for _, offset := range offsets {
+			for i := 0; i < size; i++ {
+				body = append(body, byte(offset>>(8*i)))
+			}
+		}
+		return body
+	}
+	panic("container too large")
+}
+
+// array serializes an array of variable-size elements
+func array(elements [][]byte, alignment int) []byte {
+	var body []byte
+	var offsets []int
+	for _, element := range elements {
+		body = append(pad(body, alignment), element...)
+		offsets = append(offsets, len(body))
+	}
+	return frame(body, offsets)
+}
+
+// tuple serializes a tuple whose members have the given alignment, fixed
+// is set for the fixed-size members
+func tuple(members [][]byte, alignments []int, fixed []bool) []byte {
+	var body []byte
+	var offsets []int
+	for i, member := range members {
+		body = append(pad(body, alignments[i]), member...)
+		if !fixed[i] && i != len(members)-1 {
+			offsets = append([]int{len(body)}, offsets...)
+		}
+	}
+	return frame(body, offsets)
+}
+
+// dict serializes an a{sv} dictionary of serialized variants
+func dict(values map[string][]byte) []byte {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, tuple([][]byte{str(key), values[key]}, []int{1, 8}, []bool{false, false}))
+	}
+	return array(entries, 8)
+}
+
+// commitObject serializes the commit as (a{sv}aya(say)sstayay)
+func commitObject(commit Commit) []byte {
+	meta := map[string][]byte{}
+	if commit.Version != "" {
+		meta["version"] = variant(str(commit.Version), "s")
+	}
+	if commit.KernelVersion != "" {
+		meta["ostree.linux"] = variant(str(commit.KernelVersion), "s")
+		meta["ostree.bootable"] = variant([]byte{1}, "b")
+	}
+	var parent []byte
+	if commit.Parent != "" {
+		parent = checksumBytes(commit.Parent)
+	}
+	timestamp := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestamp, uint64(commit.Timestamp.Unix()))
+	contents := sha256.Sum256([]byte(commit.Subject + "contents"))
+	dirmeta := sha256.Sum256([]byte(commit.Subject + "dirmeta"))
+
+	return tuple(
+		[][]byte{dict(meta), parent, nil, str(commit.Subject), str(commit.Body), timestamp, contents[:], dirmeta[:]},
+		[]int{8, 1, 1, 1, 1, 8, 1, 1},
+		[]bool{false, false, false, false, false, true, false, false},
+	)
+}
+
+// summary serializes the summary of the refs as (a(s(taya{sv}))a{sv})
+func summary(refs map[string]string) []byte {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([][]byte, 0, len(names))
+	for _, name := range names {
+		value := tuple([][]byte{make([]byte, 8), checksumBytes(refs[name]), dict(nil)}, []int{8, 1, 8}, []bool{true, false, false})
+		entries = append(entries, tuple([][]byte{str(name), value}, []int{1, 8}, []bool{false, false}))
+	}
+	return tuple([][]byte{array(entries, 8), dict(nil)}, []int{8, 8}, []bool{false, false})
+}
@@ -28,12 +28,21 @@ var (
 type SourceSpec struct {
 	URL string
 	Ref string
+	// ContentURL is the location the objects of the commits are fetched
+	// from, if it differs from the URL that serves the refs (optional)
+	ContentURL string
 	// RHSM indicates to use RHSM secrets when pulling from the remote. Alternatively, you can use MTLS with plain certs.
 	RHSM bool
 	// MTLS information. Will be ignored if RHSM is set.
 	MTLS *MTLS
 	// Proxy as HTTP proxy to use when fetching the ref.
 	Proxy string
+	// RequireComposefs verifies that the resolved commit carries composefs
+	// metadata. Requires a URL.
+	RequireComposefs bool
+	// Verify the signature of the resolved commit with the public keys.
+	// Requires a URL.
+	Verify *VerifyOptions
 }
 
 // MTLS contains the options for resolving an ostree source.
@@ -85,6 +94,26 @@ type ImageOptions struct {
 	// Indicate if the 'org.osbuild.rhsm.consumer' secret should be added when pulling from the
 	// remote.
 	RHSM bool `json:"rhsm"`
+
+	// For ostree commit and container types: Sign the new commit.
+	// For ostree installers and raw images: Sign does not apply.
+	Sign *SigningOptions `json:"sign,omitempty"`
+
+	// For ostree commit types: DeltaFrom is the ref or checksum of a commit
+	// that a static delta to the new commit is generated from. The commit
+	// is fetched from the URL.
+	// For all other types: DeltaFrom does not apply.
+	DeltaFrom string `json:"delta_from,omitempty"`
+
+	// For ostree commit types: Generate a static delta from scratch, which
+	// contains the whole new commit.
+	// For all other types: DeltaFromScratch does not apply.
+	DeltaFromScratch bool `json:"delta_from_scratch,omitempty"`
+
+	// For ostree installers and raw images: Verify the signature of the
+	// commit being embedded or deployed.
+	// For ostree commit and container types: Verify does not apply.
+	Verify *VerifyOptions `json:"verify,omitempty"`
 }
 
 // Validate the image options. This doesn't verify the existence of any remote
@@ -96,7 +125,10 @@ type ImageOptions struct {
 // checksum.
 // - The ParentRef, if specified, must be a valid ref or a checksum.
 // - If the ParentRef is specified, the URL must also be specified.
+// - The DeltaFrom, if specified, must be a valid ref or a checksum and the URL
+// must also be specified.
 // - URLs must be valid.
+// - The signing and verification options, if specified, must be valid.
 func (options ImageOptions) Validate() error {
 	if ref := options.ImageRef; ref != "" {
 		// image ref must not look like a checksum
@@ -119,6 +151,17 @@ func (options ImageOptions) Validate() error {
 		}
 	}
 
+	if from := options.DeltaFrom; from != "" {
+		if !verifyChecksum(from) && !verifyRef(from) {
+			return NewRefError("invalid ostree static delta ref or commit %q", from)
+		}
+
+		// valid URL required
+		if purl := options.URL; purl == "" {
+			return NewParameterComboError("ostree static delta ref specified, but no URL to retrieve it")
+		}
+	}
+
 	// whether required or not, any URL specified must be valid
 	if purl := options.URL; purl != "" {
 		if _, err := url.ParseRequestURI(purl); err != nil {
@@ -132,6 +175,18 @@ func (options ImageOptions) Validate() error {
 		}
 	}
 
+	if options.Sign != nil {
+		if err := options.Sign.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if options.Verify != nil {
+		if err := options.Verify.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -207,104 +262,157 @@ func httpClientForRef(scheme string, ss SourceSpec) (*http.Client, error) {
 // (location+"refs/heads/"+ref) and returns the commit ID for the named ref. If
 // there is an error, it will be of type ResolveRefError.
 func resolveRef(ss SourceSpec) (string, error) {
-	u, err := url.Parse(ss.URL)
+	body, err := fetchFile(ss, path.Join("refs", "heads", ss.Ref), false)
+	if err != nil {
+		return "", err
+	}
+	checksum := strings.TrimSpace(string(body))
+	// Check that this is at least a hex string.
+	_, err = hex.DecodeString(checksum)
 	if err != nil {
-		return "", NewResolveRefError("error parsing ostree repository location: %v", err)
+		return "", NewResolveRefError("ostree repository %q returned invalid reference for %q", ss.URL, ss.Ref)
 	}
-	u.Path = path.Join(u.Path, "refs", "heads", ss.Ref)
+	return checksum, nil
+}
 
-	client, err := httpClientForRef(u.Scheme, ss)
+// fetchObject fetches the object with the given checksum and extension,
+// e.g. "commit", from the repository at ss.ContentURL, or ss.URL if it is
+// not set. If optional is set a missing object is not an error and nil is
+// returned. If there is an error, it will be of type ResolveRefError.
+func fetchObject(ss SourceSpec, checksum, ext string, optional bool) ([]byte, error) {
+	if len(checksum) < 3 {
+		return nil, NewResolveRefError("invalid commit checksum %q", checksum)
+	}
+	if ss.ContentURL != "" {
+		ss.URL = ss.ContentURL
+	}
+	return fetchFile(ss, path.Join("objects", checksum[:2], checksum[2:]+"."+ext), optional)
+}
+
+// fetchFile fetches the file at the path relative to the repository at
+// ss.URL. If optional is set a missing file is not an error and nil is
+// returned. If there is an error, it will be of type ResolveRefError.
+func fetchFile(ss SourceSpec, relPath string, optional bool) ([]byte, error) {
+	u, err := url.Parse(ss.URL)
 	if err != nil {
-		return "", err
+		return nil, NewResolveRefError("error parsing ostree repository location: %v", err)
 	}
+	u.Path = path.Join(u.Path, relPath)
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	client, err := httpClientForRef(u.Scheme, ss)
 	if err != nil {
-		return "", NewResolveRefError("error preparing ostree resolve request: %s", err)
+		return nil, err
 	}
 
-	resp, err := client.Do(req)
+	resp, err := client.Get(u.String())
 	if err != nil {
-		return "", NewResolveRefError("error sending request to ostree repository %q: %v", u.String(), err)
+		return nil, NewResolveRefError("error sending request to ostree repository %q: %v", u.String(), err)
+	}
+	defer resp.Body.Close()
+	if optional && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
 	}
 	if resp.StatusCode != http.StatusOK {
-		return "", NewResolveRefError("ostree repository %q returned status: %s", u.String(), resp.Status)
+		return nil, NewResolveRefError("ostree repository %q returned status: %s", u.String(), resp.Status)
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", NewResolveRefError("error reading response from ostree repository %q: %v", u.String(), err)
+		return nil, NewResolveRefError("error reading response from ostree repository %q: %v", u.String(), err)
 	}
-	checksum := strings.TrimSpace(string(body))
-	// Check that this is at least a hex string.
-	_, err = hex.DecodeString(checksum)
-	if err != nil {
-		return "", NewResolveRefError("ostree repository %q returned invalid reference", u.String())
-	}
-	return checksum, nil
+	return body, nil
 }
 
-// Resolve the ostree source specification to a commit specification.
-//
-// If a URL is defined in the source specification, the checksum of the ref is
-// resolved, otherwise the checksum is an empty string. Failure to resolve the
-// checksum results in a ResolveRefError.
-//
-// If the ref is already a checksum (64 alphanumeric characters), it is not
-// resolved or checked against the repository.
-//
-// If the ref is malformed, the function returns with a RefError.
-func Resolve(source SourceSpec) (CommitSpec, error) {
-	commit := CommitSpec{
-		Ref: source.Ref,
-		URL: source.URL,
-	}
-
+// withSubscription returns the source with the MTLS options set to the
+// consumer certificates of the system's RHSM subscription if RHSM is set,
+// and the name of the secrets that are needed to fetch from the source.
+// If there is an error, it will be of type ResolveRefError.
+func withSubscription(source SourceSpec) (SourceSpec, string, error) {
 	if source.RHSM && source.MTLS != nil {
-		return commit, NewResolveRefError("cannot use both RHSM and MTLS when resolving ref")
+		return source, "", NewResolveRefError("cannot use both RHSM and MTLS when resolving ref")
 	}
 
 	if source.RHSM {
-		var subs *rhsm.Subscriptions
-		var err error
-
-		commit.Secrets = "org.osbuild.rhsm.consumer"
-		subs, err = rhsm.LoadSystemSubscriptions()
-
+		subs, err := rhsm.LoadSystemSubscriptions()
 		if err != nil {
-			return commit, NewResolveRefError("error adding rhsm certificates when resolving ref: %s", err)
+			return source, "", NewResolveRefError("error adding rhsm certificates when resolving ref: %s", err)
 		}
 
 		if subs.Consumer == nil {
-			return commit, NewResolveRefError("error adding rhsm certificates when resolving ref")
+			return source, "", NewResolveRefError("error adding rhsm certificates when resolving ref")
 		}
 
+		source.RHSM = false
 		source.MTLS = &MTLS{
 			ClientCert: subs.Consumer.ConsumerCert,
 			ClientKey:  subs.Consumer.ConsumerKey,
 		}
+		return source, "org.osbuild.rhsm.consumer", nil
 	} else if source.MTLS != nil {
-		commit.Secrets = "org.osbuild.mtls"
+		return source, "org.osbuild.mtls", nil
 	}
+	return source, "", nil
+}
 
-	if verifyChecksum(source.Ref) {
-		// the ref is a commit: return as is
-		commit.Checksum = source.Ref
-		return commit, nil
+// Resolve the ostree source specification to a commit specification.
+//
+// If a URL is defined in the source specification, the checksum of the ref is
+// resolved, otherwise the checksum is an empty string. Failure to resolve the
+// checksum results in a ResolveRefError.
+//
+// If the ref is already a checksum (64 alphanumeric characters), it is not
+// resolved or checked against the repository.
+//
+// If the ref is malformed, the function returns with a RefError.
+//
+// If RequireComposefs is set, the commit object is fetched from the
+// repository and must carry composefs metadata.
+//
+// If Verify is set, the commit must be signed by one of its keys, otherwise
+// the function returns with a SignatureError.
+func Resolve(source SourceSpec) (CommitSpec, error) {
+	commit := CommitSpec{
+		Ref:        source.Ref,
+		URL:        source.URL,
+		ContentURL: source.ContentURL,
 	}
 
-	if !verifyRef(source.Ref) {
-		// the ref is not a commit and it's also an invalid ref
-		return CommitSpec{}, NewRefError("Invalid ostree ref or commit %q", source.Ref)
+	source, secrets, err := withSubscription(source)
+	if err != nil {
+		return commit, err
 	}
+	commit.Secrets = secrets
 
-	// URL set: Resolve checksum
-	if source.URL != "" {
-		// If a URL is specified, we need to fetch the commit at the URL.
+	if verifyChecksum(source.Ref) {
+		// the ref is a commit: use as is
+		commit.Checksum = source.Ref
+	} else if !verifyRef(source.Ref) {
+		// the ref is not a commit and it's also an invalid ref
+		return CommitSpec{}, NewRefError("Invalid ostree ref or commit %q", source.Ref)
+	} else if source.URL != "" {
+		// URL set: Resolve checksum
 		checksum, err := resolveRef(source)
 		if err != nil {
 			return CommitSpec{}, err // ResolveRefError
 		}
 		commit.Checksum = checksum
 	}
+
+	if source.RequireComposefs {
+		if source.URL == "" {
+			return CommitSpec{}, NewParameterComboError("composefs verification of ostree ref %q requires a URL", source.Ref)
+		}
+		if err := verifyComposefs(source, commit.Checksum); err != nil {
+			return CommitSpec{}, err
+		}
+	}
+
+	if source.Verify != nil {
+		if source.URL == "" {
+			return CommitSpec{}, NewParameterComboError("signature verification of ostree ref %q requires a URL", source.Ref)
+		}
+		if err := verifySignature(source, commit.Checksum); err != nil {
+			return CommitSpec{}, err
+		}
+	}
 	return commit, nil
 }
@@ -82,9 +82,12 @@ func TestOstreeResolveRef(t *testing.T) {
 			out, err := resolveRef(SourceSpec{
 				in.location,
 				in.ref,
+				"",
 				srvConf.RHSM,
 				&MTLS{mTLSSrv.CAPath, mTLSSrv.ClientCrtPath, mTLSSrv.ClientKeyPath},
 				"",
+				false,
+				nil,
 			})
 			require.NoError(t, err)
 			assert.Equal(t, expOut, out)
@@ -95,15 +98,18 @@ func TestOstreeResolveRef(t *testing.T) {
 			{"http://0.0.0.0:10/repo", "whatever"}:  "error sending request to ostree repository \"http://0.0.0.0:10/repo/refs/heads/whatever\": Get \"http://0.0.0.0:10/repo/refs/heads/whatever\": dial tcp 0.0.0.0:10: connect: connection refused",
 			{srvConf.Srv.URL, "rhel/8/x86_64/edge"}: fmt.Sprintf("ostree repository \"%s/refs/heads/rhel/8/x86_64/edge\" returned status: 404 Not Found", srvConf.Srv.URL),
 			{srvConf.Srv.URL, "test_forbidden"}:     fmt.Sprintf("ostree repository \"%s/refs/heads/test_forbidden\" returned status: 403 Forbidden", srvConf.Srv.URL),
-			{srvConf.Srv.URL, "get_bad_ref"}:        fmt.Sprintf("ostree repository \"%s/refs/heads/get_bad_ref\" returned invalid reference", srvConf.Srv.URL),
+			{srvConf.Srv.URL, "get_bad_ref"}:        fmt.Sprintf("ostree repository %q returned invalid reference for \"get_bad_ref\"", srvConf.Srv.URL),
 		}
 		for in, expMsg := range errCases {
 			_, err := resolveRef(SourceSpec{
 				in.location,
 				in.ref,
+				"",
 				srvConf.RHSM,
 				&MTLS{mTLSSrv.CAPath, mTLSSrv.ClientCrtPath, mTLSSrv.ClientKeyPath},
 				"",
+				false,
+				nil,
 			})
 			assert.EqualError(t, err, expMsg)
 		}
@@ -218,6 +224,29 @@ func TestValidate(t *testing.T) {
 			},
 			valid: false,
 		},
+		"delta-from-valid": {
+			options: ImageOptions{
+				ImageRef:  "fedora/39/x86_64/iot",
+				DeltaFrom: "c70e4ceff1726cb986eafd0230e2e1b0e5ebe590d0498a9f7c370c8ec3797deb",
+				URL:       "https://repo.example.com",
+			},
+			valid: true,
+		},
+		"delta-from-invalid": {
+			options: ImageOptions{
+				ImageRef:  "fedora/39/x86_64/iot",
+				DeltaFrom: "-bad",
+				URL:       "https://repo.example.com",
+			},
+			valid: false,
+		},
+		"delta-from-without-url": {
+			options: ImageOptions{
+				ImageRef:  "fedora/39/x86_64/iot",
+				DeltaFrom: "fedora/39/x86_64/iot",
+			},
+			valid: false,
+		},
 	}
 
 	for name, testCase := range cases {
@@ -0,0 +1,245 @@
+package ostree
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"time"
+)
+
+const (
+	// Keys of the commit metadata
+	versionKey  = "version"
+	linuxKey    = "ostree.linux"
+	bootableKey = "ostree.bootable"
+)
+
+// RemoteRef is a ref listed in the summary of a remote repository.
+type RemoteRef struct {
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+}
+
+// CommitInfo contains the metadata of a commit in a remote repository.
+type CommitInfo struct {
+	Checksum  string    `json:"checksum"`
+	Timestamp time.Time `json:"timestamp"`
+	Version   string    `json:"version,omitempty"`
+	Parent    string    `json:"parent,omitempty"`
+	Subject   string    `json:"subject,omitempty"`
+	Body      string    `json:"body,omitempty"`
+
+	// Bootable is set for commits that contain a kernel, whose version is
+	// KernelVersion
+	Bootable      bool   `json:"bootable"`
+	KernelVersion string `json:"kernel_version,omitempty"`
+}
+
+// ListRefs returns the refs of the repository at the source's URL as listed
+// in the summary file of the repository. The source's Ref is ignored.
+// Failures to fetch or parse the summary are of type ResolveRefError.
+func ListRefs(source SourceSpec) ([]RemoteRef, error) {
+	if source.URL == "" {
+		return nil, NewParameterComboError("listing ostree refs requires a URL")
+	}
+	source, _, err := withSubscription(source)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := fetchFile(source, "summary", false)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := parseSummary(summary)
+	if err != nil {
+		return nil, NewResolveRefError("invalid summary of ostree repository %q: %s", source.URL, err)
+	}
+	return refs, nil
+}
+
+// InspectCommit resolves the source's Ref, which may be a ref or a checksum,
+// and returns the metadata of the commit. The source is resolved like by
+// Resolve, i.e. with composefs and signature verification if required.
+func InspectCommit(source SourceSpec) (CommitInfo, error) {
+	history, err := CommitHistory(source, 1)
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	return history[0], nil
+}
+
+// CommitHistory resolves the source's Ref like InspectCommit and returns the
+// metadata of the commit followed by its ancestors, up to maxDepth commits in
+// total or all of them if maxDepth is not positive. Repositories often do not
+// contain the whole history of a ref, the history ends at the first parent
+// that is missing in the repository.
+func CommitHistory(source SourceSpec, maxDepth int) ([]CommitInfo, error) {
+	if source.URL == "" {
+		return nil, NewParameterComboError("inspecting ostree ref %q requires a URL", source.Ref)
+	}
+	commit, err := Resolve(source)
+	if err != nil {
+		return nil, err
+	}
+	source, _, err = withSubscription(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []CommitInfo
+	checksum := commit.Checksum
+	optional := false
+	for checksum != "" && (maxDepth <= 0 || len(history) < maxDepth) {
+		data, err := fetchObject(source, checksum, "commit", optional)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			break
+		}
+		info, err := parseCommit(checksum, data)
+		if err != nil {
+			return nil, NewResolveRefError("invalid ostree commit %s in %q: %s", checksum, source.URL, err)
+		}
+		history = append(history, info)
+		checksum = info.Parent
+		optional = true
+	}
+	return history, nil
+}
+
+// CheckParent checks that the ParentRef, if specified, exists in the
+// repository at the URL, so that options with a parent that cannot be
+// resolved are rejected before a build is started, e.g. by the manifest
+// generator. It accesses the repository, so the image types do not call it
+// when they validate their options. The repository is accessed with the
+// RHSM option or mtls, and the proxy, the objects are fetched from the
+// ContentURL if set. A missing parent is a RefError, failures to access the
+// repository are of type ResolveRefError.
+func (options ImageOptions) CheckParent(mtls *MTLS, proxy string) error {
+	parent := options.ParentRef
+	if parent == "" {
+		return nil
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	source, _, err := withSubscription(SourceSpec{
+		URL:        options.URL,
+		ContentURL: options.ContentURL,
+		Ref:        parent,
+		RHSM:       options.RHSM,
+		MTLS:       mtls,
+		Proxy:      proxy,
+	})
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if verifyChecksum(parent) {
+		data, err = fetchObject(source, parent, "commit", true)
+	} else {
+		data, err = fetchFile(source, path.Join("refs", "heads", parent), true)
+	}
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return NewRefError("ostree parent ref or commit %q does not exist in %q", parent, options.URL)
+	}
+	return nil
+}
+
+// parseSummary returns the refs of a summary file, a GVariant of type
+// (a(s(taya{sv}))a{sv})
+func parseSummary(data []byte) ([]RemoteRef, error) {
+	summary, err := gvariantTuple(data, []gvariantMember{{alignment: 8}, {alignment: 8}})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := gvariantArray(summary[0], 8)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]RemoteRef, 0, len(entries))
+	for _, entry := range entries {
+		ref, err := gvariantTuple(entry, []gvariantMember{{alignment: 1}, {alignment: 8}})
+		if err != nil {
+			return nil, err
+		}
+		name, err := gvariantString(ref[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := gvariantTuple(ref[1], []gvariantMember{{alignment: 8, size: 8}, {alignment: 1}, {alignment: 8}})
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, RemoteRef{
+			Name:     name,
+			Checksum: hex.EncodeToString(value[1]),
+		})
+	}
+	return refs, nil
+}
+
+// parseCommit returns the metadata of a commit object, a GVariant of type
+// (a{sv}aya(say)sstayay)
+func parseCommit(checksum string, data []byte) (CommitInfo, error) {
+	commit, err := gvariantTuple(data, []gvariantMember{
+		{alignment: 8},          // metadata
+		{alignment: 1},          // parent checksum
+		{alignment: 1},          // related objects
+		{alignment: 1},          // subject
+		{alignment: 1},          // body
+		{alignment: 8, size: 8}, // timestamp
+		{alignment: 1},          // root contents checksum
+		{alignment: 1},          // root metadata checksum
+	})
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	subject, err := gvariantString(commit[3])
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	body, err := gvariantString(commit[4])
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	info := CommitInfo{
+		Checksum:  checksum,
+		Parent:    hex.EncodeToString(commit[1]),
+		Subject:   subject,
+		Body:      body,
+		Timestamp: time.Unix(int64(binary.BigEndian.Uint64(commit[5])), 0).UTC(),
+	}
+
+	meta, err := gvariantDict(commit[0])
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	for key, value := range meta {
+		typ, value, err := gvariantVariant(value)
+		if err != nil {
+			return CommitInfo{}, err
+		}
+		switch {
+		case key == versionKey && typ == "s":
+			info.Version, err = gvariantString(value)
+		case key == linuxKey && typ == "s":
+			info.KernelVersion, err = gvariantString(value)
+		case key == bootableKey && typ == "b":
+			if len(value) != 1 {
+				err = fmt.Errorf("invalid boolean")
+			}
+			info.Bootable = len(value) == 1 && value[0] == 1
+		}
+		if err != nil {
+			return CommitInfo{}, fmt.Errorf("invalid metadata %s: %w", key, err)
+		}
+	}
+	return info, nil
+}
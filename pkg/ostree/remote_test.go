@@ -0,0 +1,193 @@
+package ostree
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/ostree/mock_ostree_repo"
+	"github.com/osbuild/images/pkg/ostree/test_mtls_server"
+)
+
+func TestRemoteInspect(t *testing.T) {
+	repo := mock_ostree_repo.Setup("test/ref")
+	defer repo.TearDown()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first := repo.AddCommit("fedora/x86_64/iot", mock_ostree_repo.Commit{
+		Subject:   "first",
+		Timestamp: created,
+		Version:   "41.1",
+	})
+	second := repo.AddCommit("fedora/x86_64/iot", mock_ostree_repo.Commit{
+		Parent:        first,
+		Subject:       "second",
+		Body:          "with a kernel",
+		Timestamp:     created.Add(time.Hour),
+		Version:       "41.2",
+		KernelVersion: "6.11.4-301.fc41.x86_64",
+	})
+	third := repo.AddCommit("fedora/x86_64/iot", mock_ostree_repo.Commit{
+		Parent:    second,
+		Subject:   "third",
+		Timestamp: created.Add(2 * time.Hour),
+	})
+
+	mTLSSrv, err := test_mtls_server.NewMTLSServer(repo)
+	require.NoError(t, err)
+	defer mTLSSrv.Server.Close()
+
+	sources := map[string]SourceSpec{
+		"plain": {URL: repo.Server.URL},
+		"mtls":  {URL: mTLSSrv.Server.URL, MTLS: &MTLS{mTLSSrv.CAPath, mTLSSrv.ClientCrtPath, mTLSSrv.ClientKeyPath}},
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			refs, err := ListRefs(source)
+			require.NoError(t, err)
+			assert.Equal(t, []RemoteRef{
+				{Name: "fedora/x86_64/iot", Checksum: third},
+				{Name: "test/ref", Checksum: fmt.Sprintf("%x", sha256.Sum256([]byte(repo.Server.URL+"test/ref")))},
+			}, refs)
+
+			source.Ref = "fedora/x86_64/iot"
+			info, err := InspectCommit(source)
+			require.NoError(t, err)
+			assert.Equal(t, CommitInfo{
+				Checksum:  third,
+				Parent:    second,
+				Subject:   "third",
+				Timestamp: created.Add(2 * time.Hour),
+			}, info)
+
+			source.Ref = second
+			info, err = InspectCommit(source)
+			require.NoError(t, err)
+			assert.Equal(t, CommitInfo{
+				Checksum:      second,
+				Parent:        first,
+				Subject:       "second",
+				Body:          "with a kernel",
+				Timestamp:     created.Add(time.Hour),
+				Version:       "41.2",
+				Bootable:      true,
+				KernelVersion: "6.11.4-301.fc41.x86_64",
+			}, info)
+
+			source.Ref = "fedora/x86_64/iot"
+			history, err := CommitHistory(source, 0)
+			require.NoError(t, err)
+			require.Len(t, history, 3)
+			assert.Equal(t, []string{third, second, first}, []string{history[0].Checksum, history[1].Checksum, history[2].Checksum})
+			assert.Equal(t, "", history[2].Parent)
+
+			history, err = CommitHistory(source, 2)
+			require.NoError(t, err)
+			assert.Len(t, history, 2)
+		})
+	}
+
+	// the history ends at the first missing parent
+	repo.RemoveCommit(first)
+	history, err := CommitHistory(SourceSpec{URL: repo.Server.URL, Ref: "fedora/x86_64/iot"}, 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	// but the commit itself has to exist
+	_, err = InspectCommit(SourceSpec{URL: repo.Server.URL, Ref: first})
+	assert.IsType(t, ResolveRefError{}, err)
+
+	_, err = InspectCommit(SourceSpec{URL: repo.Server.URL, Ref: "fedora/x86_64/missing"})
+	assert.IsType(t, ResolveRefError{}, err)
+
+	_, err = InspectCommit(SourceSpec{Ref: "fedora/x86_64/iot"})
+	assert.IsType(t, ParameterComboError{}, err)
+
+	_, err = ListRefs(SourceSpec{})
+	assert.IsType(t, ParameterComboError{}, err)
+}
+
+// Test that the objects serialized by GLib in the test repository are parsed
+func TestRemoteInspectTestRepo(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("../../test/data/ostree-repo/")))
+	defer server.Close()
+
+	first := "e26ae015e47d006c7df3b9309347a1d5a68e0e286e81b809c44c69f42a0c1d1c"
+	second := "29897957544a174ff49c0c79c6cec378785c4fe3d4b08147d56ccc0295dc5b1a"
+	third := "6e5907827078dbd9a77902121a7655c3b0e6f16618364b975ff08ccd5c7ff204"
+
+	refs, err := ListRefs(SourceSpec{URL: server.URL})
+	require.NoError(t, err)
+	assert.Equal(t, []RemoteRef{
+		{Name: "fedora/41/x86_64/iot", Checksum: third},
+		{Name: "fedora/41/x86_64/testing/iot", Checksum: second},
+	}, refs)
+
+	history, err := CommitHistory(SourceSpec{URL: server.URL, Ref: "fedora/41/x86_64/iot"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []CommitInfo{
+		{
+			Checksum:      third,
+			Timestamp:     time.Date(2024, 11, 3, 8, 0, 0, 0, time.UTC),
+			Version:       "41.20241103.0",
+			Parent:        second,
+			Subject:       "41.20241103.0",
+			Bootable:      true,
+			KernelVersion: "6.11.6-300.fc41.x86_64",
+		},
+		{
+			Checksum:      second,
+			Timestamp:     time.Date(2024, 11, 2, 8, 0, 0, 0, time.UTC),
+			Version:       "41.20241102.0",
+			Parent:        first,
+			Subject:       "41.20241102.0",
+			Body:          "Rebuilt with updated packages",
+			Bootable:      true,
+			KernelVersion: "6.11.5-300.fc41.x86_64",
+		},
+		{
+			Checksum:      first,
+			Timestamp:     time.Date(2024, 11, 1, 8, 0, 0, 0, time.UTC),
+			Version:       "41.20241101.0",
+			Subject:       "41.20241101.0",
+			Bootable:      true,
+			KernelVersion: "6.11.5-300.fc41.x86_64",
+		},
+	}, history)
+}
+
+func TestCheckParent(t *testing.T) {
+	repo := mock_ostree_repo.Setup("test/ref")
+	defer repo.TearDown()
+	checksum := repo.AddCommit("fedora/x86_64/iot", mock_ostree_repo.Commit{Subject: "commit"})
+
+	for _, parent := range []string{"", "fedora/x86_64/iot", checksum} {
+		options := ImageOptions{ImageRef: "fedora/x86_64/iot", ParentRef: parent, URL: repo.Server.URL}
+		assert.NoError(t, options.CheckParent(nil, ""), parent)
+	}
+
+	missing := "0000000000000000000000000000000000000000000000000000000000000000"
+	for _, parent := range []string{"fedora/x86_64/missing", missing} {
+		options := ImageOptions{ImageRef: "fedora/x86_64/iot", ParentRef: parent, URL: repo.Server.URL}
+		err := options.CheckParent(nil, "")
+		assert.IsType(t, RefError{}, err)
+		assert.EqualError(t, err, fmt.Sprintf("ostree parent ref or commit %q does not exist in %q", parent, repo.Server.URL))
+	}
+
+	options := ImageOptions{ImageRef: "fedora/x86_64/iot", ParentRef: "fedora/x86_64/iot"}
+	assert.IsType(t, ParameterComboError{}, options.CheckParent(nil, ""))
+}
+
+func TestParseCommitInvalid(t *testing.T) {
+	_, err := parseCommit("", []byte{0xff})
+	assert.EqualError(t, err, "invalid tuple framing")
+
+	_, err = parseSummary([]byte{0x01, 0x02, 0x03})
+	assert.Error(t, err)
+}
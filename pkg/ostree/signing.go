@@ -0,0 +1,218 @@
+package ostree
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+const (
+	// Types of commit signatures
+	SignatureTypeEd25519 = "ed25519"
+	SignatureTypeGPG     = "gpg"
+
+	// Keys of the detached commit metadata that hold the signatures of
+	// the commit
+	ed25519SignaturesKey = "ostree.sign.ed25519"
+	gpgSignaturesKey     = "ostree.gpgsigs"
+)
+
+// SigningOptions configure the signing of the commits that are built. The
+// secret key is provided by osbuild at build time through the
+// org.osbuild.ostree.sign secret and is never part of the manifest.
+type SigningOptions struct {
+	// Type of the signature, SignatureTypeEd25519 or SignatureTypeGPG
+	Type string `json:"type"`
+
+	// PublicKey is the base64 encoded ed25519 public key or the ASCII
+	// armored GPG public key of the signing key, the signature is checked
+	// against it after signing
+	PublicKey string `json:"public_key"`
+
+	// KeyID of the GPG key, required for GPG signatures
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// Validate checks that the type is known and the public key can be used
+// for it. For GPG signatures the public key must contain the key with the
+// KeyID.
+func (o *SigningOptions) Validate() error {
+	if o.PublicKey == "" {
+		return fmt.Errorf("ostree signing public key is empty")
+	}
+	switch o.Type {
+	case SignatureTypeEd25519:
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(o.PublicKey))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("ostree signing key is not a base64 encoded ed25519 public key")
+		}
+	case SignatureTypeGPG:
+		if o.KeyID == "" {
+			return NewParameterComboError("ostree GPG signing requires a key ID")
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(o.PublicKey))
+		if err != nil {
+			return fmt.Errorf("cannot read ostree signing GPG key: %w", err)
+		}
+		for _, entity := range entities {
+			if entity.PrivateKey != nil {
+				return fmt.Errorf("ostree signing GPG key is a private key, only the public key can be used")
+			}
+		}
+		if !gpgKeyringHasKeyID(entities, o.KeyID) {
+			return fmt.Errorf("ostree signing GPG key has no key with ID %q", o.KeyID)
+		}
+	default:
+		return fmt.Errorf("unknown ostree signature type %q", o.Type)
+	}
+	return nil
+}
+
+// gpgKeyringHasKeyID returns true if one of the keys or subkeys of the
+// keyring has the key ID, which can be the long or short ID or the
+// fingerprint
+func gpgKeyringHasKeyID(keyring openpgp.EntityList, keyID string) bool {
+	keyID = strings.ToUpper(strings.TrimPrefix(keyID, "0x"))
+	matches := func(fingerprint []byte) bool {
+		return strings.HasSuffix(fmt.Sprintf("%X", fingerprint), keyID)
+	}
+	for _, entity := range keyring {
+		if matches(entity.PrimaryKey.Fingerprint) {
+			return true
+		}
+		for _, subkey := range entity.Subkeys {
+			if matches(subkey.PublicKey.Fingerprint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VerifyOptions contain the trusted public keys that verify the signature of
+// a resolved commit. The commit needs a valid signature by one of them.
+type VerifyOptions struct {
+	// Ed25519Keys are base64 encoded ed25519 public keys
+	Ed25519Keys []string `json:"ed25519_keys,omitempty"`
+
+	// GPGKeys are ASCII armored GPG public keys
+	GPGKeys []string `json:"gpg_keys,omitempty"`
+}
+
+// Validate checks that there is at least one key and all keys are valid.
+func (o *VerifyOptions) Validate() error {
+	if len(o.Ed25519Keys) == 0 && len(o.GPGKeys) == 0 {
+		return fmt.Errorf("ostree signature verification requires at least one public key")
+	}
+	if _, err := o.ed25519Keys(); err != nil {
+		return err
+	}
+	if _, err := o.gpgKeyring(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *VerifyOptions) ed25519Keys() ([]ed25519.PublicKey, error) {
+	keys := make([]ed25519.PublicKey, 0, len(o.Ed25519Keys))
+	for _, encoded := range o.Ed25519Keys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("ostree verification key %q is not a base64 encoded ed25519 public key", encoded)
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	return keys, nil
+}
+
+func (o *VerifyOptions) gpgKeyring() (openpgp.EntityList, error) {
+	var keyring openpgp.EntityList
+	for _, armored := range o.GPGKeys {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ostree verification GPG key: %w", err)
+		}
+		keyring = append(keyring, entities...)
+	}
+	return keyring, nil
+}
+
+// verifySignature fetches the commit object and its detached metadata for
+// the given checksum from the repository at ss.URL and checks that the
+// commit is signed by one of the keys of ss.Verify. Failures to fetch the
+// objects are of type ResolveRefError, missing or invalid signatures of
+// type SignatureError.
+func verifySignature(ss SourceSpec, checksum string) error {
+	ed25519Keys, err := ss.Verify.ed25519Keys()
+	if err != nil {
+		return NewSignatureError("%s", err)
+	}
+	keyring, err := ss.Verify.gpgKeyring()
+	if err != nil {
+		return NewSignatureError("%s", err)
+	}
+
+	commit, err := fetchObject(ss, checksum, "commit", false)
+	if err != nil {
+		return err
+	}
+	meta, err := fetchObject(ss, checksum, "commitmeta", true)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return NewSignatureError("ostree commit %s in %q is not signed", checksum, ss.URL)
+	}
+
+	if len(ed25519Keys) > 0 {
+		sigs, err := detachedSignatures(meta, ed25519SignaturesKey)
+		if err != nil {
+			return NewSignatureError("invalid detached metadata of ostree commit %s: %s", checksum, err)
+		}
+		for _, sig := range sigs {
+			for _, key := range ed25519Keys {
+				if ed25519.Verify(key, commit, sig) {
+					return nil
+				}
+			}
+		}
+	}
+	if len(keyring) > 0 {
+		sigs, err := detachedSignatures(meta, gpgSignaturesKey)
+		if err != nil {
+			return NewSignatureError("invalid detached metadata of ostree commit %s: %s", checksum, err)
+		}
+		for _, sig := range sigs {
+			if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(commit), bytes.NewReader(sig), nil); err == nil {
+				return nil
+			}
+		}
+	}
+	return NewSignatureError("ostree commit %s in %q is not signed by any of the trusted keys", checksum, ss.URL)
+}
+
+// detachedSignatures returns the signatures stored under key in the
+// detached commit metadata, a GVariant of type a{sv} whose signatures are
+// of type aay.
+func detachedSignatures(meta []byte, key string) ([][]byte, error) {
+	dict, err := gvariantDict(meta)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := dict[key]
+	if !ok {
+		return nil, nil
+	}
+	typ, sigs, err := gvariantVariant(value)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "aay" {
+		return nil, fmt.Errorf("%s has type %q instead of \"aay\"", key, typ)
+	}
+	return gvariantArray(sigs, 1)
+}
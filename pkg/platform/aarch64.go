@@ -17,16 +17,28 @@ func (p *Aarch64) GetUEFIVendor() string {
 	return p.UEFIVendor
 }
 
+func (p *Aarch64) GetBuildPackages() []string {
+	if p.UEFIVendor != "" && p.Bootloader == BOOTLOADER_UKI {
+		return append([]string{}, ukiBuildPackages...)
+	}
+	return []string{}
+}
+
 func (p *Aarch64) GetPackages() []string {
 	packages := p.BasePlatform.FirmwarePackages
 
 	if p.UEFIVendor != "" {
-		packages = append(packages,
-			"dracut-config-generic",
-			"efibootmgr",
-			"grub2-efi-aa64",
-			"grub2-tools",
-			"shim-aa64")
+		switch p.Bootloader {
+		case BOOTLOADER_UKI:
+			packages = append(packages, ukiPackages...)
+		default:
+			packages = append(packages,
+				"dracut-config-generic",
+				"efibootmgr",
+				"grub2-efi-aa64",
+				"grub2-tools",
+				"shim-aa64")
+		}
 	}
 
 	return packages
@@ -50,17 +62,29 @@ func (p *Aarch64_Fedora) GetPackages() []string {
 	packages := p.BasePlatform.FirmwarePackages
 
 	if p.UEFIVendor != "" {
-		packages = append(packages,
-			"dracut-config-generic",
-			"efibootmgr",
-			"grub2-efi-aa64",
-			"grub2-tools",
-			"shim-aa64")
+		switch p.Bootloader {
+		case BOOTLOADER_UKI:
+			packages = append(packages, ukiPackages...)
+		default:
+			packages = append(packages,
+				"dracut-config-generic",
+				"efibootmgr",
+				"grub2-efi-aa64",
+				"grub2-tools",
+				"shim-aa64")
+		}
 	}
 
 	return packages
 }
 
+func (p *Aarch64_Fedora) GetBuildPackages() []string {
+	if p.UEFIVendor != "" && p.Bootloader == BOOTLOADER_UKI {
+		return append([]string{}, ukiBuildPackages...)
+	}
+	return []string{}
+}
+
 func (p *Aarch64_Fedora) GetBootFiles() [][2]string {
 	return p.BootFiles
 }
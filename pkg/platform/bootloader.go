@@ -0,0 +1,98 @@
+package platform
+
+import (
+	"fmt"
+)
+
+// Bootloader selects the boot chain that is installed on a bootable image.
+type Bootloader uint64
+
+const (
+	// BOOTLOADER_GRUB2 is the default boot chain: shim and grub2 for UEFI,
+	// grub2 for BIOS and zipl on s390x.
+	BOOTLOADER_GRUB2 Bootloader = iota
+
+	// BOOTLOADER_UKI boots a Unified Kernel Image (kernel, initrd, cmdline
+	// and os-release in a single EFI binary) with systemd-boot. It is only
+	// supported on UEFI-only platforms.
+	BOOTLOADER_UKI
+)
+
+var (
+	// packages installed in the image for the UKI boot chain; systemd-boot
+	// is kept in the image so that bootctl can update the ESP
+	ukiPackages = []string{
+		"dracut-config-generic",
+		"efibootmgr",
+		"systemd-boot-unsigned",
+	}
+
+	// packages needed in the build root to assemble the UKI and install
+	// systemd-boot into the ESP
+	ukiBuildPackages = []string{
+		"binutils",
+		"systemd-boot-unsigned",
+		"systemd-ukify",
+	}
+)
+
+func (b Bootloader) String() string {
+	switch b {
+	case BOOTLOADER_GRUB2:
+		return "grub2"
+	case BOOTLOADER_UKI:
+		return "uki"
+	default:
+		panic(fmt.Errorf("unknown bootloader %d", b))
+	}
+}
+
+// NewBootloader returns the bootloader with the given name. An empty name
+// selects the default (grub2).
+func NewBootloader(name string) (Bootloader, error) {
+	switch name {
+	case "", "grub2":
+		return BOOTLOADER_GRUB2, nil
+	case "uki":
+		return BOOTLOADER_UKI, nil
+	default:
+		return BOOTLOADER_GRUB2, fmt.Errorf("unknown bootloader %q, must be one of: grub2, uki", name)
+	}
+}
+
+// WithBootloader returns a copy of the platform that uses the given
+// bootloader. Selecting BOOTLOADER_UKI drops legacy BIOS support from the
+// copy, since the UKI boot chain is UEFI-only, and fails for platforms
+// without UEFI support.
+func WithBootloader(p Platform, bootloader Bootloader) (Platform, error) {
+	if bootloader == BOOTLOADER_UKI && p.GetUEFIVendor() == "" {
+		return nil, fmt.Errorf("bootloader %q requires a UEFI platform, %s does not support UEFI", bootloader, p.GetArch())
+	}
+
+	switch pf := p.(type) {
+	case *X86:
+		cp := *pf
+		cp.Bootloader = bootloader
+		if bootloader == BOOTLOADER_UKI {
+			cp.BIOS = false
+		}
+		return &cp, nil
+	case *Aarch64:
+		cp := *pf
+		cp.Bootloader = bootloader
+		return &cp, nil
+	case *Aarch64_Fedora:
+		cp := *pf
+		cp.Bootloader = bootloader
+		return &cp, nil
+	case *RISCV64:
+		cp := *pf
+		cp.Bootloader = bootloader
+		return &cp, nil
+	default:
+		if bootloader != p.GetBootloader() {
+			return nil, fmt.Errorf("bootloader %q is not supported on %s", bootloader, p.GetArch())
+		}
+		return p, nil
+	}
+}
@@ -0,0 +1,56 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/platform"
+)
+
+func TestBootloaderString(t *testing.T) {
+	assert.Equal(t, "grub2", platform.BOOTLOADER_GRUB2.String())
+	assert.Equal(t, "uki", platform.BOOTLOADER_UKI.String())
+}
+
+func TestNewBootloader(t *testing.T) {
+	for name, expected := range map[string]platform.Bootloader{
+		"":      platform.BOOTLOADER_GRUB2,
+		"grub2": platform.BOOTLOADER_GRUB2,
+		"uki":   platform.BOOTLOADER_UKI,
+	} {
+		bootloader, err := platform.NewBootloader(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, bootloader)
+	}
+
+	_, err := platform.NewBootloader("lilo")
+	assert.EqualError(t, err, `unknown bootloader "lilo", must be one of: grub2, uki`)
+}
+
+func TestWithBootloaderUKI(t *testing.T) {
+	hybrid := &platform.X86{
+		BIOS:       true,
+		UEFIVendor: "fedora",
+	}
+
+	pf, err := platform.WithBootloader(hybrid, platform.BOOTLOADER_UKI)
+	require.NoError(t, err)
+	assert.Equal(t, platform.BOOTLOADER_UKI, pf.GetBootloader())
+	// the UKI boot chain is UEFI-only
+	assert.Equal(t, "", pf.GetBIOSPlatform())
+	assert.Equal(t, "fedora", pf.GetUEFIVendor())
+	assert.Contains(t, pf.GetPackages(), "systemd-boot-unsigned")
+	assert.NotContains(t, pf.GetPackages(), "grub2-efi-x64")
+	assert.Contains(t, pf.GetBuildPackages(), "systemd-ukify")
+
+	// the original platform is not modified
+	assert.Equal(t, platform.BOOTLOADER_GRUB2, hybrid.GetBootloader())
+	assert.Equal(t, "i386-pc", hybrid.GetBIOSPlatform())
+}
+
+func TestWithBootloaderUKINoUEFI(t *testing.T) {
+	_, err := platform.WithBootloader(&platform.PPC64LE{BIOS: true}, platform.BOOTLOADER_UKI)
+	assert.EqualError(t, err, `bootloader "uki" requires a UEFI platform, ppc64le does not support UEFI`)
+}
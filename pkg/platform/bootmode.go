@@ -23,3 +23,16 @@ func (m BootMode) String() string {
 		panic("invalid boot mode")
 	}
 }
+
+// BootModeFor returns the boot mode of the platform: hybrid if it boots
+// with both UEFI and BIOS, legacy if it boots with BIOS or zipl only.
+func BootModeFor(pf Platform) BootMode {
+	if pf.GetUEFIVendor() != "" && pf.GetBIOSPlatform() != "" {
+		return BOOT_HYBRID
+	} else if pf.GetUEFIVendor() != "" {
+		return BOOT_UEFI
+	} else if pf.GetBIOSPlatform() != "" || pf.GetZiplSupport() {
+		return BOOT_LEGACY
+	}
+	return BOOT_NONE
+}
@@ -52,12 +52,14 @@ type Platform interface {
 	GetPackages() []string
 	GetBuildPackages() []string
 	GetBootFiles() [][2]string
+	GetBootloader() Bootloader
 }
 
 type BasePlatform struct {
 	ImageFormat      ImageFormat
 	QCOW2Compat      string
 	FirmwarePackages []string
+	Bootloader       Bootloader
 }
 
 func (p BasePlatform) GetImageFormat() ImageFormat {
@@ -91,3 +93,7 @@ func (p BasePlatform) GetBuildPackages() []string {
 func (p BasePlatform) GetBootFiles() [][2]string {
 	return [][2]string{}
 }
+
+func (p BasePlatform) GetBootloader() Bootloader {
+	return p.Bootloader
+}
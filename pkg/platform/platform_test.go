@@ -18,3 +18,11 @@ func TestImageFormatStringUnknown(t *testing.T) {
 		_ = platform.ImageFormat(999).String()
 	})
 }
+
+func TestBootModeFor(t *testing.T) {
+	assert.Equal(t, platform.BOOT_HYBRID, platform.BootModeFor(&platform.X86{BIOS: true, UEFIVendor: "fedora"}))
+	assert.Equal(t, platform.BOOT_UEFI, platform.BootModeFor(&platform.X86{UEFIVendor: "fedora"}))
+	assert.Equal(t, platform.BOOT_LEGACY, platform.BootModeFor(&platform.X86{BIOS: true}))
+	assert.Equal(t, platform.BOOT_LEGACY, platform.BootModeFor(&platform.S390X{Zipl: true}))
+	assert.Equal(t, platform.BOOT_NONE, platform.BootModeFor(&platform.S390X{}))
+}
@@ -17,15 +17,20 @@ func (p *RISCV64) GetPackages() []string {
 	packages := p.BasePlatform.FirmwarePackages
 
 	if p.UEFIVendor != "" {
-		packages = append(packages,
-			// XXX: this is needed to get a generic bootkernel,
-			// this should probably be part of any bootable img
-			// packagelist
-			"dracut-config-generic",
-			"grub2-efi-riscv64",
-			"grub2-efi-riscv64-modules",
-			"shim-unsigned-riscv64",
-		)
+		switch p.Bootloader {
+		case BOOTLOADER_UKI:
+			packages = append(packages, ukiPackages...)
+		default:
+			packages = append(packages,
+				// XXX: this is needed to get a generic bootkernel,
+				// this should probably be part of any bootable img
+				// packagelist
+				"dracut-config-generic",
+				"grub2-efi-riscv64",
+				"grub2-efi-riscv64-modules",
+				"shim-unsigned-riscv64",
+			)
+		}
 	}
 
 	return packages
@@ -34,6 +39,10 @@ func (p *RISCV64) GetPackages() []string {
 func (p *RISCV64) GetBuildPackages() []string {
 	var packages []string
 
+	if p.UEFIVendor != "" && p.Bootloader == BOOTLOADER_UKI {
+		packages = append(packages, ukiBuildPackages...)
+	}
+
 	return packages
 }
 
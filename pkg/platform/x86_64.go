@@ -35,11 +35,16 @@ func (p *X86) GetPackages() []string {
 	}
 
 	if p.UEFIVendor != "" {
-		packages = append(packages,
-			"dracut-config-generic",
-			"efibootmgr",
-			"grub2-efi-x64",
-			"shim-x64")
+		switch p.Bootloader {
+		case BOOTLOADER_UKI:
+			packages = append(packages, ukiPackages...)
+		default:
+			packages = append(packages,
+				"dracut-config-generic",
+				"efibootmgr",
+				"grub2-efi-x64",
+				"shim-x64")
+		}
 	}
 
 	return packages
@@ -50,5 +55,8 @@ func (p *X86) GetBuildPackages() []string {
 	if p.BIOS {
 		packages = append(packages, "grub2-pc")
 	}
+	if p.UEFIVendor != "" && p.Bootloader == BOOTLOADER_UKI {
+		packages = append(packages, ukiBuildPackages...)
+	}
 	return packages
 }
@@ -117,3 +117,14 @@ var OstreeCustomFilesPolicies = pathpolicy.NewPathPolicies(map[string]pathpolicy
 	"/etc/passwd":     {Deny: true},
 	"/etc/group":      {Deny: true},
 })
+
+// MountpointPolicies for bootc, in addition to the ostree ones
+var BootcMountpointPolicies = pathpolicy.NewPathPolicies(map[string]pathpolicy.PathPolicy{
+	"/": {},
+	// read-only and part of the container image
+	"/usr": {Deny: true},
+	// merged by ostree on updates, must be on the root filesystem
+	"/etc": {Deny: true},
+	// the physical root of the deployments
+	"/sysroot": {Deny: true},
+})
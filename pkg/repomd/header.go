@@ -0,0 +1,148 @@
+package repomd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var headerMagic = []byte{0x8e, 0xad, 0xe8, 0x01, 0x00, 0x00, 0x00, 0x00}
+
+const (
+	tagFileSizes     = 1028
+	tagFileModes     = 1030
+	tagFileFlags     = 1037
+	tagLongFileSizes = 5008
+
+	typeInt16 = 3
+	typeInt32 = 4
+	typeInt64 = 5
+
+	// RPMFILE_DOC, the flag of the files that rpm does not install with
+	// --excludedocs
+	fileFlagDoc = 1 << 1
+
+	modeTypeMask = 0o170000
+	modeRegular  = 0o100000
+)
+
+type headerIndexEntry struct {
+	Tag    uint32
+	Type   uint32
+	Offset uint32
+	Count  uint32
+}
+
+// rpmHeader is a parsed RPM header structure
+type rpmHeader struct {
+	entries map[uint32]headerIndexEntry
+	store   []byte
+}
+
+func parseHeader(data []byte) (*rpmHeader, error) {
+	if !bytes.HasPrefix(data, headerMagic) {
+		return nil, fmt.Errorf("invalid rpm header magic")
+	}
+	if len(data) < 16 {
+		return nil, fmt.Errorf("rpm header too short")
+	}
+	nindex := binary.BigEndian.Uint32(data[8:12])
+	hsize := binary.BigEndian.Uint32(data[12:16])
+	storeStart := 16 + uint64(nindex)*16
+	if storeStart+uint64(hsize) > uint64(len(data)) {
+		return nil, fmt.Errorf("rpm header truncated")
+	}
+
+	hdr := &rpmHeader{
+		entries: make(map[uint32]headerIndexEntry, nindex),
+		store:   data[storeStart : storeStart+uint64(hsize)],
+	}
+	for i := uint64(0); i < uint64(nindex); i++ {
+		raw := data[16+i*16 : 32+i*16]
+		entry := headerIndexEntry{
+			Tag:    binary.BigEndian.Uint32(raw[0:4]),
+			Type:   binary.BigEndian.Uint32(raw[4:8]),
+			Offset: binary.BigEndian.Uint32(raw[8:12]),
+			Count:  binary.BigEndian.Uint32(raw[12:16]),
+		}
+		hdr.entries[entry.Tag] = entry
+	}
+	return hdr, nil
+}
+
+// ints returns the values of an integer tag, or nil if the header does not
+// have the tag.
+func (hdr *rpmHeader) ints(tag uint32) ([]uint64, error) {
+	entry, ok := hdr.entries[tag]
+	if !ok {
+		return nil, nil
+	}
+	var size uint64
+	switch entry.Type {
+	case typeInt16:
+		size = 2
+	case typeInt32:
+		size = 4
+	case typeInt64:
+		size = 8
+	default:
+		return nil, fmt.Errorf("unexpected type %d of rpm header tag %d", entry.Type, tag)
+	}
+	end := uint64(entry.Offset) + size*uint64(entry.Count)
+	if end > uint64(len(hdr.store)) {
+		return nil, fmt.Errorf("rpm header tag %d out of bounds", tag)
+	}
+	data := hdr.store[entry.Offset:end]
+	values := make([]uint64, entry.Count)
+	for i := range values {
+		switch size {
+		case 2:
+			values[i] = uint64(binary.BigEndian.Uint16(data[uint64(i)*2:]))
+		case 4:
+			values[i] = uint64(binary.BigEndian.Uint32(data[uint64(i)*4:]))
+		case 8:
+			values[i] = binary.BigEndian.Uint64(data[uint64(i)*8:])
+		}
+	}
+	return values, nil
+}
+
+// HeaderDocSize returns the total size of the regular files that are marked as
+// documentation in the main header of an RPM package, i.e. the size that is
+// not installed with rpm --excludedocs. The header is returned by
+// Reader.ReadHeader.
+func HeaderDocSize(header []byte) (uint64, error) {
+	hdr, err := parseHeader(header)
+	if err != nil {
+		return 0, err
+	}
+	sizes, err := hdr.ints(tagLongFileSizes)
+	if err != nil {
+		return 0, err
+	}
+	if sizes == nil {
+		sizes, err = hdr.ints(tagFileSizes)
+		if err != nil {
+			return 0, err
+		}
+	}
+	flags, err := hdr.ints(tagFileFlags)
+	if err != nil {
+		return 0, err
+	}
+	modes, err := hdr.ints(tagFileModes)
+	if err != nil {
+		return 0, err
+	}
+	if len(flags) != len(sizes) || len(modes) != len(sizes) {
+		return 0, fmt.Errorf("inconsistent file tags in rpm header")
+	}
+
+	var docSize uint64
+	for i, size := range sizes {
+		if flags[i]&fileFlagDoc != 0 && modes[i]&modeTypeMask == modeRegular {
+			docSize += size
+		}
+	}
+	return docSize, nil
+}
@@ -0,0 +1,131 @@
+package repomd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+type primaryPackageXML struct {
+	Type    string `xml:"type,attr"`
+	Name    string `xml:"name"`
+	Arch    string `xml:"arch"`
+	Version struct {
+		Epoch string `xml:"epoch,attr"`
+		Ver   string `xml:"ver,attr"`
+		Rel   string `xml:"rel,attr"`
+	} `xml:"version"`
+	Checksum    checksumXML `xml:"checksum"`
+	Summary     string      `xml:"summary"`
+	Description string      `xml:"description"`
+	URL         string      `xml:"url"`
+	Time        struct {
+		Build int64 `xml:"build,attr"`
+	} `xml:"time"`
+	License string   `xml:"format>license"`
+	Files   []string `xml:"format>file"`
+}
+
+// primaryPackage is a package from the primary metadata together with the
+// data needed to match it against the filelists.
+type primaryPackage struct {
+	rpmmd.Package
+
+	pkgID string
+	files []string
+}
+
+// parsePrimary decodes the packages in primary.xml. Source packages are
+// skipped.
+func parsePrimary(content []byte) ([]primaryPackage, error) {
+	var pkgs []primaryPackage
+
+	err := forEachElement(content, "package", func(dec *xml.Decoder, start *xml.StartElement) error {
+		var p primaryPackageXML
+		if err := dec.DecodeElement(&p, start); err != nil {
+			return err
+		}
+		if p.Type != "" && p.Type != "rpm" || p.Arch == "src" || p.Arch == "nosrc" {
+			return nil
+		}
+
+		var epoch uint64
+		if p.Version.Epoch != "" {
+			var err error
+			epoch, err = strconv.ParseUint(p.Version.Epoch, 10, 32)
+			if err != nil {
+				return err
+			}
+		}
+
+		pkgs = append(pkgs, primaryPackage{
+			Package: rpmmd.Package{
+				Name:        p.Name,
+				Summary:     p.Summary,
+				Description: p.Description,
+				URL:         p.URL,
+				Epoch:       uint(epoch),
+				Version:     p.Version.Ver,
+				Release:     p.Version.Rel,
+				Arch:        p.Arch,
+				BuildTime:   time.Unix(p.Time.Build, 0).UTC(),
+				License:     p.License,
+			},
+			pkgID: p.Checksum.Value,
+			files: p.Files,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+type filelistsPackageXML struct {
+	PkgID string   `xml:"pkgid,attr"`
+	Files []string `xml:"file"`
+}
+
+// parseFilelists decodes filelists.xml into a map from package ID to the
+// files of the package.
+func parseFilelists(content []byte) (map[string][]string, error) {
+	files := make(map[string][]string)
+
+	err := forEachElement(content, "package", func(dec *xml.Decoder, start *xml.StartElement) error {
+		var p filelistsPackageXML
+		if err := dec.DecodeElement(&p, start); err != nil {
+			return err
+		}
+		files[p.PkgID] = p.Files
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// forEachElement calls fn for every element with the given local name. The
+// metadata files can be large, so they are decoded one package at a time.
+func forEachElement(content []byte, name string, fn func(*xml.Decoder, *xml.StartElement) error) error {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == name {
+			if err := fn(dec, &start); err != nil {
+				return err
+			}
+		}
+	}
+}
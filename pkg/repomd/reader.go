@@ -0,0 +1,127 @@
+package repomd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gobwas/glob"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+var _ rpmmd.MetadataReader = &Reader{}
+
+// FetchMetadata returns all packages available in the given repositories.
+func (r *Reader) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, error) {
+	var pkgs rpmmd.PackageList
+	for _, repo := range repos {
+		repoPkgs, err := r.readPackages(repo, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range repoPkgs {
+			pkgs = append(pkgs, p.Package)
+		}
+	}
+	sortPackages(pkgs)
+	return pkgs, nil
+}
+
+// SearchMetadata returns the packages in the given repositories that match
+// any of the search terms. Terms are matched like the dnfjson search:
+// a term containing '*' is a glob on the package name, any other term must
+// match the name exactly. Terms starting with '/' match files provided by the
+// package instead, which needs the filelists metadata.
+func (r *Reader) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rpmmd.PackageList, error) {
+	var nameGlobs, fileGlobs []glob.Glob
+	for _, term := range packages {
+		g, err := glob.Compile(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search term %q: %w", term, err)
+		}
+		if strings.HasPrefix(term, "/") {
+			fileGlobs = append(fileGlobs, g)
+		} else {
+			nameGlobs = append(nameGlobs, g)
+		}
+	}
+
+	matchAny := func(globs []glob.Glob, s string) bool {
+		for _, g := range globs {
+			if g.Match(s) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var pkgs rpmmd.PackageList
+	for _, repo := range repos {
+		repoPkgs, err := r.readPackages(repo, len(fileGlobs) > 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range repoPkgs {
+			if matchAny(nameGlobs, p.Name) {
+				pkgs = append(pkgs, p.Package)
+				continue
+			}
+			for _, file := range p.files {
+				if matchAny(fileGlobs, file) {
+					pkgs = append(pkgs, p.Package)
+					break
+				}
+			}
+		}
+	}
+	sortPackages(pkgs)
+	return pkgs, nil
+}
+
+// readPackages reads the primary metadata of the repository and, if
+// withFiles is set, the complete file lists of the packages.
+func (r *Reader) readPackages(repo rpmmd.RepoConfig, withFiles bool) ([]primaryPackage, error) {
+	repomd, err := r.ReadRepomd(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := r.readData(repo, repomd, "primary")
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := parsePrimary(content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse primary metadata of repository %q: %w", repo.Name, err)
+	}
+
+	if !withFiles {
+		return pkgs, nil
+	}
+
+	content, err = r.readData(repo, repomd, "filelists")
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseFilelists(content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse filelists metadata of repository %q: %w", repo.Name, err)
+	}
+	for idx := range pkgs {
+		if pkgFiles, ok := files[pkgs[idx].pkgID]; ok {
+			pkgs[idx].files = pkgFiles
+		}
+	}
+	return pkgs, nil
+}
+
+// sortPackages sorts the packages the same way as the dnfjson solver.
+func sortPackages(pkgs rpmmd.PackageList) {
+	sortID := func(pkg rpmmd.Package) string {
+		return fmt.Sprintf("%s-%s-%s", pkg.Name, pkg.Version, pkg.Release)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return sortID(pkgs[i]) < sortID(pkgs[j])
+	})
+}
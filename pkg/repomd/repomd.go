@@ -0,0 +1,242 @@
+// Package repomd reads RPM repository metadata (repomd.xml and the primary
+// and filelists data it references) without depending on dnf.
+//
+// The Reader is an alternative to the dnfjson.Solver for listing and
+// searching the packages of a set of repositories. It does not depsolve.
+package repomd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1" // #nosec G505 -- sha1 is still used by some repositories
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+type repomdXML struct {
+	Revision string    `xml:"revision"`
+	Data     []dataXML `xml:"data"`
+}
+
+type dataXML struct {
+	Type     string      `xml:"type,attr"`
+	Checksum checksumXML `xml:"checksum"`
+	Location locationXML `xml:"location"`
+	Size     int64       `xml:"size"`
+}
+
+type checksumXML struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type locationXML struct {
+	Href string `xml:"href,attr"`
+}
+
+// Repomd is the parsed repomd.xml of a repository.
+type Repomd struct {
+	Revision string
+
+	baseURL string
+	data    map[string]dataXML
+}
+
+// Has returns true if the repository provides metadata of the given type,
+// e.g. "primary" or "filelists".
+func (r *Repomd) Has(mdType string) bool {
+	_, ok := r.data[mdType]
+	return ok
+}
+
+// Reader fetches and parses the metadata of RPM repositories. Repositories
+// must be configured with a baseurl, which can use the file, http or https
+// scheme.
+type Reader struct {
+	// Timeout for a single metadata download
+	Timeout time.Duration
+}
+
+// NewReader returns a metadata reader with default settings.
+func NewReader() *Reader {
+	return &Reader{
+		Timeout: 300 * time.Second,
+	}
+}
+
+func (r *Reader) httpClient(repo rpmmd.RepoConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if repo.IgnoreSSL != nil && *repo.IgnoreSSL {
+		tlsConf.InsecureSkipVerify = true // #nosec G402 -- requested by the repository configuration
+	}
+	if repo.SSLCACert != "" {
+		caCertPEM, err := os.ReadFile(repo.SSLCACert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca certificate for repository %q: %w", repo.Name, err)
+		}
+		tlsConf.RootCAs = x509.NewCertPool()
+		if ok := tlsConf.RootCAs.AppendCertsFromPEM(caCertPEM); !ok {
+			return nil, fmt.Errorf("cannot add ca certificate for repository %q", repo.Name)
+		}
+	}
+	if repo.SSLClientCert != "" && repo.SSLClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(repo.SSLClientCert, repo.SSLClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate for repository %q: %w", repo.Name, err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConf
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   r.Timeout,
+	}, nil
+}
+
+// fetch returns the content of the file at the location relative to the
+// base URL.
+func (r *Reader) fetch(repo rpmmd.RepoConfig, baseURL, location string) ([]byte, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse baseurl %q: %w", baseURL, err)
+	}
+	u.Path = path.Join(u.Path, location)
+
+	switch u.Scheme {
+	case "file":
+		return os.ReadFile(u.Path)
+	case "http", "https":
+		client, err := r.httpClient(repo)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Get(u.String())
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s returned status: %s", u.String(), resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	default:
+		return nil, fmt.Errorf("unsupported baseurl scheme %q", u.Scheme)
+	}
+}
+
+// ReadRepomd fetches and parses the repomd.xml of the repository. The first
+// baseurl that provides the file is used.
+func (r *Reader) ReadRepomd(repo rpmmd.RepoConfig) (*Repomd, error) {
+	if len(repo.BaseURLs) == 0 {
+		return nil, fmt.Errorf("repository %q has no baseurl, metalink and mirrorlist are not supported", repo.Name)
+	}
+
+	var errs []string
+	for _, baseURL := range repo.BaseURLs {
+		content, err := r.fetch(repo, baseURL, "repodata/repomd.xml")
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		var md repomdXML
+		if err := xml.Unmarshal(content, &md); err != nil {
+			return nil, fmt.Errorf("cannot parse repomd.xml of repository %q: %w", repo.Name, err)
+		}
+		repomd := &Repomd{
+			Revision: md.Revision,
+			baseURL:  baseURL,
+			data:     make(map[string]dataXML, len(md.Data)),
+		}
+		for _, data := range md.Data {
+			repomd.data[data.Type] = data
+		}
+		return repomd, nil
+	}
+	return nil, fmt.Errorf("cannot fetch repomd.xml of repository %q: %s", repo.Name, strings.Join(errs, "; "))
+}
+
+// readData fetches the metadata of the given type, verifies its checksum
+// against repomd.xml and returns the decompressed content.
+func (r *Reader) readData(repo rpmmd.RepoConfig, repomd *Repomd, mdType string) ([]byte, error) {
+	data, ok := repomd.data[mdType]
+	if !ok {
+		return nil, fmt.Errorf("repository %q does not provide %s metadata", repo.Name, mdType)
+	}
+
+	content, err := r.fetch(repo, repomd.baseURL, data.Location.Href)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch %s metadata of repository %q: %w", mdType, repo.Name, err)
+	}
+	if err := verifyChecksum(content, data.Checksum); err != nil {
+		return nil, fmt.Errorf("%s metadata of repository %q: %w", mdType, repo.Name, err)
+	}
+
+	content, err = decompress(data.Location.Href, content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %s metadata of repository %q: %w", mdType, repo.Name, err)
+	}
+	return content, nil
+}
+
+func verifyChecksum(content []byte, checksum checksumXML) error {
+	var h hash.Hash
+	switch checksum.Type {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	case "sha1", "sha":
+		h = sha1.New() // #nosec G401
+	default:
+		return fmt.Errorf("unsupported checksum type %q", checksum.Type)
+	}
+	h.Write(content)
+	if got := hex.EncodeToString(h.Sum(nil)); got != strings.TrimSpace(checksum.Value) {
+		return fmt.Errorf("checksum mismatch: expected %s:%s, got %s:%s", checksum.Type, strings.TrimSpace(checksum.Value), checksum.Type, got)
+	}
+	return nil
+}
+
+func decompress(location string, content []byte) ([]byte, error) {
+	switch {
+	case strings.HasSuffix(location, ".gz"):
+		zr, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case strings.HasSuffix(location, ".zst"):
+		zr, err := zstd.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case strings.HasSuffix(location, ".xml"):
+		return content, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression for %q", location)
+	}
+}
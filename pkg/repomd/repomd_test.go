@@ -0,0 +1,229 @@
+package repomd_test
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/mocks/rpmrepo"
+	"github.com/osbuild/images/pkg/repomd"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func TestFetchMetadata(t *testing.T) {
+	s := rpmrepo.NewTestServer()
+	defer s.Close()
+
+	pkgs, err := repomd.NewReader().FetchMetadata([]rpmmd.RepoConfig{s.RepoConfig})
+	require.NoError(t, err)
+	assert.Len(t, pkgs, 1125)
+
+	// sorted by name-version-release
+	assert.Equal(t, "ModemManager", pkgs[0].Name)
+	assert.Equal(t, rpmmd.Package{
+		Name:        "ModemManager",
+		Summary:     "Mobile broadband modem management service",
+		Description: "The ModemManager service manages WWAN modems and provides a consistent API for\ninteracting with these devices to client applications.",
+		URL:         "http://www.freedesktop.org/wiki/Software/ModemManager/",
+		Epoch:       0,
+		Version:     "1.18.2",
+		Release:     "3.el9",
+		Arch:        "x86_64",
+		BuildTime:   time.Unix(1639745258, 0).UTC(),
+		License:     "GPLv2+",
+	}, pkgs[0])
+}
+
+func TestReadRepomd(t *testing.T) {
+	s := rpmrepo.NewTestServer()
+	defer s.Close()
+
+	md, err := repomd.NewReader().ReadRepomd(s.RepoConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "1644263915", md.Revision)
+	assert.True(t, md.Has("primary"))
+	assert.True(t, md.Has("filelists"))
+	assert.False(t, md.Has("updateinfo"))
+}
+
+func TestFetchMetadataFileURL(t *testing.T) {
+	path, err := filepath.Abs("../../test/data/testrepo")
+	require.NoError(t, err)
+	repo := rpmmd.RepoConfig{
+		Name:     "local",
+		BaseURLs: []string{"file://" + path},
+	}
+
+	pkgs, err := repomd.NewReader().FetchMetadata([]rpmmd.RepoConfig{repo})
+	require.NoError(t, err)
+	assert.Len(t, pkgs, 1125)
+}
+
+func TestSearchMetadata(t *testing.T) {
+	s := rpmrepo.NewTestServer()
+	defer s.Close()
+
+	reader := repomd.NewReader()
+	repos := []rpmmd.RepoConfig{s.RepoConfig}
+
+	names := func(pkgs rpmmd.PackageList) []string {
+		var ret []string
+		for _, p := range pkgs {
+			ret = append(ret, p.Name)
+		}
+		return ret
+	}
+
+	pkgs, err := reader.SearchMetadata(repos, []string{"ModemManager"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ModemManager"}, names(pkgs))
+
+	pkgs, err = reader.SearchMetadata(repos, []string{"ModemManager*"})
+	require.NoError(t, err)
+	// ModemManager-glib is available for x86_64 and i686
+	assert.Equal(t, []string{"ModemManager", "ModemManager-glib", "ModemManager-glib"}, names(pkgs))
+
+	// files are looked up in the filelists
+	pkgs, err = reader.SearchMetadata(repos, []string{"/usr/bin/mmcli"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ModemManager"}, names(pkgs))
+
+	pkgs, err = reader.SearchMetadata(repos, []string{"does-not-exist"})
+	require.NoError(t, err)
+	assert.Empty(t, pkgs)
+}
+
+const primaryXML = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="2">
+<package type="rpm">
+  <name>pkg1</name>
+  <arch>noarch</arch>
+  <version epoch="2" ver="1.0" rel="1"/>
+  <checksum type="sha256" pkgid="YES">aaaa</checksum>
+  <summary>first package</summary>
+  <time file="1" build="2"/>
+  <format>
+    <rpm:license>MIT</rpm:license>
+    <file>/usr/bin/pkg1</file>
+  </format>
+</package>
+<package type="rpm">
+  <name>pkg1</name>
+  <arch>src</arch>
+  <version epoch="2" ver="1.0" rel="1"/>
+  <checksum type="sha256" pkgid="YES">bbbb</checksum>
+</package>
+</metadata>
+`
+
+// makeRepo writes a repository with the given primary metadata to a temporary
+// directory and returns its path. The checksum in repomd.xml is corrupted if
+// badChecksum is set.
+func makeRepo(t *testing.T, primaryName string, primary []byte, badChecksum bool) string {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "repodata"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "repodata", primaryName), primary, 0644))
+
+	checksum := fmt.Sprintf("%x", sha256.Sum256(primary))
+	if badChecksum {
+		checksum = fmt.Sprintf("%x", sha256.Sum256(nil))
+	}
+	repomdXML := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<repomd xmlns="http://linux.duke.edu/metadata/repo">
+  <revision>1</revision>
+  <data type="primary">
+    <checksum type="sha256">%s</checksum>
+    <location href="repodata/%s"/>
+  </data>
+</repomd>
+`, checksum, primaryName)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "repodata", "repomd.xml"), []byte(repomdXML), 0644))
+	return dir
+}
+
+func compressGzip(t *testing.T, data []byte) []byte {
+	path := filepath.Join(t.TempDir(), "data.gz")
+	fp, err := os.Create(path)
+	require.NoError(t, err)
+	zw := gzip.NewWriter(fp)
+	_, err = zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, fp.Close())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return content
+}
+
+func compressZstd(t *testing.T, data []byte) []byte {
+	zw, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer zw.Close()
+	return zw.EncodeAll(data, nil)
+}
+
+func TestFetchMetadataCompression(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content []byte
+	}{
+		{"primary.xml", []byte(primaryXML)},
+		{"primary.xml.gz", compressGzip(t, []byte(primaryXML))},
+		{"primary.xml.zst", compressZstd(t, []byte(primaryXML))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := rpmmd.RepoConfig{
+				Name:     "local",
+				BaseURLs: []string{"file://" + makeRepo(t, tc.name, tc.content, false)},
+			}
+			pkgs, err := repomd.NewReader().FetchMetadata([]rpmmd.RepoConfig{repo})
+			require.NoError(t, err)
+			// the source package is skipped
+			assert.Equal(t, rpmmd.PackageList{
+				{
+					Name:      "pkg1",
+					Summary:   "first package",
+					Epoch:     2,
+					Version:   "1.0",
+					Release:   "1",
+					Arch:      "noarch",
+					BuildTime: time.Unix(2, 0).UTC(),
+					License:   "MIT",
+				},
+			}, pkgs)
+
+			// searching for files needs the filelists metadata
+			pkgs, err = repomd.NewReader().SearchMetadata([]rpmmd.RepoConfig{repo}, []string{"/usr/bin/*"})
+			assert.EqualError(t, err, `repository "local" does not provide filelists metadata`)
+			assert.Nil(t, pkgs)
+		})
+	}
+}
+
+func TestFetchMetadataErrors(t *testing.T) {
+	reader := repomd.NewReader()
+
+	repo := rpmmd.RepoConfig{
+		Name:     "corrupted",
+		BaseURLs: []string{"file://" + makeRepo(t, "primary.xml", []byte(primaryXML), true)},
+	}
+	_, err := reader.FetchMetadata([]rpmmd.RepoConfig{repo})
+	assert.ErrorContains(t, err, `primary metadata of repository "corrupted": checksum mismatch: expected sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855, got sha256:`)
+
+	_, err = reader.FetchMetadata([]rpmmd.RepoConfig{{Name: "mirrors", Metalink: "https://example.com/metalink"}})
+	assert.EqualError(t, err, `repository "mirrors" has no baseurl, metalink and mirrorlist are not supported`)
+
+	_, err = reader.FetchMetadata([]rpmmd.RepoConfig{{Name: "missing", BaseURLs: []string{"file:///does/not/exist"}}})
+	assert.ErrorContains(t, err, `cannot fetch repomd.xml of repository "missing": open /does/not/exist/repodata/repomd.xml: no such file or directory`)
+
+	_, err = reader.FetchMetadata([]rpmmd.RepoConfig{{Name: "ftp", BaseURLs: []string{"ftp://example.com/repo"}}})
+	assert.EqualError(t, err, `cannot fetch repomd.xml of repository "ftp": unsupported baseurl scheme "ftp"`)
+}
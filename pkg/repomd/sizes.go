@@ -0,0 +1,132 @@
+package repomd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// PackageSize is the size information of a package in the primary metadata of
+// a repository.
+type PackageSize struct {
+	// Size of the installed package in bytes
+	InstallSize uint64
+
+	// Byte range of the main header in the package file, the end is
+	// exclusive
+	HeaderStart uint64
+	HeaderEnd   uint64
+}
+
+type packageSizeXML struct {
+	Checksum checksumXML `xml:"checksum"`
+	Size     struct {
+		Installed uint64 `xml:"installed,attr"`
+	} `xml:"size"`
+	HeaderRange struct {
+		Start uint64 `xml:"start,attr"`
+		End   uint64 `xml:"end,attr"`
+	} `xml:"format>header-range"`
+}
+
+// ReadPackageSizes reads the size information of the packages with the given
+// checksums from a local primary metadata file, e.g. from the metadata cache
+// of dnf. The checksums use the "<type>:<value>" format of
+// rpmmd.PackageSpec.Checksum and key the returned map. Packages that are not
+// in the metadata are not in the map.
+func ReadPackageSizes(path string, checksums map[string]bool) (map[string]PackageSize, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err = decompress(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %q: %w", path, err)
+	}
+
+	sizes := make(map[string]PackageSize, len(checksums))
+	err = forEachElement(content, "package", func(dec *xml.Decoder, start *xml.StartElement) error {
+		var p packageSizeXML
+		if err := dec.DecodeElement(&p, start); err != nil {
+			return err
+		}
+		checksum := p.Checksum.Type + ":" + strings.TrimSpace(p.Checksum.Value)
+		if !checksums[checksum] {
+			return nil
+		}
+		sizes[checksum] = PackageSize{
+			InstallSize: p.Size.Installed,
+			HeaderStart: p.HeaderRange.Start,
+			HeaderEnd:   p.HeaderRange.End,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return sizes, nil
+}
+
+// ReadHeader returns the main header of the package at the location, which is
+// a file, http or https URL. Only the header range from the primary metadata
+// is downloaded if the server supports range requests.
+func (r *Reader) ReadHeader(repo rpmmd.RepoConfig, location string, size PackageSize) ([]byte, error) {
+	if size.HeaderEnd <= size.HeaderStart {
+		return nil, fmt.Errorf("no header range for %q in the primary metadata", location)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse package location %q: %w", location, err)
+	}
+	header := make([]byte, size.HeaderEnd-size.HeaderStart)
+
+	switch u.Scheme {
+	case "file":
+		f, err := os.Open(u.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if _, err := f.ReadAt(header, int64(size.HeaderStart)); err != nil {
+			return nil, fmt.Errorf("cannot read the header of %q: %w", location, err)
+		}
+		return header, nil
+	case "http", "https":
+		client, err := r.httpClient(repo)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodGet, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", size.HeaderStart, size.HeaderEnd-1))
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+		case http.StatusOK:
+			// the server ignored the range
+			if _, err := io.CopyN(io.Discard, resp.Body, int64(size.HeaderStart)); err != nil {
+				return nil, fmt.Errorf("cannot read the header of %q: %w", location, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s returned status: %s", location, resp.Status)
+		}
+		if _, err := io.ReadFull(resp.Body, header); err != nil {
+			return nil, fmt.Errorf("cannot read the header of %q: %w", location, err)
+		}
+		return header, nil
+	default:
+		return nil, fmt.Errorf("unsupported package location scheme %q", u.Scheme)
+	}
+}
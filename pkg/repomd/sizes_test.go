@@ -0,0 +1,125 @@
+package repomd_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/repomd"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func TestReadPackageSizes(t *testing.T) {
+	matches, err := filepath.Glob("../../test/data/testrepo/repodata/*-primary.xml.gz")
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	modemManager := "sha256:159adbdd220d622a93aff1ad942936e110e7b32400620bef3b1f0b52e639c964"
+	sizes, err := repomd.ReadPackageSizes(matches[0], map[string]bool{
+		modemManager:     true,
+		"sha256:0000000": true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]repomd.PackageSize{
+		modemManager: {
+			InstallSize: 4272086,
+			HeaderStart: 45616,
+			HeaderEnd:   87781,
+		},
+	}, sizes)
+}
+
+// makeHeader returns an RPM main header with the file size, mode and flag
+// tags for the given files.
+func makeHeader(sizes []uint32, modes []uint16, flags []uint32) []byte {
+	var store bytes.Buffer
+	type entry struct{ tag, typ, offset, count uint32 }
+	var entries []entry
+
+	entries = append(entries, entry{1028, 4, uint32(store.Len()), uint32(len(sizes))})
+	for _, v := range sizes {
+		_ = binary.Write(&store, binary.BigEndian, v)
+	}
+	entries = append(entries, entry{1037, 4, uint32(store.Len()), uint32(len(flags))})
+	for _, v := range flags {
+		_ = binary.Write(&store, binary.BigEndian, v)
+	}
+	entries = append(entries, entry{1030, 3, uint32(store.Len()), uint32(len(modes))})
+	for _, v := range modes {
+		_ = binary.Write(&store, binary.BigEndian, v)
+	}
+
+	var hdr bytes.Buffer
+	hdr.Write([]byte{0x8e, 0xad, 0xe8, 0x01, 0x00, 0x00, 0x00, 0x00})
+	_ = binary.Write(&hdr, binary.BigEndian, uint32(len(entries)))
+	_ = binary.Write(&hdr, binary.BigEndian, uint32(store.Len()))
+	for _, e := range entries {
+		_ = binary.Write(&hdr, binary.BigEndian, []uint32{e.tag, e.typ, e.offset, e.count})
+	}
+	hdr.Write(store.Bytes())
+	return hdr.Bytes()
+}
+
+func TestHeaderDocSize(t *testing.T) {
+	header := makeHeader(
+		[]uint32{1000, 200, 4096, 30},
+		// regular file, regular file, directory, regular file
+		[]uint16{0o100644, 0o100644, 0o040755, 0o100644},
+		// none, doc, doc, doc|license
+		[]uint32{0, 2, 2, 2 | 128},
+	)
+	docSize, err := repomd.HeaderDocSize(header)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(230), docSize)
+
+	_, err = repomd.HeaderDocSize([]byte("not a header"))
+	assert.EqualError(t, err, "invalid rpm header magic")
+
+	_, err = repomd.HeaderDocSize(header[:len(header)-1])
+	assert.EqualError(t, err, "rpm header truncated")
+}
+
+func TestReadHeader(t *testing.T) {
+	header := makeHeader([]uint32{10}, []uint16{0o100644}, []uint32{2})
+	pkg := append(bytes.Repeat([]byte{0}, 100), header...)
+	size := repomd.PackageSize{
+		HeaderStart: 100,
+		HeaderEnd:   uint64(100 + len(header)),
+	}
+
+	pkgPath := filepath.Join(t.TempDir(), "test.rpm")
+	require.NoError(t, os.WriteFile(pkgPath, pkg, 0644))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/norange.rpm" {
+			_, _ = w.Write(pkg)
+			return
+		}
+		http.ServeContent(w, r, "test.rpm", time.Time{}, bytes.NewReader(pkg))
+	}))
+	defer server.Close()
+
+	reader := repomd.NewReader()
+	for _, location := range []string{
+		"file://" + pkgPath,
+		server.URL + "/test.rpm",
+		server.URL + "/norange.rpm",
+	} {
+		t.Run(location, func(t *testing.T) {
+			got, err := reader.ReadHeader(rpmmd.RepoConfig{}, location, size)
+			require.NoError(t, err)
+			assert.Equal(t, header, got)
+		})
+	}
+
+	_, err := reader.ReadHeader(rpmmd.RepoConfig{}, "file://"+pkgPath, repomd.PackageSize{})
+	assert.EqualError(t, err, `no header range for "file://`+pkgPath+`" in the primary metadata`)
+}
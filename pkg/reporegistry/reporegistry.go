@@ -20,7 +20,10 @@ type RepoRegistry struct {
 // important here, first the paths are tried, then the FSes.
 //
 // Note that the confPaths must point directly to the directory with
-// the json repo files.
+// the json repo files. Distros can also be defined by directories of
+// yum/dnf .repo files, named after the distro and with a subdirectory per
+// architecture, e.g. "fedora-41/x86_64/fedora.repo". A json file takes
+// precedence over a directory of the same distro.
 func New(repoConfigPaths []string, repoConfigFS []fs.FS) (*RepoRegistry, error) {
 	repositories, err := LoadAllRepositories(repoConfigPaths, repoConfigFS)
 	if err != nil {
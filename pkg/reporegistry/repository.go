@@ -1,13 +1,17 @@
 package reporegistry
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distroidparser"
 	"github.com/osbuild/images/pkg/rpmmd"
 )
@@ -23,10 +27,13 @@ func LoadAllRepositories(confPaths []string, confFSes []fs.FS) (rpmmd.DistrosRep
 	mergedFSes = append(mergedFSes, confFSes...)
 
 	distrosRepoConfigs, err := loadAllRepositoriesFromFS(mergedFSes)
+	if err != nil {
+		return nil, err
+	}
 	if len(distrosRepoConfigs) == 0 {
 		return nil, &NoReposLoadedError{confPaths, confFSes}
 	}
-	return distrosRepoConfigs, err
+	return distrosRepoConfigs, nil
 }
 
 func loadAllRepositoriesFromFS(confPaths []fs.FS) (rpmmd.DistrosRepoConfigs, error) {
@@ -81,11 +88,126 @@ func loadAllRepositoriesFromFS(confPaths []fs.FS) (rpmmd.DistrosRepoConfigs, err
 				distrosRepoConfigs[distro] = distroRepos
 			}
 		}
+
+		// directories of .repo files are read after the json files, so
+		// that the json definition of a distro is preferred
+		for _, fileEntry := range fileEntries {
+			if !fileEntry.IsDir() {
+				continue
+			}
+
+			distro, distroID, err := parseRepoFileDir(fileEntry.Name())
+			if err != nil {
+				logrus.Debugf("Skipping directory %q: %v", fileEntry.Name(), err)
+				continue
+			}
+
+			// skip the distro repos definition, if it has been already read
+			if _, ok := distrosRepoConfigs[distro]; ok {
+				continue
+			}
+
+			// .repo files are written for dnf and can use variables that
+			// are not known here, e.g. $contentdir or the ones defined in
+			// /etc/dnf/vars, so one broken directory must not prevent
+			// loading the other distros
+			distroRepos, err := loadRepoFileDir(confPath, fileEntry.Name(), distroID)
+			if err != nil {
+				logrus.Warnf("Skipping directory %q: %v", fileEntry.Name(), err)
+				continue
+			}
+			if len(distroRepos) == 0 {
+				continue
+			}
+
+			logrus.Infof("Loaded repository configuration directory: %s", fileEntry.Name())
+
+			distrosRepoConfigs[distro] = distroRepos
+		}
 	}
 
 	return distrosRepoConfigs, nil
 }
 
+// parseRepoFileDir parses the name of a directory with .repo files, which
+// must be a distro ID string, e.g. "fedora-41" or "rhel-9.4".
+func parseRepoFileDir(name string) (string, *distro.ID, error) {
+	distroID, err := distroidparser.DefaultParser.Parse(name)
+	if err != nil {
+		return "", nil, err
+	}
+	return distroID.String(), distroID, nil
+}
+
+// loadRepoFileDir loads the yum/dnf .repo files of a distro directory. The
+// directory has a subdirectory for each architecture with the .repo files
+// of that architecture, e.g. "fedora-41/x86_64/fedora.repo". The variables
+// of the files are set by repoFileVars(). Disabled repositories are skipped.
+func loadRepoFileDir(confPath fs.FS, dir string, distroID *distro.ID) (map[string][]rpmmd.RepoConfig, error) {
+	archEntries, err := fs.ReadDir(confPath, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	distroRepos := make(map[string][]rpmmd.RepoConfig)
+	for _, archEntry := range archEntries {
+		if !archEntry.IsDir() {
+			continue
+		}
+		arch := archEntry.Name()
+		vars := repoFileVars(distroID, arch)
+
+		archDir := path.Join(dir, arch)
+		repoFiles, err := fs.ReadDir(confPath, archDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, repoFile := range repoFiles {
+			if repoFile.IsDir() || !strings.HasSuffix(repoFile.Name(), ".repo") {
+				continue
+			}
+			filename := path.Join(archDir, repoFile.Name())
+			f, err := confPath.Open(filename)
+			if err != nil {
+				return nil, err
+			}
+			repos, err := rpmmd.LoadRepoFileFromReader(f, vars)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("cannot load repo file %q: %w", filename, err)
+			}
+			for _, repo := range repos {
+				if repo.Enabled != nil && !*repo.Enabled {
+					continue
+				}
+				distroRepos[arch] = append(distroRepos[arch], repo)
+			}
+		}
+	}
+	return distroRepos, nil
+}
+
+// repoFileVars returns the dnf variables for the .repo files of a distro and
+// architecture. $basearch and $arch are the architecture and $releasever is
+// the version of the distro as in its name, i.e. including the minor version
+// if there is one, e.g. "9.4" for the EUS repositories of "rhel-9.4". CentOS
+// Stream repositories also use $stream, e.g. "9-stream".
+func repoFileVars(distroID *distro.ID, arch string) map[string]string {
+	releasever := strconv.Itoa(distroID.MajorVersion)
+	if distroID.MinorVersion != -1 {
+		releasever = fmt.Sprintf("%d.%d", distroID.MajorVersion, distroID.MinorVersion)
+	}
+	vars := map[string]string{
+		"arch":       arch,
+		"basearch":   arch,
+		"releasever": releasever,
+	}
+	if distroID.Name == "centos" {
+		vars["stream"] = fmt.Sprintf("%d-stream", distroID.MajorVersion)
+	}
+	return vars
+}
+
 // LoadRepositories loads distribution repositories from the given list of paths.
 // If there are duplicate distro repositories definitions found in multiple paths, the first
 // encounter is preferred. For this reason, the order of paths in the passed list should
@@ -2,10 +2,12 @@ package reporegistry
 
 import (
 	"fmt"
+	"io/fs"
 	"path/filepath"
 	"reflect"
 	"slices"
 	"testing"
+	"testing/fstest"
 
 	"github.com/sirupsen/logrus"
 	logrusTest "github.com/sirupsen/logrus/hooks/test"
@@ -314,3 +316,86 @@ func TestLoadRepositoriesLogging(t *testing.T) {
 		return needle == entry.Message
 	}), fmt.Sprintf("%q not found in look entries %+v (last: %q)", needle, logHook.AllEntries(), logHook.LastEntry().Message))
 }
+
+func TestLoadAllRepositoriesRepoFileDirs(t *testing.T) {
+	confFS := fstest.MapFS{
+		"fedora-41/x86_64/fedora.repo": &fstest.MapFile{
+			Data: []byte(`[fedora]
+name=Fedora $releasever - $basearch
+metalink=https://mirrors.fedoraproject.org/metalink?repo=fedora-$releasever&arch=$basearch
+gpgcheck=1
+gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-fedora-$releasever-$basearch
+
+[fedora-debuginfo]
+name=Fedora $releasever - $basearch - Debug
+metalink=https://mirrors.fedoraproject.org/metalink?repo=fedora-debug-$releasever&arch=$basearch
+enabled=0
+`),
+		},
+		"fedora-41/aarch64/fedora.repo": &fstest.MapFile{
+			Data: []byte("[fedora]\nbaseurl=https://example.com/$releasever/$basearch/\n"),
+		},
+		"fedora-41/aarch64/README": &fstest.MapFile{},
+		// the json definition takes precedence over the directory
+		"rhel-9.4.json": &fstest.MapFile{
+			Data: []byte(`{"x86_64": [{"name": "baseos", "baseurl": "https://example.com/json/"}]}`),
+		},
+		"rhel-9.4/x86_64/rhel.repo": &fstest.MapFile{
+			Data: []byte("[baseos]\nbaseurl=https://example.com/repo-file/\n"),
+		},
+		// EUS repositories use the minor version
+		"rhel-9.6/x86_64/rhel.repo": &fstest.MapFile{
+			Data: []byte("[baseos]\nbaseurl=https://example.com/$releasever/\n"),
+		},
+		"centos-10/x86_64/centos.repo": &fstest.MapFile{
+			Data: []byte("[baseos]\nbaseurl=https://example.com/$releasever/$stream/\n"),
+		},
+		// directories that are not named after a distro are ignored
+		"other/x86_64/other.repo": &fstest.MapFile{
+			Data: []byte("[other]\nbaseurl=https://example.com/other/\n"),
+		},
+	}
+
+	repos, err := LoadAllRepositories(nil, []fs.FS{confFS})
+	require.NoError(t, err)
+	assert.Len(t, repos, 4)
+
+	assert.Equal(t, []rpmmd.RepoConfig{
+		{
+			Id:       "fedora",
+			Name:     "Fedora 41 - x86_64",
+			Metalink: "https://mirrors.fedoraproject.org/metalink?repo=fedora-41&arch=x86_64",
+			CheckGPG: common.ToPtr(true),
+			GPGKeys:  []string{"file:///etc/pki/rpm-gpg/RPM-GPG-KEY-fedora-41-x86_64"},
+		},
+	}, repos["fedora-41"]["x86_64"])
+	assert.Equal(t, []string{"https://example.com/41/aarch64/"}, repos["fedora-41"]["aarch64"][0].BaseURLs)
+	assert.Equal(t, []string{"https://example.com/json/"}, repos["rhel-9.4"]["x86_64"][0].BaseURLs)
+	assert.Equal(t, []string{"https://example.com/9.6/"}, repos["rhel-9.6"]["x86_64"][0].BaseURLs)
+	assert.Equal(t, []string{"https://example.com/10/10-stream/"}, repos["centos-10"]["x86_64"][0].BaseURLs)
+}
+
+func TestLoadAllRepositoriesRepoFileDirsUnknownVariable(t *testing.T) {
+	_, logHook := logrusTest.NewNullLogger()
+	logrus.AddHook(logHook)
+
+	confFS := fstest.MapFS{
+		"centos-10/x86_64/centos.repo": &fstest.MapFile{
+			Data: []byte("[baseos]\nbaseurl=https://example.com/$contentdir/\n"),
+		},
+		"fedora-41/x86_64/fedora.repo": &fstest.MapFile{
+			Data: []byte("[fedora]\nbaseurl=https://example.com/$releasever/\n"),
+		},
+	}
+
+	// the directory with the unknown variable is skipped
+	repos, err := LoadAllRepositories(nil, []fs.FS{confFS})
+	require.NoError(t, err)
+	assert.Len(t, repos, 1)
+	assert.Equal(t, []string{"https://example.com/41/"}, repos["fedora-41"]["x86_64"][0].BaseURLs)
+
+	needle := `Skipping directory "centos-10": cannot load repo file "centos-10/x86_64/centos.repo": repository "baseos": undefined variable "contentdir" in "https://example.com/$contentdir/"`
+	assert.True(t, slices.ContainsFunc(logHook.AllEntries(), func(entry *logrus.Entry) bool {
+		return needle == entry.Message && entry.Level == logrus.WarnLevel
+	}), fmt.Sprintf("%q not found in log entries %+v", needle, logHook.AllEntries()))
+}
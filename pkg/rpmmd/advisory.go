@@ -0,0 +1,139 @@
+package rpmmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AdvisoryPackage is a package version that fixes an advisory
+type AdvisoryPackage struct {
+	Name    string `json:"name"`
+	Epoch   uint   `json:"epoch"`
+	Version string `json:"version"`
+	Release string `json:"release"`
+	Arch    string `json:"arch"`
+
+	// The package is available in the repositories used for depsolving
+	Available bool `json:"available"`
+}
+
+// GetNEVRA returns the package's Name-Epoch:Version-Release.Arch string
+func (p *AdvisoryPackage) GetNEVRA() string {
+	ps := PackageSpec{Name: p.Name, Epoch: p.Epoch, Version: p.Version, Release: p.Release, Arch: p.Arch}
+	return ps.GetNEVRA()
+}
+
+// Advisory is an errata entry of the updateinfo metadata of a repository
+type Advisory struct {
+	// Advisory ID, e.g. "RHSA-2024:1234" or "FEDORA-2024-0123456789"
+	ID string `json:"id"`
+
+	// Advisory type, e.g. "security", "bugfix" or "enhancement"
+	Type string `json:"type"`
+
+	// Severity, e.g. "Critical", "Important", "Moderate" or "Low"
+	Severity string `json:"severity,omitempty"`
+
+	Title string `json:"title,omitempty"`
+
+	// CVE IDs referenced by the advisory
+	CVEs []string `json:"cves,omitempty"`
+
+	// Package versions that fix the advisory
+	Packages []AdvisoryPackage `json:"packages"`
+}
+
+type AdvisoryStatus string
+
+const (
+	// The selected package is affected and no fixed version is available in
+	// the repositories
+	AdvisoryStatusAffected AdvisoryStatus = "affected"
+
+	// The selected package is affected and a fixed version is available in
+	// the repositories
+	AdvisoryStatusFixAvailable AdvisoryStatus = "fix-available"
+)
+
+// AdvisoryMatch is an advisory that affects a selected package version
+type AdvisoryMatch struct {
+	// NEVRA of the selected package
+	Package string `json:"package"`
+
+	AdvisoryID string         `json:"advisory_id"`
+	Type       string         `json:"type"`
+	Severity   string         `json:"severity,omitempty"`
+	CVEs       []string       `json:"cves,omitempty"`
+	Status     AdvisoryStatus `json:"status"`
+
+	// NEVRA of the package version that fixes the advisory
+	FixedIn string `json:"fixed_in"`
+}
+
+func (m AdvisoryMatch) String() string {
+	s := fmt.Sprintf("%s (%s", m.AdvisoryID, m.Type)
+	if m.Severity != "" {
+		s += ", " + m.Severity
+	}
+	s += ")"
+	if len(m.CVEs) > 0 {
+		s += " " + strings.Join(m.CVEs, ", ")
+	}
+	return fmt.Sprintf("%s: %s, fixed in %s (%s)", m.Package, s, m.FixedIn, m.Status)
+}
+
+// MatchAdvisories returns the advisories that affect the given packages, i.e.
+// the advisories that list a newer version of a package as fixed. If an
+// advisory lists more than one newer version of the same package, the oldest
+// one is used, preferring versions that are available. The matches are sorted
+// by package and advisory ID.
+func MatchAdvisories(pkgs []PackageSpec, advisories []Advisory) []AdvisoryMatch {
+	var matches []AdvisoryMatch
+	for _, adv := range advisories {
+		for _, pkg := range pkgs {
+			var fix *AdvisoryPackage
+			fixed := false
+			for idx := range adv.Packages {
+				advPkg := &adv.Packages[idx]
+				if advPkg.Name != pkg.Name || advPkg.Arch != pkg.Arch {
+					continue
+				}
+				if CompareEVR(advPkg.Epoch, advPkg.Version, advPkg.Release, pkg.Epoch, pkg.Version, pkg.Release) <= 0 {
+					// the selected version already contains the fix
+					fixed = true
+					break
+				}
+				if fix == nil ||
+					(advPkg.Available && !fix.Available) ||
+					(advPkg.Available == fix.Available && CompareEVR(advPkg.Epoch, advPkg.Version, advPkg.Release, fix.Epoch, fix.Version, fix.Release) < 0) {
+					fix = advPkg
+				}
+			}
+			if fix == nil || fixed {
+				continue
+			}
+			status := AdvisoryStatusAffected
+			if fix.Available {
+				status = AdvisoryStatusFixAvailable
+			}
+			matches = append(matches, AdvisoryMatch{
+				Package:    pkg.GetNEVRA(),
+				AdvisoryID: adv.ID,
+				Type:       adv.Type,
+				Severity:   adv.Severity,
+				CVEs:       adv.CVEs,
+				Status:     status,
+				FixedIn:    fix.GetNEVRA(),
+			})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b AdvisoryMatch) int {
+		if c := strings.Compare(a.Package, b.Package); c != 0 {
+			return c
+		}
+		return strings.Compare(a.AdvisoryID, b.AdvisoryID)
+	})
+	return matches
+}
@@ -0,0 +1,86 @@
+package rpmmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchAdvisories(t *testing.T) {
+	pkgs := []PackageSpec{
+		{Name: "openssl-libs", Epoch: 1, Version: "3.2.2", Release: "3.fc41", Arch: "x86_64"},
+		{Name: "bash", Version: "5.2.32", Release: "1.fc41", Arch: "x86_64"},
+		{Name: "tzdata", Version: "2024a", Release: "9.fc41", Arch: "noarch"},
+	}
+	advisories := []Advisory{
+		{
+			ID:       "FEDORA-2024-0000000001",
+			Type:     "security",
+			Severity: "Critical",
+			CVEs:     []string{"CVE-2024-0001"},
+			Packages: []AdvisoryPackage{
+				{Name: "openssl-libs", Epoch: 1, Version: "3.2.2", Release: "5.fc41", Arch: "x86_64", Available: true},
+				{Name: "openssl-libs", Epoch: 1, Version: "3.2.2", Release: "4.fc41", Arch: "x86_64"},
+				{Name: "openssl-libs", Epoch: 1, Version: "3.2.2", Release: "4.fc41", Arch: "i686", Available: true},
+			},
+		},
+		{
+			// fixed by the selected version
+			ID:   "FEDORA-2024-0000000002",
+			Type: "bugfix",
+			Packages: []AdvisoryPackage{
+				{Name: "bash", Version: "5.2.32", Release: "1.fc41", Arch: "x86_64", Available: true},
+			},
+		},
+		{
+			ID:       "FEDORA-2024-0000000003",
+			Type:     "security",
+			Severity: "Low",
+			Packages: []AdvisoryPackage{
+				{Name: "tzdata", Version: "2024b", Release: "1.fc41", Arch: "noarch"},
+				{Name: "openssl-libs", Epoch: 1, Version: "3.2.3", Release: "1.fc41", Arch: "x86_64"},
+			},
+		},
+	}
+
+	assert.Equal(t, []AdvisoryMatch{
+		{
+			Package:    "openssl-libs-1:3.2.2-3.fc41.x86_64",
+			AdvisoryID: "FEDORA-2024-0000000001",
+			Type:       "security",
+			Severity:   "Critical",
+			CVEs:       []string{"CVE-2024-0001"},
+			Status:     AdvisoryStatusFixAvailable,
+			FixedIn:    "openssl-libs-1:3.2.2-5.fc41.x86_64",
+		},
+		{
+			Package:    "openssl-libs-1:3.2.2-3.fc41.x86_64",
+			AdvisoryID: "FEDORA-2024-0000000003",
+			Type:       "security",
+			Severity:   "Low",
+			Status:     AdvisoryStatusAffected,
+			FixedIn:    "openssl-libs-1:3.2.3-1.fc41.x86_64",
+		},
+		{
+			Package:    "tzdata-2024a-9.fc41.noarch",
+			AdvisoryID: "FEDORA-2024-0000000003",
+			Type:       "security",
+			Severity:   "Low",
+			Status:     AdvisoryStatusAffected,
+			FixedIn:    "tzdata-2024b-1.fc41.noarch",
+		},
+	}, MatchAdvisories(pkgs, advisories))
+}
+
+func TestAdvisoryMatchString(t *testing.T) {
+	m := AdvisoryMatch{
+		Package:    "openssl-libs-1:3.2.2-3.fc41.x86_64",
+		AdvisoryID: "FEDORA-2024-0000000001",
+		Type:       "security",
+		Severity:   "Critical",
+		CVEs:       []string{"CVE-2024-0001", "CVE-2024-0002"},
+		Status:     AdvisoryStatusFixAvailable,
+		FixedIn:    "openssl-libs-1:3.2.2-5.fc41.x86_64",
+	}
+	assert.Equal(t, "openssl-libs-1:3.2.2-3.fc41.x86_64: FEDORA-2024-0000000001 (security, Critical) CVE-2024-0001, CVE-2024-0002, fixed in openssl-libs-1:3.2.2-5.fc41.x86_64 (fix-available)", m.String())
+}
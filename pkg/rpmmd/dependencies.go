@@ -0,0 +1,148 @@
+package rpmmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Requirement is a single requires edge of a depsolved transaction
+type Requirement struct {
+	// The requirement as found in the package metadata, e.g.
+	// "libc.so.6()(64bit)" or "systemd >= 254"
+	Name string `json:"name"`
+
+	// NEVRA of the package in the transaction that provides the requirement
+	ProvidedBy string `json:"provided_by"`
+}
+
+// DependencyGraph describes why the packages of a depsolved transaction are
+// part of it. Packages are identified by their NEVRA, in the same format as
+// PackageSpec.GetNEVRA().
+type DependencyGraph struct {
+	// NEVRAs of the packages that were requested explicitly, i.e. that
+	// matched the include specs of the package sets
+	Requested []string `json:"requested"`
+
+	// Requirements of each package in the transaction, keyed by the package
+	// NEVRA
+	Requires map[string][]Requirement `json:"requires"`
+}
+
+// Packages returns the sorted NEVRAs of all packages in the graph.
+func (g *DependencyGraph) Packages() []string {
+	seen := make(map[string]bool)
+	for _, nevra := range g.Requested {
+		seen[nevra] = true
+	}
+	for nevra, reqs := range g.Requires {
+		seen[nevra] = true
+		for _, req := range reqs {
+			seen[req.ProvidedBy] = true
+		}
+	}
+	pkgs := make([]string, 0, len(seen))
+	for nevra := range seen {
+		pkgs = append(pkgs, nevra)
+	}
+	slices.Sort(pkgs)
+	return pkgs
+}
+
+// IsRequested returns true if the package with the given NEVRA was requested
+// explicitly.
+func (g *DependencyGraph) IsRequested(nevra string) bool {
+	return slices.Contains(g.Requested, nevra)
+}
+
+// Find returns the sorted NEVRAs of the packages in the graph that match the
+// given package name or NEVRA. A name can match more than one package, e.g.
+// for multilib packages.
+func (g *DependencyGraph) Find(pkg string) []string {
+	var matches []string
+	for _, nevra := range g.Packages() {
+		if nevra == pkg || nevraName(nevra) == pkg {
+			matches = append(matches, nevra)
+		}
+	}
+	return matches
+}
+
+// Why returns the dependency chains that explain why the given package, a
+// name or NEVRA, is part of the transaction. Each chain starts with a
+// requested package and ends with the given package. Only the shortest chain
+// is returned for each requested package that pulls in the given package,
+// chains are sorted by the requested package. A requested package is
+// returned as a chain of its own.
+func (g *DependencyGraph) Why(pkg string) ([][]string, error) {
+	targets := g.Find(pkg)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("package %q is not part of the transaction", pkg)
+	}
+
+	// reverse edges: package -> packages that require it
+	requiredBy := make(map[string][]string)
+	for nevra, reqs := range g.Requires {
+		for _, req := range reqs {
+			if req.ProvidedBy == nevra || slices.Contains(requiredBy[req.ProvidedBy], nevra) {
+				continue
+			}
+			requiredBy[req.ProvidedBy] = append(requiredBy[req.ProvidedBy], nevra)
+		}
+	}
+	for _, dependents := range requiredBy {
+		slices.Sort(dependents)
+	}
+
+	// breadth-first search from the targets towards the requested packages;
+	// next points one step closer to a target
+	next := make(map[string]string)
+	visited := make(map[string]bool)
+	queue := slices.Clone(targets)
+	for _, target := range targets {
+		visited[target] = true
+	}
+	for len(queue) > 0 {
+		nevra := queue[0]
+		queue = queue[1:]
+		for _, dependent := range requiredBy[nevra] {
+			if visited[dependent] {
+				continue
+			}
+			visited[dependent] = true
+			next[dependent] = nevra
+			queue = append(queue, dependent)
+		}
+	}
+
+	requested := slices.Clone(g.Requested)
+	slices.Sort(requested)
+	var chains [][]string
+	for _, root := range slices.Compact(requested) {
+		if !visited[root] {
+			continue
+		}
+		chain := []string{root}
+		for nevra := root; !slices.Contains(targets, nevra); {
+			nevra = next[nevra]
+			chain = append(chain, nevra)
+		}
+		chains = append(chains, chain)
+	}
+	return chains, nil
+}
+
+// nevraName returns the name part of a Name-[Epoch:]Version-Release.Arch
+// string.
+func nevraName(nevra string) string {
+	// the name is everything before the second to last dash
+	idx := strings.LastIndex(nevra, "-")
+	if idx < 0 {
+		return nevra
+	}
+	idx = strings.LastIndex(nevra[:idx], "-")
+	if idx < 0 {
+		return nevra
+	}
+	return nevra[:idx]
+}
@@ -0,0 +1,113 @@
+package rpmmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		Requested: []string{
+			"vim-enhanced-2:9.1.0-1.fc41.x86_64",
+			"bash-5.2.32-1.fc41.x86_64",
+		},
+		Requires: map[string][]Requirement{
+			"vim-enhanced-2:9.1.0-1.fc41.x86_64": {
+				{Name: "vim-common = 2:9.1.0-1.fc41", ProvidedBy: "vim-common-2:9.1.0-1.fc41.x86_64"},
+				{Name: "libc.so.6()(64bit)", ProvidedBy: "glibc-2.40-1.fc41.x86_64"},
+			},
+			"vim-common-2:9.1.0-1.fc41.x86_64": {
+				{Name: "vim-filesystem", ProvidedBy: "vim-filesystem-2:9.1.0-1.fc41.noarch"},
+			},
+			"vim-filesystem-2:9.1.0-1.fc41.noarch": {
+				{Name: "libc.so.6()(64bit)", ProvidedBy: "glibc-2.40-1.fc41.x86_64"},
+			},
+			"bash-5.2.32-1.fc41.x86_64": {
+				{Name: "libc.so.6()(64bit)", ProvidedBy: "glibc-2.40-1.fc41.x86_64"},
+				{Name: "filesystem >= 3", ProvidedBy: "filesystem-3.18-1.fc41.x86_64"},
+			},
+			"glibc-2.40-1.fc41.x86_64": {
+				{Name: "glibc-common = 2.40-1.fc41", ProvidedBy: "glibc-common-2.40-1.fc41.x86_64"},
+				{Name: "libc.so.6", ProvidedBy: "glibc-2.40-1.fc41.x86_64"},
+			},
+			"glibc-common-2.40-1.fc41.x86_64": {
+				{Name: "glibc = 2.40-1.fc41", ProvidedBy: "glibc-2.40-1.fc41.x86_64"},
+			},
+		},
+	}
+}
+
+func TestDependencyGraphPackages(t *testing.T) {
+	g := testDependencyGraph()
+	assert.Equal(t, []string{
+		"bash-5.2.32-1.fc41.x86_64",
+		"filesystem-3.18-1.fc41.x86_64",
+		"glibc-2.40-1.fc41.x86_64",
+		"glibc-common-2.40-1.fc41.x86_64",
+		"vim-common-2:9.1.0-1.fc41.x86_64",
+		"vim-enhanced-2:9.1.0-1.fc41.x86_64",
+		"vim-filesystem-2:9.1.0-1.fc41.noarch",
+	}, g.Packages())
+	assert.True(t, g.IsRequested("bash-5.2.32-1.fc41.x86_64"))
+	assert.False(t, g.IsRequested("glibc-2.40-1.fc41.x86_64"))
+}
+
+func TestDependencyGraphFind(t *testing.T) {
+	g := testDependencyGraph()
+	assert.Equal(t, []string{"glibc-2.40-1.fc41.x86_64"}, g.Find("glibc"))
+	assert.Equal(t, []string{"vim-common-2:9.1.0-1.fc41.x86_64"}, g.Find("vim-common-2:9.1.0-1.fc41.x86_64"))
+	assert.Nil(t, g.Find("vim"))
+}
+
+func TestDependencyGraphWhy(t *testing.T) {
+	g := testDependencyGraph()
+
+	type testCase struct {
+		pkg      string
+		expected [][]string
+	}
+	testCases := map[string]testCase{
+		"shared": {
+			pkg: "glibc",
+			expected: [][]string{
+				{"bash-5.2.32-1.fc41.x86_64", "glibc-2.40-1.fc41.x86_64"},
+				{"vim-enhanced-2:9.1.0-1.fc41.x86_64", "glibc-2.40-1.fc41.x86_64"},
+			},
+		},
+		"transitive": {
+			pkg: "vim-filesystem",
+			expected: [][]string{
+				{"vim-enhanced-2:9.1.0-1.fc41.x86_64", "vim-common-2:9.1.0-1.fc41.x86_64", "vim-filesystem-2:9.1.0-1.fc41.noarch"},
+			},
+		},
+		"cycle": {
+			pkg: "glibc-common-2.40-1.fc41.x86_64",
+			expected: [][]string{
+				{"bash-5.2.32-1.fc41.x86_64", "glibc-2.40-1.fc41.x86_64", "glibc-common-2.40-1.fc41.x86_64"},
+				{"vim-enhanced-2:9.1.0-1.fc41.x86_64", "glibc-2.40-1.fc41.x86_64", "glibc-common-2.40-1.fc41.x86_64"},
+			},
+		},
+		"requested": {
+			pkg: "bash",
+			expected: [][]string{
+				{"bash-5.2.32-1.fc41.x86_64"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			chains, err := g.Why(tc.pkg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, chains)
+		})
+	}
+}
+
+func TestDependencyGraphWhyNotFound(t *testing.T) {
+	g := testDependencyGraph()
+	_, err := g.Why("emacs")
+	assert.EqualError(t, err, `package "emacs" is not part of the transaction`)
+}
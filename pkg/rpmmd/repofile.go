@@ -0,0 +1,244 @@
+package rpmmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// repoFileVarRegex matches the $name and ${name} variables of repo files
+var repoFileVarRegex = regexp.MustCompile(`\$(?:\{([A-Za-z0-9_]+)\}|([A-Za-z0-9_]+))`)
+
+// substituteRepoFileVars replaces the variables in a value of a repo file,
+// e.g. $releasever or $basearch, with the given values.
+func substituteRepoFileVars(value string, vars map[string]string) (string, error) {
+	var err error
+	res := repoFileVarRegex.ReplaceAllStringFunc(value, func(match string) string {
+		sub := repoFileVarRegex.FindStringSubmatch(match)
+		name := sub[1] + sub[2]
+		val, ok := vars[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("undefined variable %q in %q", name, value)
+			}
+			return match
+		}
+		return val
+	})
+	return res, err
+}
+
+// splitRepoFileList splits a list value of a repo file, e.g. a list of
+// baseurls or gpgkeys, which are separated by whitespace or commas.
+func splitRepoFileList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+func parseRepoFileBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "yes", "true", "on":
+		return true, nil
+	case "0", "no", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", value)
+}
+
+// LoadRepoFile reads the repositories of a yum/dnf .repo file. See
+// LoadRepoFileFromReader().
+func LoadRepoFile(filename string, vars map[string]string) ([]RepoConfig, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	repos, err := LoadRepoFileFromReader(f, vars)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load repo file %q: %w", filename, err)
+	}
+	return repos, nil
+}
+
+// LoadRepoFileFromReader parses a yum/dnf .repo INI file, as documented in
+// dnf.conf(5), into a list of repository configurations. Each section is a
+// repository and the section name is used as the repository ID. The
+// variables of the file, e.g. $releasever and $basearch, are substituted with
+// the given vars, an undefined variable is an error. Disabled repositories
+// are returned with Enabled set to false. Options that have no equivalent in
+// RepoConfig are ignored.
+func LoadRepoFileFromReader(r io.Reader, vars map[string]string) ([]RepoConfig, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ini.LoadSources(ini.LoadOptions{
+		// baseurl and gpgkey lists are often continued on indented lines
+		AllowPythonMultilineValues: true,
+		SpaceBeforeInlineComment:   true,
+	}, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []RepoConfig
+	for _, section := range cfg.Sections() {
+		// the default section holds keys without a section, "main" the
+		// global options of a dnf.conf
+		if section.Name() == ini.DefaultSection || section.Name() == "main" {
+			continue
+		}
+		repo, err := repoConfigFromSection(section, vars)
+		if err != nil {
+			return nil, fmt.Errorf("repository %q: %w", section.Name(), err)
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+func repoConfigFromSection(section *ini.Section, vars map[string]string) (RepoConfig, error) {
+	repo := RepoConfig{
+		Id: section.Name(),
+	}
+	for _, key := range section.Keys() {
+		value, err := substituteRepoFileVars(strings.TrimSpace(key.Value()), vars)
+		if err != nil {
+			return repo, err
+		}
+		if value == "" {
+			continue
+		}
+
+		var boolValue *bool
+		switch key.Name() {
+		case "enabled", "gpgcheck", "repo_gpgcheck", "sslverify", "module_hotfixes":
+			b, err := parseRepoFileBool(value)
+			if err != nil {
+				return repo, fmt.Errorf("option %q: %w", key.Name(), err)
+			}
+			boolValue = &b
+		}
+
+		switch key.Name() {
+		case "name":
+			repo.Name = value
+		case "baseurl":
+			repo.BaseURLs = splitRepoFileList(value)
+		case "metalink":
+			repo.Metalink = value
+		case "mirrorlist":
+			repo.MirrorList = value
+		case "gpgkey":
+			repo.GPGKeys = splitRepoFileList(value)
+		case "enabled":
+			repo.Enabled = boolValue
+		case "gpgcheck":
+			repo.CheckGPG = boolValue
+		case "repo_gpgcheck":
+			repo.CheckRepoGPG = boolValue
+		case "sslverify":
+			ignoreSSL := !*boolValue
+			repo.IgnoreSSL = &ignoreSSL
+		case "module_hotfixes":
+			repo.ModuleHotfixes = boolValue
+		case "sslcacert":
+			repo.SSLCACert = value
+		case "sslclientkey":
+			repo.SSLClientKey = value
+		case "sslclientcert":
+			repo.SSLClientCert = value
+		case "metadata_expire":
+			repo.MetadataExpire = value
+		case "priority", "cost":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return repo, fmt.Errorf("option %q: invalid integer value %q", key.Name(), value)
+			}
+			if key.Name() == "priority" {
+				repo.Priority = &n
+			} else {
+				repo.Cost = &n
+			}
+		case "includepkgs":
+			repo.IncludePackages = splitRepoFileList(value)
+		case "excludepkgs", "exclude":
+			repo.ExcludePackages = append(repo.ExcludePackages, splitRepoFileList(value)...)
+		}
+	}
+
+	if len(repo.BaseURLs) == 0 && repo.Metalink == "" && repo.MirrorList == "" {
+		return repo, fmt.Errorf("baseurl, metalink or mirrorlist is required")
+	}
+	return repo, nil
+}
+
+func repositoryFromRepoConfig(repo RepoConfig) repository {
+	r := repository{
+		Id:              repo.Id,
+		Name:            repo.Name,
+		Metalink:        repo.Metalink,
+		MirrorList:      repo.MirrorList,
+		GPGKeys:         repo.GPGKeys,
+		CheckRepoGPG:    repo.CheckRepoGPG,
+		RHSM:            repo.RHSM,
+		Enabled:         repo.Enabled,
+		ModuleHotfixes:  repo.ModuleHotfixes,
+		MetadataExpire:  repo.MetadataExpire,
+		Priority:        repo.Priority,
+		ImageTypeTags:   repo.ImageTypeTags,
+		PackageSets:     repo.PackageSets,
+		SnapshotURL:     repo.SnapshotURL,
+		IncludePackages: repo.IncludePackages,
+		ExcludePackages: repo.ExcludePackages,
+		Cost:            repo.Cost,
+		SSLCACert:       repo.SSLCACert,
+		SSLClientKey:    repo.SSLClientKey,
+		SSLClientCert:   repo.SSLClientCert,
+	}
+	if len(repo.BaseURLs) == 1 {
+		r.BaseURL = repo.BaseURLs[0]
+	} else {
+		r.BaseURLs = repo.BaseURLs
+	}
+	if repo.CheckGPG != nil {
+		r.CheckGPG = *repo.CheckGPG
+	}
+	if repo.IgnoreSSL != nil {
+		r.IgnoreSSL = *repo.IgnoreSSL
+	}
+	return r
+}
+
+// WriteRepositories writes the repositories of a distribution, keyed by
+// architecture, in the JSON format that is read by
+// LoadRepositoriesFromReader().
+func WriteRepositories(w io.Writer, repoConfigs map[string][]RepoConfig) error {
+	reposMap := make(map[string][]repository, len(repoConfigs))
+	for arch, repos := range repoConfigs {
+		reposMap[arch] = make([]repository, 0, len(repos))
+		for _, repo := range repos {
+			reposMap[arch] = append(reposMap[arch], repositoryFromRepoConfig(repo))
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	// keep the query parameters of metalinks readable
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(reposMap); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
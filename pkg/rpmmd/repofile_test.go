@@ -0,0 +1,133 @@
+package rpmmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+const testRepoFile = `# comment
+[main]
+gpgcheck=1
+
+[baseos]
+name=BaseOS $releasever - $basearch
+baseurl=https://mirror1.example.com/$releasever/BaseOS/$basearch/os/
+        https://mirror2.example.com/${releasever}/BaseOS/${basearch}/os/
+enabled=1
+gpgcheck=1
+repo_gpgcheck=0
+gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-1, file:///etc/pki/rpm-gpg/RPM-GPG-KEY-2
+sslverify=0
+sslcacert=/etc/rhsm/ca/redhat-uep.pem
+sslclientkey=/etc/pki/entitlement/key.pem
+sslclientcert=/etc/pki/entitlement/cert.pem
+metadata_expire=1d
+priority=10
+cost=500
+
+[epel]
+name=EPEL
+metalink=https://mirrors.example.com/metalink?repo=epel-$releasever&arch=$basearch
+module_hotfixes=true
+includepkgs=htop tmux*
+exclude=kernel*
+enabled=0
+`
+
+func TestLoadRepoFileFromReader(t *testing.T) {
+	vars := map[string]string{"releasever": "9", "basearch": "x86_64"}
+	repos, err := LoadRepoFileFromReader(strings.NewReader(testRepoFile), vars)
+	require.NoError(t, err)
+	assert.Equal(t, []RepoConfig{
+		{
+			Id:   "baseos",
+			Name: "BaseOS 9 - x86_64",
+			BaseURLs: []string{
+				"https://mirror1.example.com/9/BaseOS/x86_64/os/",
+				"https://mirror2.example.com/9/BaseOS/x86_64/os/",
+			},
+			Enabled:        common.ToPtr(true),
+			CheckGPG:       common.ToPtr(true),
+			CheckRepoGPG:   common.ToPtr(false),
+			GPGKeys:        []string{"file:///etc/pki/rpm-gpg/RPM-GPG-KEY-1", "file:///etc/pki/rpm-gpg/RPM-GPG-KEY-2"},
+			IgnoreSSL:      common.ToPtr(true),
+			SSLCACert:      "/etc/rhsm/ca/redhat-uep.pem",
+			SSLClientKey:   "/etc/pki/entitlement/key.pem",
+			SSLClientCert:  "/etc/pki/entitlement/cert.pem",
+			MetadataExpire: "1d",
+			Priority:       common.ToPtr(10),
+			Cost:           common.ToPtr(500),
+		},
+		{
+			Id:              "epel",
+			Name:            "EPEL",
+			Metalink:        "https://mirrors.example.com/metalink?repo=epel-9&arch=x86_64",
+			ModuleHotfixes:  common.ToPtr(true),
+			IncludePackages: []string{"htop", "tmux*"},
+			ExcludePackages: []string{"kernel*"},
+			Enabled:         common.ToPtr(false),
+		},
+	}, repos)
+}
+
+func TestLoadRepoFileFromReaderErrors(t *testing.T) {
+	vars := map[string]string{"releasever": "9", "basearch": "x86_64"}
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "undefined-variable",
+			content: "[repo]\nbaseurl=https://example.com/$stream/$basearch/\n",
+			err:     `repository "repo": undefined variable "stream" in "https://example.com/$stream/$basearch/"`,
+		},
+		{
+			name:    "no-url",
+			content: "[repo]\nname=Repo\n",
+			err:     `repository "repo": baseurl, metalink or mirrorlist is required`,
+		},
+		{
+			name:    "invalid-bool",
+			content: "[repo]\nbaseurl=https://example.com/\ngpgcheck=maybe\n",
+			err:     `repository "repo": option "gpgcheck": invalid boolean value "maybe"`,
+		},
+		{
+			name:    "invalid-int",
+			content: "[repo]\nbaseurl=https://example.com/\npriority=high\n",
+			err:     `repository "repo": option "priority": invalid integer value "high"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRepoFileFromReader(strings.NewReader(tt.content), vars)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestWriteRepositoriesRoundtrip(t *testing.T) {
+	vars := map[string]string{"releasever": "9", "basearch": "x86_64"}
+	repos, err := LoadRepoFileFromReader(strings.NewReader(testRepoFile), vars)
+	require.NoError(t, err)
+	repos[0].ImageTypeTags = []string{"qcow2"}
+	repos[1].BaseURLs = []string{"https://example.com/epel/"}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteRepositories(&buf, map[string][]RepoConfig{"x86_64": repos}))
+	assert.Contains(t, buf.String(), `"metalink": "https://mirrors.example.com/metalink?repo=epel-9&arch=x86_64"`)
+	assert.Contains(t, buf.String(), `"baseurl": "https://example.com/epel/"`)
+
+	loaded, err := LoadRepositoriesFromReader(&buf)
+	require.NoError(t, err)
+	require.Len(t, loaded["x86_64"], 2)
+	// the json format always sets check_gpg
+	repos[1].CheckGPG = common.ToPtr(false)
+	assert.Equal(t, repos, loaded["x86_64"])
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -14,19 +15,31 @@ import (
 )
 
 type repository struct {
-	Name           string   `json:"name"`
-	BaseURL        string   `json:"baseurl,omitempty"`
-	Metalink       string   `json:"metalink,omitempty"`
-	MirrorList     string   `json:"mirrorlist,omitempty"`
-	GPGKey         string   `json:"gpgkey,omitempty"`
-	GPGKeys        []string `json:"gpgkeys,omitempty"`
-	CheckGPG       bool     `json:"check_gpg,omitempty"`
-	IgnoreSSL      bool     `json:"ignore_ssl,omitempty"`
-	RHSM           bool     `json:"rhsm,omitempty"`
-	ModuleHotfixes *bool    `json:"module_hotfixes,omitempty"`
-	MetadataExpire string   `json:"metadata_expire,omitempty"`
-	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
-	PackageSets    []string `json:"package_sets,omitempty"`
+	Id              string   `json:"id,omitempty"`
+	Name            string   `json:"name"`
+	BaseURL         string   `json:"baseurl,omitempty"`
+	BaseURLs        []string `json:"baseurls,omitempty"`
+	Metalink        string   `json:"metalink,omitempty"`
+	MirrorList      string   `json:"mirrorlist,omitempty"`
+	GPGKey          string   `json:"gpgkey,omitempty"`
+	GPGKeys         []string `json:"gpgkeys,omitempty"`
+	CheckGPG        bool     `json:"check_gpg,omitempty"`
+	CheckRepoGPG    *bool    `json:"check_repo_gpg,omitempty"`
+	IgnoreSSL       bool     `json:"ignore_ssl,omitempty"`
+	RHSM            bool     `json:"rhsm,omitempty"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+	ModuleHotfixes  *bool    `json:"module_hotfixes,omitempty"`
+	MetadataExpire  string   `json:"metadata_expire,omitempty"`
+	Priority        *int     `json:"priority,omitempty"`
+	ImageTypeTags   []string `json:"image_type_tags,omitempty"`
+	PackageSets     []string `json:"package_sets,omitempty"`
+	SnapshotURL     string   `json:"snapshot_url,omitempty"`
+	IncludePackages []string `json:"include_packages,omitempty"`
+	ExcludePackages []string `json:"exclude_packages,omitempty"`
+	Cost            *int     `json:"cost,omitempty"`
+	SSLCACert       string   `json:"sslcacert,omitempty"`
+	SSLClientKey    string   `json:"sslclientkey,omitempty"`
+	SSLClientCert   string   `json:"sslclientcert,omitempty"`
 }
 
 type RepoConfig struct {
@@ -42,6 +55,7 @@ type RepoConfig struct {
 	CheckGPG       *bool    `json:"check_gpg,omitempty"`
 	CheckRepoGPG   *bool    `json:"check_repo_gpg,omitempty"`
 	Priority       *int     `json:"priority,omitempty"`
+	Cost           *int     `json:"cost,omitempty"`
 	IgnoreSSL      *bool    `json:"ignore_ssl,omitempty"`
 	MetadataExpire string   `json:"metadata_expire,omitempty"`
 	ModuleHotfixes *bool    `json:"module_hotfixes,omitempty"`
@@ -50,6 +64,19 @@ type RepoConfig struct {
 	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
 	PackageSets    []string `json:"package_sets,omitempty"`
 
+	// Package name globs, only matching packages are used from the
+	// repository (DNF's includepkgs)
+	IncludePackages []string `json:"include_packages,omitempty"`
+	// Package name globs, matching packages are never used from the
+	// repository (DNF's excludepkgs)
+	ExcludePackages []string `json:"exclude_packages,omitempty"`
+
+	// URL pattern of the dated content snapshots of the repository, the
+	// SnapshotPlaceholder is replaced by the snapshot date or ID
+	SnapshotURL string `json:"snapshot_url,omitempty"`
+	// Snapshot date or ID the repository is pinned to, see WithSnapshot()
+	Snapshot string `json:"snapshot,omitempty"`
+
 	// These fields are only filled out by the worker during the
 	// depsolve job for certain baseurls.
 	SSLCACert     string `json:"sslcacert,omitempty"`
@@ -73,6 +100,20 @@ func (r *RepoConfig) Hash() string {
 	ats := func(s []string) string {
 		return strings.Join(s, "")
 	}
+	// newer fields are added with a key so that the hash of repositories
+	// that do not set them does not change
+	kv := func(key, value string) string {
+		if value == "" {
+			return ""
+		}
+		return "\x00" + key + "=" + value
+	}
+	its := func(i *int) string {
+		if i == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", *i)
+	}
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(ats(r.BaseURLs)+
 		r.Metalink+
 		r.MirrorList+
@@ -85,11 +126,70 @@ func (r *RepoConfig) Hash() string {
 		bpts(r.ModuleHotfixes)+
 		r.SSLCACert+
 		r.SSLClientKey+
-		r.SSLClientCert)))
+		r.SSLClientCert+
+		kv("includepkgs", strings.Join(r.IncludePackages, "\x00"))+
+		kv("excludepkgs", strings.Join(r.ExcludePackages, "\x00"))+
+		kv("cost", its(r.Cost)))))
+}
+
+// SnapshotPlaceholder is replaced by the snapshot date or ID in the
+// SnapshotURL of a repository
+const SnapshotPlaceholder = "{snapshot}"
+
+var snapshotRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+
+// WithSnapshot returns a copy of the repository that is pinned to the given
+// snapshot date or ID. The baseurl is set to the SnapshotURL of the
+// repository with the placeholder replaced by the snapshot; the metalink and
+// mirrorlist are dropped so that only the snapshot content is used.
+func (r RepoConfig) WithSnapshot(snapshot string) (RepoConfig, error) {
+	if !snapshotRegex.MatchString(snapshot) {
+		return RepoConfig{}, fmt.Errorf("invalid repository snapshot %q", snapshot)
+	}
+	if r.SnapshotURL == "" {
+		return RepoConfig{}, fmt.Errorf("repository %q does not define a snapshot_url", r.Name)
+	}
+	if !strings.Contains(r.SnapshotURL, SnapshotPlaceholder) {
+		return RepoConfig{}, fmt.Errorf("snapshot_url %q of repository %q does not contain %s", r.SnapshotURL, r.Name, SnapshotPlaceholder)
+	}
+
+	r.BaseURLs = []string{strings.ReplaceAll(r.SnapshotURL, SnapshotPlaceholder, snapshot)}
+	r.Metalink = ""
+	r.MirrorList = ""
+	r.Snapshot = snapshot
+	return r, nil
+}
+
+// ApplySnapshot pins the repositories to the given snapshot date or ID, see
+// RepoConfig.WithSnapshot(). Repositories without a snapshot_url are not
+// pinned, their names are returned so that the caller can warn about them.
+func ApplySnapshot(repos []RepoConfig, snapshot string) ([]RepoConfig, []string, error) {
+	pinned := make([]RepoConfig, len(repos))
+	var unpinned []string
+	for idx, repo := range repos {
+		if repo.SnapshotURL == "" {
+			pinned[idx] = repo
+			unpinned = append(unpinned, repo.Name)
+			continue
+		}
+		var err error
+		pinned[idx], err = repo.WithSnapshot(snapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return pinned, unpinned, nil
 }
 
 type DistrosRepoConfigs map[string]map[string][]RepoConfig
 
+// MetadataReader lists and searches the packages available in a set of
+// repositories.
+type MetadataReader interface {
+	FetchMetadata(repos []RepoConfig) (PackageList, error)
+	SearchMetadata(repos []RepoConfig, packages []string) (PackageList, error)
+}
+
 type PackageList []Package
 
 type Package struct {
@@ -170,6 +270,27 @@ type PackageSpec struct {
 
 	Path   string `json:"path,omitempty"`
 	RepoID string `json:"repo_id,omitempty"`
+
+	// Size of the installed package in bytes, as recorded in the
+	// repository metadata
+	InstallSize uint64 `json:"install_size,omitempty"`
+
+	// Size of the files of the package that are marked as
+	// documentation in bytes, i.e. the size not installed when
+	// documentation is excluded
+	DocSize uint64 `json:"doc_size,omitempty"`
+
+	// Absolute path or URL of a package that was supplied locally
+	// instead of by a repository, e.g. by a blueprint package source.
+	// Manifests refer to the package by this path or URL, so a local
+	// file must stay in place until the manifest is built.
+	LocalSource string `json:"local_source,omitempty"`
+}
+
+// IsLocalFile returns true if the package was supplied as a local RPM file
+// instead of by a repository or a URL.
+func (ps *PackageSpec) IsLocalFile() bool {
+	return strings.HasPrefix(ps.LocalSource, "/")
 }
 
 type PackageSource struct {
@@ -279,6 +400,7 @@ func LoadRepositoriesFromReader(r io.Reader) (map[string][]RepoConfig, error) {
 			if repo.BaseURL != "" {
 				urls = []string{repo.BaseURL}
 			}
+			urls = append(urls, repo.BaseURLs...)
 			var keys []string
 			if repo.GPGKey != "" {
 				keys = []string{repo.GPGKey}
@@ -287,17 +409,31 @@ func LoadRepositoriesFromReader(r io.Reader) (map[string][]RepoConfig, error) {
 				keys = append(keys, repo.GPGKeys...)
 			}
 			config := RepoConfig{
-				Name:           repo.Name,
-				BaseURLs:       urls,
-				Metalink:       repo.Metalink,
-				MirrorList:     repo.MirrorList,
-				GPGKeys:        keys,
-				CheckGPG:       &repo.CheckGPG,
-				RHSM:           repo.RHSM,
-				MetadataExpire: repo.MetadataExpire,
-				ModuleHotfixes: repo.ModuleHotfixes,
-				ImageTypeTags:  repo.ImageTypeTags,
-				PackageSets:    repo.PackageSets,
+				Id:              repo.Id,
+				Name:            repo.Name,
+				BaseURLs:        urls,
+				Metalink:        repo.Metalink,
+				MirrorList:      repo.MirrorList,
+				GPGKeys:         keys,
+				CheckGPG:        &repo.CheckGPG,
+				CheckRepoGPG:    repo.CheckRepoGPG,
+				RHSM:            repo.RHSM,
+				Enabled:         repo.Enabled,
+				Priority:        repo.Priority,
+				MetadataExpire:  repo.MetadataExpire,
+				ModuleHotfixes:  repo.ModuleHotfixes,
+				ImageTypeTags:   repo.ImageTypeTags,
+				PackageSets:     repo.PackageSets,
+				SnapshotURL:     repo.SnapshotURL,
+				IncludePackages: repo.IncludePackages,
+				ExcludePackages: repo.ExcludePackages,
+				Cost:            repo.Cost,
+				SSLCACert:       repo.SSLCACert,
+				SSLClientKey:    repo.SSLClientKey,
+				SSLClientCert:   repo.SSLClientCert,
+			}
+			if repo.IgnoreSSL {
+				config.IgnoreSSL = &repo.IgnoreSSL
 			}
 
 			repoConfigs[arch] = append(repoConfigs[arch], config)
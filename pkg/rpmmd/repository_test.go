@@ -2,9 +2,13 @@ package rpmmd
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
 )
 
 func TestPackageSpecGetEVRA(t *testing.T) {
@@ -96,3 +100,109 @@ func TestPackageSpecFull(t *testing.T) {
  "repo_id": "813859d10fe28ff54dbde44655a18b071c8adbaa849a551ec23cc415f0f7f1b0"
 }`)
 }
+
+func TestLoadRepositoriesSnapshotURL(t *testing.T) {
+	repos, err := LoadRepositoriesFromReader(strings.NewReader(`{
+  "x86_64": [
+    {
+      "name": "fedora",
+      "metalink": "https://mirrors.fedoraproject.org/metalink?repo=fedora-41&arch=x86_64",
+      "snapshot_url": "https://snapshots.example.com/{snapshot}/fedora/41/x86_64/"
+    }
+  ]
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, "https://snapshots.example.com/{snapshot}/fedora/41/x86_64/", repos["x86_64"][0].SnapshotURL)
+	assert.Equal(t, "", repos["x86_64"][0].Snapshot)
+}
+
+func TestLoadRepositoriesPackageFilters(t *testing.T) {
+	repos, err := LoadRepositoriesFromReader(strings.NewReader(`{
+  "x86_64": [
+    {
+      "name": "epel",
+      "baseurl": "https://example.com/epel/9/x86_64/",
+      "include_packages": ["htop", "tmux*"],
+      "exclude_packages": ["kernel*"],
+      "cost": 2000
+    }
+  ]
+}`))
+	require.NoError(t, err)
+	repo := repos["x86_64"][0]
+	assert.Equal(t, []string{"htop", "tmux*"}, repo.IncludePackages)
+	assert.Equal(t, []string{"kernel*"}, repo.ExcludePackages)
+	assert.Equal(t, common.ToPtr(2000), repo.Cost)
+}
+
+func TestRepoConfigHashPackageFilters(t *testing.T) {
+	repo := RepoConfig{
+		Name:     "epel",
+		BaseURLs: []string{"https://example.com/epel/9/x86_64/"},
+	}
+	hash := repo.Hash()
+
+	// empty filters do not change the hash
+	repo.IncludePackages = []string{}
+	assert.Equal(t, hash, repo.Hash())
+
+	hashes := map[string]bool{hash: true}
+	for _, r := range []RepoConfig{
+		{Name: repo.Name, BaseURLs: repo.BaseURLs, IncludePackages: []string{"htop"}},
+		{Name: repo.Name, BaseURLs: repo.BaseURLs, ExcludePackages: []string{"htop"}},
+		{Name: repo.Name, BaseURLs: repo.BaseURLs, IncludePackages: []string{"htop", "tmux"}},
+		{Name: repo.Name, BaseURLs: repo.BaseURLs, IncludePackages: []string{"htoptmux"}},
+		{Name: repo.Name, BaseURLs: repo.BaseURLs, Cost: common.ToPtr(2000)},
+	} {
+		assert.False(t, hashes[r.Hash()], "duplicate hash for %+v", r)
+		hashes[r.Hash()] = true
+	}
+}
+
+func TestRepoConfigWithSnapshot(t *testing.T) {
+	repo := RepoConfig{
+		Name:        "fedora",
+		Metalink:    "https://mirrors.fedoraproject.org/metalink?repo=fedora-41&arch=x86_64",
+		SnapshotURL: "https://snapshots.example.com/{snapshot}/fedora/41/x86_64/",
+	}
+
+	pinned, err := repo.WithSnapshot("2024-05-01")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://snapshots.example.com/2024-05-01/fedora/41/x86_64/"}, pinned.BaseURLs)
+	assert.Equal(t, "", pinned.Metalink)
+	assert.Equal(t, "2024-05-01", pinned.Snapshot)
+	assert.NotEqual(t, repo.Hash(), pinned.Hash())
+	// the original is not modified
+	assert.Nil(t, repo.BaseURLs)
+
+	_, err = repo.WithSnapshot("../2024-05-01")
+	assert.EqualError(t, err, `invalid repository snapshot "../2024-05-01"`)
+
+	_, err = RepoConfig{Name: "live"}.WithSnapshot("2024-05-01")
+	assert.EqualError(t, err, `repository "live" does not define a snapshot_url`)
+
+	_, err = RepoConfig{Name: "static", SnapshotURL: "https://example.com/repo"}.WithSnapshot("2024-05-01")
+	assert.EqualError(t, err, `snapshot_url "https://example.com/repo" of repository "static" does not contain {snapshot}`)
+}
+
+func TestApplySnapshot(t *testing.T) {
+	repos := []RepoConfig{
+		{Name: "baseos", SnapshotURL: "https://snapshots.example.com/{snapshot}/baseos/"},
+		{Name: "appstream", SnapshotURL: "https://snapshots.example.com/{snapshot}/appstream/"},
+	}
+	pinned, unpinned, err := ApplySnapshot(repos, "snap-42")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://snapshots.example.com/snap-42/baseos/"}, pinned[0].BaseURLs)
+	assert.Equal(t, []string{"https://snapshots.example.com/snap-42/appstream/"}, pinned[1].BaseURLs)
+	assert.Empty(t, unpinned)
+
+	// repositories without snapshot_url are not pinned
+	live := RepoConfig{Name: "live", BaseURLs: []string{"https://example.com/live/"}}
+	pinned, unpinned, err = ApplySnapshot(append(repos, live), "snap-42")
+	require.NoError(t, err)
+	assert.Equal(t, live, pinned[2])
+	assert.Equal(t, []string{"live"}, unpinned)
+
+	_, _, err = ApplySnapshot(repos, "../snap-42")
+	assert.EqualError(t, err, `invalid repository snapshot "../snap-42"`)
+}
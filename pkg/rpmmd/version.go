@@ -0,0 +1,133 @@
+package rpmmd
+
+import (
+	"strings"
+)
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// CompareVersions compares two version or release strings the same way as
+// rpmvercmp() in rpm. It returns -1 if a is older than b, 1 if a is newer
+// than b and 0 if both are equal.
+func CompareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		// separators are ignored, except for the tilde and the caret
+		for i < len(a) && !isDigit(a[i]) && !isAlpha(a[i]) && a[i] != '~' && a[i] != '^' {
+			i++
+		}
+		for j < len(b) && !isDigit(b[j]) && !isAlpha(b[j]) && b[j] != '~' && b[j] != '^' {
+			j++
+		}
+
+		// a tilde sorts before everything, even the end of the string
+		if (i < len(a) && a[i] == '~') || (j < len(b) && b[j] == '~') {
+			if i >= len(a) || a[i] != '~' {
+				return 1
+			}
+			if j >= len(b) || b[j] != '~' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		// a caret sorts after the end of the string, but before everything
+		// else
+		if (i < len(a) && a[i] == '^') || (j < len(b) && b[j] == '^') {
+			if i >= len(a) {
+				return -1
+			}
+			if j >= len(b) {
+				return 1
+			}
+			if a[i] != '^' {
+				return 1
+			}
+			if b[j] != '^' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		// compare the next segment, which is either numeric or alphabetic
+		// depending on the first character of a
+		isNum := isDigit(a[i])
+		isSegment := isAlpha
+		if isNum {
+			isSegment = isDigit
+		}
+		si, sj := i, j
+		for i < len(a) && isSegment(a[i]) {
+			i++
+		}
+		for j < len(b) && isSegment(b[j]) {
+			j++
+		}
+		segA, segB := a[si:i], b[sj:j]
+
+		// numeric segments are newer than alphabetic ones
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			// the longer number is the larger one
+			if len(segA) != len(segB) {
+				if len(segA) > len(segB) {
+					return 1
+				}
+				return -1
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+
+	if i >= len(a) && j >= len(b) {
+		return 0
+	}
+	// the version with characters left over is newer
+	if i >= len(a) {
+		return -1
+	}
+	return 1
+}
+
+// CompareEVR compares two epoch, version, release tuples. It returns -1 if
+// the first one is older, 1 if it is newer and 0 if both are equal.
+func CompareEVR(epochA uint, versionA, releaseA string, epochB uint, versionB, releaseB string) int {
+	if epochA != epochB {
+		if epochA > epochB {
+			return 1
+		}
+		return -1
+	}
+	if c := CompareVersions(versionA, versionB); c != 0 {
+		return c
+	}
+	return CompareVersions(releaseA, releaseB)
+}
@@ -3,8 +3,14 @@ package sbom
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 )
 
+// annotator is recorded as the creator of the annotations added to SPDX
+// documents
+const annotator = "Tool: osbuild-images"
+
 type StandardType uint64
 
 const (
@@ -68,3 +74,157 @@ func NewDocument(docType StandardType, doc json.RawMessage) (*Document, error) {
 		Document: doc,
 	}, nil
 }
+
+// AddComment appends a line to the document level comment of the SBOM.
+func (d *Document) AddComment(comment string) error {
+	if d.DocType != StandardTypeSpdx {
+		return fmt.Errorf("adding a comment is not supported for SBOM document type: %s", d.DocType)
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(d.Document, &doc); err != nil {
+		return fmt.Errorf("cannot parse SBOM document: %w", err)
+	}
+	var existing string
+	if raw, ok := doc["comment"]; ok {
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			return fmt.Errorf("cannot parse SBOM document comment: %w", err)
+		}
+		comment = existing + "\n" + comment
+	}
+	raw, err := json.Marshal(comment)
+	if err != nil {
+		return err
+	}
+	doc["comment"] = raw
+
+	newDoc, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	d.Document = newDoc
+	return nil
+}
+
+// AddAnnotation adds a document level annotation of type OTHER to the SBOM.
+// The annotation date is the creation date of the document, or the current
+// time if the document does not record one.
+func (d *Document) AddAnnotation(comment string) error {
+	if d.DocType != StandardTypeSpdx {
+		return fmt.Errorf("adding an annotation is not supported for SBOM document type: %s", d.DocType)
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(d.Document, &doc); err != nil {
+		return fmt.Errorf("cannot parse SBOM document: %w", err)
+	}
+	var creationInfo struct {
+		Created string `json:"created"`
+	}
+	if raw, ok := doc["creationInfo"]; ok {
+		if err := json.Unmarshal(raw, &creationInfo); err != nil {
+			return fmt.Errorf("cannot parse SBOM document creation info: %w", err)
+		}
+	}
+	date := creationInfo.Created
+	if date == "" {
+		date = time.Now().UTC().Format(time.RFC3339)
+	}
+	var annotations []json.RawMessage
+	if raw, ok := doc["annotations"]; ok {
+		if err := json.Unmarshal(raw, &annotations); err != nil {
+			return fmt.Errorf("cannot parse SBOM document annotations: %w", err)
+		}
+	}
+	annotation, err := json.Marshal(map[string]string{
+		"annotator":      annotator,
+		"annotationDate": date,
+		"annotationType": "OTHER",
+		"comment":        comment,
+	})
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(append(annotations, annotation))
+	if err != nil {
+		return err
+	}
+	doc["annotations"] = raw
+
+	newDoc, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	d.Document = newDoc
+	return nil
+}
+
+// MarkLocalPackage records in the SBOM that the packages with the given
+// "sha256:<hex>" checksum were supplied locally from source instead of by a
+// repository.
+func (d *Document) MarkLocalPackage(checksum, source string) error {
+	if d.DocType != StandardTypeSpdx {
+		return fmt.Errorf("marking a local package is not supported for SBOM document type: %s", d.DocType)
+	}
+	algo, value, ok := strings.Cut(checksum, ":")
+	if !ok || algo != "sha256" {
+		return fmt.Errorf("unsupported local package checksum %q", checksum)
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(d.Document, &doc); err != nil {
+		return fmt.Errorf("cannot parse SBOM document: %w", err)
+	}
+	var pkgs []map[string]json.RawMessage
+	if raw, ok := doc["packages"]; ok {
+		if err := json.Unmarshal(raw, &pkgs); err != nil {
+			return fmt.Errorf("cannot parse SBOM document packages: %w", err)
+		}
+	}
+
+	downloadLocation := "NOASSERTION"
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		downloadLocation = source
+	}
+	for _, pkg := range pkgs {
+		var checksums []struct {
+			Algorithm     string `json:"algorithm"`
+			ChecksumValue string `json:"checksumValue"`
+		}
+		if raw, ok := pkg["checksums"]; ok {
+			if err := json.Unmarshal(raw, &checksums); err != nil {
+				return fmt.Errorf("cannot parse SBOM document package checksums: %w", err)
+			}
+		}
+		matches := false
+		for _, c := range checksums {
+			if strings.EqualFold(c.Algorithm, algo) && c.ChecksumValue == value {
+				matches = true
+			}
+		}
+		if !matches {
+			continue
+		}
+		var err error
+		if pkg["downloadLocation"], err = json.Marshal(downloadLocation); err != nil {
+			return err
+		}
+		if pkg["comment"], err = json.Marshal("locally supplied package: " + source); err != nil {
+			return err
+		}
+	}
+
+	if len(pkgs) > 0 {
+		raw, err := json.Marshal(pkgs)
+		if err != nil {
+			return err
+		}
+		doc["packages"] = raw
+	}
+	newDoc, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	d.Document = newDoc
+	return nil
+}
@@ -84,3 +84,63 @@ func TestStandardTypeJSONMarhsall(t *testing.T) {
 		})
 	}
 }
+
+func TestDocumentAddComment(t *testing.T) {
+	doc, err := NewDocument(StandardTypeSpdx, json.RawMessage(`{"spdxVersion":"SPDX-2.3"}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, doc.AddComment("first"))
+	assert.JSONEq(t, `{"spdxVersion":"SPDX-2.3","comment":"first"}`, string(doc.Document))
+
+	assert.NoError(t, doc.AddComment("second"))
+	assert.JSONEq(t, `{"spdxVersion":"SPDX-2.3","comment":"first\nsecond"}`, string(doc.Document))
+
+	doc.Document = json.RawMessage(`[]`)
+	assert.ErrorContains(t, doc.AddComment("third"), "cannot parse SBOM document")
+
+	doc.DocType = StandardTypeNone
+	assert.EqualError(t, doc.AddComment("third"), "adding a comment is not supported for SBOM document type: none")
+}
+
+func TestDocumentAddAnnotation(t *testing.T) {
+	doc, err := NewDocument(StandardTypeSpdx, json.RawMessage(`{"creationInfo":{"created":"2024-05-01T00:00:00Z"}}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, doc.AddAnnotation("first"))
+	assert.NoError(t, doc.AddAnnotation("second"))
+	assert.JSONEq(t, `{"creationInfo":{"created":"2024-05-01T00:00:00Z"},"annotations":[
+		{"annotator":"Tool: osbuild-images","annotationDate":"2024-05-01T00:00:00Z","annotationType":"OTHER","comment":"first"},
+		{"annotator":"Tool: osbuild-images","annotationDate":"2024-05-01T00:00:00Z","annotationType":"OTHER","comment":"second"}
+	]}`, string(doc.Document))
+
+	doc.Document = json.RawMessage(`[]`)
+	assert.ErrorContains(t, doc.AddAnnotation("third"), "cannot parse SBOM document")
+
+	doc.DocType = StandardTypeNone
+	assert.EqualError(t, doc.AddAnnotation("third"), "adding an annotation is not supported for SBOM document type: none")
+}
+
+func TestDocumentMarkLocalPackage(t *testing.T) {
+	doc, err := NewDocument(StandardTypeSpdx, json.RawMessage(`{"packages":[
+		{"name":"local","downloadLocation":"NOASSERTION","checksums":[{"algorithm":"SHA256","checksumValue":"aaaa"}]},
+		{"name":"remote","downloadLocation":"NOASSERTION","checksums":[{"algorithm":"SHA256","checksumValue":"bbbb"}]}
+	]}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, doc.MarkLocalPackage("sha256:aaaa", "https://example.com/local.rpm"))
+	assert.JSONEq(t, `{"packages":[
+		{"name":"local","downloadLocation":"https://example.com/local.rpm","comment":"locally supplied package: https://example.com/local.rpm","checksums":[{"algorithm":"SHA256","checksumValue":"aaaa"}]},
+		{"name":"remote","downloadLocation":"NOASSERTION","checksums":[{"algorithm":"SHA256","checksumValue":"bbbb"}]}
+	]}`, string(doc.Document))
+
+	assert.NoError(t, doc.MarkLocalPackage("sha256:bbbb", "/srv/remote.rpm"))
+	assert.JSONEq(t, `{"packages":[
+		{"name":"local","downloadLocation":"https://example.com/local.rpm","comment":"locally supplied package: https://example.com/local.rpm","checksums":[{"algorithm":"SHA256","checksumValue":"aaaa"}]},
+		{"name":"remote","downloadLocation":"NOASSERTION","comment":"locally supplied package: /srv/remote.rpm","checksums":[{"algorithm":"SHA256","checksumValue":"bbbb"}]}
+	]}`, string(doc.Document))
+
+	assert.EqualError(t, doc.MarkLocalPackage("md5:aaaa", "/srv/local.rpm"), `unsupported local package checksum "md5:aaaa"`)
+
+	doc.DocType = StandardTypeNone
+	assert.EqualError(t, doc.MarkLocalPackage("sha256:aaaa", "/srv/local.rpm"), "marking a local package is not supported for SBOM document type: none")
+}
@@ -0,0 +1,99 @@
+#!/usr/bin/env python3
+"""
+Write the summary, refs and commit objects of the test ostree repository.
+
+The objects are serialized by GLib's GVariant implementation, which is what
+ostree uses to write them, so the data does not depend on the GVariant code
+of pkg/ostree. Only libglib-2.0 is required.
+"""
+import ctypes
+import ctypes.util
+import hashlib
+import os
+
+GLIB = ctypes.CDLL(ctypes.util.find_library("glib-2.0") or "libglib-2.0.so.0")
+GLIB.g_variant_type_new.restype = ctypes.c_void_p
+GLIB.g_variant_type_new.argtypes = [ctypes.c_char_p]
+GLIB.g_variant_parse.restype = ctypes.c_void_p
+GLIB.g_variant_parse.argtypes = [ctypes.c_void_p, ctypes.c_char_p, ctypes.c_char_p, ctypes.c_void_p, ctypes.c_void_p]
+GLIB.g_variant_get_size.restype = ctypes.c_size_t
+GLIB.g_variant_get_size.argtypes = [ctypes.c_void_p]
+GLIB.g_variant_get_data.restype = ctypes.c_void_p
+GLIB.g_variant_get_data.argtypes = [ctypes.c_void_p]
+
+REPO = os.path.dirname(os.path.abspath(__file__))
+REF = "fedora/41/x86_64/iot"
+TESTING_REF = "fedora/41/x86_64/testing/iot"
+
+
+def serialize(typ, text):
+    value = GLIB.g_variant_parse(GLIB.g_variant_type_new(typ.encode()), text.encode(), None, None, None)
+    if not value:
+        raise ValueError(f"cannot parse {text}")
+    return ctypes.string_at(GLIB.g_variant_get_data(value), GLIB.g_variant_get_size(value))
+
+
+def checksum_bytes(checksum):
+    if not checksum:
+        return "@ay []"
+    return "[" + ", ".join(f"byte 0x{b:02x}" for b in bytes.fromhex(checksum)) + "]"
+
+
+def big_endian(timestamp):
+    # ostree stores the timestamps in big endian
+    return int.from_bytes(timestamp.to_bytes(8, "big"), "little")
+
+
+def write(path, data):
+    path = os.path.join(REPO, path)
+    os.makedirs(os.path.dirname(path), exist_ok=True)
+    with open(path, "wb") as f:
+        f.write(data)
+
+
+def commit(parent, subject, body, timestamp, version, kernel):
+    metadata = (
+        f"{{'version': <'{version}'>, "
+        f"'ostree.bootable': <true>, "
+        f"'ostree.linux': <'{kernel}'>, "
+        f"'ostree.ref-binding': <['{REF}']>, "
+        f"'rpmostree.inputhash': <'{hashlib.sha256(version.encode()).hexdigest()}'>}}"
+    )
+    root_contents = hashlib.sha256(b"dirtree " + version.encode()).hexdigest()
+    root_meta = hashlib.sha256(b"dirmeta").hexdigest()
+    data = serialize("(a{sv}aya(say)sstayay)", (
+        f"({metadata}, {checksum_bytes(parent)}, @a(say) [], '{subject}', '{body}', "
+        f"uint64 {big_endian(timestamp)}, {checksum_bytes(root_contents)}, {checksum_bytes(root_meta)})"
+    ))
+    checksum = hashlib.sha256(data).hexdigest()
+    write(f"objects/{checksum[:2]}/{checksum[2:]}.commit", data)
+    return checksum, data, timestamp
+
+
+def main():
+    first = commit("", "41.20241101.0", "", 1730448000, "41.20241101.0", "6.11.5-300.fc41.x86_64")
+    second = commit(first[0], "41.20241102.0", "Rebuilt with updated packages", 1730534400, "41.20241102.0",
+                    "6.11.5-300.fc41.x86_64")
+    third = commit(second[0], "41.20241103.0", "", 1730620800, "41.20241103.0", "6.11.6-300.fc41.x86_64")
+
+    refs = {REF: third, TESTING_REF: second}
+    entries = []
+    for ref in sorted(refs):
+        checksum, data, timestamp = refs[ref]
+        write(f"refs/heads/{ref}", checksum.encode() + b"\n")
+        entries.append(
+            f"('{ref}', (uint64 {len(data)}, {checksum_bytes(checksum)}, "
+            f"{{'ostree.commit.timestamp': <uint64 {big_endian(timestamp)}>}}))"
+        )
+    write("summary", serialize("(a(s(taya{sv}))a{sv})", (
+        f"([{', '.join(entries)}], "
+        f"{{'ostree.summary.last-modified': <uint64 {big_endian(third[2])}>, "
+        f"'ostree.summary.mode': <'archive-z2'>, "
+        f"'ostree.summary.tombstone-commits': <false>, "
+        f"'ostree.summary.indexed-deltas': <true>}})"
+    )))
+    write("config", b"[core]\nrepo_version=1\nmode=archive-z2\n")
+
+
+if __name__ == "__main__":
+    main()